@@ -0,0 +1,308 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Lock file timings of PLUGIN_AGGREGATE_FILE. A lock older than
+// aggregateStaleLock was left behind by a crashed leg and is broken.
+const (
+	aggregateLockWait  = 30 * time.Second
+	aggregateStaleLock = time.Minute
+)
+
+// aggregatePollInterval is how often a leg waiting for PLUGIN_AGGREGATE_TIMEOUT
+// checks for its siblings; tests shorten it
+var aggregatePollInterval = 2 * time.Second
+
+// aggregateLeg is the result of one matrix leg
+type aggregateLeg struct {
+	Matrix   string    `json:"matrix"`
+	Status   string    `json:"status"`
+	Duration string    `json:"duration,omitempty"`
+	Reported time.Time `json:"reported"`
+}
+
+// aggregateState is the content of PLUGIN_AGGREGATE_FILE. Sent is set by the
+// leg that sends the combined notification, so that no other leg does.
+type aggregateState struct {
+	Pipeline string         `json:"pipeline"`
+	Legs     []aggregateLeg `json:"legs"`
+	Sent     bool           `json:"sent"`
+}
+
+// aggregatedLegs is set by main when this leg sends the combined
+// notification, and is empty otherwise
+var aggregatedLegs []aggregateLeg
+
+// aggregateMissing is the number of legs that did not report before
+// PLUGIN_AGGREGATE_TIMEOUT
+var aggregateMissing int
+
+// checkAggregateSettings reports invalid aggregation settings
+func checkAggregateSettings(getenv func(string) string) []error {
+	if getenv("PLUGIN_AGGREGATE_FILE") == "" {
+		return nil
+	}
+	var problems []error
+	if total, err := strconv.Atoi(getenv("PLUGIN_AGGREGATE_TOTAL")); err != nil || total < 1 {
+		problems = append(problems, fmt.Errorf("PLUGIN_AGGREGATE_TOTAL must be the number of matrix legs, got %q", getenv("PLUGIN_AGGREGATE_TOTAL")))
+	}
+	if value := getenv("PLUGIN_AGGREGATE_TIMEOUT"); value != "" {
+		if timeout, err := time.ParseDuration(value); err != nil || timeout < 0 {
+			problems = append(problems, fmt.Errorf("PLUGIN_AGGREGATE_TIMEOUT must be a duration such as 10m, got %q", value))
+		}
+	}
+	return problems
+}
+
+// isAggregated reports whether this run sends the combined notification of
+// all matrix legs
+func isAggregated() bool {
+	return len(aggregatedLegs) > 0
+}
+
+// legMatrix returns the matrix of this leg for display, or "" for the
+// combined notification, which lists every leg instead
+func legMatrix() string {
+	if isAggregated() {
+		return ""
+	}
+	return matrixString()
+}
+
+// aggregateStatus is the overall status of the legs: failed if any leg
+// failed, successful if all succeeded and otherwise the first other status
+func aggregateStatus(legs []aggregateLeg) string {
+	status := "success"
+	for _, leg := range legs {
+		switch {
+		case isFailedStatus(leg.Status):
+			return "failure"
+		case status == "success" && leg.Status != "success" && leg.Status != "":
+			status = leg.Status
+		}
+	}
+	return status
+}
+
+// aggregateLegs records the result of this leg in PLUGIN_AGGREGATE_FILE and
+// reports whether this leg sends the notification. Without aggregation every
+// leg sends its own; with it only the leg that completes the set sends, and
+// aggregatedLegs holds the legs to list. With PLUGIN_AGGREGATE_TIMEOUT the leg
+// waits for the missing siblings; when they never report, the leg that
+// reported last sends the legs present.
+func aggregateLegs() (bool, error) {
+	aggregatedLegs, aggregateMissing = nil, 0
+	path := getEnvOrDefault("PLUGIN_AGGREGATE_FILE", "")
+	if path == "" {
+		return true, nil
+	}
+	total, _ := strconv.Atoi(getEnvOrDefault("PLUGIN_AGGREGATE_TOTAL", ""))
+	timeout, _ := time.ParseDuration(getEnvOrDefault("PLUGIN_AGGREGATE_TIMEOUT", "0"))
+
+	matrix := matrixString()
+	if matrix == "" {
+		logWarn("PLUGIN_AGGREGATE_FILE needs PLUGIN_MATRIX or PLUGIN_MATRIX_VARS to tell the legs apart")
+	}
+	leg := aggregateLeg{Matrix: matrix, Status: getBuildStatus(), Duration: getBuildDuration(), Reported: timeNow()}
+
+	legs, missing, err := recordLeg(path, leg, total)
+	switch {
+	case err != nil:
+		return false, err
+	case legs != nil:
+		aggregatedLegs = legs
+		return true, nil
+	case missing <= 0:
+		logInfo("the combined notification was already sent", "pipeline", getPipelineNumber())
+		return false, nil
+	}
+	logInfo(fmt.Sprintf("waiting for %d more legs", missing), "pipeline", getPipelineNumber())
+	if timeout == 0 {
+		return false, nil
+	}
+
+	deadline := timeNow().Add(timeout)
+	for {
+		time.Sleep(aggregatePollInterval)
+		expired := !timeNow().Before(deadline)
+		done := false
+		err := updateAggregateState(path, func(state *aggregateState) {
+			missing = total - len(state.Legs)
+			switch {
+			case state.Sent:
+				done = true
+			case expired && isLastLeg(state.Legs, matrix):
+				state.Sent = true
+				legs = state.Legs
+			case expired:
+				done = true
+			}
+		})
+		if err != nil || done {
+			return false, err
+		}
+		if legs != nil {
+			aggregatedLegs, aggregateMissing = legs, missing
+			logWarn(fmt.Sprintf("%d legs did not report within PLUGIN_AGGREGATE_TIMEOUT, sending the others", missing))
+			return true, nil
+		}
+	}
+}
+
+// recordLeg adds leg to the file at path, replacing an earlier report of the
+// same leg. It returns the legs when leg completes the set of total and is
+// the one to send, and the number of legs still missing.
+func recordLeg(path string, leg aggregateLeg, total int) ([]aggregateLeg, int, error) {
+	var legs []aggregateLeg
+	missing := 0
+	err := updateAggregateState(path, func(state *aggregateState) {
+		state.Legs = append(removeLeg(state.Legs, leg.Matrix), leg)
+		missing = total - len(state.Legs)
+		if missing <= 0 && !state.Sent {
+			state.Sent = true
+			legs = state.Legs
+		}
+	})
+	return legs, missing, err
+}
+
+// removeLeg drops an earlier report of a leg, such as one from a restart
+func removeLeg(legs []aggregateLeg, matrix string) []aggregateLeg {
+	kept := []aggregateLeg{}
+	for _, leg := range legs {
+		if leg.Matrix != matrix {
+			kept = append(kept, leg)
+		}
+	}
+	return kept
+}
+
+// isLastLeg reports whether matrix is the leg that reported last
+func isLastLeg(legs []aggregateLeg, matrix string) bool {
+	return len(legs) > 0 && legs[len(legs)-1].Matrix == matrix
+}
+
+// updateAggregateState changes PLUGIN_AGGREGATE_FILE under its lock. Entries
+// of other pipelines, and unreadable files, are replaced by an empty state
+// for the current pipeline.
+func updateAggregateState(path string, update func(*aggregateState)) error {
+	unlock, err := lockFile(path + ".lock")
+	if err != nil {
+		return fmt.Errorf("cannot lock PLUGIN_AGGREGATE_FILE: %w", err)
+	}
+	defer unlock()
+
+	pipeline := getPipelineNumber()
+	var state aggregateState
+	if data, err := os.ReadFile(path); err == nil {
+		if json.Unmarshal(data, &state) != nil {
+			logWarn("ignoring corrupt PLUGIN_AGGREGATE_FILE", "path", path)
+			state = aggregateState{}
+		}
+	}
+	if state.Pipeline != pipeline {
+		state = aggregateState{Pipeline: pipeline}
+	}
+
+	update(&state)
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("cannot write PLUGIN_AGGREGATE_FILE: %w", err)
+	}
+	return nil
+}
+
+// lockFile takes an advisory lock by creating path exclusively, which works
+// on shared volumes where flock may not. It waits for aggregateLockWait and
+// breaks locks older than aggregateStaleLock.
+func lockFile(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(aggregateLockWait)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > aggregateStaleLock {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s is held by another process", path)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// createAggregateElements lists every leg of a combined notification with its
+// status icon
+func createAggregateElements() []map[string]any {
+	if !isAggregated() {
+		return nil
+	}
+	content := fmt.Sprintf("**%s:**", tr("Matrix"))
+	for _, line := range aggregateLines() {
+		content += "\n" + escapeMarkdown(line)
+	}
+	return []map[string]any{
+		{
+			"tag": "hr",
+		},
+		{
+			"tag": "div",
+			"text": map[string]any{
+				"content": content,
+				"tag":     "lark_md",
+			},
+		},
+	}
+}
+
+// createAggregateText lists the legs in text messages
+func createAggregateText() string {
+	if !isAggregated() {
+		return ""
+	}
+	text := "\n" + withIcon("🧩", tr("Matrix")+":\n")
+	for _, line := range aggregateLines() {
+		text += "• " + escapeText(line) + "\n"
+	}
+	return text
+}
+
+// aggregateLines renders each leg as "✅ go=1.22 (3m 2s)", followed by the
+// number of legs that never reported
+func aggregateLines() []string {
+	var lines []string
+	for _, leg := range aggregatedLegs {
+		line := iconText(classifyStatus(leg.Status).Icon, leg.Matrix)
+		if leg.Duration != "" {
+			line += " (" + leg.Duration + ")"
+		}
+		lines = append(lines, strings.TrimSpace(line))
+	}
+	if aggregateMissing > 0 {
+		lines = append(lines, withIcon("⏳", fmt.Sprintf("%d legs did not report", aggregateMissing)))
+	}
+	return lines
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRecordLeg_ConcurrentLegs(t *testing.T) {
+	setEnvFixture(t, map[string]string{"CI_PIPELINE_NUMBER": "42"})
+	path := filepath.Join(t.TempDir(), "aggregate.json")
+
+	const total = 6
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	senders := 0
+	var sent []aggregateLeg
+	for i := 0; i < total; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			leg := aggregateLeg{Matrix: fmt.Sprintf("leg=%d", i), Status: "success"}
+			legs, _, err := recordLeg(path, leg, total)
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if legs != nil {
+				mu.Lock()
+				senders++
+				sent = legs
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if senders != 1 {
+		t.Fatalf("Expected exactly one leg to send, got %d", senders)
+	}
+	if len(sent) != total {
+		t.Errorf("Expected the sender to see all %d legs, got %d", total, len(sent))
+	}
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("Expected the lock to be released, got %v", err)
+	}
+}
+
+func TestRecordLeg_IgnoresOtherPipelines(t *testing.T) {
+	setEnvFixture(t, map[string]string{"CI_PIPELINE_NUMBER": "42"})
+	path := filepath.Join(t.TempDir(), "aggregate.json")
+	stale, _ := json.Marshal(aggregateState{Pipeline: "41", Legs: []aggregateLeg{{Matrix: "leg=1"}, {Matrix: "leg=2"}}})
+	os.WriteFile(path, stale, 0600)
+
+	legs, missing, err := recordLeg(path, aggregateLeg{Matrix: "leg=1", Status: "success"}, 2)
+	if err != nil || legs != nil || missing != 1 {
+		t.Errorf("Expected the stale legs to be ignored, got %v, %d missing, %v", legs, missing, err)
+	}
+}
+
+func TestRecordLeg_CorruptFile(t *testing.T) {
+	setEnvFixture(t, map[string]string{"CI_PIPELINE_NUMBER": "42"})
+	path := filepath.Join(t.TempDir(), "aggregate.json")
+	os.WriteFile(path, []byte("{"), 0600)
+
+	var legs []aggregateLeg
+	captureStdout(t, func() { legs, _, _ = recordLeg(path, aggregateLeg{Matrix: "leg=1", Status: "success"}, 1) })
+	if len(legs) != 1 {
+		t.Errorf("Expected a corrupt file to count as empty, got %v", legs)
+	}
+}
+
+func TestAggregateStatus(t *testing.T) {
+	tests := []struct {
+		statuses []string
+		expected string
+	}{
+		{[]string{"success", "success"}, "success"},
+		{[]string{"success", "failure", "canceled"}, "failure"},
+		{[]string{"success", "canceled"}, "canceled"},
+		{[]string{"killed", "success"}, "failure"},
+	}
+	for _, tc := range tests {
+		var legs []aggregateLeg
+		for _, status := range tc.statuses {
+			legs = append(legs, aggregateLeg{Status: status})
+		}
+		if status := aggregateStatus(legs); status != tc.expected {
+			t.Errorf("Expected %v to be %s, got %s", tc.statuses, tc.expected, status)
+		}
+	}
+}
+
+func TestMain_AggregatesMatrixLegs(t *testing.T) {
+	var bodies []string
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		w.Write([]byte(`{"code": 0}`))
+	}))
+	defer testServer.Close()
+
+	originalOsExit := osExit
+	defer func() { osExit = originalOsExit }()
+	osExit = func(code int) {}
+	t.Cleanup(func() { aggregatedLegs, aggregateMissing = nil, 0 })
+
+	env := map[string]string{
+		"PLUGIN_WEBHOOK_URL":     testServer.URL,
+		"PLUGIN_AGGREGATE_FILE":  filepath.Join(t.TempDir(), "aggregate.json"),
+		"PLUGIN_AGGREGATE_TOTAL": "2",
+		"PLUGIN_MATRIX_IN_TITLE": "true",
+		"CI_PIPELINE_NUMBER":     "42",
+		"CI_REPO_NAME":           "backend",
+	}
+
+	setEnvFixture(t, env)
+	setEnvFixture(t, map[string]string{"PLUGIN_MATRIX": "go=1.22", "DRONE_BUILD_STATUS": "failure"})
+	output := captureStdout(t, main)
+	if len(bodies) != 0 || !strings.Contains(output, "waiting for 1 more legs") {
+		t.Fatalf("Expected the first leg to wait, got %d requests and:\n%s", len(bodies), output)
+	}
+
+	setEnvFixture(t, env)
+	setEnvFixture(t, map[string]string{"PLUGIN_MATRIX": "go=1.23", "DRONE_BUILD_STATUS": "success"})
+	captureStdout(t, main)
+	if len(bodies) != 1 {
+		t.Fatalf("Expected the last leg to send one combined card, got %d requests", len(bodies))
+	}
+
+	var message struct {
+		Card struct {
+			Header struct {
+				Title    struct{ Content string } `json:"title"`
+				Template string                   `json:"template"`
+			} `json:"header"`
+		} `json:"card"`
+	}
+	json.Unmarshal([]byte(bodies[0]), &message)
+	if message.Card.Header.Template != "red" || message.Card.Header.Title.Content != "backend #42 - 🚨 Pipeline Failed" {
+		t.Errorf("Expected a failed header without leg values, got %+v", message.Card.Header)
+	}
+	for _, line := range []string{`🚨 go=1.22`, `✅ go=1.23`} {
+		if !strings.Contains(bodies[0], line) {
+			t.Errorf("Expected the card to list '%s', got %s", line, bodies[0])
+		}
+	}
+}
+
+func TestAggregateLegs_Timeout(t *testing.T) {
+	originalInterval := aggregatePollInterval
+	defer func() { aggregatePollInterval = originalInterval }()
+	aggregatePollInterval = time.Millisecond
+	t.Cleanup(func() { aggregatedLegs, aggregateMissing = nil, 0 })
+
+	// Every reading of the clock is a minute later
+	now := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	originalTimeNow := timeNow
+	defer func() { timeNow = originalTimeNow }()
+	timeNow = func() time.Time {
+		now = now.Add(time.Minute)
+		return now
+	}
+
+	path := filepath.Join(t.TempDir(), "aggregate.json")
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_AGGREGATE_FILE":    path,
+		"PLUGIN_AGGREGATE_TOTAL":   "3",
+		"PLUGIN_AGGREGATE_TIMEOUT": "5m",
+		"PLUGIN_MATRIX":            "go=1.22",
+		"PLUGIN_STATUS":            "success",
+		"CI_PIPELINE_NUMBER":       "42",
+	})
+	recordLeg(path, aggregateLeg{Matrix: "go=1.21", Status: "success"}, 3)
+
+	var send bool
+	output := captureStdout(t, func() { send, _ = aggregateLegs() })
+	if !send || len(aggregatedLegs) != 2 || aggregateMissing != 1 {
+		t.Fatalf("Expected the last leg to send a partial card, got send=%v, %v, %d missing", send, aggregatedLegs, aggregateMissing)
+	}
+	if !strings.Contains(output, "1 legs did not report within PLUGIN_AGGREGATE_TIMEOUT") {
+		t.Errorf("Expected a warning about the missing leg, got:\n%s", output)
+	}
+	if text := createAggregateText(); !strings.Contains(text, "⏳ 1 legs did not report") {
+		t.Errorf("Expected the missing legs in the message, got '%s'", text)
+	}
+
+	// The earlier leg finds the notification sent
+	setEnvFixture(t, map[string]string{"PLUGIN_MATRIX": "go=1.21"})
+	captureStdout(t, func() { send, _ = aggregateLegs() })
+	if send {
+		t.Errorf("Expected only one leg to send")
+	}
+}
+
+func TestCheckAggregateSettings(t *testing.T) {
+	problems := checkAggregateSettings(mapGetenv(map[string]string{
+		"PLUGIN_AGGREGATE_FILE":    "/shared/aggregate.json",
+		"PLUGIN_AGGREGATE_TIMEOUT": "soon",
+	}))
+	if len(problems) != 2 {
+		t.Errorf("Expected the missing total and invalid timeout to be reported, got %v", problems)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// authorOpenID is the commit author's Lark open_id, resolved by main when
+// PLUGIN_MENTION_AUTHOR is enabled
+var authorOpenID string
+
+// lookupOpenIDByEmail resolves an email address to the open_id of a user in the app's tenant
+func lookupOpenIDByEmail(ctx context.Context, appID, appSecret, email string) (string, error) {
+	var openID string
+	err := withTenantAccessToken(ctx, appID, appSecret, func(token string) error {
+		var data struct {
+			UserList []struct {
+				Email  string `json:"email"`
+				UserID string `json:"user_id"`
+			} `json:"user_list"`
+		}
+		body := map[string][]string{"emails": {email}}
+		if err := callOpenAPI(ctx, http.MethodPost, "/open-apis/contact/v3/users/batch_get_id?user_id_type=open_id", token, body, &data); err != nil {
+			return err
+		}
+
+		for _, user := range data.UserList {
+			if strings.EqualFold(user.Email, email) && user.UserID != "" {
+				openID = user.UserID
+				return nil
+			}
+		}
+		return fmt.Errorf("no Lark user with email %s", email)
+	})
+	return openID, err
+}
+
+// resolveAuthorOpenID looks up the commit author when PLUGIN_MENTION_AUTHOR is
+// enabled and the build is one to mention people for. Scheduled pipelines show
+// the cron job instead of the author. Failures only warn, the author is then
+// shown by name.
+func resolveAuthorOpenID(ctx context.Context) string {
+	if getEnvOrDefault("PLUGIN_MENTION_AUTHOR", "false") != "true" || !mentionStatusMatches(getBuildStatus()) || cronJob() != "" {
+		return ""
+	}
+
+	appID := getEnvOrDefault("PLUGIN_APP_ID", "")
+	appSecret := getEnvOrDefault("PLUGIN_APP_SECRET", "")
+	if appID == "" || appSecret == "" {
+		logWarn("PLUGIN_MENTION_AUTHOR needs PLUGIN_APP_ID and PLUGIN_APP_SECRET, not mentioning the author")
+		return ""
+	}
+
+	email := getEnvOrDefault("CI_COMMIT_AUTHOR_EMAIL", "")
+	if email == "" {
+		logWarn("CI_COMMIT_AUTHOR_EMAIL is not set, not mentioning the author")
+		return ""
+	}
+
+	openID, err := lookupOpenIDByEmail(ctx, appID, appSecret, email)
+	if err != nil {
+		logWarn(fmt.Sprintf("could not resolve the commit author in Lark: %v", err))
+		return ""
+	}
+	return openID
+}
+
+// authorMentionValue appends the author's at-tag to the author name, in
+// lark_md or text message syntax
+func authorMentionValue(name string, markdown bool) string {
+	if authorOpenID == "" {
+		return name
+	}
+	if markdown {
+		return fmt.Sprintf("%s <at id=%s></at>", name, authorOpenID)
+	}
+	return fmt.Sprintf(`%s <at user_id="%s"></at>`, name, authorOpenID)
+}
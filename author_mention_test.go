@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setupContactServer mimics the token and batch_get_id endpoints. Emails in
+// users resolve to their open_id, lookupCode makes the lookup fail.
+func setupContactServer(t *testing.T, users map[string]string, lookupCode int, delay time.Duration) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/open-apis/auth/v3/tenant_access_token/internal":
+			json.NewEncoder(w).Encode(map[string]any{"code": 0, "tenant_access_token": "t-1", "expire": 7200})
+		case "/open-apis/contact/v3/users/batch_get_id":
+			if r.Header.Get("Authorization") != "Bearer t-1" || r.URL.Query().Get("user_id_type") != "open_id" {
+				t.Errorf("Unexpected lookup request %s %v", r.URL, r.Header)
+			}
+			if lookupCode != 0 {
+				json.NewEncoder(w).Encode(map[string]any{"code": lookupCode, "msg": "no permission"})
+				return
+			}
+
+			var body struct {
+				Emails []string `json:"emails"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			var userList []map[string]string
+			for _, email := range body.Emails {
+				user := map[string]string{"email": email}
+				if openID, ok := users[email]; ok {
+					user["user_id"] = openID
+				}
+				userList = append(userList, user)
+			}
+			json.NewEncoder(w).Encode(map[string]any{"code": 0, "data": map[string]any{"user_list": userList}})
+		default:
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	originalAPIClient := openAPIClient
+	t.Cleanup(func() {
+		openAPIClient = originalAPIClient
+		authorOpenID = ""
+	})
+
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_API_BASE_URL":   server.URL,
+		"PLUGIN_APP_ID":         "cli_test",
+		"PLUGIN_APP_SECRET":     "app_secret",
+		"PLUGIN_MENTION_AUTHOR": "true",
+		"PLUGIN_STATUS":         "failure",
+		"CI_COMMIT_AUTHOR":      "octocat",
+	})
+}
+
+func TestResolveAuthorOpenID(t *testing.T) {
+	setupContactServer(t, map[string]string{"octocat@example.com": "ou_octocat"}, 0, 0)
+	setEnvFixture(t, map[string]string{"CI_COMMIT_AUTHOR_EMAIL": "octocat@example.com"})
+
+	authorOpenID = resolveAuthorOpenID(context.Background())
+	if authorOpenID != "ou_octocat" {
+		t.Fatalf("Expected 'ou_octocat', got '%s'", authorOpenID)
+	}
+
+	card := createLarkCard(Config{Status: "failure"}, "v1.0.0")["card"].(map[string]any)
+	metadata := card["elements"].([]map[string]any)[0]["text"].(map[string]any)["content"].(string)
+	if !strings.Contains(metadata, "**Author:** octocat <at id=ou_octocat></at>") {
+		t.Errorf("Expected author mention in card, got '%s'", metadata)
+	}
+
+	text := createLarkTextMessage(Config{Status: "failure"}, "v1.0.0")["content"].(map[string]any)["text"].(string)
+	if !strings.Contains(text, `👤 Author: octocat <at user_id="ou_octocat"></at>`) {
+		t.Errorf("Expected author mention in text, got '%s'", text)
+	}
+}
+
+func TestResolveAuthorOpenID_FallsBack(t *testing.T) {
+	tests := []struct {
+		name       string
+		email      string
+		lookupCode int
+		delay      time.Duration
+	}{
+		{"User not in tenant", "stranger@example.com", 0, 0},
+		{"API error", "octocat@example.com", 41050, 0},
+		{"Timeout", "octocat@example.com", 0, 200 * time.Millisecond},
+		{"No email", "", 0, 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setupContactServer(t, map[string]string{"octocat@example.com": "ou_octocat"}, tc.lookupCode, tc.delay)
+			setEnvFixture(t, map[string]string{"CI_COMMIT_AUTHOR_EMAIL": tc.email})
+			openAPIClient = &http.Client{Timeout: 50 * time.Millisecond}
+
+			output := captureStdout(t, func() { authorOpenID = resolveAuthorOpenID(context.Background()) })
+			if authorOpenID != "" {
+				t.Errorf("Expected no open_id, got '%s'", authorOpenID)
+			}
+			if !strings.Contains(output, "Warning:") {
+				t.Errorf("Expected a warning, got %q", output)
+			}
+
+			card := createLarkCard(Config{Status: "failure"}, "v1.0.0")["card"].(map[string]any)
+			metadata := card["elements"].([]map[string]any)[0]["text"].(map[string]any)["content"].(string)
+			if !strings.Contains(metadata, "**Author:** octocat\n") {
+				t.Errorf("Expected the plain author name, got '%s'", metadata)
+			}
+		})
+	}
+}
+
+func TestResolveAuthorOpenID_SkippedOnSuccess(t *testing.T) {
+	setupContactServer(t, map[string]string{"octocat@example.com": "ou_octocat"}, 0, 0)
+	setEnvFixture(t, map[string]string{"CI_COMMIT_AUTHOR_EMAIL": "octocat@example.com", "PLUGIN_STATUS": "success"})
+
+	if openID := resolveAuthorOpenID(context.Background()); openID != "" {
+		t.Errorf("Expected no lookup for a successful build, got '%s'", openID)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import "ci-lark-notification/pkg/lark"
+
+// buildInfo maps the environment to the lark.BuildInfo of the card. The
+// header title and the sections are composed by the plugin, as selected by
+// PLUGIN_COMPACT, PLUGIN_DETAIL and PLUGIN_SECTIONS, in currentLocale.
+func buildInfo(config Config, projectVersion string) lark.BuildInfo {
+	style := getStatusStyle(config.Status)
+	info := lark.BuildInfo{
+		Project:       getEnvOrDefault("CI_REPO", ""),
+		Branch:        getEnvOrDefault("CI_COMMIT_BRANCH", ""),
+		Author:        getEnvOrDefault("CI_COMMIT_AUTHOR", ""),
+		Version:       projectVersion,
+		Status:        config.Status,
+		CommitMessage: getEnvOrDefault("CI_COMMIT_MESSAGE", ""),
+		PipelineURL:   getEnvOrDefault("CI_PIPELINE_URL", ""),
+		CommitURL:     getEnvOrDefault("CI_PIPELINE_FORGE_URL", ""),
+		Color:         style.Color,
+	}
+
+	var elements []map[string]any
+	switch {
+	case config.Compact:
+		info.Title, elements = compactCardContent(config, projectVersion, style.Icon, tr(style.Text))
+	case isMinimalDetail(config.Status):
+		info.Title, elements = minimalCardContent(config, projectVersion, style.Icon, tr(style.Text))
+	default:
+		var header bool
+		info.Title, header, elements = composeCard(sectionInput{Config: config, ProjectVersion: projectVersion, Style: style})
+		info.NoHeader = !header
+	}
+
+	info.Elements = []lark.Element{}
+	for _, element := range elements {
+		info.Elements = append(info.Elements, element)
+	}
+	return info
+}
+
+// cardMessage returns the interactive message of a card, in the map form the
+// rest of the plugin works on
+func cardMessage(card *lark.Card) map[string]any {
+	elements := []map[string]any{}
+	for _, element := range card.Elements {
+		elements = append(elements, element)
+	}
+
+	content := map[string]any{"elements": elements}
+	if !card.NoHeader {
+		content["header"] = map[string]any{
+			"title": map[string]any{
+				"content": card.Title,
+				"tag":     "plain_text",
+			},
+			"template": card.Template,
+		}
+	}
+	return map[string]any{
+		"msg_type": "interactive",
+		"card":     content,
+	}
+}
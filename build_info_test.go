@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"ci-lark-notification/pkg/lark"
+)
+
+func TestBuildInfo(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"CI_REPO":               "octo/backend",
+		"CI_REPO_NAME":          "backend",
+		"CI_COMMIT_BRANCH":      "main",
+		"CI_COMMIT_AUTHOR":      "alice",
+		"CI_PIPELINE_URL":       "https://ci.example.com/1",
+		"CI_PIPELINE_FORGE_URL": "https://git.example.com/commit/1",
+	})
+
+	info := buildInfo(Config{Status: "failure"}, "v1.0.0")
+	if info.Project != "octo/backend" || info.Branch != "main" || info.Author != "alice" || info.Version != "v1.0.0" || info.Status != "failure" {
+		t.Errorf("Expected the build details from the environment, got %+v", info)
+	}
+	if info.PipelineURL != "https://ci.example.com/1" || info.CommitURL != "https://git.example.com/commit/1" {
+		t.Errorf("Expected the pipeline and commit URLs, got %+v", info)
+	}
+	if info.Color != "red" || info.NoHeader || len(info.Elements) == 0 {
+		t.Errorf("Expected the red header and the plugin's sections, got %+v", info)
+	}
+
+	// The card is the one NewBuildCard makes of the info
+	originalTimeNow := timeNow
+	defer func() { timeNow = originalTimeNow }()
+	now := timeNow()
+	timeNow = func() time.Time { return now }
+	info = buildInfo(Config{Status: "failure"}, "v1.0.0")
+	card, _ := json.Marshal(buildLarkCard(Config{Status: "failure"}, "v1.0.0"))
+	expected, _ := json.Marshal(lark.NewCardMessage(lark.NewBuildCard(info)))
+	if string(card) != string(expected) {
+		t.Errorf("Expected %s, got %s", expected, card)
+	}
+}
+
+func TestBuildInfo_NoHeaderSection(t *testing.T) {
+	setEnvFixture(t, map[string]string{"PLUGIN_SECTIONS": "details,buttons"})
+
+	info := buildInfo(Config{Status: "success"}, "v1.0.0")
+	if !info.NoHeader {
+		t.Error("Expected no header when PLUGIN_SECTIONS leaves it out")
+	}
+	card := buildLarkCard(Config{Status: "success"}, "v1.0.0")["card"].(map[string]any)
+	if _, ok := card["header"]; ok {
+		t.Errorf("Expected a card without header, got %v", card)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+// getCardTemplateID returns PLUGIN_CARD_TEMPLATE_ID, the id of a card built in
+// Lark's card builder
+func getCardTemplateID() string {
+	return getEnvOrDefault("PLUGIN_CARD_TEMPLATE_ID", "")
+}
+
+// builderTemplateVariables returns the values filled into a card builder template
+func builderTemplateVariables(config Config, projectVersion string) map[string]any {
+	style := getStatusStyle(config.Status)
+	variables := map[string]any{
+		"project":        getEnvOrDefault("CI_REPO", ""),
+		"branch":         getEnvOrDefault("CI_COMMIT_BRANCH", ""),
+		"author":         getEnvOrDefault("CI_COMMIT_AUTHOR", ""),
+		"version":        projectVersion,
+		"status":         config.Status,
+		"status_text":    style.Text,
+		"commit_message": getEnvOrDefault("CI_COMMIT_MESSAGE", ""),
+		"pipeline_url":   getEnvOrDefault("CI_PIPELINE_URL", ""),
+	}
+
+	if names, showValues := visibleVariables(config.Variables); showValues {
+		for _, variable := range names {
+			variables[variable.Name] = variableValue(variable.Name)
+		}
+	}
+	return variables
+}
+
+// createBuilderTemplateCard builds an interactive message that fills in a card
+// builder template instead of describing the card itself
+func createBuilderTemplateCard(config Config, projectVersion string) map[string]any {
+	data := map[string]any{
+		"template_id":       getCardTemplateID(),
+		"template_variable": builderTemplateVariables(config, projectVersion),
+	}
+	if version := getEnvOrDefault("PLUGIN_CARD_TEMPLATE_VERSION", ""); version != "" {
+		data["template_version_name"] = version
+	}
+
+	return map[string]any{
+		"msg_type": "interactive",
+		"card": map[string]any{
+			"type": "template",
+			"data": data,
+		},
+	}
+}
+
+// noteBuilderTemplateOverrides logs the settings a card builder template takes precedence over
+func noteBuilderTemplateOverrides() {
+	if getCardTemplateID() == "" {
+		return
+	}
+	if getEnvOrDefault("PLUGIN_USE_CARD", "true") != "true" {
+		logInfo("PLUGIN_CARD_TEMPLATE_ID is set, sending the template card instead of a text message")
+	}
+	if getEnvOrDefault("PLUGIN_TEMPLATE_FILE", "") != "" {
+		logInfo("PLUGIN_CARD_TEMPLATE_ID is set, ignoring PLUGIN_TEMPLATE_FILE")
+	}
+}
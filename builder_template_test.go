@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCreateBuilderTemplateCard(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_CARD_TEMPLATE_ID":      "AAqk1234",
+		"PLUGIN_CARD_TEMPLATE_VERSION": "1.0.2",
+		"DEPLOY_ENV":                   "staging",
+		"CI_REPO":                      "octo/backend",
+		"CI_COMMIT_BRANCH":             "main",
+		"CI_COMMIT_AUTHOR":             "octocat",
+		"CI_COMMIT_MESSAGE":            "Fix the build",
+		"CI_PIPELINE_URL":              "https://ci.example.com/repos/1/pipeline/42",
+	})
+
+	data, err := json.Marshal(createBuilderTemplateCard(Config{Status: "failure", Variables: []string{"DEPLOY_ENV"}}, "v1.0.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"card":{"data":{"template_id":"AAqk1234","template_variable":{"DEPLOY_ENV":"staging","author":"octocat","branch":"main","commit_message":"Fix the build","pipeline_url":"https://ci.example.com/repos/1/pipeline/42","project":"octo/backend","status":"failure","status_text":"Pipeline Failed","version":"v1.0.0"},"template_version_name":"1.0.2"},"type":"template"},"msg_type":"interactive"}`
+	if string(data) != expected {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+}
+
+func TestMain_BuilderTemplateWinsOverTextMode(t *testing.T) {
+	var body []byte
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		w.Write([]byte(`{"code": 0}`))
+	}))
+	defer testServer.Close()
+
+	originalOsExit := osExit
+	defer func() { osExit = originalOsExit }()
+	osExit = func(code int) {}
+
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_WEBHOOK_URL":      testServer.URL,
+		"PLUGIN_CARD_TEMPLATE_ID": "AAqk1234",
+		"PLUGIN_USE_CARD":         "false",
+		"PLUGIN_SECRET":           "lark-secret",
+		"DRONE_BUILD_STATUS":      "success",
+	})
+
+	output := captureStdout(t, main)
+
+	if !strings.Contains(output, "PLUGIN_CARD_TEMPLATE_ID is set") {
+		t.Errorf("Expected a note about the template, got:\n%s", output)
+	}
+	var message struct {
+		MsgType   string `json:"msg_type"`
+		Sign      string `json:"sign"`
+		Timestamp string `json:"timestamp"`
+		Card      struct {
+			Type string `json:"type"`
+			Data struct {
+				TemplateID string `json:"template_id"`
+			} `json:"data"`
+		} `json:"card"`
+	}
+	if err := json.Unmarshal(body, &message); err != nil {
+		t.Fatalf("Invalid payload %s", body)
+	}
+	if message.MsgType != "interactive" || message.Card.Type != "template" || message.Card.Data.TemplateID != "AAqk1234" {
+		t.Errorf("Expected a template card, got %s", body)
+	}
+	if message.Sign == "" || message.Timestamp == "" {
+		t.Errorf("Expected the template card to be signed, got %s", body)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Identifiers of the built-in buttons in PLUGIN_BUTTONS. They stay the same
+// when a label changes or is translated.
+const (
+	buttonPipeline    = "pipeline"
+	buttonFailedStep  = "failed-step"
+	buttonCommit      = "commit"
+	buttonRelease     = "release"
+	buttonPullRequest = "pr"
+	buttonParent      = "parent"
+)
+
+// builtinButtonIDs lists the built-in buttons in their default order
+var builtinButtonIDs = []string{buttonPipeline, buttonFailedStep, buttonCommit, buttonRelease, buttonPullRequest, buttonParent}
+
+// actionButton is a card button with the identifier PLUGIN_BUTTONS selects it by
+type actionButton struct {
+	ID     string
+	Action map[string]any
+}
+
+// linkButton renders a card button opening url
+func linkButton(label, url, buttonType string) map[string]any {
+	return map[string]any{
+		"tag": "button",
+		"text": map[string]any{
+			"content": label,
+			"tag":     "plain_text",
+		},
+		"type": buttonType,
+		"url":  url,
+	}
+}
+
+// selectButtons returns the buttons named by the PLUGIN_BUTTONS entries names,
+// in that order, or all of them when there are none. An entry such as
+// "docs=https://wiki.example.com/runbook" adds a link button labelled docs;
+// ${VAR} references in its URL are expanded. Names of buttons that do not
+// apply to this build are skipped.
+func selectButtons(names []string, buttons []actionButton) []map[string]any {
+	if len(names) == 0 {
+		var actions []map[string]any
+		for _, button := range buttons {
+			actions = append(actions, button.Action)
+		}
+		return actions
+	}
+
+	var actions []map[string]any
+	for _, name := range names {
+		if label, url, ok := inlineButton(name); ok {
+			if expanded, _ := expandTemplateEnv(url); expanded != "" {
+				actions = append(actions, linkButton(label, expanded, "default"))
+			}
+			continue
+		}
+		for _, button := range buttons {
+			if strings.EqualFold(name, button.ID) {
+				actions = append(actions, button.Action)
+				break
+			}
+		}
+	}
+	return actions
+}
+
+// inlineButton splits a PLUGIN_BUTTONS entry "label=url"
+func inlineButton(name string) (string, string, bool) {
+	label, url, ok := strings.Cut(name, "=")
+	label, url = strings.TrimSpace(label), strings.TrimSpace(url)
+	return label, url, ok && label != "" && url != ""
+}
+
+// warnUnknownButtons warns about PLUGIN_BUTTONS entries that name neither a
+// built-in button, a custom button nor an inline link
+func warnUnknownButtons(names []string) {
+	valid := slices.Clone(builtinButtonIDs)
+	buttons, _ := parseCustomButtons()
+	for _, button := range buttons {
+		valid = append(valid, strings.ToLower(button.Label))
+	}
+
+	for _, name := range names {
+		if _, _, ok := inlineButton(name); ok || slices.Contains(valid, strings.ToLower(name)) {
+			continue
+		}
+		logWarn(fmt.Sprintf("PLUGIN_BUTTONS: unknown button %q, expected one of %s or label=url", name, strings.Join(valid, ", ")))
+	}
+}
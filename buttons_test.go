@@ -0,0 +1,105 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// buttonURLs returns the url of each button
+func buttonURLs(actions []map[string]any) []string {
+	var urls []string
+	for _, action := range actions {
+		url, _ := action["url"].(string)
+		urls = append(urls, url)
+	}
+	return urls
+}
+
+func TestSelectButtons_Order(t *testing.T) {
+	tests := []struct {
+		name    string
+		buttons []string
+		want    string
+	}{
+		{"Default order", nil, "https://ci.example.com/1 https://git.example.com/repo/releases/tag/v1.0.0"},
+		{"PLUGIN_BUTTONS order", []string{"release", "pipeline"}, "https://git.example.com/repo/releases/tag/v1.0.0 https://ci.example.com/1"},
+		{"Case is ignored", []string{"Release"}, "https://git.example.com/repo/releases/tag/v1.0.0"},
+		{"Buttons that do not apply are skipped", []string{"commit", "pipeline"}, "https://ci.example.com/1"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setEnvFixture(t, map[string]string{
+				"CI_PIPELINE_URL": "https://ci.example.com/1",
+				"CI_REPO_URL":     "https://git.example.com/repo",
+				"CI_COMMIT_TAG":   "v1.0.0",
+			})
+
+			if got := strings.Join(buttonURLs(createActionButtons(Config{Buttons: tc.buttons})), " "); got != tc.want {
+				t.Errorf("Expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSelectButtons_StableIdentifiers(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"CI_PIPELINE_URL":   "https://ci.example.com/1",
+		"CI_PIPELINE_EVENT": "deployment",
+	})
+
+	actions := createActionButtons(Config{Buttons: []string{"pipeline"}})
+	if len(actions) != 1 || actions[0]["url"] != "https://ci.example.com/1" {
+		t.Errorf("Expected the relabelled pipeline button, got %v", actions)
+	}
+}
+
+func TestSelectButtons_InlineLinks(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"CI_PIPELINE_URL":       "https://ci.example.com/1",
+		"CI_COMMIT_SHA":         "abc123",
+		"PLUGIN_CUSTOM_BUTTONS": `[{"label":"Grafana","url":"https://grafana.example.com","type":"danger"}]`,
+	})
+	buttons := []string{"docs=https://wiki.example.com/runbook", "grafana", "Diff=https://git.example.com/commit/${CI_COMMIT_SHA}", "pipeline"}
+
+	actions := createActionButtons(Config{Buttons: buttons})
+	want := "https://wiki.example.com/runbook https://grafana.example.com https://git.example.com/commit/abc123 https://ci.example.com/1"
+	if got := strings.Join(buttonURLs(actions), " "); got != want {
+		t.Fatalf("Expected %q, got %q", want, got)
+	}
+	if label := actions[0]["text"].(map[string]any)["content"]; label != "docs" || actions[0]["type"] != "default" {
+		t.Errorf("Unexpected inline button %v", actions[0])
+	}
+	if actions[1]["type"] != "danger" {
+		t.Errorf("Expected the custom button to keep its type, got %v", actions[1])
+	}
+
+	text := createCustomButtonText(buttons)
+	if !strings.Contains(text, "docs: https://wiki.example.com/runbook") || !strings.Contains(text, "Grafana: https://grafana.example.com") || strings.Contains(text, "ci.example.com") {
+		t.Errorf("Expected the inline and custom links in text, got %q", text)
+	}
+}
+
+func TestSelectButtons_InlineLinkWithoutURL(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"CI_PIPELINE_URL": "https://ci.example.com/1",
+	})
+
+	if got := buttonURLs(createActionButtons(Config{Buttons: []string{"pipeline", "deploy=${DEPLOY_URL}"}})); len(got) != 1 {
+		t.Errorf("Expected the link expanding to nothing to be dropped, got %v", got)
+	}
+}
+
+func TestWarnUnknownButtons(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_CUSTOM_BUTTONS": `[{"label":"Grafana","url":"https://grafana.example.com"}]`,
+	})
+
+	output := captureOutput(t, func() {
+		warnUnknownButtons([]string{"pipeline", "grafana", "docs=https://wiki.example.com", "pipline"})
+	})
+	if strings.Count(output, "unknown button") != 1 || !strings.Contains(output, `"pipline"`) ||
+		!strings.Contains(output, "pipeline, failed-step, commit, release, pr, parent, grafana") {
+		t.Errorf("Expected one warning listing the valid names, got %q", output)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+)
+
+// getCardLinkURL returns the URL the whole card opens, or "" when PLUGIN_CARD_LINK
+// is off. PLUGIN_CARD_LINK_URL overrides the pipeline URL; an empty or invalid
+// override skips the link with a warning, since Lark rejects such cards.
+func getCardLinkURL() string {
+	if getEnvOrDefault("PLUGIN_CARD_LINK", "false") != "true" {
+		return ""
+	}
+
+	if _, set := os.LookupEnv("PLUGIN_CARD_LINK_URL"); !set {
+		return getEnvOrDefault("CI_PIPELINE_URL", "")
+	}
+
+	linkURL, err := expandTemplateEnv(getEnvOrDefault("PLUGIN_CARD_LINK_URL", ""))
+	if err == nil && linkURL == "" {
+		err = fmt.Errorf("it is empty")
+	}
+	if err == nil {
+		if u, parseErr := url.Parse(linkURL); parseErr != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			err = fmt.Errorf("%q is not an http(s) URL", linkURL)
+		}
+	}
+	if err != nil {
+		logWarn(fmt.Sprintf("ignoring PLUGIN_CARD_LINK_URL, %v", err))
+		return ""
+	}
+	return linkURL
+}
+
+// applyCardLink makes the whole card open the card link URL on every platform
+func applyCardLink(message map[string]any) map[string]any {
+	linkURL := getCardLinkURL()
+	if linkURL == "" {
+		return message
+	}
+	if card, ok := message["card"].(map[string]any); ok {
+		card["card_link"] = map[string]any{
+			"url":         linkURL,
+			"android_url": linkURL,
+			"ios_url":     linkURL,
+			"pc_url":      linkURL,
+		}
+	}
+	return message
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCardLinkDisabledByDefault(t *testing.T) {
+	setEnvFixture(t, map[string]string{"CI_PIPELINE_URL": "https://ci.example.com/1"})
+
+	card := createLarkCard(Config{}, "v1.0.0")["card"].(map[string]any)
+	if _, ok := card["card_link"]; ok {
+		t.Errorf("Expected no card_link by default, got %v", card["card_link"])
+	}
+}
+
+func TestCardLinkJSON(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_CARD_LINK": "true",
+		"CI_PIPELINE_URL":  "https://ci.example.com/1",
+	})
+
+	data, err := json.Marshal(createLarkCard(Config{}, "v1.0.0"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `"card_link":{"android_url":"https://ci.example.com/1","ios_url":"https://ci.example.com/1","pc_url":"https://ci.example.com/1","url":"https://ci.example.com/1"}`
+	if !strings.Contains(string(data), expected) {
+		t.Errorf("Expected card JSON to contain %s, got %s", expected, data)
+	}
+	if !strings.Contains(string(data), `"tag":"action"`) {
+		t.Errorf("Expected the action buttons to remain, got %s", data)
+	}
+}
+
+func TestCardLinkURLOverride(t *testing.T) {
+	tests := []struct {
+		name     string
+		override string
+		expected string
+		warning  bool
+	}{
+		{"Override", "https://grafana.example.com/d/${CI_REPO_NAME}", "https://grafana.example.com/d/backend", false},
+		{"Empty override", "", "", true},
+		{"Invalid override", "not a url", "", true},
+		{"Unsupported scheme", "javascript:alert(1)", "", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setEnvFixture(t, map[string]string{
+				"PLUGIN_CARD_LINK":     "true",
+				"PLUGIN_CARD_LINK_URL": "placeholder",
+				"CI_PIPELINE_URL":      "https://ci.example.com/1",
+				"CI_REPO_NAME":         "backend",
+			})
+			t.Setenv("PLUGIN_CARD_LINK_URL", tc.override)
+
+			var linkURL string
+			output := captureStdout(t, func() { linkURL = getCardLinkURL() })
+
+			if linkURL != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, linkURL)
+			}
+			if strings.Contains(output, "Warning") != tc.warning {
+				t.Errorf("Unexpected warning output %q", output)
+			}
+		})
+	}
+}
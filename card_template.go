@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// cardTemplate is the parsed PLUGIN_TEMPLATE_FILE, or nil for the built-in card
+var cardTemplate *template.Template
+
+// cardTemplateContext is the data available to card templates
+type cardTemplateContext struct {
+	Repo          string
+	RepoName      string
+	Branch        string
+	Author        string
+	Version       string
+	Status        string
+	StatusText    string
+	StatusIcon    string
+	HeaderColor   string
+	CommitMessage string
+	PipelineURL   string
+	Duration      string
+	Event         string
+	// Env holds the environment variables templates may read, see PLUGIN_TEMPLATE_ENV_ALLOW
+	Env map[string]string
+}
+
+// loadCardTemplate reads and parses PLUGIN_TEMPLATE_FILE, a local path or https:// URL
+func loadCardTemplate() error {
+	cardTemplate = nil
+	ref := getEnvOrDefault("PLUGIN_TEMPLATE_FILE", "")
+	if ref == "" || getCardTemplateID() != "" {
+		return nil
+	}
+
+	data, err := loadTemplateSource("PLUGIN_TEMPLATE_FILE", ref)
+	if err != nil {
+		return err
+	}
+
+	tmpl, err := template.New("card").Funcs(templateFuncs()).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return fmt.Errorf("cannot parse PLUGIN_TEMPLATE_FILE: %w", err)
+	}
+	cardTemplate = tmpl
+	return nil
+}
+
+// templateEnv returns the allowed environment variables, resolved like any other value
+func templateEnv() map[string]string {
+	env := map[string]string{}
+	names := map[string]bool{}
+	for _, entry := range os.Environ() {
+		if name, _, ok := strings.Cut(entry, "="); ok {
+			names[name] = true
+		}
+	}
+	for name := range ciEnv {
+		names[name] = true
+	}
+	for name := range envFile {
+		names[name] = true
+	}
+
+	for name := range names {
+		if isTemplateEnvAllowed(name) {
+			env[name] = getEnvOrDefault(name, "")
+		}
+	}
+	return env
+}
+
+func newCardTemplateContext(config Config, projectVersion string) cardTemplateContext {
+	style := getStatusStyle(config.Status)
+	return cardTemplateContext{
+		Repo:          getEnvOrDefault("CI_REPO", ""),
+		RepoName:      getEnvOrDefault("CI_REPO_NAME", ""),
+		Branch:        getEnvOrDefault("CI_COMMIT_BRANCH", ""),
+		Author:        getEnvOrDefault("CI_COMMIT_AUTHOR", ""),
+		Version:       projectVersion,
+		Status:        config.Status,
+		StatusText:    style.Text,
+		StatusIcon:    style.Icon,
+		HeaderColor:   style.Color,
+		CommitMessage: getEnvOrDefault("CI_COMMIT_MESSAGE", ""),
+		PipelineURL:   getEnvOrDefault("CI_PIPELINE_URL", ""),
+		Duration:      getBuildDuration(),
+		Event:         getPipelineEvent(),
+		Env:           templateEnv(),
+	}
+}
+
+// jsonErrorLocation describes where in the rendered output a JSON error occurred
+func jsonErrorLocation(output []byte, err error) string {
+	var syntaxErr *json.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		return ""
+	}
+
+	offset := int(syntaxErr.Offset)
+	if offset > len(output) {
+		offset = len(output)
+	}
+	line := bytes.Count(output[:offset], []byte("\n")) + 1
+	start := bytes.LastIndexByte(output[:offset], '\n') + 1
+	end := bytes.IndexByte(output[offset:], '\n')
+	if end < 0 {
+		end = len(output)
+	} else {
+		end += offset
+	}
+	return fmt.Sprintf(" at output line %d, column %d:\n  %s", line, offset-start+1, output[start:end])
+}
+
+// renderTemplateCard renders cardTemplate into an interactive message. The
+// template produces either the whole card object or just its elements array,
+// which then gets the built-in header.
+func renderTemplateCard(config Config, projectVersion string) (map[string]any, error) {
+	context := newCardTemplateContext(config, projectVersion)
+
+	var output bytes.Buffer
+	if err := cardTemplate.Execute(&output, context); err != nil {
+		return nil, fmt.Errorf("rendering PLUGIN_TEMPLATE_FILE: %w", err)
+	}
+
+	var card any
+	if err := json.Unmarshal(output.Bytes(), &card); err != nil {
+		return nil, fmt.Errorf("PLUGIN_TEMPLATE_FILE did not render valid JSON: %v%s", err, jsonErrorLocation(output.Bytes(), err))
+	}
+
+	switch rendered := card.(type) {
+	case map[string]any:
+		return map[string]any{"msg_type": "interactive", "card": rendered}, nil
+	case []any:
+		headerTitle := fmt.Sprintf("%s - %s", context.RepoName, iconText(context.StatusIcon, context.StatusText))
+		if title, ok := customTitle(config, projectVersion, context.StatusText); ok {
+			headerTitle = title
+		}
+		return map[string]any{
+			"msg_type": "interactive",
+			"card": map[string]any{
+				"header": map[string]any{
+					"title": map[string]any{
+						"content": headerTitle,
+						"tag":     "plain_text",
+					},
+					"template": context.HeaderColor,
+				},
+				"elements": rendered,
+			},
+		}, nil
+	default:
+		return nil, fmt.Errorf("PLUGIN_TEMPLATE_FILE must render a JSON object or array")
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeCardTemplate stores a template and points PLUGIN_TEMPLATE_FILE at it
+func writeCardTemplate(t *testing.T, content string) {
+	path := filepath.Join(t.TempDir(), "card.tmpl")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	setEnvFixture(t, map[string]string{"PLUGIN_TEMPLATE_FILE": path})
+	t.Cleanup(func() { cardTemplate = nil })
+}
+
+func TestRenderTemplateCard_FullCard(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"CI_REPO":           "octo/backend",
+		"CI_COMMIT_BRANCH":  "main",
+		"CI_COMMIT_MESSAGE": "Fix \"quoted\" bug\nwith details",
+		"CI_PIPELINE_URL":   "https://ci.example.com/repos/1/pipeline/42",
+		"CI_SYSTEM_NAME":    "woodpecker",
+		"DEPLOY_TOKEN":      "super-secret-token",
+	})
+	writeCardTemplate(t, `{
+  "header": {"title": {"tag": "plain_text", "content": "{{.Repo}} {{.Status}}"}, "template": "{{.HeaderColor}}"},
+  "elements": [
+    {"tag": "div", "text": {"tag": "lark_md", "content": {{json .CommitMessage}}}},
+    {"tag": "div", "text": {"tag": "lark_md", "content": "{{.Branch}} on {{index .Env "CI_SYSTEM_NAME"}}{{index .Env "DEPLOY_TOKEN"}}"}}
+  ]
+}`)
+
+	if err := loadCardTemplate(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	message, err := renderTemplateCard(Config{Status: "failure"}, "v1.0.0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	data, _ := json.Marshal(message)
+	expected := `{"card":{"elements":[{"tag":"div","text":{"content":"Fix \"quoted\" bug\nwith details","tag":"lark_md"}},{"tag":"div","text":{"content":"main on woodpecker","tag":"lark_md"}}],"header":{"template":"red","title":{"content":"octo/backend failure","tag":"plain_text"}}},"msg_type":"interactive"}`
+	if string(data) != expected {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+}
+
+func TestRenderTemplateCard_ElementsOnly(t *testing.T) {
+	setEnvFixture(t, map[string]string{"CI_REPO_NAME": "backend"})
+	writeCardTemplate(t, `[{"tag": "div", "text": {"tag": "lark_md", "content": "Version {{.Version}}"}}]`)
+
+	if err := loadCardTemplate(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	message, err := renderTemplateCard(Config{Status: "success"}, "v1.0.0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	card := message["card"].(map[string]any)
+	header := card["header"].(map[string]any)
+	if header["template"] != "green" || header["title"].(map[string]any)["content"] != "backend - ✅ Pipeline Succeeded" {
+		t.Errorf("Expected the built-in header, got %v", header)
+	}
+	if len(card["elements"].([]any)) != 1 {
+		t.Errorf("Expected the rendered elements, got %v", card["elements"])
+	}
+}
+
+func TestRenderTemplateCard_InvalidJSON(t *testing.T) {
+	writeCardTemplate(t, "[\n  {\"tag\": \"div\"},\n  {\"tag\": \"div\" \"text\": {}}\n]")
+
+	if err := loadCardTemplate(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, err := renderTemplateCard(Config{}, "v1.0.0")
+	if err == nil || !strings.Contains(err.Error(), "at output line 3, column 18") || !strings.Contains(err.Error(), `{"tag": "div" "text": {}}`) {
+		t.Errorf("Expected an error pointing at line 3, got %v", err)
+	}
+}
+
+func TestLoadCardTemplate_Errors(t *testing.T) {
+	setEnvFixture(t, map[string]string{"PLUGIN_TEMPLATE_FILE": filepath.Join(t.TempDir(), "missing.tmpl")})
+	if err := loadCardTemplate(); err == nil {
+		t.Error("Expected an error for a missing template")
+	}
+
+	writeCardTemplate(t, `{{.Repo`)
+	if err := loadCardTemplate(); err == nil || !strings.Contains(err.Error(), "card:1") {
+		t.Errorf("Expected a parse error with the template line, got %v", err)
+	}
+}
+
+func TestMain_TemplateCard(t *testing.T) {
+	var body []byte
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		w.Write([]byte(`{"code": 0}`))
+	}))
+	defer testServer.Close()
+
+	originalOsExit := osExit
+	defer func() { osExit = originalOsExit }()
+	exitCode := 0
+	osExit = func(code int) { exitCode = code }
+
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_WEBHOOK_URL": testServer.URL,
+		"PLUGIN_SECRET":      "lark-secret",
+		"DRONE_BUILD_STATUS": "success",
+	})
+	writeCardTemplate(t, `{"elements": [{"tag": "div", "text": {"tag": "lark_md", "content": "templated"}}]}`)
+
+	main()
+
+	if exitCode != 0 {
+		t.Errorf("Expected exit code 0, got %d", exitCode)
+	}
+	var message map[string]any
+	if err := json.Unmarshal(body, &message); err != nil {
+		t.Fatalf("Invalid payload %s", body)
+	}
+	if message["sign"] == nil || message["timestamp"] == nil || !strings.Contains(string(body), "templated") {
+		t.Errorf("Expected a signed templated card, got %s", body)
+	}
+
+	// A missing template stops the step before anything is sent
+	body = nil
+	setEnvFixture(t, map[string]string{"PLUGIN_TEMPLATE_FILE": "/nonexistent/card.tmpl"})
+	main()
+	if exitCode != exitConfiguration || body != nil {
+		t.Errorf("Expected exit code 2 without sending, got %d and %s", exitCode, body)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultChangelogMax = 15
+	// maxChangelogEntries and maxChangelogSubjectLength bound the section size
+	maxChangelogEntries       = 100
+	maxChangelogSubjectLength = 120
+)
+
+// changelog lists the commits of a tag build
+type changelog struct {
+	// PreviousTag is "" when the tag is the first one
+	PreviousTag string
+	Subjects    []string
+	// More counts the commits left out of Subjects
+	More int
+}
+
+// buildChangelog is set by main when PLUGIN_CHANGELOG is on for a tag build
+var buildChangelog *changelog
+
+func getChangelogMax() int {
+	n, err := strconv.Atoi(getEnvOrDefault("PLUGIN_CHANGELOG_MAX", ""))
+	if err != nil || n < 1 {
+		return defaultChangelogMax
+	}
+	return min(n, maxChangelogEntries)
+}
+
+// loadChangelog collects the commit subjects between the previous tag and
+// CI_COMMIT_TAG, or all commits up to the tag when it is the first one
+func loadChangelog() *changelog {
+	tag := getEnvOrDefault("CI_COMMIT_TAG", "")
+	if tag == "" || getEnvOrDefault("PLUGIN_CHANGELOG", "false") != "true" {
+		return nil
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		logWarn("git is not available, skipping the changelog")
+		return nil
+	}
+
+	log := &changelog{}
+	revisions := tag
+	if previous, err := runGit("describe", "--tags", "--abbrev=0", tag+"^"); err == nil {
+		log.PreviousTag = strings.TrimSpace(previous)
+		revisions = log.PreviousTag + ".." + tag
+	}
+
+	args := []string{"log", "--format=%s"}
+	if getEnvOrDefault("PLUGIN_CHANGELOG_NO_MERGES", "false") == "true" {
+		args = append(args, "--no-merges")
+	}
+	output, err := runGit(append(args, revisions, "--")...)
+	if err != nil {
+		logWarn(fmt.Sprintf("cannot read the changelog for %s: %v", tag, err))
+		return nil
+	}
+
+	subjects := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	if len(subjects) == 1 && subjects[0] == "" {
+		subjects = nil
+	}
+	if limit := getChangelogMax(); len(subjects) > limit {
+		log.More = len(subjects) - limit
+		subjects = subjects[:limit]
+	}
+	for _, subject := range subjects {
+		log.Subjects = append(log.Subjects, truncateRunes(subject, maxChangelogSubjectLength))
+	}
+	return log
+}
+
+// title is the section heading, naming the previous tag when there is one
+func (c *changelog) title() string {
+	if c.PreviousTag == "" {
+		return tr("Changes")
+	}
+	return fmt.Sprintf("%s (%s %s)", tr("Changes"), tr("since"), c.PreviousTag)
+}
+
+// createChangelogElements returns the card section listing the changelog
+func createChangelogElements() []map[string]any {
+	if buildChangelog == nil || len(buildChangelog.Subjects) == 0 || publicMode {
+		return nil
+	}
+
+	content := fmt.Sprintf("**%s:**", escapeMarkdown(buildChangelog.title()))
+	for _, subject := range buildChangelog.Subjects {
+		content += "\n• " + escapeMarkdown(subject)
+	}
+	if buildChangelog.More > 0 {
+		content += fmt.Sprintf("\n… and %d more", buildChangelog.More)
+	}
+	return []map[string]any{
+		{
+			"tag": "hr",
+		},
+		{
+			"tag": "div",
+			"text": map[string]any{
+				"content": content,
+				"tag":     "lark_md",
+			},
+		},
+	}
+}
+
+// createChangelogText returns the changelog as bullet lines
+func createChangelogText() string {
+	if buildChangelog == nil || len(buildChangelog.Subjects) == 0 || publicMode {
+		return ""
+	}
+
+	text := "\n" + withIcon("📜", buildChangelog.title()+":\n")
+	for _, subject := range buildChangelog.Subjects {
+		text += "• " + escapeText(subject) + "\n"
+	}
+	if buildChangelog.More > 0 {
+		text += fmt.Sprintf("… and %d more\n", buildChangelog.More)
+	}
+	return text
+}
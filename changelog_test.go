@@ -0,0 +1,135 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// changelogRepoFixture creates a repository with the tags v1.0.0 and v1.1.0,
+// and a merge commit in the second release
+func changelogRepoFixture(t *testing.T) string {
+	t.Helper()
+	dir, git, write := newGitRepo(t)
+
+	write("app.txt", "1\n")
+	gitCommit(git, "Initial commit")
+	gitCommit(git, "Add *login* page")
+	git("tag", "v1.0.0")
+
+	gitCommit(git, "Fix [logout] bug")
+	git("checkout", "-q", "-b", "feature")
+	gitCommit(git, "Add settings")
+	git("checkout", "-q", "main")
+	gitCommit(git, "Update docs")
+	git("merge", "-q", "--no-ff", "-m", "Merge branch 'feature'", "feature")
+	git("tag", "v1.1.0")
+
+	return dir
+}
+
+func TestLoadChangelog(t *testing.T) {
+	dir := changelogRepoFixture(t)
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		previous string
+		subjects []string
+		more     int
+	}{
+		{
+			name:     "Since previous tag",
+			env:      map[string]string{"CI_COMMIT_TAG": "v1.1.0"},
+			previous: "v1.0.0",
+			subjects: []string{"Merge branch 'feature'", "Update docs", "Add settings", "Fix [logout] bug"},
+		},
+		{
+			name:     "Without merges",
+			env:      map[string]string{"CI_COMMIT_TAG": "v1.1.0", "PLUGIN_CHANGELOG_NO_MERGES": "true"},
+			previous: "v1.0.0",
+			subjects: []string{"Update docs", "Add settings", "Fix [logout] bug"},
+		},
+		{
+			name:     "Capped",
+			env:      map[string]string{"CI_COMMIT_TAG": "v1.1.0", "PLUGIN_CHANGELOG_MAX": "2"},
+			previous: "v1.0.0",
+			subjects: []string{"Merge branch 'feature'", "Update docs"},
+			more:     2,
+		},
+		{
+			name:     "First tag",
+			env:      map[string]string{"CI_COMMIT_TAG": "v1.0.0"},
+			subjects: []string{"Add *login* page", "Initial commit"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.env["PLUGIN_CHANGELOG"] = "true"
+			tc.env["CI_WORKSPACE"] = dir
+			setEnvFixture(t, tc.env)
+
+			log := loadChangelog()
+			if log == nil {
+				t.Fatal("Expected a changelog")
+			}
+			if log.PreviousTag != tc.previous || log.More != tc.more || !reflect.DeepEqual(log.Subjects, tc.subjects) {
+				t.Errorf("Expected %q %q +%d, got %q %q +%d", tc.previous, tc.subjects, tc.more, log.PreviousTag, log.Subjects, log.More)
+			}
+		})
+	}
+}
+
+func TestLoadChangelogSkipped(t *testing.T) {
+	dir := changelogRepoFixture(t)
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		warning bool
+	}{
+		{"Disabled", map[string]string{"CI_COMMIT_TAG": "v1.1.0"}, false},
+		{"Not a tag build", map[string]string{"PLUGIN_CHANGELOG": "true"}, false},
+		{"Unknown tag", map[string]string{"PLUGIN_CHANGELOG": "true", "CI_COMMIT_TAG": "v9.9.9"}, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.env["CI_WORKSPACE"] = dir
+			setEnvFixture(t, tc.env)
+
+			var log *changelog
+			output := captureStdout(t, func() { log = loadChangelog() })
+			if log != nil {
+				t.Errorf("Expected no changelog, got %+v", log)
+			}
+			if strings.Contains(output, "Warning") != tc.warning {
+				t.Errorf("Unexpected output %q", output)
+			}
+		})
+	}
+}
+
+func TestChangelogInMessages(t *testing.T) {
+	setEnvFixture(t, map[string]string{"CI_COMMIT_TAG": "v1.1.0"})
+	buildChangelog = &changelog{PreviousTag: "v1.0.0", Subjects: []string{"Fix [logout] bug", "Add *login* <at id=all></at>"}, More: 3}
+	defer func() { buildChangelog = nil }()
+
+	elements := createLarkCard(Config{}, "v1.1.0")["card"].(map[string]any)["elements"].([]map[string]any)
+	expected := "**Changes (since v1.0.0):**\n• Fix &#91;logout&#93; bug\n• Add &#42;login&#42; &lt;at id=all&gt;&lt;/at&gt;\n… and 3 more"
+	found := false
+	for _, element := range elements {
+		if text, ok := element["text"].(map[string]any); ok && text["content"] == expected {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected a changelog section %q, got %v", expected, elements)
+	}
+
+	text := createLarkTextMessage(Config{}, "v1.1.0")["content"].(map[string]any)["text"].(string)
+	if !strings.Contains(text, "📜 Changes (since v1.0.0):\n• Fix [logout] bug\n• Add *login* <\u200bat id=all></\u200bat>\n… and 3 more\n") {
+		t.Errorf("Unexpected changelog in text %q", text)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// chatTargetPrefix marks delivery targets that are chat ids rather than webhook URLs
+const chatTargetPrefix = "lark-chat:"
+
+// chatTargets returns the PLUGIN_CHAT_ID entries as delivery targets. They are
+// only used when the app credentials are configured.
+func chatTargets() []string {
+	chatIDs := getListSetting("PLUGIN_CHAT_ID")
+	if len(chatIDs) == 0 {
+		return nil
+	}
+	if getEnvOrDefault("PLUGIN_APP_ID", "") == "" || getEnvOrDefault("PLUGIN_APP_SECRET", "") == "" {
+		logWarn("PLUGIN_CHAT_ID needs PLUGIN_APP_ID and PLUGIN_APP_SECRET, ignoring it")
+		return nil
+	}
+
+	var targets []string
+	for _, chatID := range chatIDs {
+		targets = append(targets, chatTargetPrefix+chatID)
+	}
+	return targets
+}
+
+// openAPIMessage converts a webhook payload into an im/v1/messages request
+// body. The API wants msg_type at the top level and the card or text content
+// as a JSON string; webhook signatures do not apply.
+func openAPIMessage(chatID string, messageBytes []byte) (map[string]string, error) {
+	var message map[string]json.RawMessage
+	if err := json.Unmarshal(messageBytes, &message); err != nil {
+		return nil, err
+	}
+
+	var msgType string
+	if err := json.Unmarshal(message["msg_type"], &msgType); err != nil {
+		return nil, fmt.Errorf("message has no msg_type")
+	}
+
+	content := message["content"]
+	switch msgType {
+	case "interactive":
+		content = message["card"]
+	case "post":
+		// The webhook wraps the locales in "post", the API takes them directly
+		var post struct {
+			Post json.RawMessage `json:"post"`
+		}
+		json.Unmarshal(content, &post)
+		content = post.Post
+	}
+	if len(content) == 0 {
+		return nil, fmt.Errorf("message has no content")
+	}
+
+	return map[string]string{
+		"receive_id": chatID,
+		"msg_type":   msgType,
+		"content":    string(content),
+	}, nil
+}
+
+// deliverToChat sends the message to a chat as the app bot. In the finish
+// phase the running card is updated instead, or a new message is sent when
+// that fails.
+func deliverToChat(ctx context.Context, chatID string, messageBytes []byte) error {
+	if messageID := phaseMessages[chatID]; messageID != "" {
+		logInfo("Updating the Lark chat message...", "target", "chat:"+chatID, "message_id", messageID)
+		err := updateChatMessage(ctx, messageID, messageBytes)
+		if err == nil {
+			logInfo("Done!", "target", "chat:"+chatID)
+			return nil
+		}
+		logWarn(fmt.Sprintf("cannot update the running card, sending a new message: %v", err), "target", "chat:"+chatID)
+	}
+
+	logInfo("Sending to Lark chat...", "target", "chat:"+chatID)
+
+	body, err := openAPIMessage(chatID, messageBytes)
+	if err == nil {
+		err = shareCard(body)
+	}
+	if err != nil {
+		return fmt.Errorf("error sending to Lark: %w", err)
+	}
+
+	var data struct {
+		MessageID string `json:"message_id"`
+	}
+	appID := getEnvOrDefault("PLUGIN_APP_ID", "")
+	appSecret := getEnvOrDefault("PLUGIN_APP_SECRET", "")
+	err = withTenantAccessToken(ctx, appID, appSecret, func(token string) error {
+		return callOpenAPI(ctx, http.MethodPost, "/open-apis/im/v1/messages?receive_id_type=chat_id", token, body, &data)
+	})
+
+	var apiErr *larkAPIError
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr
+	case err != nil:
+		return fmt.Errorf("error sending to Lark: %w", err)
+	}
+
+	if getPhase() == phaseStart && data.MessageID != "" {
+		phaseMessages[chatID] = data.MessageID
+	}
+	logInfo("Done!", "target", "chat:"+chatID)
+	return nil
+}
+
+// deliverToTarget sends the message to a webhook URL or, for chat targets, through the OpenAPI
+func deliverToTarget(ctx context.Context, target string, messageBytes []byte, secret string) error {
+	if chatID, ok := strings.CutPrefix(target, chatTargetPrefix); ok {
+		return deliverToChat(ctx, chatID, messageBytes)
+	}
+	return deliverMessage(ctx, target, messageBytes, secret)
+}
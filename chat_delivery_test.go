@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// setupChatServer mimics the token and im/v1/messages endpoints and returns
+// the message request bodies it received. Chats listed in failing get an API error.
+func setupChatServer(t *testing.T, failing map[string]bool) *[]map[string]string {
+	var mu sync.Mutex
+	var received []map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/open-apis/auth/v3/tenant_access_token/internal":
+			json.NewEncoder(w).Encode(map[string]any{"code": 0, "tenant_access_token": "t-1", "expire": 7200})
+		case "/open-apis/im/v1/messages":
+			if r.URL.Query().Get("receive_id_type") != "chat_id" || r.Header.Get("Authorization") != "Bearer t-1" {
+				t.Errorf("Unexpected message request %s %v", r.URL, r.Header)
+			}
+			var body map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("Expected string fields only: %v", err)
+			}
+			mu.Lock()
+			received = append(received, body)
+			mu.Unlock()
+
+			if failing[body["receive_id"]] {
+				json.NewEncoder(w).Encode(map[string]any{"code": 230002, "msg": "Bot/User can NOT be out of the chat."})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{"code": 0, "msg": "success"})
+		default:
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	originalOsExit := osExit
+	t.Cleanup(func() { osExit = originalOsExit })
+
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_API_BASE_URL": server.URL,
+		"PLUGIN_APP_ID":       "cli_test",
+		"PLUGIN_APP_SECRET":   "app_secret",
+		"PLUGIN_WEBHOOK_URL":  "",
+		"CI_REPO_NAME":        "backend",
+		"DRONE_BUILD_STATUS":  "success",
+	})
+	return &received
+}
+
+func TestMain_DeliversToChats(t *testing.T) {
+	for _, useCard := range []string{"true", "false"} {
+		t.Run("use_card="+useCard, func(t *testing.T) {
+			received := setupChatServer(t, nil)
+			setEnvFixture(t, map[string]string{
+				"PLUGIN_CHAT_ID":  "oc_one, oc_two",
+				"PLUGIN_USE_CARD": useCard,
+				"PLUGIN_SECRET":   "webhook-secret",
+			})
+			exitCode := 0
+			osExit = func(code int) { exitCode = code }
+
+			main()
+
+			if exitCode != 0 {
+				t.Errorf("Expected exit code 0, got %d", exitCode)
+			}
+			if len(*received) != 2 || (*received)[0]["receive_id"] != "oc_one" || (*received)[1]["receive_id"] != "oc_two" {
+				t.Fatalf("Expected messages to oc_one and oc_two, got %v", *received)
+			}
+
+			body := (*received)[0]
+			var content map[string]any
+			if err := json.Unmarshal([]byte(body["content"]), &content); err != nil {
+				t.Fatalf("Expected content to be a JSON string, got %q", body["content"])
+			}
+			if useCard == "true" {
+				if body["msg_type"] != "interactive" || content["header"] == nil {
+					t.Errorf("Expected an interactive card, got %v", body)
+				}
+			} else if body["msg_type"] != "text" || !strings.Contains(content["text"].(string), "PIPELINE SUCCEEDED") {
+				t.Errorf("Expected a text message, got %v", body)
+			}
+			if _, ok := body["sign"]; ok {
+				t.Error("Webhook signatures must not be sent to the OpenAPI")
+			}
+		})
+	}
+}
+
+func TestMain_ChatAPIError(t *testing.T) {
+	setupChatServer(t, map[string]bool{"oc_gone": true})
+	setEnvFixture(t, map[string]string{"PLUGIN_CHAT_ID": "oc_gone"})
+	exitCode := 0
+	osExit = func(code int) { exitCode = code }
+
+	output := captureOutput(t, main)
+
+	if exitCode != exitRejected {
+		t.Errorf("Expected exit code 4, got %d", exitCode)
+	}
+	if !strings.Contains(output, "Lark API error 230002") {
+		t.Errorf("Expected the API error to be reported, got:\n%s", output)
+	}
+}
+
+func TestChatTargets_RequireCredentials(t *testing.T) {
+	setEnvFixture(t, map[string]string{"PLUGIN_CHAT_ID": "oc_one", "PLUGIN_APP_ID": "", "PLUGIN_APP_SECRET": ""})
+
+	if targets := chatTargets(); targets != nil {
+		t.Errorf("Expected no chat targets without credentials, got %v", targets)
+	}
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CI systems that need their variables mapped onto the Woodpecker names
+const (
+	ciProviderGitHubActions = "github-actions"
+	ciProviderGiteaActions  = "gitea-actions"
+	ciProviderGitLabCI      = "gitlab-ci"
+)
+
+// Forge types as reported by Woodpecker in CI_FORGE_TYPE
+const (
+	forgeGitHub  = "github"
+	forgeGitea   = "gitea"
+	forgeForgejo = "forgejo"
+	forgeGitLab  = "gitlab"
+)
+
+// ciEnv holds CI_* values derived from a non-Woodpecker CI environment.
+// getEnvOrDefault consults it before the process environment.
+var ciEnv map[string]string
+
+// detectCIProvider returns the CI system whose variables must be mapped, or ""
+// when the Woodpecker/Drone variables can be used as they are. Gitea Actions
+// is only recognized by GITEA_ACTIONS=true: a GITHUB_SERVER_URL other than
+// github.com is deliberately not taken as a sign of Gitea, because GitHub
+// Enterprise Server runners have one too.
+func detectCIProvider() string {
+	// GitLab uses the CI_ prefix too, with other names and meanings
+	if os.Getenv("GITLAB_CI") == "true" {
+		return ciProviderGitLabCI
+	}
+	if os.Getenv("GITEA_ACTIONS") == "true" {
+		return ciProviderGiteaActions
+	}
+	// Gitea Actions sets GITHUB_ACTIONS too, and was recognized above
+	if os.Getenv("GITHUB_ACTIONS") == "true" {
+		return ciProviderGitHubActions
+	}
+	return ""
+}
+
+// applyCIEnvironment detects the CI system and fills ciEnv with the
+// corresponding CI_* values, then applies PLUGIN_ENV_MAPPING.
+func applyCIEnvironment() {
+	switch detectCIProvider() {
+	case ciProviderGitHubActions:
+		ciEnv = mapGitHubEnvironment(forgeGitHub)
+	case ciProviderGiteaActions:
+		ciEnv = mapGitHubEnvironment(forgeGitea)
+	case ciProviderGitLabCI:
+		ciEnv = mapGitLabEnvironment()
+	default:
+		ciEnv = nil
+	}
+	applyEnvMapping()
+}
+
+// mapGitHubEnvironment maps the GITHUB_* variables used by GitHub Actions and
+// Gitea Actions onto the Woodpecker variable names.
+func mapGitHubEnvironment(forgeType string) map[string]string {
+	serverURL := strings.TrimSuffix(os.Getenv("GITHUB_SERVER_URL"), "/")
+	repo := os.Getenv("GITHUB_REPOSITORY")
+	sha := os.Getenv("GITHUB_SHA")
+
+	env := map[string]string{
+		"CI_FORGE_TYPE":      forgeType,
+		"CI_FORGE_URL":       serverURL,
+		"CI_REPO":            repo,
+		"CI_COMMIT_SHA":      sha,
+		"CI_COMMIT_AUTHOR":   os.Getenv("GITHUB_ACTOR"),
+		"CI_PIPELINE_EVENT":  os.Getenv("GITHUB_EVENT_NAME"),
+		"CI_PIPELINE_NUMBER": os.Getenv("GITHUB_RUN_NUMBER"),
+		"CI_COMMIT_REF":      os.Getenv("GITHUB_REF"),
+	}
+
+	if _, name, found := strings.Cut(repo, "/"); found {
+		env["CI_REPO_NAME"] = name
+	}
+
+	// GITHUB_RUN_ATTEMPT counts from 1, CI_PIPELINE_RETRY counts the re-runs
+	if attempt, err := strconv.Atoi(os.Getenv("GITHUB_RUN_ATTEMPT")); err == nil && attempt > 1 {
+		env["CI_PIPELINE_RETRY"] = strconv.Itoa(attempt - 1)
+	}
+
+	if os.Getenv("GITHUB_REF_TYPE") == "tag" {
+		env["CI_COMMIT_TAG"] = os.Getenv("GITHUB_REF_NAME")
+	} else if headRef := os.Getenv("GITHUB_HEAD_REF"); headRef != "" {
+		env["CI_COMMIT_BRANCH"] = headRef
+	} else {
+		env["CI_COMMIT_BRANCH"] = os.Getenv("GITHUB_REF_NAME")
+	}
+
+	if serverURL != "" && repo != "" {
+		repoURL := serverURL + "/" + repo
+		env["CI_REPO_URL"] = repoURL
+
+		// GitHub links runs by id, Gitea by the per-repository run number
+		runID := os.Getenv("GITHUB_RUN_ID")
+		if forgeType == forgeGitea {
+			runID = os.Getenv("GITHUB_RUN_NUMBER")
+		}
+		if runID != "" {
+			env["CI_PIPELINE_URL"] = fmt.Sprintf("%s/actions/runs/%s", repoURL, runID)
+		}
+		if sha != "" {
+			env["CI_PIPELINE_FORGE_URL"] = forgeCommitURL(repoURL, sha)
+		}
+	}
+
+	return env
+}
+
+// gitLabEvents maps CI_PIPELINE_SOURCE onto the Woodpecker pipeline events
+var gitLabEvents = map[string]string{
+	"push":                "push",
+	"merge_request_event": "pull_request",
+	"schedule":            "cron",
+	"web":                 "manual",
+	"api":                 "manual",
+	"trigger":             "manual",
+	"chat":                "manual",
+}
+
+// gitLabStatuses maps CI_JOB_STATUS onto the Woodpecker statuses
+var gitLabStatuses = map[string]string{
+	"success":  "success",
+	"failed":   "failure",
+	"canceled": "canceled",
+}
+
+// mapGitLabEnvironment maps GitLab's predefined variables onto the Woodpecker
+// variable names. Names both systems share, such as CI_COMMIT_SHA,
+// CI_COMMIT_TAG and CI_PIPELINE_URL, mean the same and are read as they are.
+func mapGitLabEnvironment() map[string]string {
+	repoURL := os.Getenv("CI_PROJECT_URL")
+	sha := os.Getenv("CI_COMMIT_SHA")
+	tag := os.Getenv("CI_COMMIT_TAG")
+
+	env := map[string]string{
+		"CI_FORGE_TYPE":      forgeGitLab,
+		"CI_FORGE_URL":       os.Getenv("CI_SERVER_URL"),
+		"CI_REPO":            os.Getenv("CI_PROJECT_PATH"),
+		"CI_REPO_NAME":       os.Getenv("CI_PROJECT_NAME"),
+		"CI_REPO_URL":        repoURL,
+		"CI_PIPELINE_NUMBER": os.Getenv("CI_PIPELINE_IID"),
+		"CI_MACHINE":         os.Getenv("CI_RUNNER_DESCRIPTION"),
+		"DRONE_BUILD_STATUS": gitLabStatuses[os.Getenv("CI_JOB_STATUS")],
+	}
+
+	// GitLab's CI_COMMIT_AUTHOR is "Name <email>"
+	author := os.Getenv("CI_COMMIT_AUTHOR")
+	if name, email, found := strings.Cut(author, " <"); found {
+		env["CI_COMMIT_AUTHOR"] = name
+		env["CI_COMMIT_AUTHOR_EMAIL"] = strings.TrimSuffix(email, ">")
+	}
+
+	event := gitLabEvents[os.Getenv("CI_PIPELINE_SOURCE")]
+	if tag != "" {
+		event = "tag"
+	}
+	env["CI_PIPELINE_EVENT"] = event
+
+	if mr := os.Getenv("CI_MERGE_REQUEST_IID"); mr != "" {
+		env["CI_COMMIT_PULL_REQUEST"] = mr
+		env["CI_COMMIT_PULL_REQUEST_TITLE"] = os.Getenv("CI_MERGE_REQUEST_TITLE")
+		env["CI_COMMIT_SOURCE_BRANCH"] = os.Getenv("CI_MERGE_REQUEST_SOURCE_BRANCH_NAME")
+		env["CI_COMMIT_TARGET_BRANCH"] = os.Getenv("CI_MERGE_REQUEST_TARGET_BRANCH_NAME")
+		env["CI_COMMIT_BRANCH"] = env["CI_COMMIT_SOURCE_BRANCH"]
+	} else if tag == "" {
+		env["CI_COMMIT_BRANCH"] = os.Getenv("CI_COMMIT_REF_NAME")
+	}
+
+	if started, err := time.Parse(time.RFC3339, os.Getenv("CI_PIPELINE_CREATED_AT")); err == nil {
+		env["CI_PIPELINE_STARTED"] = strconv.FormatInt(started.Unix(), 10)
+	}
+	if repoURL != "" && sha != "" {
+		env["CI_PIPELINE_FORGE_URL"] = fmt.Sprintf("%s/-/commit/%s", repoURL, sha)
+	}
+
+	return env
+}
+
+// actionInput returns the GitHub Actions input of a PLUGIN_* setting. Actions
+// pass the with: inputs of Docker actions as INPUT_<NAME>, upper-cased with
+// hyphens kept, so both webhook_url and webhook-url are found.
+func actionInput(key string) string {
+	name, ok := strings.CutPrefix(key, "PLUGIN_")
+	if !ok {
+		return ""
+	}
+	if value := os.Getenv("INPUT_" + name); value != "" {
+		return value
+	}
+	return os.Getenv("INPUT_" + strings.ReplaceAll(name, "_", "-"))
+}
+
+// getForgeType returns CI_FORGE_TYPE, or guesses it from the CI_REPO_URL host
+// when it is unset
+func getForgeType() string {
+	if forgeType := getEnvOrDefault("CI_FORGE_TYPE", ""); forgeType != "" {
+		return forgeType
+	}
+	u, err := url.Parse(getEnvOrDefault("CI_REPO_URL", ""))
+	if err != nil {
+		return ""
+	}
+	host := strings.ToLower(u.Hostname())
+	switch {
+	case strings.Contains(host, "gitlab"):
+		return forgeGitLab
+	case strings.Contains(host, "forgejo"), strings.Contains(host, "codeberg"):
+		return forgeForgejo
+	case strings.Contains(host, "gitea"):
+		return forgeGitea
+	}
+	return ""
+}
+
+func isGiteaForge() bool {
+	forgeType := getForgeType()
+	return forgeType == forgeGitea || forgeType == forgeForgejo
+}
+
+func isGitLabForge() bool {
+	return getForgeType() == forgeGitLab
+}
+
+// forgeCommitURL builds the web URL of a commit for the current forge
+func forgeCommitURL(repoURL, sha string) string {
+	if isGitLabForge() {
+		return fmt.Sprintf("%s/-/commit/%s", repoURL, sha)
+	}
+	return fmt.Sprintf("%s/commit/%s", repoURL, sha)
+}
+
+// forgeBranchURL builds the web URL of a branch for the current forge
+func forgeBranchURL(repoURL, branch string) string {
+	switch {
+	case isGiteaForge():
+		return fmt.Sprintf("%s/src/branch/%s", repoURL, branch)
+	case isGitLabForge():
+		return fmt.Sprintf("%s/-/tree/%s", repoURL, branch)
+	default:
+		return fmt.Sprintf("%s/tree/%s", repoURL, branch)
+	}
+}
+
+// forgeReleaseURL builds the web URL of a release for the current forge
+func forgeReleaseURL(repoURL, tag string) string {
+	if isGitLabForge() {
+		return fmt.Sprintf("%s/-/releases/%s", repoURL, tag)
+	}
+	return fmt.Sprintf("%s/releases/tag/%s", repoURL, tag)
+}
+
+// forgeCompareURL builds the web URL comparing two revisions for the current forge
+func forgeCompareURL(repoURL, from, to string) string {
+	if isGitLabForge() {
+		return fmt.Sprintf("%s/-/compare/%s...%s", repoURL, from, to)
+	}
+	return fmt.Sprintf("%s/compare/%s...%s", repoURL, from, to)
+}
+
+// forgePullRequestURL builds the web URL of a pull request for the current
+// forge. PLUGIN_PR_URL_FORMAT overrides it, with {repo} and {number} placeholders.
+func forgePullRequestURL(repoURL, number string) string {
+	if format := getEnvOrDefault("PLUGIN_PR_URL_FORMAT", ""); format != "" {
+		return strings.NewReplacer("{repo}", repoURL, "{number}", number).Replace(format)
+	}
+
+	switch {
+	case isGiteaForge():
+		return fmt.Sprintf("%s/pulls/%s", repoURL, number)
+	case isGitLabForge():
+		return fmt.Sprintf("%s/-/merge_requests/%s", repoURL, number)
+	default:
+		return fmt.Sprintf("%s/pull/%s", repoURL, number)
+	}
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// setEnvFixture sets every variable in env for the duration of the test and
+// clears the derived CI environment afterwards. Variables of the CI system the
+// tests run on, such as GITHUB_ACTIONS on this repo's own runners, are unset
+// unless a fixture sets them. Previous values are restored after the test.
+func setEnvFixture(t *testing.T, env map[string]string) {
+	t.Helper()
+	for _, entry := range os.Environ() {
+		name, _, _ := strings.Cut(entry, "=")
+		if _, ok := env[name]; !ok && isCIProviderVariable(name) && fixtureVariables[name] == 0 {
+			unsetEnv(t, name)
+		}
+	}
+	for key, value := range env {
+		t.Setenv(key, value)
+		fixtureVariables[key]++
+	}
+	t.Cleanup(func() {
+		for key := range env {
+			fixtureVariables[key]--
+		}
+		ciEnv = nil
+		fileSettings = nil
+		flagSettings = nil
+		envFile = nil
+	})
+}
+
+// isCIProviderVariable reports whether name is set by the CI system the tests
+// may run on: the GitHub, Gitea and GitLab markers and the Woodpecker and
+// Drone CI_* and DRONE_* variables
+func isCIProviderVariable(name string) bool {
+	for _, prefix := range []string{"GITHUB_", "CI_", "DRONE_"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return name == "CI" || name == "GITEA_ACTIONS" || name == "GITLAB_CI"
+}
+
+// fixtureVariables counts the active fixtures setting each variable, so that
+// a fixture layered on top of another keeps the CI variables of the first
+var fixtureVariables = map[string]int{}
+
+// unsetEnv unsets name for the duration of the test
+func unsetEnv(t *testing.T, name string) {
+	t.Setenv(name, "")
+	os.Unsetenv(name)
+}
+
+var githubActionsFixture = map[string]string{
+	"GITHUB_ACTIONS":    "true",
+	"GITHUB_SERVER_URL": "https://github.com",
+	"GITHUB_REPOSITORY": "octo-org/backend",
+	"GITHUB_SHA":        "0123456789abcdef0123456789abcdef01234567",
+	"GITHUB_REF":        "refs/heads/main",
+	"GITHUB_REF_NAME":   "main",
+	"GITHUB_REF_TYPE":   "branch",
+	"GITHUB_ACTOR":      "octocat",
+	"GITHUB_EVENT_NAME": "push",
+	"GITHUB_RUN_ID":     "9876543210",
+	"GITHUB_RUN_NUMBER": "42",
+}
+
+var giteaActionsFixture = map[string]string{
+	"GITEA_ACTIONS":     "true",
+	"GITHUB_ACTIONS":    "true",
+	"GITHUB_SERVER_URL": "https://gitea.example.com",
+	"GITHUB_REPOSITORY": "platform/backend",
+	"GITHUB_SHA":        "0123456789abcdef0123456789abcdef01234567",
+	"GITHUB_REF":        "refs/tags/v1.2.0",
+	"GITHUB_REF_NAME":   "v1.2.0",
+	"GITHUB_REF_TYPE":   "tag",
+	"GITHUB_ACTOR":      "alice",
+	"GITHUB_EVENT_NAME": "push",
+	"GITHUB_RUN_ID":     "1234",
+	"GITHUB_RUN_NUMBER": "17",
+}
+
+var gitLabCIFixture = map[string]string{
+	"GITLAB_CI":              "true",
+	"CI_SERVER_URL":          "https://gitlab.example.com",
+	"CI_PROJECT_PATH":        "group/backend",
+	"CI_PROJECT_NAME":        "backend",
+	"CI_PROJECT_URL":         "https://gitlab.example.com/group/backend",
+	"CI_COMMIT_SHA":          "0123456789abcdef0123456789abcdef01234567",
+	"CI_COMMIT_SHORT_SHA":    "01234567",
+	"CI_COMMIT_REF_NAME":     "main",
+	"CI_COMMIT_BRANCH":       "main",
+	"CI_COMMIT_AUTHOR":       "Alice Liddell <alice@example.com>",
+	"CI_COMMIT_MESSAGE":      "Fix the build",
+	"CI_PIPELINE_URL":        "https://gitlab.example.com/group/backend/-/pipelines/1234",
+	"CI_PIPELINE_IID":        "56",
+	"CI_PIPELINE_SOURCE":     "push",
+	"CI_PIPELINE_CREATED_AT": "2024-06-01T10:00:00Z",
+	"CI_JOB_STATUS":          "failed",
+}
+
+func TestDetectCIProvider(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected string
+	}{
+		{"Woodpecker", map[string]string{"CI": "woodpecker"}, ""},
+		{"GitHub Actions", githubActionsFixture, ciProviderGitHubActions},
+		{"Gitea Actions", giteaActionsFixture, ciProviderGiteaActions},
+		{"Gitea Actions by marker", map[string]string{"GITHUB_ACTIONS": "true", "GITEA_ACTIONS": "true"}, ciProviderGiteaActions},
+		{"GitHub Enterprise Server", map[string]string{"GITHUB_ACTIONS": "true", "GITHUB_SERVER_URL": "https://github.example.com"}, ciProviderGitHubActions},
+		{"GitHub Actions without server URL", map[string]string{"GITHUB_ACTIONS": "true"}, ciProviderGitHubActions},
+		{"GitLab CI", gitLabCIFixture, ciProviderGitLabCI},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setEnvFixture(t, tc.env)
+
+			if provider := detectCIProvider(); provider != tc.expected {
+				t.Errorf("Expected provider '%s', got '%s'", tc.expected, provider)
+			}
+		})
+	}
+}
+
+func TestApplyCIEnvironment_GiteaActions(t *testing.T) {
+	setEnvFixture(t, giteaActionsFixture)
+	applyCIEnvironment()
+
+	expected := map[string]string{
+		"CI_FORGE_TYPE":         forgeGitea,
+		"CI_REPO":               "platform/backend",
+		"CI_REPO_NAME":          "backend",
+		"CI_REPO_URL":           "https://gitea.example.com/platform/backend",
+		"CI_COMMIT_TAG":         "v1.2.0",
+		"CI_COMMIT_BRANCH":      "",
+		"CI_COMMIT_AUTHOR":      "alice",
+		"CI_PIPELINE_URL":       "https://gitea.example.com/platform/backend/actions/runs/17",
+		"CI_PIPELINE_FORGE_URL": "https://gitea.example.com/platform/backend/commit/0123456789abcdef0123456789abcdef01234567",
+	}
+	for key, value := range expected {
+		if actual := getEnvOrDefault(key, ""); actual != value {
+			t.Errorf("Expected %s='%s', got '%s'", key, value, actual)
+		}
+	}
+
+	actions := createActionButtons(Config{})
+	if len(actions) != 2 {
+		t.Fatalf("Expected 2 buttons, got %d", len(actions))
+	}
+	if url := actions[1]["url"]; url != "https://gitea.example.com/platform/backend/releases/tag/v1.2.0" {
+		t.Errorf("Unexpected release URL '%s'", url)
+	}
+}
+
+func TestApplyCIEnvironment_GitHubActions(t *testing.T) {
+	setEnvFixture(t, githubActionsFixture)
+	setEnvFixture(t, map[string]string{"INPUT_STATUS": "failure", "PLUGIN_STATUS": "", "PLUGIN_EMOJI": "false"})
+	applyCIEnvironment()
+
+	// The status comes from the mapped CI variables
+	config := Config{Status: getBuildStatus()}
+	card := createLarkCard(config, getProjectVersion())["card"].(map[string]any)
+	if title := card["header"].(map[string]any)["title"].(map[string]any)["content"].(string); !strings.Contains(title, "Pipeline Failed") {
+		t.Errorf("Expected the INPUT_STATUS status in the title, got %s", title)
+	}
+
+	elements := card["elements"].([]map[string]any)
+	metadata := elements[0]["text"].(map[string]any)["content"].(string)
+	for _, expected := range []string{
+		"**Project:** octo-org/backend",
+		"**Branch:** main",
+		"**Author:** octocat",
+		"**Version:** 0123456",
+	} {
+		if !strings.Contains(metadata, expected) {
+			t.Errorf("Expected %q in the card details:\n%s", expected, metadata)
+		}
+	}
+
+	actions := createActionButtons(config)
+	if len(actions) != 2 {
+		t.Fatalf("Expected pipeline and commit buttons, got %v", actions)
+	}
+	if url := actions[0]["url"]; url != "https://github.com/octo-org/backend/actions/runs/9876543210" {
+		t.Errorf("Unexpected pipeline URL '%s'", url)
+	}
+	if url := actions[1]["url"]; url != "https://github.com/octo-org/backend/commit/0123456789abcdef0123456789abcdef01234567" {
+		t.Errorf("Unexpected commit URL '%s'", url)
+	}
+	if event := getPipelineEvent(); event != "push" {
+		t.Errorf("Expected event push, got %s", event)
+	}
+}
+
+func TestApplyCIEnvironment_GitLabCI(t *testing.T) {
+	setEnvFixture(t, gitLabCIFixture)
+	setEnvFixture(t, map[string]string{"PLUGIN_STATUS": "", "PLUGIN_EMOJI": "false", "CI_REPO": "", "CI_REPO_URL": ""})
+	applyCIEnvironment()
+
+	expected := map[string]string{
+		"CI_FORGE_TYPE":          forgeGitLab,
+		"CI_REPO":                "group/backend",
+		"CI_REPO_NAME":           "backend",
+		"CI_COMMIT_BRANCH":       "main",
+		"CI_COMMIT_AUTHOR":       "Alice Liddell",
+		"CI_COMMIT_AUTHOR_EMAIL": "alice@example.com",
+		"CI_PIPELINE_EVENT":      "push",
+		"CI_PIPELINE_NUMBER":     "56",
+		"CI_PIPELINE_STARTED":    "1717236000",
+		"DRONE_BUILD_STATUS":     "failure",
+	}
+	for key, value := range expected {
+		if actual := getEnvOrDefault(key, ""); actual != value {
+			t.Errorf("Expected %s='%s', got '%s'", key, value, actual)
+		}
+	}
+
+	// The status comes from the mapped CI variables
+	config := Config{Status: getBuildStatus()}
+	card := createLarkCard(config, getProjectVersion())["card"].(map[string]any)
+	if template := card["header"].(map[string]any)["template"]; template != "red" {
+		t.Errorf("Expected a red failure card, got %v", template)
+	}
+	metadata := card["elements"].([]map[string]any)[0]["text"].(map[string]any)["content"].(string)
+	for _, expected := range []string{"**Project:** group/backend", "**Branch:** main", "**Author:** Alice Liddell", "**Version:** 0123456"} {
+		if !strings.Contains(metadata, expected) {
+			t.Errorf("Expected %q in the card details:\n%s", expected, metadata)
+		}
+	}
+
+	actions := createActionButtons(config)
+	if len(actions) != 2 {
+		t.Fatalf("Expected pipeline and commit buttons, got %v", actions)
+	}
+	if url := actions[0]["url"]; url != "https://gitlab.example.com/group/backend/-/pipelines/1234" {
+		t.Errorf("Unexpected pipeline URL '%s'", url)
+	}
+	if url := actions[1]["url"]; url != "https://gitlab.example.com/group/backend/-/commit/0123456789abcdef0123456789abcdef01234567" {
+		t.Errorf("Unexpected commit URL '%s'", url)
+	}
+}
+
+func TestApplyCIEnvironment_GitLabMergeRequestAndTag(t *testing.T) {
+	setEnvFixture(t, gitLabCIFixture)
+	setEnvFixture(t, map[string]string{
+		"CI_PIPELINE_SOURCE":                  "merge_request_event",
+		"CI_MERGE_REQUEST_IID":                "7",
+		"CI_MERGE_REQUEST_TITLE":              "Add caching",
+		"CI_MERGE_REQUEST_SOURCE_BRANCH_NAME": "feature/cache",
+		"CI_MERGE_REQUEST_TARGET_BRANCH_NAME": "main",
+	})
+	applyCIEnvironment()
+
+	if event := getEnvOrDefault("CI_PIPELINE_EVENT", ""); event != "pull_request" {
+		t.Errorf("Expected a pull_request event, got '%s'", event)
+	}
+	if branch := getEnvOrDefault("CI_COMMIT_BRANCH", ""); branch != "feature/cache" {
+		t.Errorf("Expected the source branch, got '%s'", branch)
+	}
+	if _, url := pullRequestRef(); url != "https://gitlab.example.com/group/backend/-/merge_requests/7" {
+		t.Errorf("Unexpected merge request URL '%s'", url)
+	}
+
+	os.Unsetenv("CI_MERGE_REQUEST_IID")
+	os.Setenv("CI_PIPELINE_SOURCE", "push")
+	os.Setenv("CI_COMMIT_TAG", "v1.2.0")
+	os.Unsetenv("CI_COMMIT_BRANCH")
+	defer os.Unsetenv("CI_COMMIT_TAG")
+	applyCIEnvironment()
+
+	if event := getEnvOrDefault("CI_PIPELINE_EVENT", ""); event != "tag" {
+		t.Errorf("Expected a tag event, got '%s'", event)
+	}
+	if version := getProjectVersion(); version != "v1.2.0" {
+		t.Errorf("Expected the tag as the version, got '%s'", version)
+	}
+}
+
+func TestActionInputs(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"INPUT_WEBHOOK_URL": "https://open.larksuite.com/hook/input",
+		"INPUT_USE-CARD":    "false",
+		"INPUT_SECRET":      "input-secret",
+		"PLUGIN_SECRET":     "plugin-secret",
+		"INPUT_STATUS":      "cancelled",
+	})
+
+	if value := getEnvOrDefault("PLUGIN_WEBHOOK_URL", ""); value != "https://open.larksuite.com/hook/input" {
+		t.Errorf("Expected INPUT_WEBHOOK_URL, got '%s'", value)
+	}
+	if value := getEnvOrDefault("PLUGIN_USE_CARD", "true"); value != "false" {
+		t.Errorf("Expected the hyphenated INPUT_USE-CARD, got '%s'", value)
+	}
+	if value := getEnvOrDefault("PLUGIN_SECRET", ""); value != "plugin-secret" {
+		t.Errorf("Expected PLUGIN_SECRET to win over INPUT_SECRET, got '%s'", value)
+	}
+	if value := getEnvOrDefault("CI_REPO", "default"); value != "default" {
+		t.Errorf("Expected INPUT_* to apply to plugin settings only, got '%s'", value)
+	}
+	if status := getBuildStatus(); status != "canceled" {
+		t.Errorf("Expected GitHub's cancelled to be read as canceled, got '%s'", status)
+	}
+}
+
+func TestApplyCIEnvironment_GitHubActionsIsNotGitea(t *testing.T) {
+	setEnvFixture(t, githubActionsFixture)
+	applyCIEnvironment()
+
+	if forgeType := getEnvOrDefault("CI_FORGE_TYPE", ""); forgeType == forgeGitea {
+		t.Error("GitHub Actions must not be mapped as a Gitea forge")
+	}
+	if isGiteaForge() {
+		t.Error("Expected GitHub style URLs for GitHub Actions")
+	}
+}
+
+func TestForgeURLs(t *testing.T) {
+	repoURL := "https://forge.example.com/org/repo"
+
+	tests := []struct {
+		forgeType       string
+		expectedBranch  string
+		expectedRelease string
+		expectedCompare string
+		expectedCommit  string
+	}{
+		{
+			forgeType:       forgeGitHub,
+			expectedBranch:  repoURL + "/tree/main",
+			expectedRelease: repoURL + "/releases/tag/v1.0.0",
+			expectedCompare: repoURL + "/compare/v0.9.0...v1.0.0",
+			expectedCommit:  repoURL + "/commit/abc123",
+		},
+		{
+			forgeType:       forgeGitea,
+			expectedBranch:  repoURL + "/src/branch/main",
+			expectedRelease: repoURL + "/releases/tag/v1.0.0",
+			expectedCompare: repoURL + "/compare/v0.9.0...v1.0.0",
+			expectedCommit:  repoURL + "/commit/abc123",
+		},
+		{
+			forgeType:       forgeGitLab,
+			expectedBranch:  repoURL + "/-/tree/main",
+			expectedRelease: repoURL + "/-/releases/v1.0.0",
+			expectedCompare: repoURL + "/-/compare/v0.9.0...v1.0.0",
+			expectedCommit:  repoURL + "/-/commit/abc123",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.forgeType, func(t *testing.T) {
+			os.Setenv("CI_FORGE_TYPE", tc.forgeType)
+			defer os.Unsetenv("CI_FORGE_TYPE")
+
+			if url := forgeBranchURL(repoURL, "main"); url != tc.expectedBranch {
+				t.Errorf("Expected branch URL '%s', got '%s'", tc.expectedBranch, url)
+			}
+			if url := forgeReleaseURL(repoURL, "v1.0.0"); url != tc.expectedRelease {
+				t.Errorf("Expected release URL '%s', got '%s'", tc.expectedRelease, url)
+			}
+			if url := forgeCompareURL(repoURL, "v0.9.0", "v1.0.0"); url != tc.expectedCompare {
+				t.Errorf("Expected compare URL '%s', got '%s'", tc.expectedCompare, url)
+			}
+			if url := forgeCommitURL(repoURL, "abc123"); url != tc.expectedCommit {
+				t.Errorf("Expected commit URL '%s', got '%s'", tc.expectedCommit, url)
+			}
+		})
+	}
+}
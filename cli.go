@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// cliFlag mirrors a setting as a command line flag
+type cliFlag struct {
+	Name    string
+	Setting string
+	Default string
+	Usage   string
+	Bool    bool
+}
+
+// cliFlags are the settings available as flags, for running the plugin
+// outside of CI. Every other setting is only read from the environment.
+var cliFlags = []cliFlag{
+	{Name: "provider", Setting: "PLUGIN_PROVIDER", Default: providerLark, Usage: "Chat service: lark, wecom or dingtalk"},
+	{Name: "webhook-url", Setting: "PLUGIN_WEBHOOK_URL", Usage: "Lark webhook URL, or a comma-separated list"},
+	{Name: "secret", Setting: "PLUGIN_SECRET", Usage: "Secret for signature verification"},
+	{Name: "chat-id", Setting: "PLUGIN_CHAT_ID", Usage: "Comma-separated chat ids to send to as the app bot"},
+	{Name: "status", Setting: "PLUGIN_STATUS", Usage: "Override the build status"},
+	{Name: "use-card", Setting: "PLUGIN_USE_CARD", Default: "true", Usage: "Send an interactive card instead of a text message", Bool: true},
+	{Name: "msg-type", Setting: "PLUGIN_MSG_TYPE", Usage: "Message type: card, text or post, overrides --use-card"},
+	{Name: "compact", Setting: "PLUGIN_COMPACT", Default: "false", Usage: "Send only the header, one line of details and the pipeline button", Bool: true},
+	{Name: "detail", Setting: "PLUGIN_DETAIL", Default: detailFull, Usage: "Detail level: full, minimal, or auto for minimal on success"},
+	{Name: "layout", Setting: "PLUGIN_LAYOUT", Default: layoutList, Usage: "Card layout, list or columns"},
+	{Name: "lang", Setting: "PLUGIN_LANG", Default: defaultLocale, Usage: "Comma-separated card languages"},
+	{Name: "variables", Setting: "PLUGIN_VARIABLES", Usage: "Comma-separated environment variables to show"},
+	{Name: "buttons", Setting: "PLUGIN_BUTTONS", Usage: "Comma-separated buttons to show"},
+	{Name: "message", Setting: "PLUGIN_MESSAGE", Usage: "Custom message appended to the notification"},
+	{Name: "content-file", Setting: "PLUGIN_CONTENT_FILE", Usage: "Comma-separated markdown files appended as sections"},
+	{Name: "template-file", Setting: "PLUGIN_TEMPLATE_FILE", Usage: "Card template file or https URL"},
+	{Name: "title-template", Setting: "PLUGIN_TITLE_TEMPLATE", Usage: "Template for the card title"},
+	{Name: "notify-on", Setting: "PLUGIN_NOTIFY_ON", Usage: "Comma-separated statuses to notify on"},
+	{Name: "payload-file", Setting: "PLUGIN_PAYLOAD_FILE", Usage: "Prebuilt Lark message file or https URL to sign and send, - for stdin"},
+	{Name: "output-file", Setting: "PLUGIN_OUTPUT_FILE", Usage: "File to write the sent payload to"},
+	{Name: "fail-on-error", Setting: "PLUGIN_FAIL_ON_ERROR", Default: "true", Usage: "Fail when the notification cannot be sent", Bool: true},
+	{Name: "dry-run", Setting: "PLUGIN_DRY_RUN", Default: "false", Usage: "Print the payload instead of sending it", Bool: true},
+	{Name: "debug", Setting: "PLUGIN_DEBUG", Default: "false", Usage: "Log the message JSON and the environment", Bool: true},
+	{Name: "quiet", Setting: "PLUGIN_QUIET", Default: "false", Usage: "Print only warnings and errors, to stderr", Bool: true},
+	{Name: "log-level", Setting: "PLUGIN_LOG_LEVEL", Default: "info", Usage: "Minimum log level: debug, info, warn or error"},
+	{Name: "log-format", Setting: "PLUGIN_LOG_FORMAT", Default: logFormatText, Usage: "Log format: text or json"},
+	{Name: "version", Setting: "PLUGIN_PRINT_VERSION", Default: "false", Usage: "Print the version and exit", Bool: true},
+}
+
+// flagSettings holds the settings given as flags. They take precedence over
+// every other source.
+var flagSettings map[string]string
+
+// parseFlags reads cliFlags from args into flagSettings. Only flags present
+// on the command line are set, so without flags the environment is used as
+// before. --help prints the flags and returns flag.ErrHelp.
+func parseFlags(args []string) error {
+	flagSettings = map[string]string{}
+
+	fs := flag.NewFlagSet("ci-lark-notification", flag.ContinueOnError)
+	fs.SetOutput(os.Stdout)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ci-lark-notification [flags]\n\nFlags override the environment variable shown with them:")
+		fs.PrintDefaults()
+	}
+
+	// values returns the setting name and value of each flag
+	values := map[string]func() (string, string){}
+	for _, f := range cliFlags {
+		usage := fmt.Sprintf("%s (%s)", f.Usage, f.Setting)
+		if f.Bool {
+			defaultValue, _ := strconv.ParseBool(f.Default)
+			value := fs.Bool(f.Name, defaultValue, usage)
+			values[f.Name] = func() (string, string) { return f.Setting, strconv.FormatBool(*value) }
+		} else {
+			value := fs.String(f.Name, f.Default, usage)
+			values[f.Name] = func() (string, string) { return f.Setting, *value }
+		}
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected argument %q, see --help", fs.Arg(0))
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		setting, value := values[f.Name]()
+		flagSettings[setting] = value
+	})
+	return nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		env      map[string]string
+		expected map[string]string
+	}{
+		{
+			name:     "No flags",
+			env:      map[string]string{"PLUGIN_USE_CARD": "false", "PLUGIN_STATUS": "failure"},
+			expected: map[string]string{"PLUGIN_USE_CARD": "false", "PLUGIN_STATUS": "failure", "PLUGIN_DEBUG": "default"},
+		},
+		{
+			name:     "Flag overrides environment",
+			args:     []string{"--status", "success", "--webhook-url=https://example.com/hook"},
+			env:      map[string]string{"PLUGIN_STATUS": "failure"},
+			expected: map[string]string{"PLUGIN_STATUS": "success", "PLUGIN_WEBHOOK_URL": "https://example.com/hook"},
+		},
+		{
+			name:     "Boolean false overrides true",
+			args:     []string{"--use-card=false"},
+			env:      map[string]string{"PLUGIN_USE_CARD": "true"},
+			expected: map[string]string{"PLUGIN_USE_CARD": "false"},
+		},
+		{
+			name:     "Boolean without value",
+			args:     []string{"-dry-run", "--debug"},
+			env:      map[string]string{"PLUGIN_DRY_RUN": "false"},
+			expected: map[string]string{"PLUGIN_DRY_RUN": "true", "PLUGIN_DEBUG": "true"},
+		},
+		{
+			name:     "Boolean default not applied",
+			args:     []string{"--status", "failure"},
+			env:      map[string]string{"PLUGIN_USE_CARD": "false"},
+			expected: map[string]string{"PLUGIN_USE_CARD": "false", "PLUGIN_FAIL_ON_ERROR": "default"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnvFixture(t, tt.env)
+			if err := parseFlags(tt.args); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for key, expected := range tt.expected {
+				if actual := getEnvOrDefault(key, "default"); actual != expected {
+					t.Errorf("Expected %s to be %q, got %q", key, expected, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestParseFlags_Errors(t *testing.T) {
+	t.Cleanup(func() { flagSettings = nil })
+
+	var err error
+	output := captureStdout(t, func() { err = parseFlags([]string{"--use-card=maybe"}) })
+	if err == nil || !strings.Contains(output, "invalid boolean value") {
+		t.Errorf("Expected an invalid boolean error, got %v and %q", err, output)
+	}
+
+	if err := parseFlags([]string{"--status", "failure", "extra"}); err == nil || !strings.Contains(err.Error(), `unexpected argument "extra"`) {
+		t.Errorf("Expected an unexpected argument error, got %v", err)
+	}
+}
+
+func TestMain_Help(t *testing.T) {
+	originalOsExit := osExit
+	originalArgs := os.Args
+	defer func() {
+		osExit = originalOsExit
+		os.Args = originalArgs
+		flagSettings = nil
+	}()
+	exitCode := 0
+	osExit = func(code int) { exitCode = code }
+
+	os.Args = []string{"app-entrypoint", "--help"}
+	output := captureOutput(t, main)
+
+	if exitCode != 0 {
+		t.Errorf("Expected exit code 0, got %d", exitCode)
+	}
+	for _, expected := range []string{
+		"Usage: ci-lark-notification [flags]",
+		"-webhook-url string\n",
+		"Lark webhook URL, or a comma-separated list (PLUGIN_WEBHOOK_URL)",
+		"-use-card\n",
+		"(PLUGIN_USE_CARD) (default true)",
+		"(PLUGIN_LOG_LEVEL) (default \"info\")",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in the help, got:\n%s", expected, output)
+		}
+	}
+
+	os.Args = []string{"app-entrypoint", "--no-such-flag"}
+	output = captureOutput(t, main)
+	if exitCode != 2 || !strings.Contains(output, "Error: flag provided but not defined: -no-such-flag") {
+		t.Errorf("Expected exit code 2 and an error, got %d and %q", exitCode, output)
+	}
+}
+
+func TestParseFlags_Help(t *testing.T) {
+	t.Cleanup(func() { flagSettings = nil })
+	captureStdout(t, func() {
+		if err := parseFlags([]string{"-h"}); !errors.Is(err, flag.ErrHelp) {
+			t.Errorf("Expected flag.ErrHelp, got %v", err)
+		}
+	})
+}
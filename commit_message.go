@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// defaultCommitMessageMaxLines limits PLUGIN_COMMIT_MESSAGE=full
+const defaultCommitMessageMaxLines = 20
+
+// normalizeCommitMessage removes carriage returns and trailing whitespace
+func normalizeCommitMessage(message string) string {
+	lines := strings.Split(strings.ReplaceAll(message, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
+
+func getCommitMessageMaxLines() int {
+	value := getEnvOrDefault("PLUGIN_COMMIT_MESSAGE_MAX_LINES", "")
+	if value == "" {
+		return defaultCommitMessageMaxLines
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		logWarn(fmt.Sprintf("invalid PLUGIN_COMMIT_MESSAGE_MAX_LINES %q, using %d", value, defaultCommitMessageMaxLines))
+		return defaultCommitMessageMaxLines
+	}
+	return n
+}
+
+// getCommitMessage returns the commit message as configured by
+// PLUGIN_COMMIT_MESSAGE: the first line (default) or the full message,
+// capped at PLUGIN_COMMIT_MESSAGE_MAX_LINES lines
+func getCommitMessage() string {
+	message := normalizeCommitMessage(getEnvOrDefault("CI_COMMIT_MESSAGE", ""))
+	lines := strings.Split(message, "\n")
+	if getEnvOrDefault("PLUGIN_COMMIT_MESSAGE", "first-line") != "full" {
+		return lines[0]
+	}
+
+	if maxLines := getCommitMessageMaxLines(); len(lines) > maxLines {
+		more := len(lines) - maxLines
+		return strings.Join(lines[:maxLines], "\n") + fmt.Sprintf("\n… (%d more lines)", more)
+	}
+	return message
+}
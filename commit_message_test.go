@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetCommitMessage(t *testing.T) {
+	message := "Release v2.0.0  \r\n\r\n- Add login\t\r\n- Fix logout\r\n\r\n"
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected string
+	}{
+		{"Default is the first line", map[string]string{}, "Release v2.0.0"},
+		{"Full message", map[string]string{"PLUGIN_COMMIT_MESSAGE": "full"}, "Release v2.0.0\n\n- Add login\n- Fix logout"},
+		{"Truncated", map[string]string{"PLUGIN_COMMIT_MESSAGE": "full", "PLUGIN_COMMIT_MESSAGE_MAX_LINES": "2"}, "Release v2.0.0\n\n… (2 more lines)"},
+		{"Invalid limit", map[string]string{"PLUGIN_COMMIT_MESSAGE": "full", "PLUGIN_COMMIT_MESSAGE_MAX_LINES": "zero"}, "Release v2.0.0\n\n- Add login\n- Fix logout"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.env["CI_COMMIT_MESSAGE"] = message
+			setEnvFixture(t, tc.env)
+
+			var result string
+			captureStdout(t, func() { result = getCommitMessage() })
+			if result != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestFullCommitMessageInCardAndText(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_COMMIT_MESSAGE": "full",
+		"CI_COMMIT_MESSAGE":     "Release v2.0.0\n\n- Add login",
+	})
+
+	elements := createLarkCard(Config{}, "v1.0.0")["card"].(map[string]any)["elements"].([]map[string]any)
+	content := elements[2]["text"].(map[string]any)["content"]
+	if content != "**Commit Message:**\nRelease v2.0.0\n\n- Add login" {
+		t.Errorf("Unexpected commit section %q", content)
+	}
+
+	text := createLarkTextMessage(Config{}, "v1.0.0")["content"].(map[string]any)["text"].(string)
+	if !strings.Contains(text, "💬 Message:\nRelease v2.0.0\n\n- Add login\n") {
+		t.Errorf("Expected full commit message in text, got %q", text)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// compactDetails joins the non-empty one-line details of a compact message
+func compactDetails() string {
+	var parts []string
+	for _, part := range []string{
+		getEnvOrDefault("CI_COMMIT_BRANCH", ""),
+		getEnvOrDefault("CI_COMMIT_AUTHOR", ""),
+		getBuildDuration(),
+	} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, " · ")
+}
+
+// compactCardContent returns the header title and the elements of the compact
+// card: one line of details and the pipeline button, whatever other sections
+// are configured
+func compactCardContent(config Config, projectVersion, statusIcon, statusText string) (string, []map[string]any) {
+	headerTitle := fmt.Sprintf("%s - %s", headerProjectName(), iconText(statusIcon, statusText))
+	if target := deployTarget(); target != "" {
+		headerTitle = fmt.Sprintf("%s %s", deployHeaderProject(headerProjectName(), target), iconText(statusIcon, statusText))
+	}
+	if projectVersion != "" {
+		headerTitle += " · " + projectVersion
+	}
+	if title, ok := customTitle(config, projectVersion, statusText); ok {
+		headerTitle = title
+	}
+	return headerTitle, oneLineElements(compactDetails(), mentionElement(config.Status))
+}
+
+// oneLineElements returns the elements of a one-line card: the details, the
+// mention element when not nil and the pipeline button
+func oneLineElements(details string, mention map[string]any) []map[string]any {
+	elements := []map[string]any{}
+	if details != "" {
+		elements = append(elements, map[string]any{
+			"tag": "div",
+			"text": map[string]any{
+				"content": escapeMarkdown(details),
+				"tag":     "lark_md",
+			},
+		})
+	}
+
+	if mention != nil {
+		elements = append(elements, mention)
+	}
+
+	if pipelineURL := getEnvOrDefault("CI_PIPELINE_URL", ""); pipelineURL != "" {
+		elements = append(elements, map[string]any{
+			"tag": "action",
+			"actions": []map[string]any{
+				{
+					"tag": "button",
+					"text": map[string]any{
+						"content": tr(deploymentLabel("View Pipeline")),
+						"tag":     "plain_text",
+					},
+					"type": "primary",
+					"url":  pipelineURL,
+				},
+			},
+		})
+	}
+	return elements
+}
+
+// createCompactLarkTextMessage is the two-line text equivalent of the compact card
+func createCompactLarkTextMessage(config Config, projectVersion, statusIcon, statusText string) map[string]any {
+	message := iconText(statusIcon, fmt.Sprintf("%s %s", getEnvOrDefault("CI_REPO_NAME", ""), statusText))
+	if projectVersion != "" {
+		message += " · " + projectVersion
+	}
+	if title, ok := customTitle(config, projectVersion, statusText); ok {
+		message = title
+	}
+
+	var details []string
+	if line := compactDetails(); line != "" {
+		details = append(details, escapeText(line))
+	}
+	if pipelineURL := getEnvOrDefault("CI_PIPELINE_URL", ""); pipelineURL != "" {
+		details = append(details, pipelineURL)
+	}
+	if len(details) > 0 {
+		message += "\n" + strings.Join(details, " · ")
+	}
+	if mentions := textMentionLine(config.Status); mentions != "" {
+		message += "\n" + mentions
+	}
+
+	return map[string]any{
+		"msg_type": "text",
+		"content": map[string]any{
+			"text": message,
+		},
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+var compactFixture = map[string]string{
+	"CI_REPO_NAME":      "backend",
+	"CI_COMMIT_BRANCH":  "main",
+	"CI_COMMIT_AUTHOR":  "octocat",
+	"CI_COMMIT_MESSAGE": "Fix the flaky test",
+	"CI_PIPELINE_URL":   "https://ci.example.com/repos/1/pipeline/42",
+}
+
+// compactConfig is a compact message for status that would otherwise list a variable
+func compactConfig(status string) Config {
+	return Config{Status: status, Compact: true, Variables: []string{"CI_COMMIT_MESSAGE"}}
+}
+
+func TestCreateLarkCard_CompactGolden(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   string
+		expected string
+	}{
+		{
+			name:     "Success",
+			status:   "success",
+			expected: `{"card":{"elements":[{"tag":"div","text":{"content":"main · octocat","tag":"lark_md"}},{"actions":[{"tag":"button","text":{"content":"View Pipeline","tag":"plain_text"},"type":"primary","url":"https://ci.example.com/repos/1/pipeline/42"}],"tag":"action"}],"header":{"template":"green","title":{"content":"backend - ✅ Pipeline Succeeded · v1.0.0","tag":"plain_text"}}},"msg_type":"interactive"}`,
+		},
+		{
+			name:     "Failure",
+			status:   "failure",
+			expected: `{"card":{"elements":[{"tag":"div","text":{"content":"main · octocat","tag":"lark_md"}},{"actions":[{"tag":"button","text":{"content":"View Pipeline","tag":"plain_text"},"type":"primary","url":"https://ci.example.com/repos/1/pipeline/42"}],"tag":"action"}],"header":{"template":"red","title":{"content":"backend - 🚨 Pipeline Failed · v1.0.0","tag":"plain_text"}}},"msg_type":"interactive"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setEnvFixture(t, compactFixture)
+
+			data, err := json.Marshal(createLarkCard(compactConfig(tc.status), "v1.0.0"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, data)
+			}
+		})
+	}
+}
+
+func TestCreateLarkTextMessage_CompactGolden(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   string
+		expected string
+	}{
+		{
+			name:     "Success",
+			status:   "success",
+			expected: "✅ backend PIPELINE SUCCEEDED · v1.0.0\nmain · octocat · https://ci.example.com/repos/1/pipeline/42",
+		},
+		{
+			name:     "Failure",
+			status:   "failure",
+			expected: "🚨 backend PIPELINE FAILED · v1.0.0\nmain · octocat · https://ci.example.com/repos/1/pipeline/42",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setEnvFixture(t, compactFixture)
+
+			message := createLarkTextMessage(compactConfig(tc.status), "v1.0.0")
+			text := message["content"].(map[string]any)["text"].(string)
+			if text != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, text)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// boolSettings are the true/false settings with their defaults
+var boolSettings = map[string]bool{
+	"PLUGIN_CARD_LINK":             false,
+	"PLUGIN_CHANGELOG":             false,
+	"PLUGIN_CHANGELOG_NO_MERGES":   false,
+	"PLUGIN_COMPACT":               false,
+	"PLUGIN_DEBUG":                 false,
+	"PLUGIN_DRY_RUN":               false,
+	"PLUGIN_EMOJI":                 true,
+	"PLUGIN_ENV_FILE_OVERRIDE":     false,
+	"PLUGIN_FAIL_ON_ERROR":         true,
+	"PLUGIN_FORCE_SIGN":            false,
+	"PLUGIN_HISTORY_PAYLOAD":       false,
+	"PLUGIN_INSECURE_SKIP_VERIFY":  false,
+	"PLUGIN_MATRIX_IN_TITLE":       false,
+	"PLUGIN_MENTION_AUTHOR":        false,
+	"PLUGIN_OUTPUT_PRETTY":         false,
+	"PLUGIN_PRINT_VERSION":         false,
+	"PLUGIN_PUBLIC_MODE":           false,
+	"PLUGIN_PUBLIC_SHOW_VAR_NAMES": false,
+	"PLUGIN_QUIET":                 false,
+	"PLUGIN_RAW_MARKDOWN":          false,
+	"PLUGIN_RETRY_BADGE":           false,
+	"PLUGIN_ROUTE_ADDITIVE":        false,
+	"PLUGIN_SHOW_DIFFSTAT":         false,
+	"PLUGIN_SHOW_DURATION":         true,
+	"PLUGIN_SHOW_FOOTER":           true,
+	"PLUGIN_SHOW_PLUGIN_VERSION":   false,
+	"PLUGIN_STRICT":                false,
+	"PLUGIN_USE_CARD":              true,
+	"PLUGIN_VARIABLES_SKIP_EMPTY":  true,
+}
+
+// Config holds the settings that decide how the notification is built and
+// delivered. It is loaded and validated once, before anything is built, and
+// the message builders take it as a parameter.
+type Config struct {
+	Provider    string
+	WebhookURLs []string
+	// StatusWebhookURLs are the PLUGIN_WEBHOOK_URL_<STATUS> lists by setting name
+	StatusWebhookURLs map[string][]string
+	ChatIDs           []string
+	Secret            string
+	// Status is the reported status; notify replaces it with the combined
+	// status of aggregated matrix legs
+	Status      string
+	UseCard     bool
+	MsgType     string
+	Compact     bool
+	Variables   []string
+	Buttons     []string
+	Debug       bool
+	Quiet       bool
+	DryRun      bool
+	FailOnError bool
+	Strict      bool
+	Phase       string
+	ImageFile   string
+
+	// hasAppCredentials tells whether ChatIDs can be used
+	hasAppCredentials bool
+	// problems are the invalid values found while loading
+	problems []error
+}
+
+// allWebhookURLs returns PLUGIN_WEBHOOK_URL and the status-scoped webhooks
+func (c Config) allWebhookURLs() []string {
+	webhookURLs := slices.Clone(c.WebhookURLs)
+	for _, name := range slices.Sorted(maps.Keys(c.StatusWebhookURLs)) {
+		webhookURLs = append(webhookURLs, c.StatusWebhookURLs[name]...)
+	}
+	return webhookURLs
+}
+
+// LoadConfig reads the settings through getenv, which returns "" for unset
+// settings, and validates them
+func LoadConfig(getenv func(string) string) (Config, error) {
+	config := Config{
+		Provider:          getenv("PLUGIN_PROVIDER"),
+		WebhookURLs:       configList(getenv, "PLUGIN_WEBHOOK_URL"),
+		ChatIDs:           configList(getenv, "PLUGIN_CHAT_ID"),
+		Secret:            getenv("PLUGIN_SECRET"),
+		Status:            resolveStatus(getenv),
+		UseCard:           configBool(getenv, "PLUGIN_USE_CARD"),
+		MsgType:           getenv("PLUGIN_MSG_TYPE"),
+		Compact:           configBool(getenv, "PLUGIN_COMPACT"),
+		Variables:         configList(getenv, "PLUGIN_VARIABLES"),
+		Buttons:           configList(getenv, "PLUGIN_BUTTONS"),
+		Debug:             configBool(getenv, "PLUGIN_DEBUG"),
+		Quiet:             configBool(getenv, "PLUGIN_QUIET"),
+		DryRun:            configBool(getenv, "PLUGIN_DRY_RUN"),
+		FailOnError:       configBool(getenv, "PLUGIN_FAIL_ON_ERROR"),
+		Strict:            configBool(getenv, "PLUGIN_STRICT"),
+		Phase:             getenv("PLUGIN_PHASE"),
+		ImageFile:         getenv("PLUGIN_IMAGE_FILE"),
+		hasAppCredentials: getenv("PLUGIN_APP_ID") != "" && getenv("PLUGIN_APP_SECRET") != "",
+		problems:          checkSettings(getenv),
+	}
+	if config.Provider == "" {
+		config.Provider = providerLark
+	}
+	for i, webhookURL := range config.WebhookURLs {
+		config.WebhookURLs[i] = cleanWebhookURL(webhookURL)
+	}
+	for _, name := range statusWebhookSettings() {
+		if config.StatusWebhookURLs == nil {
+			config.StatusWebhookURLs = map[string][]string{}
+		}
+		for _, webhookURL := range configList(getenv, name) {
+			config.StatusWebhookURLs[name] = append(config.StatusWebhookURLs[name], cleanWebhookURL(webhookURL))
+		}
+	}
+	// PLUGIN_MSG_TYPE supersedes PLUGIN_USE_CARD
+	switch config.MsgType {
+	case "":
+		config.MsgType = msgTypeText
+		if config.UseCard {
+			config.MsgType = msgTypeCard
+		}
+	case msgTypeCard, msgTypeText, msgTypePost:
+		config.UseCard = config.MsgType == msgTypeCard
+	default:
+		config.problems = append(config.problems, fmt.Errorf("PLUGIN_MSG_TYPE must be %s, %s or %s, got %q", msgTypeCard, msgTypeText, msgTypePost, config.MsgType))
+	}
+	return config, config.Validate()
+}
+
+// checkSettings reports the boolean, mapping, detail, result and
+// aggregation settings with invalid values
+func checkSettings(getenv func(string) string) []error {
+	var names []string
+	for name := range boolSettings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var problems []error
+	for _, name := range names {
+		if value := getenv(name); value != "" && value != "true" && value != "false" {
+			problems = append(problems, fmt.Errorf("%s must be true or false, got %q", name, value))
+		}
+	}
+	if _, err := parseEnvMapping(getenv("PLUGIN_ENV_MAPPING")); err != nil {
+		problems = append(problems, err)
+	}
+	if err := checkDetailSetting(getenv("PLUGIN_DETAIL")); err != nil {
+		problems = append(problems, err)
+	}
+	if err := checkResultSettings(getenv); err != nil {
+		problems = append(problems, err)
+	}
+	problems = append(problems, checkAggregateSettings(getenv)...)
+	problems = append(problems, checkQuietHoursSettings(getenv)...)
+	if err := checkSectionsSetting(getenv); err != nil {
+		problems = append(problems, err)
+	}
+	return problems
+}
+
+// isValidList reports whether value is a plain list or a JSON string array.
+// parseList falls back to splitting other values starting with '[' on commas.
+func isValidList(value string) bool {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, "[") {
+		return true
+	}
+	var items []string
+	return json.Unmarshal([]byte(value), &items) == nil
+}
+
+// configBool reads a boolean setting; invalid values are reported by checkSettings
+func configBool(getenv func(string) string, name string) bool {
+	if value := getenv(name); value == "true" || value == "false" {
+		return value == "true"
+	}
+	return boolSettings[name]
+}
+
+// configList reads a list setting like getListSetting
+func configList(getenv func(string) string, name string) []string {
+	return parseList(name, getenv(name))
+}
+
+// Validate reports every problem with the configuration at once
+func (c Config) Validate() error {
+	problems := append([]error{}, c.problems...)
+	if len(c.WebhookURLs) == 0 && len(c.StatusWebhookURLs) == 0 && !(len(c.ChatIDs) > 0 && c.hasAppCredentials) && !c.DryRun {
+		problems = append(problems, errors.New("Need to set Lark Webhook URL"))
+	}
+	if err := checkProvider(c); err != nil {
+		problems = append(problems, err)
+	}
+	for _, webhookURL := range c.allWebhookURLs() {
+		if _, err := checkWebhookURL(webhookURL, c.Provider); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	if err := checkPhase(c.Phase, len(c.ChatIDs) > 0 && c.hasAppCredentials); err != nil {
+		problems = append(problems, err)
+	}
+	if c.ImageFile != "" && !c.hasAppCredentials {
+		problems = append(problems, errors.New("PLUGIN_IMAGE_FILE needs PLUGIN_APP_ID and PLUGIN_APP_SECRET to upload the image to Lark"))
+	}
+	return errors.Join(problems...)
+}
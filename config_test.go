@@ -0,0 +1,209 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// mapGetenv looks settings up in a map instead of the environment
+func mapGetenv(env map[string]string) func(string) string {
+	return func(name string) string { return env[name] }
+}
+
+func TestLoadConfig(t *testing.T) {
+	config, err := LoadConfig(mapGetenv(map[string]string{
+		"PLUGIN_WEBHOOK_URL": `["https://example.com/a","https://example.com/b#public"]`,
+		"PLUGIN_SECRET":      "lark-secret",
+		"PLUGIN_VARIABLES":   "DEPLOY_ENV, REGION",
+		"PLUGIN_BUTTONS":     "pipeline",
+		"PLUGIN_COMPACT":     "true",
+		"PLUGIN_USE_CARD":    "false",
+		"PLUGIN_DEBUG":       "true",
+		"DRONE_BUILD_STATUS": "failure",
+	}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := Config{
+		Provider:    providerLark,
+		WebhookURLs: []string{"https://example.com/a", "https://example.com/b#public"},
+		Secret:      "lark-secret",
+		Status:      "failure",
+		UseCard:     false,
+		MsgType:     msgTypeText,
+		Compact:     true,
+		Variables:   []string{"DEPLOY_ENV", "REGION"},
+		Buttons:     []string{"pipeline"},
+		Debug:       true,
+		FailOnError: true,
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, config)
+	}
+}
+
+func TestLoadConfig_Status(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected string
+	}{
+		{"Drone status", map[string]string{"DRONE_BUILD_STATUS": "failure"}, "failure"},
+		{"Override", map[string]string{"DRONE_BUILD_STATUS": "success", "PLUGIN_STATUS": "failure"}, "failure"},
+		{
+			name:     "Start phase",
+			env:      map[string]string{"DRONE_BUILD_STATUS": "success", "PLUGIN_PHASE": phaseStart, "PLUGIN_APP_ID": "cli_test", "PLUGIN_APP_SECRET": "app_secret", "PLUGIN_CHAT_ID": "oc_test"},
+			expected: "running",
+		},
+		{"British spelling", map[string]string{"PLUGIN_STATUS": "cancelled"}, "canceled"},
+		{"Unset", map[string]string{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.env["PLUGIN_WEBHOOK_URL"] = "https://example.com/a"
+			config, err := LoadConfig(mapGetenv(tt.env))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if config.Status != tt.expected {
+				t.Errorf("Expected status %q, got %q", tt.expected, config.Status)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_MsgType(t *testing.T) {
+	tests := []struct {
+		useCard, msgType string
+		expected         string
+		expectedUseCard  bool
+	}{
+		{"", "", msgTypeCard, true},
+		{"false", "", msgTypeText, false},
+		{"false", "card", msgTypeCard, true},
+		{"true", "post", msgTypePost, false},
+	}
+	for _, tt := range tests {
+		config, err := LoadConfig(mapGetenv(map[string]string{
+			"PLUGIN_WEBHOOK_URL": "https://example.com/a",
+			"PLUGIN_USE_CARD":    tt.useCard,
+			"PLUGIN_MSG_TYPE":    tt.msgType,
+		}))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if config.MsgType != tt.expected || config.UseCard != tt.expectedUseCard {
+			t.Errorf("use_card=%q msg_type=%q: expected %s (UseCard %v), got %s (UseCard %v)",
+				tt.useCard, tt.msgType, tt.expected, tt.expectedUseCard, config.MsgType, config.UseCard)
+		}
+	}
+
+	_, err := LoadConfig(mapGetenv(map[string]string{"PLUGIN_WEBHOOK_URL": "https://example.com/a", "PLUGIN_MSG_TYPE": "markdown"}))
+	if err == nil || !strings.Contains(err.Error(), `PLUGIN_MSG_TYPE must be card, text or post, got "markdown"`) {
+		t.Errorf("Expected an invalid message type error, got %v", err)
+	}
+}
+
+func TestLoadConfig_Defaults(t *testing.T) {
+	config, err := LoadConfig(mapGetenv(map[string]string{"PLUGIN_WEBHOOK_URL": "https://example.com/a"}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !config.UseCard || config.MsgType != msgTypeCard || config.DryRun || config.Strict {
+		t.Errorf("Expected the documented defaults, got %+v", config)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected []string
+	}{
+		{
+			name: "Every problem at once",
+			env: map[string]string{
+				"PLUGIN_USE_CARD":    "yes",
+				"PLUGIN_SHOW_FOOTER": "0",
+			},
+			expected: []string{
+				`PLUGIN_SHOW_FOOTER must be true or false, got "0"`,
+				`PLUGIN_USE_CARD must be true or false, got "yes"`,
+				"Need to set Lark Webhook URL",
+			},
+		},
+		{
+			name: "Malformed JSON lists are split on commas",
+			env:  map[string]string{"PLUGIN_WEBHOOK_URL": "https://example.com/a", "PLUGIN_VARIABLES": "[FOO, BAR"},
+		},
+		{
+			name:     "Chat targets need app credentials",
+			env:      map[string]string{"PLUGIN_CHAT_ID": "oc_1"},
+			expected: []string{"Need to set Lark Webhook URL"},
+		},
+		{
+			name: "Chat targets",
+			env:  map[string]string{"PLUGIN_CHAT_ID": "oc_1", "PLUGIN_APP_ID": "cli_1", "PLUGIN_APP_SECRET": "s"},
+		},
+		{
+			name:     "Image needs app credentials",
+			env:      map[string]string{"PLUGIN_DRY_RUN": "true", "PLUGIN_IMAGE_FILE": "badge.png"},
+			expected: []string{"PLUGIN_IMAGE_FILE needs PLUGIN_APP_ID and PLUGIN_APP_SECRET to upload the image to Lark"},
+		},
+		{
+			name: "Dry run without webhook",
+			env:  map[string]string{"PLUGIN_DRY_RUN": "true"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadConfig(mapGetenv(tt.env))
+			var problems []string
+			if err != nil {
+				problems = strings.Split(err.Error(), "\n")
+			}
+			if !reflect.DeepEqual(problems, tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, problems)
+			}
+		})
+	}
+}
+
+func TestBuildMessage(t *testing.T) {
+	prebuilt := map[string]any{"msg_type": "text", "content": map[string]any{"text": "hi"}}
+
+	tests := []struct {
+		name     string
+		config   Config
+		prebuilt map[string]any
+		expected string
+	}{
+		{"Card", Config{UseCard: true}, nil, "interactive"},
+		{"Text", Config{UseCard: false}, nil, "text"},
+		{"Prebuilt", Config{UseCard: true}, prebuilt, "text"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message, err := buildMessage(tt.config, "v1.0.0", tt.prebuilt)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if message["msg_type"] != tt.expected {
+				t.Errorf("Expected msg_type %s, got %v", tt.expected, message["msg_type"])
+			}
+		})
+	}
+
+	message, _ := buildMessage(Config{}, "", prebuilt)
+	signMessage(message, "lark-secret", timeNow())
+	if _, ok := prebuilt["sign"]; ok {
+		t.Error("Expected signing to leave the prebuilt message unchanged")
+	}
+	if message["sign"] != generateSignature(message["timestamp"].(string), "lark-secret") {
+		t.Errorf("Expected a valid signature, got %v", message)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// maxListedImages is how many images are shown before "… and N more"
+const maxListedImages = 10
+
+// buildImages is set by main to the images pushed by the build
+var buildImages []string
+
+// loadBuildImages collects the image references of PLUGIN_IMAGES, with ${VAR}
+// references expanded, followed by the lines of PLUGIN_IMAGES_FILE. Repeated
+// references are listed once, in the order they first appear.
+func loadBuildImages() []string {
+	var images []string
+	add := func(image string) {
+		if image = strings.TrimSpace(image); image != "" && !slices.Contains(images, image) {
+			images = append(images, image)
+		}
+	}
+
+	for _, image := range getListSetting("PLUGIN_IMAGES") {
+		expanded, _ := expandTemplateEnv(image)
+		add(expanded)
+	}
+	if path := getEnvOrDefault("PLUGIN_IMAGES_FILE", ""); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			logWarn(fmt.Sprintf("cannot read images file: %v", err))
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			add(line)
+		}
+	}
+	return images
+}
+
+// listedImages returns the images to show and how many are left out
+func listedImages() ([]string, int) {
+	if len(buildImages) > maxListedImages {
+		return buildImages[:maxListedImages], len(buildImages) - maxListedImages
+	}
+	return buildImages, 0
+}
+
+// createImagesElements returns the card section listing the images, one
+// inline code reference per line so that each can be copied on its own
+func createImagesElements() []map[string]any {
+	if len(buildImages) == 0 || publicMode {
+		return nil
+	}
+
+	images, more := listedImages()
+	content := fmt.Sprintf("**%s:**", tr("Images"))
+	for _, image := range images {
+		content += "\n`" + strings.ReplaceAll(image, "`", "") + "`"
+	}
+	if more > 0 {
+		content += fmt.Sprintf("\n… and %d more", more)
+	}
+	return []map[string]any{
+		{
+			"tag": "hr",
+		},
+		{
+			"tag": "div",
+			"text": map[string]any{
+				"content": content,
+				"tag":     "lark_md",
+			},
+		},
+	}
+}
+
+// createImagesText returns the images as bullet lines
+func createImagesText() string {
+	if len(buildImages) == 0 || publicMode {
+		return ""
+	}
+
+	images, more := listedImages()
+	text := "\n" + withIcon("📦", tr("Images")+":\n")
+	for _, image := range images {
+		text += "• " + image + "\n"
+	}
+	if more > 0 {
+		text += fmt.Sprintf("… and %d more\n", more)
+	}
+	return text
+}
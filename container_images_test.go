@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadBuildImages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "images.txt")
+	os.WriteFile(path, []byte("registry.example.com/app@sha256:abc\n\nregistry.example.com/app:abc123\n"), 0644)
+	setEnvFixture(t, map[string]string{
+		"CI_COMMIT_SHA":      "abc123",
+		"PLUGIN_IMAGES":      "registry.example.com/app:${CI_COMMIT_SHA}, registry.example.com/app:latest,registry.example.com/app:abc123",
+		"PLUGIN_IMAGES_FILE": path,
+	})
+
+	got := strings.Join(loadBuildImages(), " ")
+	want := "registry.example.com/app:abc123 registry.example.com/app:latest registry.example.com/app@sha256:abc"
+	if got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestLoadBuildImages_MissingFile(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_IMAGES":      "app:1",
+		"PLUGIN_IMAGES_FILE": filepath.Join(t.TempDir(), "missing.txt"),
+	})
+
+	var images []string
+	output := captureOutput(t, func() { images = loadBuildImages() })
+	if len(images) != 1 || !strings.Contains(output, "cannot read images file") {
+		t.Errorf("Expected the inline image and a warning, got %v and %q", images, output)
+	}
+}
+
+func TestImagesSection(t *testing.T) {
+	setEnvFixture(t, map[string]string{})
+	defer func() { buildImages = nil }()
+
+	buildImages = nil
+	if createImagesElements() != nil || createImagesText() != "" {
+		t.Error("Expected no section without images")
+	}
+
+	for i := 1; i <= 12; i++ {
+		buildImages = append(buildImages, fmt.Sprintf("app:%d", i))
+	}
+	elements := createImagesElements()
+	if len(elements) != 2 {
+		t.Fatalf("Expected a divider and the list, got %v", elements)
+	}
+	content := elements[1]["text"].(map[string]any)["content"].(string)
+	if !strings.HasPrefix(content, "**Images:**\n`app:1`\n") || !strings.Contains(content, "`app:10`") || strings.Contains(content, "app:11") || !strings.HasSuffix(content, "… and 2 more") {
+		t.Errorf("Unexpected card section %q", content)
+	}
+
+	text := createImagesText()
+	if !strings.Contains(text, "📦 Images:\n• app:1\n") || !strings.HasSuffix(text, "… and 2 more\n") {
+		t.Errorf("Unexpected text section %q", text)
+	}
+	if message := createLarkTextMessage(Config{}, "v1.0.0")["content"].(map[string]any)["text"].(string); !strings.Contains(message, "• app:10") {
+		t.Errorf("Expected the images in the text message, got %q", message)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxContentFileLength caps how many characters of a content file end up in the message
+const maxContentFileLength = 2000
+
+type contentSection struct {
+	Title   string
+	Content string
+}
+
+var (
+	headingPattern = regexp.MustCompile(`(?m)^#{1,6}[ \t]+(.+?)[ \t#]*$`)
+	atTagPattern   = regexp.MustCompile(`(?i)</?at\b[^>]*>`)
+)
+
+func isStrictMode() bool {
+	return getEnvOrDefault("PLUGIN_STRICT", "false") == "true"
+}
+
+// parseContentFileEntry splits a "Title|path" entry, deriving the title from the
+// filename when none is given.
+func parseContentFileEntry(entry string) (title, path string) {
+	if before, after, found := strings.Cut(entry, "|"); found {
+		return strings.TrimSpace(before), strings.TrimSpace(after)
+	}
+
+	path = strings.TrimSpace(entry)
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	words := strings.FieldsFunc(name, func(r rune) bool { return r == '-' || r == '_' || r == ' ' })
+	for i, word := range words {
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		words[i] = string(runes)
+	}
+	return strings.Join(words, " "), path
+}
+
+func isBinaryContent(data []byte) bool {
+	return bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data)
+}
+
+// sanitizeContentFile prepares markdown for a lark_md block. Lark has no headings
+// in card markdown, so headings are demoted to bold lines. Mention tags and
+// control characters are dropped and the result is capped in length.
+func sanitizeContentFile(content string) string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	content = headingPattern.ReplaceAllString(content, "**$1**")
+	content = atTagPattern.ReplaceAllString(content, "")
+	content = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && r != '\n' && r != '\t' {
+			return -1
+		}
+		return r
+	}, content)
+	content = strings.TrimSpace(content)
+
+	return truncateRunes(content, maxContentFileLength)
+}
+
+func readContentSection(entry string) (contentSection, error) {
+	title, path := parseContentFileEntry(entry)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return contentSection{}, fmt.Errorf("cannot read content file %s: %w", path, err)
+	}
+	if isBinaryContent(data) {
+		return contentSection{}, fmt.Errorf("content file %s looks binary, skipping", path)
+	}
+
+	return contentSection{Title: title, Content: sanitizeContentFile(string(data))}, nil
+}
+
+// loadContentSections reads every file listed in PLUGIN_CONTENT_FILE. Problems are
+// returned as errors so strict mode can refuse to send; the sections that could be
+// read are returned regardless.
+func loadContentSections() ([]contentSection, []error) {
+	var sections []contentSection
+	var errs []error
+
+	for _, entry := range getListSetting("PLUGIN_CONTENT_FILE") {
+		section, err := readContentSection(entry)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if section.Content != "" {
+			sections = append(sections, section)
+		}
+	}
+	return sections, errs
+}
+
+// contentSectionsOrWarn loads the content sections for the message builders,
+// printing a warning for every file that had to be skipped.
+func contentSectionsOrWarn() []contentSection {
+	sections, errs := loadContentSections()
+	for _, err := range errs {
+		logWarn(err.Error())
+	}
+	return sections
+}
+
+func createContentFileElements() []map[string]any {
+	var elements []map[string]any
+
+	for _, section := range contentSectionsOrWarn() {
+		content := section.Content
+		if section.Title != "" {
+			content = fmt.Sprintf("**%s:**\n%s", section.Title, content)
+		}
+
+		elements = append(elements, map[string]any{
+			"tag": "hr",
+		}, map[string]any{
+			"tag": "div",
+			"text": map[string]any{
+				"content": content,
+				"tag":     "lark_md",
+			},
+		})
+	}
+	return elements
+}
+
+func createContentFileText() string {
+	var text string
+	for _, section := range contentSectionsOrWarn() {
+		if section.Title != "" {
+			text += "\n" + withIcon("📄", section.Title+":") + "\n"
+		} else {
+			text += "\n"
+		}
+		text += section.Content + "\n"
+	}
+	return text
+}
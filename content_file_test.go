@@ -0,0 +1,133 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseContentFileEntry(t *testing.T) {
+	tests := []struct {
+		entry         string
+		expectedTitle string
+		expectedPath  string
+	}{
+		{"out/migration-summary.md", "Migration Summary", "out/migration-summary.md"},
+		{"plan_digest.txt", "Plan Digest", "plan_digest.txt"},
+		{"Terraform Plan|out/plan.md", "Terraform Plan", "out/plan.md"},
+		{" Notes | notes.md ", "Notes", "notes.md"},
+		{"|notes.md", "", "notes.md"},
+	}
+
+	for _, tc := range tests {
+		title, path := parseContentFileEntry(tc.entry)
+		if title != tc.expectedTitle || path != tc.expectedPath {
+			t.Errorf("%q: expected (%q, %q), got (%q, %q)", tc.entry, tc.expectedTitle, tc.expectedPath, title, path)
+		}
+	}
+}
+
+func TestSanitizeContentFile(t *testing.T) {
+	content := "# Summary\r\n\r\n## Applied\r\n- 3 migrations <at user_id=\"all\"></at>\x07\r\n"
+	expected := "**Summary**\n\n**Applied**\n- 3 migrations"
+
+	if result := sanitizeContentFile(content); result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+
+	long := strings.Repeat("a", maxContentFileLength+10)
+	result := sanitizeContentFile(long)
+	if len([]rune(result)) != maxContentFileLength+1 || !strings.HasSuffix(result, "…") {
+		t.Errorf("Expected content capped at %d characters with ellipsis, got %d", maxContentFileLength, len([]rune(result)))
+	}
+}
+
+func TestCreateContentFileElements_MultipleFiles(t *testing.T) {
+	dir := t.TempDir()
+	migrations := filepath.Join(dir, "migration-summary.md")
+	plan := filepath.Join(dir, "plan.md")
+	os.WriteFile(migrations, []byte("# Migrations\n3 applied"), 0644)
+	os.WriteFile(plan, []byte("No changes."), 0644)
+
+	os.Setenv("PLUGIN_CONTENT_FILE", migrations+", Terraform|"+plan)
+	defer os.Unsetenv("PLUGIN_CONTENT_FILE")
+
+	elements := createContentFileElements()
+	if len(elements) != 4 {
+		t.Fatalf("Expected 4 elements (hr + div per file), got %d", len(elements))
+	}
+
+	expected := []string{
+		"**Migration Summary:**\n**Migrations**\n3 applied",
+		"**Terraform:**\nNo changes.",
+	}
+	for i, content := range expected {
+		text := elements[i*2+1]["text"].(map[string]any)
+		if text["content"] != content {
+			t.Errorf("Expected %q, got %q", content, text["content"])
+		}
+	}
+
+	message := createLarkTextMessage(Config{}, "v1.0.0")
+	text := message["content"].(map[string]any)["text"].(string)
+	if !strings.Contains(text, "📄 Terraform:\nNo changes.\n") {
+		t.Errorf("Expected text message to contain the Terraform section, got %q", text)
+	}
+}
+
+func TestLoadContentSections_RejectsBinaryAndMissing(t *testing.T) {
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "image.png")
+	valid := filepath.Join(dir, "notes.md")
+	os.WriteFile(binary, []byte{0x89, 'P', 'N', 'G', 0x00, 0x1a}, 0644)
+	os.WriteFile(valid, []byte("all good"), 0644)
+
+	os.Setenv("PLUGIN_CONTENT_FILE", binary+","+filepath.Join(dir, "missing.md")+","+valid)
+	defer os.Unsetenv("PLUGIN_CONTENT_FILE")
+
+	sections, errs := loadContentSections()
+	if len(errs) != 2 {
+		t.Errorf("Expected 2 errors, got %d: %v", len(errs), errs)
+	}
+	if len(errs) > 0 && !strings.Contains(errs[0].Error(), "looks binary") {
+		t.Errorf("Expected binary rejection, got %v", errs[0])
+	}
+	if len(sections) != 1 || sections[0].Content != "all good" {
+		t.Errorf("Expected only the valid file to be loaded, got %v", sections)
+	}
+}
+
+func TestMain_StrictModeMissingContentFile(t *testing.T) {
+	originalOsExit := osExit
+	defer func() { osExit = originalOsExit }()
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code": 0}`))
+	}))
+	defer testServer.Close()
+
+	os.Setenv("PLUGIN_WEBHOOK_URL", testServer.URL)
+	os.Setenv("PLUGIN_CONTENT_FILE", filepath.Join(t.TempDir(), "missing.md"))
+	os.Setenv("PLUGIN_STRICT", "true")
+	defer func() {
+		os.Unsetenv("PLUGIN_WEBHOOK_URL")
+		os.Unsetenv("PLUGIN_CONTENT_FILE")
+		os.Unsetenv("PLUGIN_STRICT")
+	}()
+
+	exitCode := 0
+	osExit = func(code int) {
+		if exitCode == 0 {
+			exitCode = code
+		}
+	}
+
+	main()
+
+	if exitCode != exitConfiguration {
+		t.Errorf("Expected exit code 2, got %d", exitCode)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// buildCoverage is set by main from PLUGIN_COVERAGE_FILE; nil means no coverage is shown
+var buildCoverage *float64
+
+// loadCoverage reads PLUGIN_COVERAGE_FILE, warning and returning nil when the
+// file is missing or not in a known format
+func loadCoverage() *float64 {
+	path := getEnvOrDefault("PLUGIN_COVERAGE_FILE", "")
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		logWarn(fmt.Sprintf("cannot read coverage file: %v", err))
+		return nil
+	}
+	percent, err := parseCoverage(data)
+	if err != nil {
+		logWarn(fmt.Sprintf("cannot parse coverage file %s: %v", path, err))
+		return nil
+	}
+	return &percent
+}
+
+// parseCoverage detects the format of a coverage file and returns the
+// covered percentage: a Go cover profile, a Cobertura XML report or a number
+func parseCoverage(data []byte) (float64, error) {
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("mode:")):
+		return parseGoCoverProfile(trimmed)
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		return parseCoberturaCoverage(trimmed)
+	}
+
+	value := strings.TrimSuffix(string(trimmed), "%")
+	percent, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || percent < 0 || percent > 100 {
+		return 0, fmt.Errorf("expected a Go cover profile, Cobertura XML or a percentage")
+	}
+	return percent, nil
+}
+
+// parseGoCoverProfile computes statement coverage like go tool cover -func.
+// Blocks listed more than once count as covered if any entry covers them.
+func parseGoCoverProfile(data []byte) (float64, error) {
+	type block struct {
+		statements int
+		covered    bool
+	}
+	blocks := map[string]*block{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Scan() // mode line
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return 0, fmt.Errorf("malformed profile line %q", line)
+		}
+		statements, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return 0, fmt.Errorf("malformed profile line %q", line)
+		}
+		count, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return 0, fmt.Errorf("malformed profile line %q", line)
+		}
+
+		b, ok := blocks[fields[0]]
+		if !ok {
+			b = &block{statements: statements}
+			blocks[fields[0]] = b
+		}
+		b.covered = b.covered || count > 0
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+
+	var total, covered int
+	for _, b := range blocks {
+		total += b.statements
+		if b.covered {
+			covered += b.statements
+		}
+	}
+	if total == 0 {
+		return 0, fmt.Errorf("profile contains no statements")
+	}
+	return 100 * float64(covered) / float64(total), nil
+}
+
+// parseCoberturaCoverage reads the line-rate attribute of a Cobertura report
+func parseCoberturaCoverage(data []byte) (float64, error) {
+	var report struct {
+		XMLName  xml.Name `xml:"coverage"`
+		LineRate *float64 `xml:"line-rate,attr"`
+	}
+	if err := xml.Unmarshal(data, &report); err != nil {
+		return 0, err
+	}
+	if report.LineRate == nil {
+		return 0, fmt.Errorf("coverage element has no line-rate attribute")
+	}
+	return *report.LineRate * 100, nil
+}
+
+// coverageValue formats buildCoverage, with a warning icon when it is below
+// PLUGIN_COVERAGE_THRESHOLD. It returns "" when there is no coverage.
+func coverageValue() string {
+	if buildCoverage == nil {
+		return ""
+	}
+	value := fmt.Sprintf("%.1f%%", *buildCoverage)
+
+	if threshold := getEnvOrDefault("PLUGIN_COVERAGE_THRESHOLD", ""); threshold != "" {
+		limit, err := strconv.ParseFloat(strings.TrimSuffix(threshold, "%"), 64)
+		if err != nil {
+			logWarn(fmt.Sprintf("invalid PLUGIN_COVERAGE_THRESHOLD %q", threshold))
+		} else if *buildCoverage < limit {
+			value += " " + withIcon("⚠️", fmt.Sprintf("below %s%%", strconv.FormatFloat(limit, 'f', -1, 64)))
+		}
+	}
+	return value
+}
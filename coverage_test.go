@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseCoverage(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			name: "Go profile set mode",
+			content: "mode: set\n" +
+				"example.com/app/main.go:10.2,12.3 3 1\n" +
+				"example.com/app/main.go:14.2,16.3 2 0\n" +
+				"example.com/app/util.go:5.2,9.3 1 1\n",
+			expected: "66.7",
+		},
+		{
+			name: "Go profile atomic mode with merged packages",
+			content: "mode: atomic\n" +
+				"example.com/app/main.go:10.2,12.3 4 0\n" +
+				"example.com/app/main.go:14.2,16.3 6 12\n" +
+				"example.com/app/main.go:10.2,12.3 4 3\n",
+			expected: "100.0",
+		},
+		{
+			name: "Go profile partially covered",
+			content: "mode: atomic\n" +
+				"example.com/app/a.go:1.1,2.2 7 5\n" +
+				"example.com/app/a.go:3.1,4.2 5 0\n",
+			expected: "58.3",
+		},
+		{
+			name:     "Cobertura",
+			content:  `<?xml version="1.0" ?>` + "\n" + `<coverage line-rate="0.8342" branch-rate="0.5" version="1.9"><packages/></coverage>`,
+			expected: "83.4",
+		},
+		{
+			name:     "Cobertura full coverage",
+			content:  `<coverage line-rate="1" branch-rate="1"></coverage>`,
+			expected: "100.0",
+		},
+		{"Plain number", "83.44\n", "83.4"},
+		{"Plain percentage", " 71.25% ", "71.2"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "coverage")
+			if err := os.WriteFile(path, []byte(tc.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			setEnvFixture(t, map[string]string{"PLUGIN_COVERAGE_FILE": path})
+
+			percent := loadCoverage()
+			if percent == nil {
+				t.Fatal("Expected coverage to be parsed")
+			}
+			if result := fmt.Sprintf("%.1f", *percent); result != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseCoverageInvalid(t *testing.T) {
+	for _, content := range []string{
+		"mode: set\nnot a profile line\n",
+		"mode: set\n",
+		`<coverage branch-rate="0.5"></coverage>`,
+		"<html>",
+		"all good",
+		"150",
+	} {
+		path := filepath.Join(t.TempDir(), "coverage")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		setEnvFixture(t, map[string]string{"PLUGIN_COVERAGE_FILE": path})
+
+		var percent *float64
+		output := captureStdout(t, func() { percent = loadCoverage() })
+		if percent != nil || !strings.Contains(output, "Warning: cannot parse coverage file") {
+			t.Errorf("%q: expected a warning and no coverage, got %v and %q", content, percent, output)
+		}
+	}
+}
+
+func TestCoverageInMessages(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold string
+		card      string
+		text      string
+	}{
+		{"Without threshold", "", "**Coverage:** 78.0%", "📈 Coverage: 78.0%\n"},
+		{"Above threshold", "75", "**Coverage:** 78.0%", "📈 Coverage: 78.0%\n"},
+		{"Below threshold", "80", "**Coverage:** 78.0% ⚠️ below 80%", "📈 Coverage: 78.0% ⚠️ below 80%\n"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setEnvFixture(t, map[string]string{"PLUGIN_COVERAGE_THRESHOLD": tc.threshold})
+			percent := 78.0
+			buildCoverage = &percent
+			defer func() { buildCoverage = nil }()
+
+			elements := createLarkCard(Config{}, "v1.0.0")["card"].(map[string]any)["elements"].([]map[string]any)
+			if coverage := elements[1]["text"].(map[string]any)["content"].(string); coverage != tc.card {
+				t.Errorf("Expected the coverage element %q after the metadata, got %q", tc.card, coverage)
+			}
+
+			text := createLarkTextMessage(Config{}, "v1.0.0")["content"].(map[string]any)["text"].(string)
+			if !strings.Contains(text, tc.text) {
+				t.Errorf("Expected text to contain %q, got %q", tc.text, text)
+			}
+		})
+	}
+}
+
+func TestCoverageSection(t *testing.T) {
+	percent := 78.0
+	buildCoverage = &percent
+	defer func() { buildCoverage = nil }()
+
+	setEnvFixture(t, map[string]string{"PLUGIN_SECTIONS": "coverage,header"})
+	elements := createLarkCard(Config{}, "v1.0.0")["card"].(map[string]any)["elements"].([]map[string]any)
+	if len(elements) != 1 || elements[0]["text"].(map[string]any)["content"] != "**Coverage:** 78.0%" {
+		t.Errorf("Expected only the coverage element, got %v", elements)
+	}
+
+	setEnvFixture(t, map[string]string{"PLUGIN_SECTIONS": "header,metadata", "PLUGIN_LAYOUT": "columns"})
+	if card, _ := json.Marshal(createLarkCard(Config{}, "v1.0.0")); strings.Contains(string(card), "Coverage") {
+		t.Errorf("Expected no coverage without its section, got %s", card)
+	}
+	if text := textContent(createLarkTextMessage(Config{}, "v1.0.0")); strings.Contains(text, "Coverage") {
+		t.Errorf("Expected no coverage without its section, got %q", text)
+	}
+
+	setEnvFixture(t, map[string]string{"PLUGIN_SECTIONS": "coverage"})
+	elements = createLarkCard(Config{}, "v1.0.0")["card"].(map[string]any)["elements"].([]map[string]any)
+	if fields, _ := elements[0]["fields"].([]map[string]any); len(fields) != 1 {
+		t.Errorf("Expected a coverage field in the columns layout, got %v", elements)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// isCron reports whether the pipeline was started by a schedule
+func isCron() bool {
+	return getPipelineEvent() == "cron"
+}
+
+// cronJob returns the name of the schedule that started the pipeline, or ""
+// for other pipelines
+func cronJob() string {
+	if !isCron() {
+		return ""
+	}
+	return getEnvOrDefault("CI_PIPELINE_CRON", getEnvOrDefault("DRONE_CRON", ""))
+}
+
+// cronNotifyOn returns the statuses scheduled pipelines notify on. "all"
+// notifies on every status.
+func cronNotifyOn() []string {
+	notifyOn := getListSetting("PLUGIN_CRON_NOTIFY_ON")
+	for _, status := range notifyOn {
+		if strings.EqualFold(status, "all") {
+			return nil
+		}
+	}
+	return notifyOn
+}
+
+// cronDefaultSkipReason returns why a scheduled pipeline is not notified
+// when PLUGIN_CRON_NOTIFY_ON is unset: by default only failed pipelines are,
+// every status isFailedStatus counts as one
+func cronDefaultSkipReason() string {
+	if isFailedStatus(strings.ToLower(getBuildStatus())) {
+		return ""
+	}
+	return fmt.Sprintf("status '%s' is not a failure, PLUGIN_CRON_NOTIFY_ON is unset", strings.Join(notifyStatuses(getBuildStatus()), "/"))
+}
+
+// isUnchangedCron reports whether a scheduled pipeline built the same commit
+// as the previous run, so its commit message tells nothing new
+func isUnchangedCron() bool {
+	sha := getEnvOrDefault("CI_COMMIT_SHA", "")
+	return isCron() && sha != "" && getEnvOrDefault("CI_PREV_COMMIT_SHA", "") == sha
+}
+
+// showCommitMessage reports whether the commit message section is shown:
+// public targets never see it and unchanged scheduled pipelines leave it out
+func showCommitMessage() bool {
+	return !publicMode && !isUnchangedCron()
+}
+
+// cronStyle rewords the status of scheduled pipelines, "Pipeline Succeeded"
+// becomes "Scheduled Pipeline Succeeded"
+func cronStyle(style statusStyle) statusStyle {
+	if isCron() && strings.HasPrefix(style.Text, "Pipeline ") {
+		style.Text = "Scheduled " + style.Text
+	}
+	return style
+}
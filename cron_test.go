@@ -0,0 +1,114 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNotifySkipReason_Cron(t *testing.T) {
+	tests := []struct {
+		name         string
+		event        string
+		notifyOn     string
+		cronNotifyOn string
+		status       string
+		expected     string
+	}{
+		{"Cron successes are skipped by default", "cron", "", "", "success", "status 'success' is not a failure, PLUGIN_CRON_NOTIFY_ON is unset"},
+		{"Cron cancellations are skipped by default", "cron", "", "", "canceled", "status 'canceled' is not a failure, PLUGIN_CRON_NOTIFY_ON is unset"},
+		{"Cron failures are sent by default", "cron", "", "", "failure", ""},
+		{"Cron errors are sent by default", "cron", "", "", "error", ""},
+		{"Cron killed pipelines are sent by default", "cron", "", "", "killed", ""},
+		{"Cron setting wins over notify_on", "cron", "success", "failure", "success", "status 'success' is not in PLUGIN_CRON_NOTIFY_ON (failure)"},
+		{"Notify_on does not apply to cron", "cron", "failure", "success", "success", ""},
+		{"All sends every cron status", "cron", "", "all", "success", ""},
+		{"Cron setting does not apply to pushes", "push", "", "failure", "success", ""},
+		{"Notify_on still applies to pushes", "push", "failure", "success", "success", "status 'success' is not in PLUGIN_NOTIFY_ON (failure)"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setEnvFixture(t, map[string]string{
+				"CI_PIPELINE_EVENT":     tc.event,
+				"PLUGIN_NOTIFY_ON":      tc.notifyOn,
+				"PLUGIN_CRON_NOTIFY_ON": tc.cronNotifyOn,
+				"PLUGIN_STATUS":         tc.status,
+			})
+
+			if reason := notifySkipReason(); reason != tc.expected {
+				t.Errorf("Expected skip reason '%s', got '%s'", tc.expected, reason)
+			}
+		})
+	}
+}
+
+func TestCronMessages(t *testing.T) {
+	tests := []struct {
+		name          string
+		prevSHA       string
+		commitMessage bool
+	}{
+		{"New commit", "0123456", true},
+		{"Same commit as the previous run", "abcdef1", false},
+		{"Previous run unknown", "", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setEnvFixture(t, map[string]string{
+				"CI_PIPELINE_EVENT":  "cron",
+				"CI_PIPELINE_CRON":   "nightly",
+				"CI_REPO_NAME":       "backend",
+				"CI_COMMIT_AUTHOR":   "alice",
+				"CI_COMMIT_MESSAGE":  "Bump dependencies",
+				"CI_COMMIT_SHA":      "abcdef1",
+				"CI_PREV_COMMIT_SHA": tc.prevSHA,
+			})
+
+			card := createLarkCard(Config{Status: "failure"}, "v1.0.0")["card"].(map[string]any)
+			title := card["header"].(map[string]any)["title"].(map[string]any)["content"]
+			if title != "backend - 🚨 Scheduled Pipeline Failed · ⏰ Cron" {
+				t.Errorf("Expected the scheduled title, got '%s'", title)
+			}
+			elements := card["elements"].([]map[string]any)
+			metadata := elements[0]["text"].(map[string]any)["content"].(string)
+			if !strings.Contains(metadata, "**Cron Job:** nightly") || strings.Contains(metadata, "alice") {
+				t.Errorf("Expected the cron job instead of the author, got '%s'", metadata)
+			}
+			hasCommit := false
+			for _, element := range elements {
+				if text, ok := element["text"].(map[string]any); ok && strings.Contains(text["content"].(string), "Bump dependencies") {
+					hasCommit = true
+				}
+			}
+			if hasCommit != tc.commitMessage {
+				t.Errorf("Expected commit message shown=%v in the card", tc.commitMessage)
+			}
+
+			text := createLarkTextMessage(Config{Status: "failure"}, "v1.0.0")["content"].(map[string]any)["text"].(string)
+			if !strings.HasPrefix(text, "🚨 SCHEDULED PIPELINE FAILED · ⏰ Cron") {
+				t.Errorf("Expected the scheduled title in the text message, got '%s'", text)
+			}
+			if !strings.Contains(text, "⏰ Cron Job: nightly") || strings.Contains(text, "alice") {
+				t.Errorf("Expected the cron job instead of the author in the text message, got '%s'", text)
+			}
+			if strings.Contains(text, "Bump dependencies") != tc.commitMessage {
+				t.Errorf("Expected commit message shown=%v in the text message, got '%s'", tc.commitMessage, text)
+			}
+		})
+	}
+}
+
+func TestCronWithoutJobName(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"CI_PIPELINE_EVENT": "cron",
+		"CI_PIPELINE_CRON":  "",
+		"DRONE_CRON":        "",
+		"CI_COMMIT_AUTHOR":  "alice",
+	})
+
+	fields := authorFields()
+	if len(fields) != 1 || fields[0] != [2]string{"Author", "alice"} {
+		t.Errorf("Expected the author when the cron job is unknown, got %v", fields)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxConfirmTitleLength and maxConfirmTextLength bound the confirmation
+// dialog of a button, in characters
+const (
+	maxConfirmTitleLength = 100
+	maxConfirmTextLength  = 500
+)
+
+// customButton is one entry of PLUGIN_CUSTOM_BUTTONS
+type customButton struct {
+	Label string `json:"label"`
+	URL   string `json:"url"`
+	Type  string `json:"type"`
+	// ConfirmTitle and ConfirmText make Lark ask before opening the URL
+	ConfirmTitle string `json:"confirm_title"`
+	ConfirmText  string `json:"confirm_text"`
+}
+
+// larkButtonTypes are the button types Lark accepts; others fall back to default
+var larkButtonTypes = []string{"default", "primary", "danger"}
+
+// parseCustomButtons reads and validates PLUGIN_CUSTOM_BUTTONS
+func parseCustomButtons() ([]customButton, error) {
+	value := strings.TrimSpace(getEnvOrDefault("PLUGIN_CUSTOM_BUTTONS", ""))
+	if value == "" {
+		return nil, nil
+	}
+
+	var buttons []customButton
+	if err := json.Unmarshal([]byte(value), &buttons); err != nil {
+		return nil, fmt.Errorf("PLUGIN_CUSTOM_BUTTONS is not a valid JSON array of buttons: %w", err)
+	}
+	for i, button := range buttons {
+		if strings.TrimSpace(button.Label) == "" {
+			return nil, fmt.Errorf("PLUGIN_CUSTOM_BUTTONS: button %d is missing a label", i+1)
+		}
+		if strings.TrimSpace(button.URL) == "" {
+			return nil, fmt.Errorf("PLUGIN_CUSTOM_BUTTONS: button %q is missing a url", button.Label)
+		}
+		if err := checkButtonConfirm(button); err != nil {
+			return nil, err
+		}
+	}
+	return buttons, nil
+}
+
+// checkButtonConfirm reports a confirmation dialog that is incomplete or too long
+func checkButtonConfirm(button customButton) error {
+	title, text := strings.TrimSpace(button.ConfirmTitle), strings.TrimSpace(button.ConfirmText)
+	switch {
+	case (title == "") != (text == ""):
+		return fmt.Errorf("PLUGIN_CUSTOM_BUTTONS: button %q needs both confirm_title and confirm_text", button.Label)
+	case utf8.RuneCountInString(title) > maxConfirmTitleLength:
+		return fmt.Errorf("PLUGIN_CUSTOM_BUTTONS: confirm_title of button %q is longer than %d characters", button.Label, maxConfirmTitleLength)
+	case utf8.RuneCountInString(text) > maxConfirmTextLength:
+		return fmt.Errorf("PLUGIN_CUSTOM_BUTTONS: confirm_text of button %q is longer than %d characters", button.Label, maxConfirmTextLength)
+	}
+	return nil
+}
+
+// getCustomButtons returns the custom buttons with ${VAR} references in their
+// URLs expanded. Buttons whose URL expands to nothing are dropped.
+func getCustomButtons() []customButton {
+	buttons, _ := parseCustomButtons()
+
+	var result []customButton
+	for _, button := range buttons {
+		expanded, _ := expandTemplateEnv(button.URL)
+		if expanded == "" {
+			continue
+		}
+		button.URL = expanded
+		if !slices.Contains(larkButtonTypes, button.Type) {
+			button.Type = "default"
+		}
+		result = append(result, button)
+	}
+	return result
+}
+
+// customButtonActions renders the custom buttons as card buttons, identified
+// by their lowercase label
+func customButtonActions() []actionButton {
+	var buttons []actionButton
+	for _, button := range getCustomButtons() {
+		action := linkButton(button.Label, button.URL, button.Type)
+		if button.ConfirmTitle != "" {
+			action["confirm"] = map[string]any{
+				"title": map[string]any{"tag": "plain_text", "content": strings.TrimSpace(button.ConfirmTitle)},
+				"text":  map[string]any{"tag": "plain_text", "content": strings.TrimSpace(button.ConfirmText)},
+			}
+		}
+		buttons = append(buttons, actionButton{ID: strings.ToLower(button.Label), Action: action})
+	}
+	return buttons
+}
+
+// createCustomButtonText lists the custom and inline buttons selected by
+// the PLUGIN_BUTTONS entries names as plain links
+func createCustomButtonText(names []string) string {
+	var text string
+	for _, action := range selectButtons(names, customButtonActions()) {
+		label, _ := action["text"].(map[string]any)["content"].(string)
+		text += "\n" + withIcon("🔗", fmt.Sprintf("%s: %s", label, action["url"]))
+	}
+	return text
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseCustomButtonsErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		err   string
+	}{
+		{"Malformed JSON", `[{"label":"Grafana"`, "not a valid JSON array"},
+		{"Not an array", `{"label":"Grafana","url":"https://grafana"}`, "not a valid JSON array"},
+		{"Missing label", `[{"url":"https://grafana"}]`, "button 1 is missing a label"},
+		{"Missing url", `[{"label":"Grafana"}]`, `button "Grafana" is missing a url`},
+		{"Confirm title without text", `[{"label":"Rollback","url":"https://deploy","confirm_title":"Sure?"}]`, `button "Rollback" needs both confirm_title and confirm_text`},
+		{"Confirm title too long", `[{"label":"Rollback","url":"https://deploy","confirm_title":"` + strings.Repeat("确", 101) + `","confirm_text":"Sure?"}]`, "confirm_title of button \"Rollback\" is longer than 100 characters"},
+		{"Confirm text too long", `[{"label":"Rollback","url":"https://deploy","confirm_title":"Sure?","confirm_text":"` + strings.Repeat("x", 501) + `"}]`, "confirm_text of button \"Rollback\" is longer than 500 characters"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setEnvFixture(t, map[string]string{"PLUGIN_CUSTOM_BUTTONS": tc.value})
+
+			_, err := parseCustomButtons()
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("Expected error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestGetCustomButtons(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_CUSTOM_BUTTONS": `[{"label":"Grafana","url":"https://grafana.example.com/?sha=${CI_COMMIT_SHA}","type":"primary"},` +
+			`{"label":"Rollback runbook","url":"https://wiki.example.com/rollback","type":"warning"},` +
+			`{"label":"Empty","url":"${CI_UNSET_URL}"}]`,
+		"CI_COMMIT_SHA": "abc123",
+	})
+
+	buttons := getCustomButtons()
+	if len(buttons) != 2 {
+		t.Fatalf("Expected 2 buttons, got %v", buttons)
+	}
+	if buttons[0].URL != "https://grafana.example.com/?sha=abc123" || buttons[0].Type != "primary" {
+		t.Errorf("Unexpected first button %+v", buttons[0])
+	}
+	if buttons[1].Type != "default" {
+		t.Errorf("Expected unknown type to fall back to default, got %q", buttons[1].Type)
+	}
+}
+
+func TestCustomButtonsInActions(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"CI_PIPELINE_URL":       "https://ci.example.com/1",
+		"PLUGIN_CUSTOM_BUTTONS": `[{"label":"Grafana","url":"https://grafana.example.com","type":"danger"}]`,
+	})
+
+	actions := createActionButtons(Config{})
+	if len(actions) != 2 {
+		t.Fatalf("Expected pipeline and custom buttons, got %v", actions)
+	}
+	custom := actions[1]
+	if custom["url"] != "https://grafana.example.com" || custom["type"] != "danger" {
+		t.Errorf("Unexpected custom button %v", custom)
+	}
+
+	text := createLarkTextMessage(Config{}, "v1.0.0")["content"].(map[string]any)["text"].(string)
+	if !strings.Contains(text, "🔗 Grafana: https://grafana.example.com") {
+		t.Errorf("Expected text message to link the custom button, got %q", text)
+	}
+}
+
+func TestCustomButtonsFilter(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"CI_PIPELINE_URL": "https://ci.example.com/1",
+		"PLUGIN_CUSTOM_BUTTONS": `[{"label":"Grafana","url":"https://grafana.example.com"},` +
+			`{"label":"Rollback Runbook","url":"https://wiki.example.com/rollback"}]`,
+	})
+
+	buttons := []string{"rollback runbook", "pipeline"}
+	actions := createActionButtons(Config{Buttons: buttons})
+	if len(actions) != 2 {
+		t.Fatalf("Expected 2 buttons, got %v", actions)
+	}
+	if actions[0]["url"] != "https://wiki.example.com/rollback" || actions[1]["url"] != "https://ci.example.com/1" {
+		t.Errorf("Expected buttons in the configured order, got %v", actions)
+	}
+
+	text := createCustomButtonText(buttons)
+	if strings.Contains(text, "Grafana") || !strings.Contains(text, "Rollback Runbook") {
+		t.Errorf("Expected only the selected custom button in text, got %q", text)
+	}
+}
+
+func TestMain_InvalidCustomButtons(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_WEBHOOK_URL":    "https://open.larksuite.com/open-apis/bot/v2/hook/test",
+		"PLUGIN_CUSTOM_BUTTONS": `[{"label":"Grafana"}]`,
+	})
+
+	originalOsExit := osExit
+	defer func() { osExit = originalOsExit }()
+	exitCode := 0
+	osExit = func(code int) { exitCode = code }
+
+	output := captureOutput(t, main)
+
+	if exitCode != exitConfiguration {
+		t.Errorf("Expected exit code 2, got %d", exitCode)
+	}
+	if !strings.Contains(output, `button "Grafana" is missing a url`) {
+		t.Errorf("Expected a clear error, got %q", output)
+	}
+}
+
+func TestCustomButtonsConfirm(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_CUSTOM_BUTTONS": `[{"label":"Trigger Rollback","url":"https://deploy.example.com/rollback","type":"danger",` +
+			`"confirm_title":"Roll back?","confirm_text":"This redeploys the previous release."},` +
+			`{"label":"Grafana","url":"https://grafana.example.com"}]`,
+	})
+
+	actions := createActionButtons(Config{})
+	if len(actions) != 2 {
+		t.Fatalf("Expected 2 buttons, got %v", actions)
+	}
+	data, _ := json.Marshal(actions[0])
+	want := `{"confirm":{"text":{"content":"This redeploys the previous release.","tag":"plain_text"},"title":{"content":"Roll back?","tag":"plain_text"}},` +
+		`"tag":"button","text":{"content":"Trigger Rollback","tag":"plain_text"},"type":"danger","url":"https://deploy.example.com/rollback"}`
+	if string(data) != want {
+		t.Errorf("Unexpected button JSON\n got: %s\nwant: %s", data, want)
+	}
+	if _, ok := actions[1]["confirm"]; ok || len(actions[1]) != 4 {
+		t.Errorf("Expected the button without confirm settings to be unchanged, got %v", actions[1])
+	}
+}
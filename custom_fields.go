@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// customField is one entry of PLUGIN_CUSTOM_FIELDS
+type customField struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// parseCustomFields reads PLUGIN_CUSTOM_FIELDS, either a JSON object or an
+// array of {"label": ..., "value": ...}. Both keep the order they are written in.
+func parseCustomFields() ([]customField, error) {
+	value := strings.TrimSpace(getEnvOrDefault("PLUGIN_CUSTOM_FIELDS", ""))
+	if value == "" {
+		return nil, nil
+	}
+
+	var fields []customField
+	if strings.HasPrefix(value, "[") {
+		if err := json.Unmarshal([]byte(value), &fields); err != nil {
+			return nil, fmt.Errorf("PLUGIN_CUSTOM_FIELDS is not a valid JSON array of fields: %w", err)
+		}
+		for i, field := range fields {
+			if strings.TrimSpace(field.Label) == "" {
+				return nil, fmt.Errorf("PLUGIN_CUSTOM_FIELDS: field %d is missing a label", i+1)
+			}
+		}
+		return fields, nil
+	}
+
+	fields, err := parseOrderedObject([]byte(value))
+	if err != nil {
+		return nil, fmt.Errorf("PLUGIN_CUSTOM_FIELDS is not a valid JSON object or array: %w", err)
+	}
+	return fields, nil
+}
+
+// parseOrderedObject decodes a JSON object of strings keeping the key order
+func parseOrderedObject(data []byte) ([]customField, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil {
+		return nil, err
+	} else if token != json.Delim('{') {
+		return nil, fmt.Errorf("expected an object, got %v", token)
+	}
+
+	var fields []customField
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value string
+		if err := decoder.Decode(&value); err != nil {
+			return nil, fmt.Errorf("value of %q: %w", token, err)
+		}
+		fields = append(fields, customField{Label: token.(string), Value: value})
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err == nil {
+		return nil, fmt.Errorf("unexpected data after the object")
+	}
+	return fields, nil
+}
+
+// getCustomFields returns the custom fields with ${VAR} references in their
+// values expanded
+func getCustomFields() []customField {
+	fields, _ := parseCustomFields()
+	for i, field := range fields {
+		fields[i].Value, _ = expandTemplateEnv(field.Value)
+	}
+	return fields
+}
+
+// createCustomFieldElements returns the card section for PLUGIN_CUSTOM_FIELDS
+func createCustomFieldElements() []map[string]any {
+	fields := getCustomFields()
+	if len(fields) == 0 {
+		return nil
+	}
+
+	element := map[string]any{"tag": "div"}
+	if isColumnsLayout() {
+		var columns []map[string]any
+		for _, field := range fields {
+			columns = append(columns, cardField(fmt.Sprintf("**%s:**\n%s", field.Label, escapeMarkdown(field.Value)), true))
+		}
+		element["fields"] = columns
+	} else {
+		var lines []string
+		for _, field := range fields {
+			lines = append(lines, fmt.Sprintf("**%s:** %s", field.Label, escapeMarkdown(field.Value)))
+		}
+		element["text"] = map[string]any{
+			"content": strings.Join(lines, "\n"),
+			"tag":     "lark_md",
+		}
+	}
+	return []map[string]any{{"tag": "hr"}, element}
+}
+
+// createCustomFieldText lists PLUGIN_CUSTOM_FIELDS as bullet lines
+func createCustomFieldText() string {
+	fields := getCustomFields()
+	if len(fields) == 0 {
+		return ""
+	}
+
+	text := "\n"
+	for _, field := range fields {
+		text += fmt.Sprintf("• %s: %s\n", field.Label, escapeText(field.Value))
+	}
+	return text
+}
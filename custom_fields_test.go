@@ -0,0 +1,86 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCustomFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected []customField
+	}{
+		{"Object keeps order", `{"Zone": "eu-1", "Image": "app:1.2", "Build": "42"}`, []customField{{"Zone", "eu-1"}, {"Image", "app:1.2"}, {"Build", "42"}}},
+		{"Array keeps order", `[{"label": "Zone", "value": "eu-1"}, {"label": "Image", "value": "app:1.2"}]`, []customField{{"Zone", "eu-1"}, {"Image", "app:1.2"}}},
+		{"Empty object", `{}`, nil},
+		{"Unset", ``, nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setEnvFixture(t, map[string]string{"PLUGIN_CUSTOM_FIELDS": tc.value})
+
+			fields, err := parseCustomFields()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(fields, tc.expected) {
+				t.Errorf("Expected %+v, got %+v", tc.expected, fields)
+			}
+		})
+	}
+}
+
+func TestParseCustomFieldsErrors(t *testing.T) {
+	for _, value := range []string{`{"Zone": "eu-1"`, `{"Zone": 1}`, `[{"value": "eu-1"}]`, `"eu-1"`, `{"Zone": "eu-1"} {}`} {
+		setEnvFixture(t, map[string]string{"PLUGIN_CUSTOM_FIELDS": value})
+
+		if _, err := parseCustomFields(); err == nil || !strings.Contains(err.Error(), "PLUGIN_CUSTOM_FIELDS") {
+			t.Errorf("%s: expected an error naming the setting, got %v", value, err)
+		}
+	}
+}
+
+func TestCustomFieldsSection(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_CUSTOM_FIELDS": `[{"label": "Image digest", "value": "sha256:${CI_COMMIT_SHA}"}, {"label": "Rollout", "value": "canary_10%"}]`,
+		"DEPLOY_ENV":           "staging",
+		"CI_COMMIT_SHA":        "abc123",
+	})
+
+	config := Config{Variables: []string{"DEPLOY_ENV"}}
+	elements := createLarkCard(config, "v1.0.0")["card"].(map[string]any)["elements"].([]map[string]any)
+	variables := elements[4]["text"].(map[string]any)["content"].(string)
+	if !strings.HasPrefix(variables, "**Variables:**") || elements[5]["tag"] != "hr" {
+		t.Fatalf("Expected custom fields after the variables, got %v", elements)
+	}
+	content := elements[6]["text"].(map[string]any)["content"]
+	if expected := "**Image digest:** sha256:abc123\n**Rollout:** canary&#95;10%"; content != expected {
+		t.Errorf("Expected %q, got %q", expected, content)
+	}
+
+	text := createLarkTextMessage(config, "v1.0.0")["content"].(map[string]any)["text"].(string)
+	if !strings.Contains(text, "\n• Image digest: sha256:abc123\n• Rollout: canary_10%\n") {
+		t.Errorf("Unexpected custom fields in text %q", text)
+	}
+}
+
+func TestMain_InvalidCustomFields(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_WEBHOOK_URL":   "https://open.larksuite.com/open-apis/bot/v2/hook/test",
+		"PLUGIN_CUSTOM_FIELDS": `{"Zone": `,
+	})
+
+	originalOsExit := osExit
+	defer func() { osExit = originalOsExit }()
+	exitCode := 0
+	osExit = func(code int) { exitCode = code }
+
+	output := captureOutput(t, main)
+
+	if exitCode != exitConfiguration || !strings.Contains(output, "Error: PLUGIN_CUSTOM_FIELDS") {
+		t.Errorf("Expected a startup error naming the setting, got exit code %d and %q", exitCode, output)
+	}
+}
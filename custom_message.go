@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxCustomMessageLength caps PLUGIN_MESSAGE so a long value cannot break the card
+const maxCustomMessageLength = 2000
+
+// truncateRunes shortens s to at most limit runes, marking the cut with an ellipsis
+func truncateRunes(s string, limit int) string {
+	if runes := []rune(s); len(runes) > limit {
+		return string(runes[:limit]) + "…"
+	}
+	return s
+}
+
+// getCustomMessage returns PLUGIN_MESSAGE with ${VAR} references expanded and
+// its length capped. Expansion errors only occur in strict mode, main reports
+// them before building the message.
+func getCustomMessage() string {
+	message := getEnvOrDefault("PLUGIN_MESSAGE", "")
+	if message == "" {
+		return ""
+	}
+	expanded, _ := expandTemplateEnv(strings.ReplaceAll(message, "\r\n", "\n"))
+	return truncateRunes(expanded, maxCustomMessageLength)
+}
+
+// checkCustomMessage reports a PLUGIN_MESSAGE that cannot be expanded
+func checkCustomMessage() error {
+	if _, err := expandTemplateEnv(getEnvOrDefault("PLUGIN_MESSAGE", "")); err != nil {
+		return fmt.Errorf("PLUGIN_MESSAGE: %w", err)
+	}
+	return nil
+}
+
+// createCustomMessageElements returns the card section for PLUGIN_MESSAGE
+func createCustomMessageElements() []map[string]any {
+	message := getCustomMessage()
+	if message == "" {
+		return nil
+	}
+	return []map[string]any{
+		{
+			"tag": "hr",
+		},
+		{
+			"tag": "div",
+			"text": map[string]any{
+				"content": message,
+				"tag":     "lark_md",
+			},
+		},
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetCustomMessage(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_MESSAGE":            "**Nightly scan** on ${DEPLOY_ENV}\r\n[Ticket](https://jira.example.com/OPS-1)",
+		"PLUGIN_TEMPLATE_ENV_ALLOW": "DEPLOY_ENV",
+		"DEPLOY_ENV":                "staging",
+	})
+
+	expected := "**Nightly scan** on staging\n[Ticket](https://jira.example.com/OPS-1)"
+	if message := getCustomMessage(); message != expected {
+		t.Errorf("Expected %q, got %q", expected, message)
+	}
+}
+
+func TestGetCustomMessageTruncates(t *testing.T) {
+	setEnvFixture(t, map[string]string{"PLUGIN_MESSAGE": strings.Repeat("é", maxCustomMessageLength+10)})
+
+	message := getCustomMessage()
+	if !strings.HasSuffix(message, "…") {
+		t.Errorf("Expected truncated message to end with an ellipsis")
+	}
+	if length := len([]rune(message)); length != maxCustomMessageLength+1 {
+		t.Errorf("Expected %d runes, got %d", maxCustomMessageLength+1, length)
+	}
+}
+
+func TestCustomMessageInCardAndText(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_MESSAGE":   "Deploy ticket OPS-1",
+		"CI_REPO_NAME":     "backend",
+		"CI_PIPELINE_URL":  "https://ci.example.com/1",
+		"CI_COMMIT_BRANCH": "main",
+	})
+
+	elements := createLarkCard(Config{}, "v1.0.0")["card"].(map[string]any)["elements"].([]map[string]any)
+	found := false
+	for i, element := range elements {
+		text, ok := element["text"].(map[string]any)
+		if ok && text["content"] == "Deploy ticket OPS-1" {
+			found = true
+			if elements[i-1]["tag"] != "hr" {
+				t.Errorf("Expected an hr before the message section")
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Expected the card to contain the message section")
+	}
+
+	text := createLarkTextMessage(Config{}, "v1.0.0")["content"].(map[string]any)["text"].(string)
+	if !strings.Contains(text, "\n\nDeploy ticket OPS-1") {
+		t.Errorf("Expected text message to include the custom message, got %q", text)
+	}
+}
+
+func TestCustomMessageEmpty(t *testing.T) {
+	setEnvFixture(t, map[string]string{"PLUGIN_MESSAGE": ""})
+
+	if elements := createCustomMessageElements(); elements != nil {
+		t.Errorf("Expected no section, got %v", elements)
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// defaultDedupeTTL is how long a sent notification suppresses identical ones
+const defaultDedupeTTL = 24 * time.Hour
+
+// dedupeState is the content of PLUGIN_DEDUPE_FILE: when each notification
+// fingerprint was last sent
+type dedupeState struct {
+	Sent map[string]time.Time `json:"sent"`
+}
+
+// dedupeSent holds the fingerprints read from PLUGIN_DEDUPE_FILE, and
+// dedupeRecorded those sent by this run
+var (
+	dedupeSent     map[string]time.Time
+	dedupeRecorded map[string]time.Time
+)
+
+// getDedupeTTL returns PLUGIN_DEDUPE_TTL, invalid values fall back to the
+// default with a warning
+func getDedupeTTL() time.Duration {
+	value := getEnvOrDefault("PLUGIN_DEDUPE_TTL", "")
+	if value == "" {
+		return defaultDedupeTTL
+	}
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl <= 0 {
+		logWarn(fmt.Sprintf("invalid PLUGIN_DEDUPE_TTL %q, using %s", value, defaultDedupeTTL))
+		return defaultDedupeTTL
+	}
+	return ttl
+}
+
+// dedupeFingerprint identifies the notification of this build for a target:
+// the repository, commit and event, or PLUGIN_NOTIFICATION_ID when the caller
+// manages its own IDs, the final status and matrix leg, and a hash of the
+// target so that the file holds no webhook URLs
+func dedupeFingerprint(target string) string {
+	targetHash := sha256.Sum256([]byte(target))
+	build := []string{
+		getEnvOrDefault("CI_REPO", ""),
+		getEnvOrDefault("CI_COMMIT_SHA", ""),
+		getPipelineEvent(),
+	}
+	if getEnvOrDefault("PLUGIN_NOTIFICATION_ID", "") != "" {
+		build = []string{notificationID()}
+	}
+	key := strings.Join(append(build,
+		getBuildStatus(),
+		matrixString(),
+		hex.EncodeToString(targetHash[:]),
+	), "\x00")
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:16])
+}
+
+// readDedupeState returns the unexpired fingerprints of path. Missing,
+// unreadable or corrupt files count as empty.
+func readDedupeState(path string) map[string]time.Time {
+	sent := map[string]time.Time{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return sent
+	}
+	var state dedupeState
+	if err := json.Unmarshal(data, &state); err != nil {
+		logWarn(fmt.Sprintf("ignoring corrupt PLUGIN_DEDUPE_FILE: %v", err), "path", path)
+		return sent
+	}
+	cutoff := timeNow().Add(-getDedupeTTL())
+	for fingerprint, at := range state.Sent {
+		if at.After(cutoff) {
+			sent[fingerprint] = at
+		}
+	}
+	return sent
+}
+
+// loadDedupeState reads PLUGIN_DEDUPE_FILE before anything is sent
+func loadDedupeState() {
+	dedupeSent, dedupeRecorded = map[string]time.Time{}, map[string]time.Time{}
+	if path := getEnvOrDefault("PLUGIN_DEDUPE_FILE", ""); path != "" {
+		dedupeSent = readDedupeState(path)
+	}
+}
+
+// isDuplicateNotification reports whether the notification for target was
+// already sent by an earlier run
+func isDuplicateNotification(target string) bool {
+	if getEnvOrDefault("PLUGIN_DEDUPE_FILE", "") == "" {
+		return false
+	}
+	_, ok := dedupeSent[dedupeFingerprint(target)]
+	return ok
+}
+
+// recordNotification remembers a successful send to target
+func recordNotification(target string) {
+	if getEnvOrDefault("PLUGIN_DEDUPE_FILE", "") == "" {
+		return
+	}
+	dedupeRecorded[dedupeFingerprint(target)] = timeNow()
+}
+
+// saveDedupeState adds the notifications of this run to PLUGIN_DEDUPE_FILE.
+// The file is read again and replaced atomically while holding the lock
+// file next to it, so matrix legs finishing at the same time keep each
+// other's entries and never see a partial file. Failures only warn: the
+// notification has been sent already.
+func saveDedupeState() {
+	path := getEnvOrDefault("PLUGIN_DEDUPE_FILE", "")
+	if path == "" || len(dedupeRecorded) == 0 {
+		return
+	}
+
+	unlock, err := lockFile(path + ".lock")
+	if err != nil {
+		logWarn(fmt.Sprintf("could not lock PLUGIN_DEDUPE_FILE: %v", err), "path", path)
+		return
+	}
+	defer unlock()
+
+	sent := readDedupeState(path)
+	for fingerprint, at := range dedupeRecorded {
+		sent[fingerprint] = at
+	}
+	if err := writeDedupeState(path, dedupeState{Sent: sent}); err != nil {
+		logWarn(fmt.Sprintf("could not update PLUGIN_DEDUPE_FILE: %v", err), "path", path)
+	}
+}
+
+func writeDedupeState(path string, state dedupeState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".dedupe_*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain_DedupeSuppressesRestart(t *testing.T) {
+	requests := 0
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"code": 0}`))
+	}))
+	defer testServer.Close()
+
+	originalOsExit := osExit
+	defer func() { osExit = originalOsExit }()
+	exitCode := 0
+	osExit = func(code int) { exitCode = code }
+
+	dedupeFile := filepath.Join(t.TempDir(), "dedupe.json")
+	env := map[string]string{
+		"PLUGIN_WEBHOOK_URL": testServer.URL,
+		"PLUGIN_DEDUPE_FILE": dedupeFile,
+		"DRONE_BUILD_STATUS": "success",
+		"CI_REPO":            "octo/backend",
+		"CI_COMMIT_SHA":      "abcdef1234567890",
+		"CI_PIPELINE_EVENT":  "push",
+	}
+
+	setEnvFixture(t, env)
+	captureStdout(t, main)
+	if requests != 1 {
+		t.Fatalf("Expected the first run to send, got %d requests", requests)
+	}
+
+	// The restart runs in a fresh process
+	setEnvFixture(t, env)
+	output := captureStdout(t, main)
+	if requests != 1 {
+		t.Errorf("Expected the restart to send nothing, got %d requests", requests)
+	}
+	if exitCode != 0 {
+		t.Errorf("Expected exit code 0, got %d", exitCode)
+	}
+	if !strings.Contains(output, "duplicate notification suppressed") {
+		t.Errorf("Expected the suppression to be logged, got:\n%s", output)
+	}
+
+	// A different result is a new notification
+	setEnvFixture(t, env)
+	setEnvFixture(t, map[string]string{"DRONE_BUILD_STATUS": "failure"})
+	captureOutput(t, main)
+	if requests != 2 {
+		t.Errorf("Expected a failed restart to send, got %d requests", requests)
+	}
+}
+
+func TestDedupeFingerprint(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"CI_REPO":          "octo/backend",
+		"CI_COMMIT_SHA":    "abcdef1",
+		"PLUGIN_STATUS":    "success",
+		"PLUGIN_MATRIX":    "go=1.22",
+		"CI_WORKFLOW_NAME": "",
+	})
+	base := dedupeFingerprint("https://example.com/hook/a")
+
+	if dedupeFingerprint("https://example.com/hook/b") == base {
+		t.Errorf("Expected targets to have different fingerprints")
+	}
+	setEnvFixture(t, map[string]string{"PLUGIN_MATRIX": "go=1.23"})
+	if dedupeFingerprint("https://example.com/hook/a") == base {
+		t.Errorf("Expected matrix legs to have different fingerprints")
+	}
+}
+
+func TestReadDedupeState(t *testing.T) {
+	originalTimeNow := timeNow
+	defer func() { timeNow = originalTimeNow }()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+
+	dir := t.TempDir()
+	t.Run("expired entries are dropped", func(t *testing.T) {
+		setEnvFixture(t, map[string]string{"PLUGIN_DEDUPE_TTL": "1h"})
+		path := filepath.Join(dir, "ttl.json")
+		os.WriteFile(path, []byte(`{"sent":{"old":"2026-03-01T10:00:00Z","new":"2026-03-01T11:30:00Z"}}`), 0600)
+
+		sent := readDedupeState(path)
+		if _, ok := sent["old"]; ok || len(sent) != 1 {
+			t.Errorf("Expected only the unexpired entry, got %v", sent)
+		}
+	})
+
+	t.Run("corrupt file is empty", func(t *testing.T) {
+		setEnvFixture(t, map[string]string{"PLUGIN_DEDUPE_TTL": ""})
+		path := filepath.Join(dir, "corrupt.json")
+		os.WriteFile(path, []byte(`{"sent":`), 0600)
+
+		var sent map[string]time.Time
+		output := captureStdout(t, func() { sent = readDedupeState(path) })
+		if len(sent) != 0 || !strings.Contains(output, "ignoring corrupt PLUGIN_DEDUPE_FILE") {
+			t.Errorf("Expected an empty state and a warning, got %v and '%s'", sent, output)
+		}
+	})
+
+	t.Run("missing file is empty", func(t *testing.T) {
+		if sent := readDedupeState(filepath.Join(dir, "missing.json")); len(sent) != 0 {
+			t.Errorf("Expected an empty state, got %v", sent)
+		}
+	})
+}
+
+func TestSaveDedupeState_KeepsConcurrentEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dedupe.json")
+	setEnvFixture(t, map[string]string{"PLUGIN_DEDUPE_FILE": path})
+
+	// Two matrix legs load the empty state before either has saved
+	loadDedupeState()
+	dedupeRecorded["leg-a"] = timeNow()
+	legA := dedupeRecorded
+	loadDedupeState()
+	dedupeRecorded["leg-b"] = timeNow()
+
+	saveDedupeState()
+	dedupeRecorded = legA
+	saveDedupeState()
+
+	sent := readDedupeState(path)
+	if _, ok := sent["leg-a"]; !ok {
+		t.Errorf("Expected leg-a to be recorded, got %v", sent)
+	}
+	if _, ok := sent["leg-b"]; !ok {
+		t.Errorf("Expected leg-b to be kept, got %v", sent)
+	}
+}
+
+func TestSaveDedupeState_WaitsForLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dedupe.json")
+	setEnvFixture(t, map[string]string{"PLUGIN_DEDUPE_FILE": path})
+
+	// Another leg holds the lock while it writes its entry
+	unlock, err := lockFile(path + ".lock")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	loadDedupeState()
+	dedupeRecorded["leg-a"] = timeNow()
+	done := make(chan struct{})
+	go func() {
+		saveDedupeState()
+		close(done)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	writeDedupeState(path, dedupeState{Sent: map[string]time.Time{"leg-b": timeNow()}})
+	unlock()
+	<-done
+
+	sent := readDedupeState(path)
+	if _, ok := sent["leg-a"]; !ok {
+		t.Errorf("Expected leg-a to be recorded, got %v", sent)
+	}
+	if _, ok := sent["leg-b"]; !ok {
+		t.Errorf("Expected leg-b, written under the lock, to be kept, got %v", sent)
+	}
+}
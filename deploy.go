@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// defaultDeployProdColor is the header color of production deployments
+const defaultDeployProdColor = "purple"
+
+// defaultProdEnvironments are the deploy targets that count as production
+var defaultProdEnvironments = []string{"production", "prod"}
+
+// headerColors are the header templates Lark cards accept
+var headerColors = map[string]bool{
+	"blue": true, "wathet": true, "turquoise": true, "green": true,
+	"yellow": true, "orange": true, "red": true, "carmine": true,
+	"violet": true, "purple": true, "indigo": true, "grey": true,
+}
+
+// isDeployment reports whether the pipeline is a Woodpecker deployment or a
+// Drone promotion
+func isDeployment() bool {
+	switch getPipelineEvent() {
+	case "deployment", "promote":
+		return true
+	}
+	return false
+}
+
+// deployTarget returns the environment a deployment goes to, or "" for other
+// pipelines and deployments without a target
+func deployTarget() string {
+	if !isDeployment() {
+		return ""
+	}
+	return getEnvOrDefault("CI_PIPELINE_DEPLOY_TARGET", getEnvOrDefault("DRONE_DEPLOY_TO", ""))
+}
+
+// isProductionDeploy reports whether the deploy target is listed in
+// PLUGIN_PROD_ENVIRONMENTS, compared case-insensitively
+func isProductionDeploy() bool {
+	target := deployTarget()
+	if target == "" {
+		return false
+	}
+	environments := getListSetting("PLUGIN_PROD_ENVIRONMENTS")
+	if len(environments) == 0 {
+		environments = defaultProdEnvironments
+	}
+	for _, environment := range environments {
+		if strings.EqualFold(environment, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// deployProdColor returns PLUGIN_COLOR_DEPLOY_PROD, unknown colors fall back
+// to the default with a warning
+func deployProdColor() string {
+	color := strings.ToLower(getEnvOrDefault("PLUGIN_COLOR_DEPLOY_PROD", defaultDeployProdColor))
+	if !headerColors[color] {
+		logWarn(fmt.Sprintf("unknown PLUGIN_COLOR_DEPLOY_PROD '%s', using %s", color, defaultDeployProdColor))
+		return defaultDeployProdColor
+	}
+	return color
+}
+
+// deploymentLabel rewords a label for deployments: "Pipeline Succeeded"
+// becomes "Deploy Succeeded" and "View Pipeline" "View Deployment". Other
+// pipelines keep the label.
+func deploymentLabel(label string) string {
+	if !isDeployment() {
+		return label
+	}
+	if rest, ok := strings.CutPrefix(label, "Pipeline "); ok {
+		return "Deploy " + rest
+	}
+	return strings.Replace(label, "Pipeline", "Deployment", 1)
+}
+
+// deploymentStyle rewords the status of deployments and gives successful
+// and running production deployments their own header color. Failed
+// production deployments stay red.
+func deploymentStyle(style statusStyle, status string) statusStyle {
+	if !isDeployment() {
+		return style
+	}
+	style.Text = deploymentLabel(style.Text)
+	switch status {
+	case "", "success", "running":
+		if isProductionDeploy() {
+			style.Color = deployProdColor()
+		}
+	}
+	return style
+}
+
+// deployHeaderProject is the project part of a deployment header, such as
+// "backend → production"
+func deployHeaderProject(projectName, target string) string {
+	return fmt.Sprintf("%s → %s", projectName, target)
+}
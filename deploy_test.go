@@ -0,0 +1,127 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDeploymentCard(t *testing.T) {
+	tests := []struct {
+		name        string
+		env         map[string]string
+		status      string
+		color       string
+		title       string
+		environment string
+	}{
+		{
+			name:        "Staging",
+			env:         map[string]string{"CI_PIPELINE_EVENT": "deployment", "CI_PIPELINE_DEPLOY_TARGET": "staging"},
+			status:      "success",
+			color:       "green",
+			title:       "backend → staging ✅ Deploy Succeeded",
+			environment: "**Environment:** staging",
+		},
+		{
+			name:        "Production",
+			env:         map[string]string{"CI_PIPELINE_EVENT": "deployment", "CI_PIPELINE_DEPLOY_TARGET": "production"},
+			status:      "success",
+			color:       "purple",
+			title:       "backend → production ✅ Deploy Succeeded",
+			environment: "**Environment:** production",
+		},
+		{
+			name:        "Failed production deploy stays red",
+			env:         map[string]string{"CI_PIPELINE_EVENT": "deployment", "CI_PIPELINE_DEPLOY_TARGET": "production"},
+			status:      "failure",
+			color:       "red",
+			title:       "backend → production 🚨 Deploy Failed",
+			environment: "**Environment:** production",
+		},
+		{
+			name:        "Drone promotion with custom production list and color",
+			env:         map[string]string{"DRONE_BUILD_EVENT": "promote", "DRONE_DEPLOY_TO": "Live", "PLUGIN_PROD_ENVIRONMENTS": "live,prod-eu", "PLUGIN_COLOR_DEPLOY_PROD": "indigo"},
+			status:      "success",
+			color:       "indigo",
+			title:       "backend → Live ✅ Deploy Succeeded",
+			environment: "**Environment:** Live",
+		},
+		{
+			name:   "Missing target",
+			env:    map[string]string{"CI_PIPELINE_EVENT": "deployment"},
+			status: "success",
+			color:  "green",
+			title:  "backend - ✅ Deploy Succeeded · 🚀 Deployment",
+		},
+		{
+			name:   "Not a deployment",
+			env:    map[string]string{"CI_PIPELINE_EVENT": "push", "CI_PIPELINE_DEPLOY_TARGET": "production"},
+			status: "success",
+			color:  "green",
+			title:  "backend - ✅ Pipeline Succeeded · 📤 Push",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setEnvFixture(t, map[string]string{
+				"CI_REPO_NAME":     "backend",
+				"CI_COMMIT_BRANCH": "main",
+				"CI_PIPELINE_URL":  "https://ci.example.com/octo/backend/42",
+			})
+			setEnvFixture(t, tc.env)
+
+			card := createLarkCard(Config{Status: tc.status}, "v1.0.0")["card"].(map[string]any)
+			header := card["header"].(map[string]any)
+			if color := header["template"]; color != tc.color {
+				t.Errorf("Expected color '%s', got '%s'", tc.color, color)
+			}
+			if title := header["title"].(map[string]any)["content"]; title != tc.title {
+				t.Errorf("Expected title '%s', got '%s'", tc.title, title)
+			}
+
+			elements := card["elements"].([]map[string]any)
+			metadata := elements[0]["text"].(map[string]any)["content"].(string)
+			if tc.environment != "" && !strings.Contains(metadata, tc.environment) {
+				t.Errorf("Expected '%s' in the card, got '%s'", tc.environment, metadata)
+			}
+			if tc.environment == "" && strings.Contains(metadata, "Environment") {
+				t.Errorf("Expected no environment in the card, got '%s'", metadata)
+			}
+		})
+	}
+}
+
+func TestDeploymentWording(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"CI_PIPELINE_EVENT":         "deployment",
+		"CI_PIPELINE_DEPLOY_TARGET": "staging",
+		"CI_PIPELINE_URL":           "https://ci.example.com/octo/backend/42",
+	})
+
+	config := Config{Status: "success", Buttons: []string{"pipeline"}}
+	buttons := createActionButtons(config)
+	if len(buttons) != 1 || buttons[0]["text"].(map[string]any)["content"] != "View Deployment" {
+		t.Errorf("Expected a single View Deployment button, got %v", buttons)
+	}
+
+	text := createLarkTextMessage(config, "v1.0.0")["content"].(map[string]any)["text"].(string)
+	if !strings.Contains(text, "Deployment: https://ci.example.com/octo/backend/42") || strings.Contains(text, "Pipeline") {
+		t.Errorf("Expected deployment wording in the text message, got '%s'", text)
+	}
+
+	setEnvFixture(t, map[string]string{"PLUGIN_LANG": "zh"})
+	card := createLarkCard(config, "v1.0.0")["card"].(map[string]any)
+	if title := card["header"].(map[string]any)["title"].(map[string]any)["content"]; title != " → staging ✅ 部署成功" {
+		t.Errorf("Expected the translated deployment title, got '%s'", title)
+	}
+}
+
+func TestDeployProdColor_Invalid(t *testing.T) {
+	setEnvFixture(t, map[string]string{"PLUGIN_COLOR_DEPLOY_PROD": "pink"})
+	var color string
+	output := captureStdout(t, func() { color = deployProdColor() })
+	if color != defaultDeployProdColor || !strings.Contains(output, "unknown PLUGIN_COLOR_DEPLOY_PROD 'pink'") {
+		t.Errorf("Expected a warning and the default color, got '%s' and '%s'", color, output)
+	}
+}
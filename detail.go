@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Detail levels selected by PLUGIN_DETAIL
+const (
+	detailFull    = "full"
+	detailMinimal = "minimal"
+	detailAuto    = "auto"
+)
+
+// checkDetailSetting reports an invalid PLUGIN_DETAIL
+func checkDetailSetting(value string) error {
+	switch strings.ToLower(value) {
+	case "", detailFull, detailMinimal, detailAuto:
+		return nil
+	}
+	return fmt.Errorf("PLUGIN_DETAIL must be %s, %s or %s, got %q", detailFull, detailMinimal, detailAuto, value)
+}
+
+// detailLevel resolves PLUGIN_DETAIL for a build with status: auto is
+// minimal for successful builds and full for every other status
+func detailLevel(status string) string {
+	switch strings.ToLower(getEnvOrDefault("PLUGIN_DETAIL", detailFull)) {
+	case detailMinimal:
+		return detailMinimal
+	case detailAuto:
+		if status == "success" {
+			return detailMinimal
+		}
+	}
+	return detailFull
+}
+
+// isMinimalDetail reports whether the message is rendered at the minimal
+// level. The minimal messages are built without any of the section builders,
+// so sections never check the level themselves.
+func isMinimalDetail(status string) bool {
+	return detailLevel(status) == detailMinimal
+}
+
+// minimalDetails is the one line of a minimal message: branch and version
+func minimalDetails(projectVersion string) string {
+	var parts []string
+	for _, part := range []string{getEnvOrDefault("CI_COMMIT_BRANCH", ""), projectVersion} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, " · ")
+}
+
+// minimalCardContent returns the header title of the full card and the
+// elements of the minimal card: one line with the branch and version, and the
+// pipeline button
+func minimalCardContent(config Config, projectVersion, statusIcon, statusText string) (string, []map[string]any) {
+	return cardHeaderTitle(config, projectVersion, statusIcon, statusText), oneLineElements(minimalDetails(projectVersion), nil)
+}
+
+// createMinimalLarkTextMessage is the text equivalent of the minimal card
+func createMinimalLarkTextMessage(config Config, projectVersion, statusIcon, statusText string) map[string]any {
+	message := textMessageTitle(config, projectVersion, statusIcon, statusText)
+
+	var details []string
+	if line := minimalDetails(projectVersion); line != "" {
+		details = append(details, escapeText(line))
+	}
+	if pipelineURL := getEnvOrDefault("CI_PIPELINE_URL", ""); pipelineURL != "" {
+		details = append(details, pipelineURL)
+	}
+	if len(details) > 0 {
+		message += "\n" + strings.Join(details, " · ")
+	}
+
+	return map[string]any{
+		"msg_type": "text",
+		"content": map[string]any{
+			"text": message,
+		},
+	}
+}
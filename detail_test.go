@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+var detailFixture = map[string]string{
+	"CI_REPO":           "octocat/backend",
+	"CI_REPO_NAME":      "backend",
+	"CI_COMMIT_BRANCH":  "main",
+	"CI_COMMIT_AUTHOR":  "octocat",
+	"CI_COMMIT_MESSAGE": "Fix the flaky test",
+	"CI_PIPELINE_URL":   "https://ci.example.com/repos/1/pipeline/42",
+}
+
+// detailConfig shows the author as a variable, so full messages have a variables section
+func detailConfig(status string) Config {
+	return Config{Status: status, Variables: []string{"CI_COMMIT_AUTHOR"}}
+}
+
+func TestDetailLevel_Card(t *testing.T) {
+	tests := []struct {
+		detail   string
+		status   string
+		elements int
+	}{
+		{detail: "full", status: "success", elements: 7},
+		{detail: "full", status: "failure", elements: 7},
+		{detail: "minimal", status: "success", elements: 2},
+		{detail: "minimal", status: "failure", elements: 2},
+		{detail: "auto", status: "success", elements: 2},
+		{detail: "auto", status: "failure", elements: 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.detail+"/"+tt.status, func(t *testing.T) {
+			setEnvFixture(t, detailFixture)
+			setEnvFixture(t, map[string]string{"PLUGIN_DETAIL": tt.detail})
+
+			card := createLarkCard(detailConfig(tt.status), "v1.0.0")["card"].(map[string]any)
+			elements := card["elements"].([]map[string]any)
+			if len(elements) != tt.elements {
+				t.Fatalf("Expected %d elements, got %d: %v", tt.elements, len(elements), elements)
+			}
+			data, _ := json.Marshal(card)
+			if !strings.Contains(string(data), "View Pipeline") {
+				t.Errorf("Expected the pipeline button, got %s", data)
+			}
+			if tt.elements == 2 {
+				if content := elements[0]["text"].(map[string]any)["content"]; content != "main · v1.0.0" {
+					t.Errorf("Expected the branch and version line, got %q", content)
+				}
+				if strings.Contains(string(data), "Fix the flaky test") {
+					t.Errorf("Expected no commit message in a minimal card, got %s", data)
+				}
+			} else if !strings.Contains(string(data), "Fix the flaky test") {
+				t.Errorf("Expected the commit message in a full card, got %s", data)
+			}
+		})
+	}
+}
+
+func TestDetailLevel_Text(t *testing.T) {
+	minimalSuccess := "✅ PIPELINE SUCCEEDED\nmain · v1.0.0 · https://ci.example.com/repos/1/pipeline/42"
+	minimalFailure := "🚨 PIPELINE FAILED\nmain · v1.0.0 · https://ci.example.com/repos/1/pipeline/42"
+	tests := []struct {
+		detail   string
+		status   string
+		expected string
+	}{
+		{detail: "minimal", status: "success", expected: minimalSuccess},
+		{detail: "minimal", status: "failure", expected: minimalFailure},
+		{detail: "auto", status: "success", expected: minimalSuccess},
+		{detail: "auto", status: "failure"},
+		{detail: "full", status: "success"},
+		{detail: "full", status: "failure"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.detail+"/"+tt.status, func(t *testing.T) {
+			setEnvFixture(t, detailFixture)
+			setEnvFixture(t, map[string]string{"PLUGIN_DETAIL": tt.detail})
+
+			text := createLarkTextMessage(detailConfig(tt.status), "v1.0.0")["content"].(map[string]any)["text"].(string)
+			if tt.expected != "" {
+				if text != tt.expected {
+					t.Errorf("Expected %q, got %q", tt.expected, text)
+				}
+				return
+			}
+			for _, expected := range []string{"Project: octocat/backend", "Message: Fix the flaky test", "Variables:", "https://ci.example.com/repos/1/pipeline/42"} {
+				if !strings.Contains(text, expected) {
+					t.Errorf("Expected %q in the full message, got %q", expected, text)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckDetailSetting(t *testing.T) {
+	for _, value := range []string{"", "full", "minimal", "auto", "Auto"} {
+		if err := checkDetailSetting(value); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", value, err)
+		}
+	}
+	if err := checkDetailSetting("short"); err == nil || err.Error() != `PLUGIN_DETAIL must be full, minimal or auto, got "short"` {
+		t.Errorf("Expected an error for short, got %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxDiffStatPathLength caps each listed path
+	maxDiffStatPathLength = 120
+	defaultDiffStatFiles  = 5
+)
+
+// diffStat summarizes the changes between two commits
+type diffStat struct {
+	Files      int
+	Insertions int
+	Deletions  int
+	// Top holds the most changed paths, largest first
+	Top []fileChange
+}
+
+type fileChange struct {
+	Path    string
+	Added   int
+	Deleted int
+}
+
+// buildDiffStat is set by main when PLUGIN_SHOW_DIFFSTAT is on
+var buildDiffStat *diffStat
+
+// loadDiffStat runs git in the workspace to compare CI_PREV_COMMIT_SHA, or the
+// parent commit, with CI_COMMIT_SHA. Any problem skips the section.
+func loadDiffStat() *diffStat {
+	if getEnvOrDefault("PLUGIN_SHOW_DIFFSTAT", "false") != "true" {
+		return nil
+	}
+
+	sha := getEnvOrDefault("CI_COMMIT_SHA", "")
+	if sha == "" {
+		debugLog("skipping diffstat, CI_COMMIT_SHA is not set")
+		return nil
+	}
+	base := getEnvOrDefault("CI_PREV_COMMIT_SHA", sha+"^")
+
+	output, err := runGit("diff", "--numstat", "--no-renames", base, sha, "--")
+	if err != nil {
+		debugLog("skipping diffstat, git diff %s %s failed: %v", base, sha, err)
+		return nil
+	}
+	return parseNumstat(output)
+}
+
+// parseNumstat reads git diff --numstat output. Binary files count as changed
+// files without line counts.
+func parseNumstat(output string) *diffStat {
+	stat := &diffStat{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		added, _ := strconv.Atoi(fields[0])
+		deleted, _ := strconv.Atoi(fields[1])
+		stat.Files++
+		stat.Insertions += added
+		stat.Deletions += deleted
+		stat.Top = append(stat.Top, fileChange{Path: truncateRunes(fields[2], maxDiffStatPathLength), Added: added, Deleted: deleted})
+	}
+
+	sort.SliceStable(stat.Top, func(i, j int) bool {
+		return stat.Top[i].Added+stat.Top[i].Deleted > stat.Top[j].Added+stat.Top[j].Deleted
+	})
+	limit := defaultDiffStatFiles
+	if n, err := strconv.Atoi(getEnvOrDefault("PLUGIN_DIFFSTAT_FILES", "")); err == nil && n >= 0 {
+		limit = n
+	}
+	if len(stat.Top) > limit {
+		stat.Top = stat.Top[:limit]
+	}
+	return stat
+}
+
+// summary renders the totals, e.g. "3 files changed, +10 -2"
+func (s *diffStat) summary() string {
+	files := "files"
+	if s.Files == 1 {
+		files = "file"
+	}
+	return fmt.Sprintf("%d %s changed, +%d -%d", s.Files, files, s.Insertions, s.Deletions)
+}
+
+// createDiffStatElements returns the card section for the diff stat. Public
+// targets do not see the changed paths.
+func createDiffStatElements() []map[string]any {
+	if buildDiffStat == nil || publicMode {
+		return nil
+	}
+
+	content := fmt.Sprintf("**%s:** %s", tr("Changes"), buildDiffStat.summary())
+	for _, change := range buildDiffStat.Top {
+		content += fmt.Sprintf("\n• %s (+%d -%d)", escapeMarkdown(change.Path), change.Added, change.Deleted)
+	}
+	return []map[string]any{
+		{
+			"tag": "hr",
+		},
+		{
+			"tag": "div",
+			"text": map[string]any{
+				"content": content,
+				"tag":     "lark_md",
+			},
+		},
+	}
+}
+
+// createDiffStatText returns the one-line diff stat of text messages
+func createDiffStatText() string {
+	if buildDiffStat == nil || publicMode {
+		return ""
+	}
+	return withIcon("📝", fmt.Sprintf("%s: %s\n", tr("Changes"), buildDiffStat.summary()))
+}
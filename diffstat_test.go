@@ -0,0 +1,121 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// gitRepoFixture creates a repository with two commits and returns its path
+// and the commit SHAs
+func gitRepoFixture(t *testing.T) (string, string, string) {
+	t.Helper()
+	dir, git, write := newGitRepo(t)
+
+	write("main.go", "package main\n\nfunc main() {}\n")
+	write("README.md", "# App\n")
+	first := gitCommit(git, "Initial commit")
+
+	write("main.go", "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n")
+	write("README.md", "# App\n\nUsage\n")
+	write("util.go", "package main\n")
+	second := gitCommit(git, "Print greeting")
+
+	return dir, first, second
+}
+
+func TestLoadDiffStat(t *testing.T) {
+	dir, first, second := gitRepoFixture(t)
+
+	tests := []struct {
+		name string
+		env  map[string]string
+	}{
+		{"Previous commit", map[string]string{"CI_PREV_COMMIT_SHA": first}},
+		{"Parent commit", map[string]string{}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.env["PLUGIN_SHOW_DIFFSTAT"] = "true"
+			tc.env["PLUGIN_DIFFSTAT_FILES"] = "2"
+			tc.env["CI_WORKSPACE"] = dir
+			tc.env["CI_COMMIT_SHA"] = second
+			setEnvFixture(t, tc.env)
+
+			stat := loadDiffStat()
+			if stat == nil {
+				t.Fatal("Expected a diff stat")
+			}
+			if summary := stat.summary(); summary != "3 files changed, +8 -1" {
+				t.Errorf("Unexpected summary %q", summary)
+			}
+			if len(stat.Top) != 2 || stat.Top[0].Path != "main.go" || stat.Top[1].Path != "README.md" {
+				t.Errorf("Unexpected top paths %+v", stat.Top)
+			}
+		})
+	}
+}
+
+func TestLoadDiffStatSkipped(t *testing.T) {
+	dir, _, second := gitRepoFixture(t)
+
+	tests := []struct {
+		name string
+		env  map[string]string
+	}{
+		{"Disabled", map[string]string{"CI_WORKSPACE": dir, "CI_COMMIT_SHA": second}},
+		{"Missing SHA", map[string]string{"PLUGIN_SHOW_DIFFSTAT": "true", "CI_WORKSPACE": dir}},
+		{"Unknown SHA", map[string]string{"PLUGIN_SHOW_DIFFSTAT": "true", "CI_WORKSPACE": dir, "CI_COMMIT_SHA": "0123456789abcdef0123456789abcdef01234567"}},
+		{"Not a repository", map[string]string{"PLUGIN_SHOW_DIFFSTAT": "true", "CI_WORKSPACE": t.TempDir(), "CI_COMMIT_SHA": second}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.env["PLUGIN_DEBUG"] = "true"
+			setEnvFixture(t, tc.env)
+
+			var stat *diffStat
+			captureStdout(t, func() { stat = loadDiffStat() })
+			if stat != nil {
+				t.Errorf("Expected no diff stat, got %+v", stat)
+			}
+		})
+	}
+}
+
+func TestParseNumstatCapsPaths(t *testing.T) {
+	setEnvFixture(t, map[string]string{})
+
+	stat := parseNumstat("-\t-\tlogo.png\n1\t0\t" + strings.Repeat("a/", 100) + "x.go\n")
+	if stat.Files != 2 || stat.Insertions != 1 || stat.Deletions != 0 {
+		t.Errorf("Unexpected totals %+v", stat)
+	}
+	if length := len([]rune(stat.Top[0].Path)); length != maxDiffStatPathLength+1 {
+		t.Errorf("Expected path capped at %d runes, got %d", maxDiffStatPathLength, length)
+	}
+}
+
+func TestDiffStatInMessages(t *testing.T) {
+	setEnvFixture(t, map[string]string{})
+	buildDiffStat = &diffStat{Files: 2, Insertions: 10, Deletions: 3, Top: []fileChange{{"cmd/app_main.go", 8, 1}, {"go.mod", 2, 2}}}
+	defer func() { buildDiffStat = nil }()
+
+	elements := createLarkCard(Config{}, "v1.0.0")["card"].(map[string]any)["elements"].([]map[string]any)
+	found := false
+	for _, element := range elements {
+		if text, ok := element["text"].(map[string]any); ok && strings.HasPrefix(text["content"].(string), "**Changes:**") {
+			found = true
+			if expected := "**Changes:** 2 files changed, +10 -3\n• cmd/app&#95;main.go (+8 -1)\n• go.mod (+2 -2)"; text["content"] != expected {
+				t.Errorf("Expected %q, got %q", expected, text["content"])
+			}
+		}
+	}
+	if !found {
+		t.Error("Expected a changes section in the card")
+	}
+
+	text := createLarkTextMessage(Config{}, "v1.0.0")["content"].(map[string]any)["text"].(string)
+	if !strings.Contains(text, "📝 Changes: 2 files changed, +10 -3\n") {
+		t.Errorf("Expected a changes line in text, got %q", text)
+	}
+}
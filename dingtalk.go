@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"maps"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dingTalkColors maps header colors to font colors of DingTalk markdown
+var dingTalkColors = map[string]string{
+	"green":     "#00B42A",
+	"red":       "#F53F3F",
+	"yellow":    "#FF7D00",
+	"blue":      "#165DFF",
+	"turquoise": "#14C9C9",
+	"grey":      "#86909C",
+}
+
+// dingTalkProvider sends markdown messages to DingTalk (钉钉) robots. With a
+// secret the request URL is signed.
+type dingTalkProvider struct {
+	secret string
+}
+
+func (dingTalkProvider) buildMessage(config Config, projectVersion string, prebuilt map[string]any) (map[string]any, error) {
+	if prebuilt != nil {
+		return maps.Clone(prebuilt), nil
+	}
+	return createDingTalkMessage(resolveBuildSummary(config, projectVersion)), nil
+}
+
+func (dingTalkProvider) payloadFor(target string, messageBytes []byte, now time.Time) []byte {
+	return messageBytes
+}
+
+func (p dingTalkProvider) deliver(ctx context.Context, target string, messageBytes []byte) error {
+	if secret := webhookSecret(target, p.secret); secret != "" {
+		signed, err := signDingTalkURL(target, strconv.FormatInt(timeNow().UnixMilli(), 10), secret)
+		if err != nil {
+			return fmt.Errorf("error sending to DingTalk: %w", err)
+		}
+		target = signed
+	}
+	return deliverErrcodeWebhook(ctx, "DingTalk", target, messageBytes)
+}
+
+// signDingTalkURL adds the timestamp (Unix milliseconds) and the signature
+// DingTalk checks for robots with signing enabled to the webhook URL
+func signDingTalkURL(webhookURL, timestamp, secret string) (string, error) {
+	u, err := url.Parse(webhookURL)
+	if err != nil {
+		return "", err
+	}
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(timestamp + "\n" + secret))
+
+	query := u.Query()
+	query.Set("timestamp", timestamp)
+	query.Set("sign", base64.StdEncoding.EncodeToString(h.Sum(nil)))
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// createDingTalkMessage renders the summary as a DingTalk markdown message.
+// DingTalk needs blank lines between lines of text.
+func createDingTalkMessage(summary buildSummary) map[string]any {
+	color, ok := dingTalkColors[summary.Style.Color]
+	if !ok {
+		color = dingTalkColors["grey"]
+	}
+
+	lines := []string{fmt.Sprintf(`### <font color="%s">%s</font>`, color, escapeMarkdown(summary.Title))}
+	for _, field := range summary.Fields {
+		lines = append(lines, fmt.Sprintf("**%s:** %s", field[0], escapeMarkdown(field[1])))
+	}
+	if summary.CommitMessage != "" {
+		lines = append(lines, fmt.Sprintf("> %s", strings.ReplaceAll(escapeMarkdown(summary.CommitMessage), "\n", "\n>\n> ")))
+	}
+	if summary.PipelineURL != "" {
+		lines = append(lines, fmt.Sprintf("[%s](%s)", tr(deploymentLabel("View Pipeline")), summary.PipelineURL))
+	}
+
+	return map[string]any{
+		"msgtype": "markdown",
+		"markdown": map[string]any{
+			"title": summary.Title,
+			"text":  strings.Join(lines, "\n\n"),
+		},
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignDingTalkURL(t *testing.T) {
+	signed, err := signDingTalkURL("https://oapi.dingtalk.com/robot/send?access_token=abc", "1700000000000", "SECxyz")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	u, _ := url.Parse(signed)
+	query := u.Query()
+	// base64(HMAC-SHA256(key=secret, "timestamp\nsecret"))
+	expected := "0PUR1j8g85Xg3vlFV/UrEcxXfF5HpCAGzcjrNfyJoyg="
+	if query.Get("access_token") != "abc" || query.Get("timestamp") != "1700000000000" || query.Get("sign") != expected {
+		t.Errorf("Expected the token, timestamp and sign %s, got %s", expected, signed)
+	}
+}
+
+func TestMain_DingTalk(t *testing.T) {
+	server, bodies, urls := setupProviderServer(t, `{"errcode":0,"errmsg":"ok"}`)
+	setEnvFixture(t, providerFixture)
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_PROVIDER":    providerDingTalk,
+		"PLUGIN_WEBHOOK_URL": server.URL + "/robot/send?access_token=abc",
+		"PLUGIN_SECRET":      "SECxyz",
+	})
+	originalTimeNow := timeNow
+	defer func() { timeNow = originalTimeNow }()
+	timeNow = func() time.Time { return time.UnixMilli(1700000000000) }
+	exitCode := 0
+	osExit = func(code int) { exitCode = code }
+
+	main()
+
+	if exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d", exitCode)
+	}
+	if len(*bodies) != 1 {
+		t.Fatalf("Expected one message, got %d", len(*bodies))
+	}
+
+	u, _ := url.Parse((*urls)[0])
+	if u.Query().Get("timestamp") != "1700000000000" || u.Query().Get("sign") != "0PUR1j8g85Xg3vlFV/UrEcxXfF5HpCAGzcjrNfyJoyg=" {
+		t.Errorf("Expected a signed URL, got %s", (*urls)[0])
+	}
+
+	body := (*bodies)[0]
+	if body["msgtype"] != "markdown" || len(body) != 2 {
+		t.Errorf("Expected only msgtype and markdown, got %v", body)
+	}
+	if _, ok := body["sign"]; ok {
+		t.Error("Expected the signature in the URL, not the body")
+	}
+	expected := strings.Join([]string{
+		`### <font color="#F53F3F">Pipeline Failed</font>`,
+		"**Project:** octo/backend",
+		"**Branch:** main",
+		"**Author:** octocat",
+		"**Version:** abcdef1",
+		"> Fix the build",
+		"[View Pipeline](https://ci.example.com/octo/backend/42)",
+	}, "\n\n")
+	markdown := body["markdown"].(map[string]any)
+	if markdown["title"] != "Pipeline Failed" || markdown["text"] != expected {
+		t.Errorf("Expected title Pipeline Failed and text:\n%s\ngot:\n%v", expected, markdown)
+	}
+}
+
+func TestMain_DingTalkError(t *testing.T) {
+	server, _, _ := setupProviderServer(t, `{"errcode":310000,"errmsg":"sign not match"}`)
+	setEnvFixture(t, providerFixture)
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_PROVIDER":    providerDingTalk,
+		"PLUGIN_WEBHOOK_URL": server.URL,
+	})
+	exitCode := 0
+	osExit = func(code int) { exitCode = code }
+
+	output := captureOutput(t, main)
+
+	if exitCode != exitRejected || !strings.Contains(output, "DingTalk API error 310000: sign not match") {
+		t.Errorf("Expected exit code 4 with the DingTalk error, got %d:\n%s", exitCode, output)
+	}
+}
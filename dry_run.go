@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+func isDryRun() bool {
+	return getEnvOrDefault("PLUGIN_DRY_RUN", "false") == "true"
+}
+
+// printDryRun pretty-prints the payloads that would be sent, as signed for
+// their targets, labeled with the hosts of the targets, whose tokens are left
+// out. Targets sent the same payload share one listing.
+func printDryRun(targetURLs []string, targetPublic []bool, sent [][]byte) {
+	logInfo("** DRY RUN: nothing is sent to Lark **")
+
+	printed := map[string]bool{}
+	for i, payload := range sent {
+		if printed[string(payload)] {
+			continue
+		}
+		printed[string(payload)] = true
+
+		var targets []string
+		for j, target := range targetURLs {
+			if bytes.Equal(sent[j], payload) && target != "" {
+				targets = append(targets, webhookHost(target))
+			}
+		}
+		label := "no target configured"
+		if len(targets) > 0 {
+			label = "for " + strings.Join(targets, ", ")
+		}
+		if targetPublic[i] {
+			label += ", public"
+		}
+
+		var pretty bytes.Buffer
+		if err := json.Indent(&pretty, payload, "", "  "); err != nil {
+			pretty.Reset()
+			pretty.Write(payload)
+		}
+		logInfo(fmt.Sprintf("Lark Message JSON (%s):", label), "payload", json.RawMessage(pretty.Bytes()))
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMain_DryRun(t *testing.T) {
+	requests := 0
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"code": 0}`))
+	}))
+	defer testServer.Close()
+
+	originalOsExit := osExit
+	defer func() { osExit = originalOsExit }()
+
+	tests := []struct {
+		name   string
+		env    map[string]string
+		output []string
+	}{
+		{
+			name: "With webhook",
+			env: map[string]string{
+				"PLUGIN_WEBHOOK_URL": testServer.URL + "/open-apis/bot/v2/hook/secret-token",
+				"PLUGIN_SECRET":      "lark-secret",
+			},
+			output: []string{
+				"** DRY RUN: nothing is sent to Lark **",
+				"Lark Message JSON (for 127.0.0.1:",
+				"\n  \"msg_type\": \"interactive\",",
+				"\"sign\": ",
+			},
+		},
+		{
+			name:   "Without webhook",
+			env:    map[string]string{},
+			output: []string{"** DRY RUN", "Lark Message JSON (no target configured):"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.env["PLUGIN_DRY_RUN"] = "true"
+			tt.env["CI_REPO"] = "octo/backend"
+			setEnvFixture(t, tt.env)
+			exitCode := 0
+			osExit = func(code int) { exitCode = code }
+
+			output := captureStdout(t, main)
+
+			if exitCode != 0 {
+				t.Errorf("Expected exit code 0, got %d", exitCode)
+			}
+			if requests != 0 {
+				t.Errorf("Expected no request in a dry run, got %d", requests)
+			}
+			for _, expected := range tt.output {
+				if !strings.Contains(output, expected) {
+					t.Errorf("Expected output to contain %q, got %q", expected, output)
+				}
+			}
+			if strings.Contains(output, "secret-token") || strings.Contains(output, "Sending to Lark") {
+				t.Errorf("Expected neither the webhook token nor a send, got %q", output)
+			}
+		})
+	}
+}
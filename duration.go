@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// parseUnixSetting reads a Unix timestamp, the first present key wins.
+// Missing, malformed and zero values are reported as not ok.
+func parseUnixSetting(keys ...string) (time.Time, bool) {
+	for _, key := range keys {
+		value := getEnvOrDefault(key, "")
+		if value == "" {
+			continue
+		}
+		seconds, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || seconds <= 0 {
+			return time.Time{}, false
+		}
+		return time.Unix(seconds, 0), true
+	}
+	return time.Time{}, false
+}
+
+// formatDuration renders a duration as "1h 2m 3s", "4m 32s" or "45s"
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	hours := int(d / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+	seconds := int(d % time.Minute / time.Second)
+
+	switch {
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm %ds", hours, minutes, seconds)
+	case minutes > 0:
+		return fmt.Sprintf("%dm %ds", minutes, seconds)
+	default:
+		return fmt.Sprintf("%ds", seconds)
+	}
+}
+
+// getBuildDuration returns the pipeline duration for display, or "" when it
+// is unknown or disabled. The notify step usually runs before the pipeline has
+// finished, so the current time stands in for a missing finish time and the
+// duration is marked approximate with "~".
+func getBuildDuration() string {
+	if getEnvOrDefault("PLUGIN_SHOW_DURATION", "true") == "false" {
+		return ""
+	}
+
+	started, ok := parseUnixSetting("CI_PIPELINE_STARTED", "DRONE_BUILD_STARTED")
+	if !ok {
+		return ""
+	}
+
+	prefix := ""
+	finished, ok := parseUnixSetting("CI_PIPELINE_FINISHED", "DRONE_BUILD_FINISHED")
+	if !ok {
+		finished = timeNow()
+		prefix = "~"
+	}
+
+	if finished.Before(started) {
+		return ""
+	}
+	return prefix + formatDuration(finished.Sub(started))
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		duration time.Duration
+		expected string
+	}{
+		{0, "0s"},
+		{45 * time.Second, "45s"},
+		{4*time.Minute + 32*time.Second, "4m 32s"},
+		{time.Hour + 2*time.Minute + 3*time.Second, "1h 2m 3s"},
+		{90*time.Second + 600*time.Millisecond, "1m 31s"},
+	}
+
+	for _, tc := range tests {
+		if formatted := formatDuration(tc.duration); formatted != tc.expected {
+			t.Errorf("Expected '%s' for %v, got '%s'", tc.expected, tc.duration, formatted)
+		}
+	}
+}
+
+func TestGetBuildDuration(t *testing.T) {
+	originalTimeNow := timeNow
+	defer func() { timeNow = originalTimeNow }()
+	timeNow = func() time.Time { return time.Unix(1700000600, 0) }
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected string
+	}{
+		{"Woodpecker", map[string]string{"CI_PIPELINE_STARTED": "1700000000", "CI_PIPELINE_FINISHED": "1700000272"}, "4m 32s"},
+		{"Drone", map[string]string{"DRONE_BUILD_STARTED": "1700000000", "DRONE_BUILD_FINISHED": "1700003723"}, "1h 2m 3s"},
+		{"Not finished yet is approximate", map[string]string{"CI_PIPELINE_STARTED": "1700000000"}, "~10m 0s"},
+		{"Zero finish time is approximate", map[string]string{"CI_PIPELINE_STARTED": "1700000000", "CI_PIPELINE_FINISHED": "0"}, "~10m 0s"},
+		{"Missing start", map[string]string{"CI_PIPELINE_FINISHED": "1700000272"}, ""},
+		{"Zero start", map[string]string{"CI_PIPELINE_STARTED": "0", "CI_PIPELINE_FINISHED": "1700000272"}, ""},
+		{"Malformed start", map[string]string{"CI_PIPELINE_STARTED": "yesterday", "CI_PIPELINE_FINISHED": "1700000272"}, ""},
+		{"Finished before start", map[string]string{"CI_PIPELINE_STARTED": "1700000272", "CI_PIPELINE_FINISHED": "1700000000"}, ""},
+		{"Disabled", map[string]string{"CI_PIPELINE_STARTED": "1700000000", "CI_PIPELINE_FINISHED": "1700000272", "PLUGIN_SHOW_DURATION": "false"}, ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setEnvFixture(t, tc.env)
+
+			if duration := getBuildDuration(); duration != tc.expected {
+				t.Errorf("Expected '%s', got '%s'", tc.expected, duration)
+			}
+		})
+	}
+}
+
+func TestDurationInMessages(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"CI_PIPELINE_STARTED":  "1700000000",
+		"CI_PIPELINE_FINISHED": "1700000272",
+	})
+
+	card := createLarkCard(Config{}, "v1.0.0")["card"].(map[string]any)
+	metadata := card["elements"].([]map[string]any)[0]["text"].(map[string]any)["content"].(string)
+	if !strings.Contains(metadata, "**Duration:** 4m 32s") {
+		t.Errorf("Expected duration in card, got '%s'", metadata)
+	}
+
+	text := createLarkTextMessage(Config{}, "v1.0.0")["content"].(map[string]any)["text"].(string)
+	if !strings.Contains(text, "⏱️ Duration: 4m 32s") {
+		t.Errorf("Expected duration in text message, got '%s'", text)
+	}
+}
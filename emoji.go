@@ -0,0 +1,42 @@
+package main
+
+// emojiEnabled reports whether icons are shown; PLUGIN_EMOJI=false removes them
+// for clients that cannot render emoji
+func emojiEnabled() bool {
+	return getEnvOrDefault("PLUGIN_EMOJI", "true") != "false"
+}
+
+// emoji returns icon, or "" when emoji are disabled. All icons in the output
+// go through it.
+func emoji(icon string) string {
+	if !emojiEnabled() {
+		return ""
+	}
+	return icon
+}
+
+// iconText prefixes text with icon, leaving out the separator when there is no icon
+func iconText(icon, text string) string {
+	if icon == "" {
+		return text
+	}
+	return icon + " " + text
+}
+
+// withIcon prefixes text with an emoji unless emoji are disabled
+func withIcon(icon, text string) string {
+	return iconText(emoji(icon), text)
+}
+
+// statusIcon returns the icon for a status. PLUGIN_ICON_SUCCESS and
+// PLUGIN_ICON_FAILURE override it whether or not emoji are enabled.
+func statusIcon(defaultIcon string, failed bool) string {
+	key := "PLUGIN_ICON_SUCCESS"
+	if failed {
+		key = "PLUGIN_ICON_FAILURE"
+	}
+	if icon := getEnvOrDefault(key, ""); icon != "" {
+		return icon
+	}
+	return emoji(defaultIcon)
+}
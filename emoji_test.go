@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEmojiDisabled(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_EMOJI":      "false",
+		"CI_REPO":           "octo/backend",
+		"CI_REPO_NAME":      "backend",
+		"CI_COMMIT_BRANCH":  "main",
+		"CI_COMMIT_AUTHOR":  "alice",
+		"CI_COMMIT_MESSAGE": "Fix login",
+		"CI_PIPELINE_EVENT": "push",
+		"CI_PIPELINE_URL":   "https://ci.example.com/1",
+	})
+
+	config := Config{Status: "failure"}
+	text := createLarkTextMessage(config, "v1.0.0")["content"].(map[string]any)["text"].(string)
+	expected := "PIPELINE FAILED · Push\n\n" +
+		"Project: octo/backend\n" +
+		"Branch: main\n" +
+		"Author: alice\n" +
+		"Version: v1.0.0\n" +
+		"Message: Fix login\n" +
+		"\nPipeline: https://ci.example.com/1"
+	if !strings.HasPrefix(text, expected) {
+		t.Errorf("Expected text without emoji:\n%q\ngot:\n%q", expected, text)
+	}
+
+	card := createLarkCard(config, "v1.0.0")["card"].(map[string]any)
+	if title := card["header"].(map[string]any)["title"].(map[string]any)["content"]; title != "backend - Pipeline Failed · Push" {
+		t.Errorf("Expected card title without emoji, got %q", title)
+	}
+}
+
+func TestCustomStatusIcons(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   string
+		env      map[string]string
+		expected string
+	}{
+		{"Success", "success", nil, "backend - [OK] Pipeline Succeeded"},
+		{"Failure", "failure", nil, "backend - [FAIL] Pipeline Failed"},
+		{"Fixed", "success", map[string]string{"CI_PREV_PIPELINE_STATUS": "failure"}, "backend - [OK] Pipeline Fixed"},
+		{"Emoji disabled", "failure", map[string]string{"PLUGIN_EMOJI": "false"}, "backend - [FAIL] Pipeline Failed"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			env := map[string]string{
+				"CI_REPO_NAME":        "backend",
+				"PLUGIN_ICON_SUCCESS": "[OK]",
+				"PLUGIN_ICON_FAILURE": "[FAIL]",
+			}
+			for key, value := range tc.env {
+				env[key] = value
+			}
+			setEnvFixture(t, env)
+
+			card := createLarkCard(Config{Status: tc.status}, "v1.0.0")["card"].(map[string]any)
+			if title := card["header"].(map[string]any)["title"].(map[string]any)["content"]; title != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, title)
+			}
+		})
+	}
+}
+
+func TestWithIcon(t *testing.T) {
+	setEnvFixture(t, map[string]string{})
+	if result := withIcon("🔗", "Pipeline"); result != "🔗 Pipeline" {
+		t.Errorf("Expected icon prefix, got %q", result)
+	}
+
+	t.Setenv("PLUGIN_EMOJI", "false")
+	if result := withIcon("🔗", "Pipeline"); result != "Pipeline" {
+		t.Errorf("Expected no icon, got %q", result)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// envFile holds the variables read from PLUGIN_ENV_FILE. getEnvOrDefault
+// consults it after the process environment, or before it with
+// PLUGIN_ENV_FILE_OVERRIDE; the process environment itself is not changed.
+var envFile map[string]string
+
+// envFileKey matches the variable names accepted in PLUGIN_ENV_FILE
+var envFileKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// isEnvFileOverride reports whether PLUGIN_ENV_FILE wins over the environment.
+// It is read from the environment only, as the file cannot hold settings.
+func isEnvFileOverride() bool {
+	return os.Getenv("PLUGIN_ENV_FILE_OVERRIDE") == "true"
+}
+
+// loadEnvFile reads PLUGIN_ENV_FILE, written by an earlier step. A missing
+// file and malformed lines only warn, the notification is sent without them.
+func loadEnvFile() {
+	envFile = nil
+	path := getEnvOrDefault("PLUGIN_ENV_FILE", "")
+	if path == "" {
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		logWarn(fmt.Sprintf("cannot read PLUGIN_ENV_FILE: %v", err))
+		return
+	}
+
+	values, problems := parseEnvFile(string(data))
+	for _, problem := range problems {
+		logWarn(fmt.Sprintf("PLUGIN_ENV_FILE %s, skipping it", problem), "path", path)
+	}
+	for key := range values {
+		if strings.HasPrefix(key, "PLUGIN_") {
+			logWarn(fmt.Sprintf("PLUGIN_ENV_FILE cannot set the setting %s, skipping it", key), "path", path)
+			delete(values, key)
+		}
+	}
+	envFile = values
+}
+
+// parseEnvFile parses dotenv lines of KEY=VALUE, optionally prefixed with
+// "export". Values may be single or double quoted; double quotes support \"
+// and \\ escapes. Unquoted values end at " #". Values spanning several lines
+// are not supported. Problems name the line number of each skipped line.
+func parseEnvFile(data string) (map[string]string, []string) {
+	values := map[string]string{}
+	var problems []string
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, err := parseEnvFileLine(line)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("line %d: %v", i+1, err))
+			continue
+		}
+		values[key] = value
+	}
+	return values, problems
+}
+
+func parseEnvFileLine(line string) (string, string, error) {
+	if rest, ok := strings.CutPrefix(line, "export"); ok && (rest == "" || rest[0] == ' ' || rest[0] == '\t') {
+		line = strings.TrimSpace(rest)
+	}
+	key, value, ok := strings.Cut(line, "=")
+	if !ok {
+		return "", "", fmt.Errorf("expected KEY=VALUE")
+	}
+	key = strings.TrimSpace(key)
+	if !envFileKey.MatchString(key) {
+		return "", "", fmt.Errorf("invalid variable name %q", key)
+	}
+	value = strings.TrimSpace(value)
+
+	if value == "" || (value[0] != '"' && value[0] != '\'') {
+		if comment := strings.Index(value, " #"); comment >= 0 {
+			value = strings.TrimSpace(value[:comment])
+		}
+		return key, value, nil
+	}
+
+	quote := value[0]
+	var unquoted strings.Builder
+	for i := 1; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == quote:
+			rest := strings.TrimSpace(value[i+1:])
+			if rest != "" && !strings.HasPrefix(rest, "#") {
+				return "", "", fmt.Errorf("unexpected %q after the quoted value of %s", rest, key)
+			}
+			return key, unquoted.String(), nil
+		case c == '\\' && quote == '"' && i+1 < len(value) && (value[i+1] == '"' || value[i+1] == '\\'):
+			i++
+			unquoted.WriteByte(value[i])
+		default:
+			unquoted.WriteByte(c)
+		}
+	}
+	return "", "", fmt.Errorf("unterminated quote in the value of %s, values spanning several lines are not supported", key)
+}
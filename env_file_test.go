@@ -0,0 +1,163 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseEnvFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected map[string]string
+		problems []string
+	}{
+		{
+			name: "Plain values, comments and export",
+			data: "# written by the build step\n\nIMAGE_DIGEST=sha256:abc\nexport REVISION=42\n  MIGRATIONS = 3  \nEMPTY=\nNOTE=ready # trailing comment\nexported=1\n",
+			expected: map[string]string{
+				"IMAGE_DIGEST": "sha256:abc",
+				"REVISION":     "42",
+				"MIGRATIONS":   "3",
+				"EMPTY":        "",
+				"NOTE":         "ready",
+				"exported":     "1",
+			},
+		},
+		{
+			name: "Quoted values",
+			data: "DSN=\"host=db port=5432\"\nQUERY='a=b&c=d'\nHASH=\"not # a comment\"\nESCAPED=\"say \\\"hi\\\" \\\\o/\"\nSINGLE='keeps \\\" as is'\nURL=https://example.com/?a=b\nCOMMENTED=\"x\" # note\n",
+			expected: map[string]string{
+				"DSN":       "host=db port=5432",
+				"QUERY":     "a=b&c=d",
+				"HASH":      "not # a comment",
+				"ESCAPED":   `say "hi" \o/`,
+				"SINGLE":    `keeps \" as is`,
+				"URL":       "https://example.com/?a=b",
+				"COMMENTED": "x",
+			},
+		},
+		{
+			name:     "Later values win",
+			data:     "REVISION=1\nREVISION=2\n",
+			expected: map[string]string{"REVISION": "2"},
+		},
+		{
+			name:     "Multi-line values are rejected",
+			data:     "BEFORE=1\nCHANGELOG=\"first line\nsecond line\"\nAFTER=2\n",
+			expected: map[string]string{"BEFORE": "1", "AFTER": "2"},
+			problems: []string{
+				"line 2: unterminated quote in the value of CHANGELOG, values spanning several lines are not supported",
+				`line 3: expected KEY=VALUE`,
+			},
+		},
+		{
+			name:     "Malformed lines",
+			data:     "GOOD=1\njust text\n1BAD=x\nBAD-NAME=x\n=x\nTRAILING=\"x\" y\r\n",
+			expected: map[string]string{"GOOD": "1"},
+			problems: []string{
+				"line 2: expected KEY=VALUE",
+				`line 3: invalid variable name "1BAD"`,
+				`line 4: invalid variable name "BAD-NAME"`,
+				`line 5: invalid variable name ""`,
+				`line 6: unexpected "y" after the quoted value of TRAILING`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, problems := parseEnvFile(tt.data)
+			if !reflect.DeepEqual(values, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, values)
+			}
+			if !reflect.DeepEqual(problems, tt.problems) {
+				t.Errorf("Expected problems %q, got %q", tt.problems, problems)
+			}
+		})
+	}
+}
+
+func writeEnvFile(t *testing.T, data string) string {
+	path := filepath.Join(t.TempDir(), "build.env")
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadEnvFile(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_ENV_FILE":  writeEnvFile(t, "IMAGE_DIGEST=sha256:abc\nCI_COMMIT_BRANCH=from-file\nPLUGIN_WEBHOOK_URL=https://evil.example.com\nbroken\n"),
+		"CI_COMMIT_BRANCH": "main",
+	})
+
+	output := captureOutput(t, loadEnvFile)
+	for _, expected := range []string{"line 4: expected KEY=VALUE, skipping it", "cannot set the setting PLUGIN_WEBHOOK_URL"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in the output, got %q", expected, output)
+		}
+	}
+
+	if value := getEnvOrDefault("IMAGE_DIGEST", ""); value != "sha256:abc" {
+		t.Errorf("Expected the file value, got %q", value)
+	}
+	if value := getEnvOrDefault("CI_COMMIT_BRANCH", ""); value != "main" {
+		t.Errorf("Expected the environment to win, got %q", value)
+	}
+	if value := getEnvOrDefault("PLUGIN_WEBHOOK_URL", ""); value != "" {
+		t.Errorf("Expected settings to be ignored, got %q", value)
+	}
+	if _, ok := os.LookupEnv("IMAGE_DIGEST"); ok {
+		t.Error("Expected the process environment to be unchanged")
+	}
+
+	setEnvFixture(t, map[string]string{"PLUGIN_ENV_FILE_OVERRIDE": "true"})
+	if value := getEnvOrDefault("CI_COMMIT_BRANCH", ""); value != "from-file" {
+		t.Errorf("Expected the file to win with PLUGIN_ENV_FILE_OVERRIDE, got %q", value)
+	}
+}
+
+func TestLoadEnvFile_Missing(t *testing.T) {
+	setEnvFixture(t, map[string]string{"PLUGIN_ENV_FILE": filepath.Join(t.TempDir(), "missing.env")})
+
+	output := captureOutput(t, loadEnvFile)
+	if !strings.Contains(output, "cannot read PLUGIN_ENV_FILE") || envFile != nil {
+		t.Errorf("Expected a warning and no values, got %q and %v", output, envFile)
+	}
+}
+
+func TestEnvFile_VariablesFieldsAndTemplates(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_ENV_FILE":      writeEnvFile(t, "IMAGE_DIGEST=sha256:abc\nREVISION=42\nDEPLOY_TOKEN=hidden\n"),
+		"PLUGIN_CUSTOM_FIELDS": `{"Revision": "r${REVISION}"}`,
+		"PLUGIN_TEXT_TEMPLATE": `{{.Env.REVISION}}|{{env "IMAGE_DIGEST"}}|{{.Env.DEPLOY_TOKEN}}`,
+	})
+	loadEnvFile()
+
+	config := Config{Variables: []string{"IMAGE_DIGEST"}}
+	card := createLarkCard(config, "v1.0.0")["card"].(map[string]any)
+	content := ""
+	for _, element := range card["elements"].([]map[string]any) {
+		if text, ok := element["text"].(map[string]any); ok {
+			content += text["content"].(string) + "\n"
+		}
+	}
+	for _, expected := range []string{"`IMAGE_DIGEST`: sha256:abc", "r42"} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("Expected %q in the card, got %q", expected, content)
+		}
+	}
+
+	if err := loadTextTemplate(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { textTemplate = nil })
+	text := createLarkTextMessage(config, "v1.0.0")["content"].(map[string]any)["text"]
+	if text != "42|sha256:abc|" {
+		t.Errorf("Expected the file values in the template, got %q", text)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// envMappingFields are the fields PLUGIN_ENV_MAPPING can map, with the
+// variables that hold them
+var envMappingFields = map[string]string{
+	"repo":           "CI_REPO",
+	"branch":         "CI_COMMIT_BRANCH",
+	"author":         "CI_COMMIT_AUTHOR",
+	"version":        "PLUGIN_VERSION",
+	"status":         "DRONE_BUILD_STATUS",
+	"commit_message": "CI_COMMIT_MESSAGE",
+	"pipeline_url":   "CI_PIPELINE_URL",
+	"commit_url":     "CI_PIPELINE_FORGE_URL",
+	"repo_url":       "CI_REPO_URL",
+	"event":          "CI_PIPELINE_EVENT",
+}
+
+// parseEnvMapping parses PLUGIN_ENV_MAPPING, a JSON object or a comma list of
+// field=VAR pairs, and rejects unknown fields
+func parseEnvMapping(value string) (map[string]string, error) {
+	value = strings.TrimSpace(value)
+	mapping := map[string]string{}
+	if value == "" {
+		return mapping, nil
+	}
+
+	if strings.HasPrefix(value, "{") {
+		if err := json.Unmarshal([]byte(value), &mapping); err != nil {
+			return nil, fmt.Errorf("PLUGIN_ENV_MAPPING is not a JSON object of strings: %v", err)
+		}
+	} else {
+		for _, pair := range strings.Split(value, ",") {
+			field, variable, found := strings.Cut(pair, "=")
+			if !found {
+				return nil, fmt.Errorf("PLUGIN_ENV_MAPPING entry %q is not field=VAR", strings.TrimSpace(pair))
+			}
+			mapping[strings.TrimSpace(field)] = strings.TrimSpace(variable)
+		}
+	}
+
+	var unknown []string
+	for field := range mapping {
+		if _, ok := envMappingFields[field]; !ok {
+			unknown = append(unknown, field)
+		}
+	}
+	if len(unknown) > 0 {
+		var known []string
+		for field := range envMappingFields {
+			known = append(known, field)
+		}
+		sort.Strings(unknown)
+		sort.Strings(known)
+		return nil, fmt.Errorf("PLUGIN_ENV_MAPPING has unknown fields %s, expected %s", strings.Join(unknown, ", "), strings.Join(known, ", "))
+	}
+	return mapping, nil
+}
+
+// applyEnvMapping copies the variables named by PLUGIN_ENV_MAPPING into
+// ciEnv, over the detected values. Unset variables are skipped; an invalid
+// mapping is reported by LoadConfig.
+func applyEnvMapping() {
+	mapping, err := parseEnvMapping(getEnvOrDefault("PLUGIN_ENV_MAPPING", ""))
+	if err != nil || len(mapping) == 0 {
+		return
+	}
+
+	if ciEnv == nil {
+		ciEnv = map[string]string{}
+	}
+	for field, variable := range mapping {
+		value := os.Getenv(variable)
+		if value == "" {
+			continue
+		}
+		ciEnv[envMappingFields[field]] = value
+		if field == "repo" {
+			ciEnv["CI_REPO_NAME"] = path.Base(value)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseEnvMapping(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected map[string]string
+		wantErr  string
+	}{
+		{"Empty", "", map[string]string{}, ""},
+		{"JSON", `{"repo":"JOB_NAME","pipeline_url":"BUILD_URL"}`, map[string]string{"repo": "JOB_NAME", "pipeline_url": "BUILD_URL"}, ""},
+		{"List", "repo=JOB_NAME, version = GIT_COMMIT", map[string]string{"repo": "JOB_NAME", "version": "GIT_COMMIT"}, ""},
+		{"Unknown field", "repo=JOB_NAME,job=JOB_ID", nil, "PLUGIN_ENV_MAPPING has unknown fields job, expected author, branch"},
+		{"Invalid JSON", `{"repo":1}`, nil, "PLUGIN_ENV_MAPPING is not a JSON object of strings"},
+		{"Missing variable", "repo", nil, `PLUGIN_ENV_MAPPING entry "repo" is not field=VAR`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapping, err := parseEnvMapping(tt.value)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(mapping, tt.expected) {
+				t.Errorf("Expected %v, got %v (%v)", tt.expected, mapping, err)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_RejectsUnknownMappingFields(t *testing.T) {
+	_, err := LoadConfig(mapGetenv(map[string]string{
+		"PLUGIN_WEBHOOK_URL": "https://example.com/a",
+		"PLUGIN_ENV_MAPPING": `{"project":"JOB_NAME"}`,
+	}))
+	if err == nil || !strings.Contains(err.Error(), "PLUGIN_ENV_MAPPING has unknown fields project") {
+		t.Errorf("Expected the unknown field to be reported, got %v", err)
+	}
+}
+
+func TestApplyEnvMapping(t *testing.T) {
+	t.Run("overrides auto-detection", func(t *testing.T) {
+		setEnvFixture(t, githubActionsFixture)
+		setEnvFixture(t, map[string]string{
+			"PLUGIN_ENV_MAPPING": `{"repo":"JOB_NAME","pipeline_url":"BUILD_URL","branch":"UNSET_BRANCH"}`,
+			"JOB_NAME":           "platform/deploy",
+			"BUILD_URL":          "https://jenkins.example.com/job/deploy/7/",
+			"UNSET_BRANCH":       "",
+		})
+		applyCIEnvironment()
+
+		expected := map[string]string{
+			"CI_REPO":          "platform/deploy",
+			"CI_REPO_NAME":     "deploy",
+			"CI_PIPELINE_URL":  "https://jenkins.example.com/job/deploy/7/",
+			"CI_COMMIT_BRANCH": "main",
+			"CI_COMMIT_AUTHOR": "octocat",
+		}
+		for key, value := range expected {
+			if actual := getEnvOrDefault(key, ""); actual != value {
+				t.Errorf("Expected %s='%s', got '%s'", key, value, actual)
+			}
+		}
+	})
+
+	t.Run("overrides defaults", func(t *testing.T) {
+		setEnvFixture(t, map[string]string{
+			"PLUGIN_ENV_MAPPING": "version=GIT_COMMIT,status=BUILD_RESULT,event=BUILD_CAUSE",
+			"GIT_COMMIT":         "1.4.2-rc1",
+			"BUILD_RESULT":       "failure",
+			"BUILD_CAUSE":        "manual",
+			"CI_COMMIT_TAG":      "",
+			"CI_COMMIT_SHA":      "abcdef1234",
+			"PLUGIN_STATUS":      "",
+		})
+		applyCIEnvironment()
+
+		if version := getProjectVersion(); version != "1.4.2-rc1" {
+			t.Errorf("Expected the mapped version, got '%s'", version)
+		}
+		if status := getBuildStatus(); status != "failure" {
+			t.Errorf("Expected the mapped status, got '%s'", status)
+		}
+		if event := getPipelineEvent(); event != "manual" {
+			t.Errorf("Expected the mapped event, got '%s'", event)
+		}
+	})
+}
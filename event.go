@@ -0,0 +1,100 @@
+package main
+
+import "fmt"
+
+// pipelineEvent describes how an event type is shown in the header
+type pipelineEvent struct {
+	Icon  string
+	Label string
+}
+
+// pipelineEvents lists the known CI_PIPELINE_EVENT values, others get the generic layout
+var pipelineEvents = map[string]pipelineEvent{
+	"push":         {"📤", "Push"},
+	"pull_request": {"🔀", "Pull Request"},
+	"tag":          {"🏷️", "Tag"},
+	"cron":         {"⏰", "Cron"},
+	"deployment":   {"🚀", "Deployment"},
+	"promote":      {"🚀", "Deployment"},
+	"manual":       {"👆", "Manual"},
+}
+
+func getPipelineEvent() string {
+	return getEnvOrDefault("CI_PIPELINE_EVENT", getEnvOrDefault("DRONE_BUILD_EVENT", ""))
+}
+
+// eventTitleSuffix returns the header suffix for the pipeline event. For
+// deployments it names the target environment when there is one.
+func eventTitleSuffix() string {
+	if target := deployTarget(); target != "" {
+		return " · " + withIcon("🚀", target)
+	}
+	if event, ok := pipelineEvents[getPipelineEvent()]; ok {
+		return " · " + withIcon(event.Icon, event.Label)
+	}
+	return ""
+}
+
+// pullRequestRef returns the pull request number and, when the forge is
+// known, its web URL
+func pullRequestRef() (string, string) {
+	number := getEnvOrDefault("CI_COMMIT_PULL_REQUEST", getEnvOrDefault("DRONE_PULL_REQUEST", ""))
+	if number == "" {
+		return "", ""
+	}
+	if repoURL := getEnvOrDefault("CI_REPO_URL", ""); repoURL != "" {
+		return number, forgePullRequestURL(repoURL, number)
+	}
+	return number, ""
+}
+
+// eventFields returns the label/value pairs specific to the pipeline event.
+// With markdown set values are formatted for lark_md.
+func eventFields(markdown bool) [][2]string {
+	escape := escapeText
+	if markdown {
+		escape = escapeMarkdown
+	}
+
+	switch getPipelineEvent() {
+	case "pull_request":
+		number, prURL := pullRequestRef()
+		if number == "" {
+			return nil
+		}
+		ref := "#" + number
+		if prURL != "" {
+			if markdown {
+				ref = fmt.Sprintf("[#%s](%s)", number, prURL)
+			} else {
+				ref += " " + prURL
+			}
+		}
+		if title := getEnvOrDefault("CI_COMMIT_PULL_REQUEST_TITLE", getEnvOrDefault("DRONE_PULL_REQUEST_TITLE", "")); title != "" {
+			ref += " " + escape(title)
+		}
+		fields := [][2]string{{"Pull Request", ref}}
+
+		source := getEnvOrDefault("CI_COMMIT_SOURCE_BRANCH", getEnvOrDefault("DRONE_SOURCE_BRANCH", ""))
+		target := getEnvOrDefault("CI_COMMIT_TARGET_BRANCH", getEnvOrDefault("DRONE_TARGET_BRANCH", ""))
+		if source != "" && target != "" {
+			fields = append(fields, [2]string{"Merge", fmt.Sprintf("%s → %s", escape(source), escape(target))})
+		}
+		return fields
+	case "tag":
+		tag := getEnvOrDefault("CI_COMMIT_TAG", "")
+		if tag == "" {
+			return nil
+		}
+		tag = escape(tag)
+		if markdown {
+			tag = fmt.Sprintf("<font color='blue'>**%s**</font>", tag)
+		}
+		return [][2]string{{"Tag", tag}}
+	case "deployment", "promote":
+		if target := deployTarget(); target != "" {
+			return [][2]string{{tr("Environment"), escape(target)}}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEventAwareMessages(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		title      string
+		cardBody   string
+		textHeader string
+		textBody   string
+	}{
+		{
+			name:       "Push",
+			env:        map[string]string{"CI_PIPELINE_EVENT": "push"},
+			title:      "backend - ✅ Pipeline Succeeded · 📤 Push",
+			cardBody:   "**Branch:** main",
+			textHeader: "✅ PIPELINE SUCCEEDED · 📤 Push",
+			textBody:   "🌿 Branch: main",
+		},
+		{
+			name: "Pull request",
+			env: map[string]string{
+				"CI_PIPELINE_EVENT":       "pull_request",
+				"CI_COMMIT_PULL_REQUEST":  "12",
+				"CI_COMMIT_SOURCE_BRANCH": "feature/login",
+				"CI_COMMIT_TARGET_BRANCH": "main",
+			},
+			title:      "backend - ✅ Pipeline Succeeded · 🔀 Pull Request",
+			cardBody:   "**Pull Request:** [#12](https://github.com/octo/backend/pull/12)\n**Merge:** feature/login → main",
+			textHeader: "✅ PIPELINE SUCCEEDED · 🔀 Pull Request",
+			textBody:   "🔀 Pull Request: #12 https://github.com/octo/backend/pull/12\n🔀 Merge: feature/login → main",
+		},
+		{
+			name:       "Tag",
+			env:        map[string]string{"CI_PIPELINE_EVENT": "tag", "CI_COMMIT_TAG": "v1.2.3"},
+			title:      "backend - ✅ Pipeline Succeeded · 🏷️ Tag",
+			cardBody:   "**Tag:** <font color='blue'>**v1.2.3**</font>",
+			textHeader: "✅ PIPELINE SUCCEEDED · 🏷️ Tag",
+			textBody:   "🏷️ Tag: v1.2.3",
+		},
+		{
+			name:       "Cron",
+			env:        map[string]string{"CI_PIPELINE_EVENT": "cron", "CI_PIPELINE_CRON": "nightly"},
+			title:      "backend - ✅ Scheduled Pipeline Succeeded · ⏰ Cron",
+			cardBody:   "**Cron Job:** nightly",
+			textHeader: "✅ SCHEDULED PIPELINE SUCCEEDED · ⏰ Cron",
+			textBody:   "⏰ Cron Job: nightly",
+		},
+		{
+			name:       "Deployment",
+			env:        map[string]string{"CI_PIPELINE_EVENT": "deployment", "CI_PIPELINE_DEPLOY_TARGET": "production"},
+			title:      "backend → production ✅ Deploy Succeeded",
+			cardBody:   "**Environment:** production",
+			textHeader: "✅ DEPLOY SUCCEEDED · 🚀 production",
+			textBody:   "🚀 Environment: production",
+		},
+		{
+			name:       "Manual",
+			env:        map[string]string{"CI_PIPELINE_EVENT": "manual", "CI_PIPELINE_CREATOR": "alice"},
+			title:      "backend - ✅ Pipeline Succeeded · 👆 Manual",
+			cardBody:   "**Triggered by:** alice",
+			textHeader: "✅ PIPELINE SUCCEEDED · 👆 Manual",
+			textBody:   "👤 Triggered by: alice",
+		},
+		{
+			name:       "Unknown event uses the generic layout",
+			env:        map[string]string{"CI_PIPELINE_EVENT": "pull_request_closed"},
+			title:      "backend - ✅ Pipeline Succeeded",
+			cardBody:   "**Branch:** main",
+			textHeader: "✅ PIPELINE SUCCEEDED\n",
+			textBody:   "🌿 Branch: main",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setEnvFixture(t, map[string]string{
+				"CI_REPO_NAME":     "backend",
+				"CI_REPO_URL":      "https://github.com/octo/backend",
+				"CI_COMMIT_BRANCH": "main",
+				"CI_COMMIT_AUTHOR": "octocat",
+			})
+			setEnvFixture(t, tc.env)
+
+			card := createLarkCard(Config{Status: "success"}, "v1.0.0")["card"].(map[string]any)
+			title := card["header"].(map[string]any)["title"].(map[string]any)["content"].(string)
+			if title != tc.title {
+				t.Errorf("Expected title '%s', got '%s'", tc.title, title)
+			}
+			metadata := card["elements"].([]map[string]any)[0]["text"].(map[string]any)["content"].(string)
+			if !strings.Contains(metadata, tc.cardBody) {
+				t.Errorf("Expected card body to contain '%s', got '%s'", tc.cardBody, metadata)
+			}
+
+			text := createLarkTextMessage(Config{Status: "success"}, "v1.0.0")["content"].(map[string]any)["text"].(string)
+			if !strings.HasPrefix(text, tc.textHeader) {
+				t.Errorf("Expected text to start with '%s', got '%s'", tc.textHeader, text)
+			}
+			if !strings.Contains(text, tc.textBody) {
+				t.Errorf("Expected text to contain '%s', got '%s'", tc.textBody, text)
+			}
+		})
+	}
+}
+
+func TestPullRequestButton(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected string
+	}{
+		{"GitHub", map[string]string{"CI_REPO_URL": "https://github.com/octo/backend"}, "https://github.com/octo/backend/pull/12"},
+		{"Gitea by forge type", map[string]string{"CI_REPO_URL": "https://git.example.com/octo/backend", "CI_FORGE_TYPE": "gitea"}, "https://git.example.com/octo/backend/pulls/12"},
+		{"GitLab by host", map[string]string{"CI_REPO_URL": "https://gitlab.example.com/octo/backend"}, "https://gitlab.example.com/octo/backend/-/merge_requests/12"},
+		{"Configured format", map[string]string{"CI_REPO_URL": "https://code.example.com/octo/backend", "PLUGIN_PR_URL_FORMAT": "{repo}/reviews/{number}"}, "https://code.example.com/octo/backend/reviews/12"},
+		{"Not a pull request", map[string]string{"CI_REPO_URL": "https://github.com/octo/backend", "CI_PIPELINE_EVENT": "push"}, ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setEnvFixture(t, map[string]string{
+				"CI_PIPELINE_EVENT":            "pull_request",
+				"CI_COMMIT_PULL_REQUEST":       "12",
+				"CI_COMMIT_PULL_REQUEST_TITLE": "Add login page",
+			})
+			setEnvFixture(t, tc.env)
+
+			var prURL string
+			for _, action := range createActionButtons(Config{}) {
+				if action["text"].(map[string]any)["content"] == "View Pull Request" {
+					prURL = action["url"].(string)
+				}
+			}
+			if prURL != tc.expected {
+				t.Errorf("Expected pull request URL '%s', got '%s'", tc.expected, prURL)
+			}
+		})
+	}
+}
+
+func TestPullRequestDetails(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"CI_PIPELINE_EVENT":            "pull_request",
+		"CI_COMMIT_PULL_REQUEST":       "12",
+		"CI_COMMIT_PULL_REQUEST_TITLE": "Add login page",
+		"CI_REPO_URL":                  "https://github.com/octo/backend",
+	})
+
+	fields := eventFields(true)
+	if len(fields) != 1 || fields[0][1] != "[#12](https://github.com/octo/backend/pull/12) Add login page" {
+		t.Errorf("Unexpected pull request fields %v", fields)
+	}
+
+	actions := createActionButtons(Config{Buttons: []string{"pr"}})
+	if len(actions) != 1 || actions[0]["url"] != "https://github.com/octo/backend/pull/12" {
+		t.Errorf("Expected only the pull request button, got %v", actions)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import "errors"
+
+// Exit codes of the plugin, so that pipelines can tell a misconfiguration
+// from an outage and retry only the latter. Skipped notifications exit 0.
+const (
+	exitConfiguration = 2
+	exitNetwork       = 3
+	exitRejected      = 4
+	exitPartial       = 5
+)
+
+// The categories of errors, matched with errors.Is
+var (
+	errConfiguration   = errors.New("configuration error")
+	errNetwork         = errors.New("network error")
+	errRejected        = errors.New("rejected by the chat service")
+	errPartialDelivery = errors.New("partial delivery")
+)
+
+// classifiedError adds a category to an error without changing its message
+type classifiedError struct {
+	err      error
+	category error
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() error {
+	return e.err
+}
+
+func (e *classifiedError) Is(target error) bool {
+	return target == e.category
+}
+
+func classify(err, category error) error {
+	if err == nil {
+		return nil
+	}
+	return &classifiedError{err: err, category: category}
+}
+
+// deliveryCategory tells whether a failed delivery was rejected by the chat
+// service, with an HTTP 4xx status or a non-zero code, or failed in transport
+// or with a server error
+func deliveryCategory(err error) error {
+	var responseErr *webhookResponseError
+	var apiErr *larkAPIError
+	var providerErr *providerResponseError
+	switch {
+	case errors.As(err, &responseErr):
+		return responseCategory(responseErr.StatusCode, responseErr.Code)
+	case errors.As(err, &apiErr):
+		return errRejected
+	case errors.As(err, &providerErr):
+		return responseCategory(providerErr.StatusCode, providerErr.Code)
+	}
+	return errNetwork
+}
+
+func responseCategory(statusCode, code int) error {
+	if code != 0 || (statusCode >= 400 && statusCode < 500) {
+		return errRejected
+	}
+	return errNetwork
+}
+
+// deliveryFailureCategory is the category of a run in which errs are the
+// failures of total targets: partial when any target got the message, else
+// rejected when any target was rejected, since a retry would not help
+func deliveryFailureCategory(errs []error, total int) error {
+	if len(errs) < total {
+		return errPartialDelivery
+	}
+	for _, err := range errs {
+		if deliveryCategory(err) == errRejected {
+			return errRejected
+		}
+	}
+	return errNetwork
+}
+
+// exitCode returns the exit code for an error returned by run, and its
+// category for the log
+func exitCode(err error) (int, error) {
+	switch {
+	case errors.Is(err, errPartialDelivery):
+		return exitPartial, errPartialDelivery
+	case errors.Is(err, errRejected):
+		return exitRejected, errRejected
+	case errors.Is(err, errNetwork):
+		return exitNetwork, errNetwork
+	default:
+		return exitConfiguration, errConfiguration
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRun_ErrorCategories(t *testing.T) {
+	respond := func(status int, body string) string {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+			w.Write([]byte(body))
+		}))
+		t.Cleanup(server.Close)
+		return server.URL
+	}
+	ok := respond(http.StatusOK, `{"code":0}`)
+	rejected := respond(http.StatusOK, `{"code":9499,"msg":"Bad Request"}`)
+	badRequest := respond(http.StatusBadRequest, `bad request`)
+	unavailable := respond(http.StatusServiceUnavailable, `{"code":0}`)
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		category error
+		exitCode int
+	}{
+		{
+			name:     "Missing webhook",
+			env:      map[string]string{},
+			category: errConfiguration,
+			exitCode: exitConfiguration,
+		},
+		{
+			name:     "Invalid setting",
+			env:      map[string]string{"PLUGIN_WEBHOOK_URL": ok, "PLUGIN_DETAIL": "short"},
+			category: errConfiguration,
+			exitCode: exitConfiguration,
+		},
+		{
+			name:     "Connection refused",
+			env:      map[string]string{"PLUGIN_WEBHOOK_URL": down.URL},
+			category: errNetwork,
+			exitCode: exitNetwork,
+		},
+		{
+			name:     "Server error",
+			env:      map[string]string{"PLUGIN_WEBHOOK_URL": unavailable},
+			category: errNetwork,
+			exitCode: exitNetwork,
+		},
+		{
+			name:     "Lark code",
+			env:      map[string]string{"PLUGIN_WEBHOOK_URL": rejected},
+			category: errRejected,
+			exitCode: exitRejected,
+		},
+		{
+			name:     "HTTP 4xx",
+			env:      map[string]string{"PLUGIN_WEBHOOK_URL": badRequest},
+			category: errRejected,
+			exitCode: exitRejected,
+		},
+		{
+			name:     "Rejected and down",
+			env:      map[string]string{"PLUGIN_WEBHOOK_URL": rejected + "," + down.URL},
+			category: errRejected,
+			exitCode: exitRejected,
+		},
+		{
+			name:     "Partial failure",
+			env:      map[string]string{"PLUGIN_WEBHOOK_URL": ok + "," + down.URL},
+			category: errPartialDelivery,
+			exitCode: exitPartial,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnvFixture(t, tt.env)
+
+			var err error
+			captureOutput(t, func() { err = run() })
+			if !errors.Is(err, tt.category) {
+				t.Fatalf("Expected a %v, got %v", tt.category, err)
+			}
+			for _, other := range []error{errConfiguration, errNetwork, errRejected, errPartialDelivery} {
+				if other != tt.category && errors.Is(err, other) {
+					t.Errorf("Expected only %v, also got %v", tt.category, other)
+				}
+			}
+			if code, category := exitCode(err); code != tt.exitCode || category != tt.category {
+				t.Errorf("Expected exit code %d (%v), got %d (%v)", tt.exitCode, tt.category, code, category)
+			}
+		})
+	}
+}
+
+func TestMain_ExitCodeLogLine(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	setEnvFixture(t, map[string]string{"PLUGIN_WEBHOOK_URL": down.URL})
+
+	originalOsExit := osExit
+	defer func() { osExit = originalOsExit }()
+	exitCode := 0
+	osExit = func(code int) { exitCode = code }
+
+	output := captureOutput(t, main)
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if last := lines[len(lines)-1]; last != "Error: exiting with code 3 (network error)" || exitCode != exitNetwork {
+		t.Errorf("Expected exit code 3 and its category last, got %d and %q", exitCode, last)
+	}
+
+	setEnvFixture(t, map[string]string{"PLUGIN_FAIL_ON_ERROR": "false"})
+	exitCode = 0
+	output = captureOutput(t, main)
+	if exitCode != 0 || !strings.Contains(output, "the notification failed (network error), but PLUGIN_FAIL_ON_ERROR is false") {
+		t.Errorf("Expected exit code 0 with the category, got %d:\n%s", exitCode, output)
+	}
+}
+
+func TestPrintError_Classified(t *testing.T) {
+	err := classify(errors.Join(errors.New("first"), errors.New("second")), errConfiguration)
+	output := captureOutput(t, func() { printError(err) })
+	if output != "Error: first\nError: second\n" {
+		t.Errorf("Expected each error on its own line, got %q", output)
+	}
+}
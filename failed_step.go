@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// failedStepURL returns the link to the logs of the failed step, or "" when
+// status is not a failure or no source yields a URL. The sources, in order:
+// PLUGIN_FAILED_STEP_URL, PLUGIN_FAILED_STEP (a step name or number) below
+// CI_PIPELINE_URL, and the first failed step reported by the CI API.
+func failedStepURL(status string) string {
+	if !isFailedStatus(status) {
+		return ""
+	}
+	if stepURL := getEnvOrDefault("PLUGIN_FAILED_STEP_URL", ""); stepURL != "" {
+		return stepURL
+	}
+
+	pipelineURL := strings.TrimSuffix(getEnvOrDefault("CI_PIPELINE_URL", ""), "/")
+	if pipelineURL == "" {
+		return ""
+	}
+	if step := getEnvOrDefault("PLUGIN_FAILED_STEP", ""); step != "" {
+		return pipelineURL + "/" + url.PathEscape(step)
+	}
+	for _, step := range buildSteps {
+		if isFailedStatus(step.State) && step.PID > 0 {
+			return fmt.Sprintf("%s/%d", pipelineURL, step.PID)
+		}
+	}
+	return ""
+}
+
+// createFailedStepButton returns the "View Failed Step" button, or nil
+func createFailedStepButton(status string) map[string]any {
+	stepURL := failedStepURL(status)
+	if stepURL == "" {
+		return nil
+	}
+	return map[string]any{
+		"tag": "button",
+		"text": map[string]any{
+			"content": "View Failed Step",
+			"tag":     "plain_text",
+		},
+		"type": "danger",
+		"url":  stepURL,
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFailedStepURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, woodpeckerPipelineFixture)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		status   string
+		expected string
+	}{
+		{
+			name:     "Explicit URL",
+			env:      map[string]string{"PLUGIN_FAILED_STEP_URL": "https://ci.example.com/logs/test", "PLUGIN_FAILED_STEP": "build"},
+			status:   "failure",
+			expected: "https://ci.example.com/logs/test",
+		},
+		{
+			name:     "Step name below the pipeline",
+			env:      map[string]string{"PLUGIN_FAILED_STEP": "unit tests"},
+			status:   "failure",
+			expected: "https://ci.example.com/repos/7/pipeline/42/unit%20tests",
+		},
+		{
+			name:     "Step number below the pipeline",
+			env:      map[string]string{"PLUGIN_FAILED_STEP": "3"},
+			status:   "failure",
+			expected: "https://ci.example.com/repos/7/pipeline/42/3",
+		},
+		{
+			name:     "First failed step from the CI API",
+			env:      map[string]string{"PLUGIN_CI_TOKEN": "ci-token", "CI_SYSTEM_URL": server.URL, "CI_REPO_ID": "7"},
+			status:   "failure",
+			expected: "https://ci.example.com/repos/7/pipeline/42/3",
+		},
+		{
+			name:     "Successful build",
+			env:      map[string]string{"PLUGIN_FAILED_STEP_URL": "https://ci.example.com/logs/test"},
+			status:   "success",
+			expected: "",
+		},
+		{
+			name:     "No source",
+			env:      map[string]string{},
+			status:   "failure",
+			expected: "",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setEnvFixture(t, map[string]string{
+				"CI_PIPELINE_URL":    "https://ci.example.com/repos/7/pipeline/42",
+				"CI_PIPELINE_NUMBER": "42",
+			})
+			setEnvFixture(t, tc.env)
+			buildSteps = loadPipelineSteps()
+			defer func() { buildSteps = nil }()
+
+			if stepURL := failedStepURL(tc.status); stepURL != tc.expected {
+				t.Errorf("Expected '%s', got '%s'", tc.expected, stepURL)
+			}
+		})
+	}
+}
+
+func TestFailedStepButton(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_FAILED_STEP": "test",
+		"CI_PIPELINE_URL":    "https://ci.example.com/repos/7/pipeline/42",
+	})
+
+	buttons := createActionButtons(Config{Status: "failure"})
+	if len(buttons) != 2 || buttons[1]["text"].(map[string]any)["content"] != "View Failed Step" || buttons[1]["url"] != "https://ci.example.com/repos/7/pipeline/42/test" {
+		t.Fatalf("Expected the failed step button after the pipeline button, got %v", buttons)
+	}
+
+	if buttons := createActionButtons(Config{Status: "failure", Buttons: []string{"failed-step"}}); len(buttons) != 1 || buttons[0]["type"] != "danger" {
+		t.Errorf("Expected only the failed step button, got %v", buttons)
+	}
+
+	if buttons := createActionButtons(Config{Status: "failure", Buttons: []string{"pipeline"}}); len(buttons) != 1 || buttons[0]["text"].(map[string]any)["content"] != "View Pipeline" {
+		t.Errorf("Expected only the pipeline button, got %v", buttons)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// osHostname is overridable in tests
+var osHostname = os.Hostname
+
+// getRunnerHostname returns the agent that ran the pipeline, falling back to
+// the local hostname. Public targets get neither.
+func getRunnerHostname() string {
+	if machine := getEnvOrDefault("CI_MACHINE", getEnvOrDefault("DRONE_MACHINE", "")); machine != "" {
+		return machine
+	}
+	if publicMode {
+		return ""
+	}
+	hostname, err := osHostname()
+	if err != nil {
+		return ""
+	}
+	return hostname
+}
+
+// footerLine joins the notification time, pipeline number, runner and, with
+// PLUGIN_SHOW_PLUGIN_VERSION, the plugin version, skipping empty parts. It returns "" when PLUGIN_SHOW_FOOTER is false.
+func footerLine() string {
+	if getEnvOrDefault("PLUGIN_SHOW_FOOTER", "true") == "false" {
+		return ""
+	}
+
+	parts := []string{withIcon("🕒", formatTimestamp(timeNow()))}
+	if number := getPipelineNumber(); number != "" {
+		parts = append(parts, "#"+number)
+	}
+	if hostname := getRunnerHostname(); hostname != "" {
+		parts = append(parts, withIcon("🖥️", hostname))
+	}
+	if getEnvOrDefault("PLUGIN_SHOW_PLUGIN_VERSION", "false") == "true" {
+		v, c, _ := pluginVersion()
+		parts = append(parts, fmt.Sprintf("ci-lark-notification %s (%s)", v, c))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// footerElement renders the footer as a card note element, ending with the
+// notification ID
+func footerElement() map[string]any {
+	footer := footerLine()
+	if footer == "" {
+		return nil
+	}
+	footer += " · ID " + notificationID()
+	return map[string]any{
+		"tag": "note",
+		"elements": []map[string]any{
+			{
+				"content": footer,
+				"tag":     "plain_text",
+			},
+		},
+	}
+}
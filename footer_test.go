@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func mockFooterClock(t *testing.T) {
+	originalTimeNow := timeNow
+	timeNow = func() time.Time { return time.Date(2026, 3, 1, 9, 30, 0, 0, time.FixedZone("CET", 3600)) }
+	t.Cleanup(func() { timeNow = originalTimeNow })
+}
+
+func mockHostname(t *testing.T, hostname string, err error) {
+	originalHostname := osHostname
+	osHostname = func() (string, error) { return hostname, err }
+	t.Cleanup(func() { osHostname = originalHostname })
+}
+
+func TestFooterLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		hostname string
+		err      error
+		expected string
+	}{
+		{
+			name:     "All components",
+			env:      map[string]string{"CI_PIPELINE_NUMBER": "42", "CI_MACHINE": "runner-1"},
+			expected: "🕒 2026-03-01T08:30:00Z · #42 · 🖥️ runner-1",
+		},
+		{
+			name:     "Hostname fallback",
+			env:      map[string]string{"CI_PIPELINE_NUMBER": "42"},
+			hostname: "build-host",
+			expected: "🕒 2026-03-01T08:30:00Z · #42 · 🖥️ build-host",
+		},
+		{
+			name:     "Empty components are skipped",
+			env:      map[string]string{},
+			err:      errors.New("no hostname"),
+			expected: "🕒 2026-03-01T08:30:00Z",
+		},
+		{
+			name:     "Disabled",
+			env:      map[string]string{"PLUGIN_SHOW_FOOTER": "false", "CI_PIPELINE_NUMBER": "42"},
+			expected: "",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setEnvFixture(t, tc.env)
+			mockFooterClock(t)
+			mockHostname(t, tc.hostname, tc.err)
+
+			if footer := footerLine(); footer != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, footer)
+			}
+		})
+	}
+}
+
+func TestFooterInCardAndText(t *testing.T) {
+	setEnvFixture(t, map[string]string{"CI_PIPELINE_NUMBER": "42", "CI_MACHINE": "runner-1", "PLUGIN_NOTIFICATION_ID": "deploy-42"})
+	mockFooterClock(t)
+
+	elements := createLarkCard(Config{}, "v1.0.0")["card"].(map[string]any)["elements"].([]map[string]any)
+	expected := map[string]any{
+		"tag": "note",
+		"elements": []map[string]any{
+			{"content": "🕒 2026-03-01T08:30:00Z · #42 · 🖥️ runner-1 · ID deploy-42", "tag": "plain_text"},
+		},
+	}
+	if last := elements[len(elements)-1]; !reflect.DeepEqual(last, expected) {
+		t.Errorf("Expected note element %v, got %v", expected, last)
+	}
+
+	text := createLarkTextMessage(Config{}, "v1.0.0")["content"].(map[string]any)["text"].(string)
+	if !strings.HasSuffix(text, "\n\n🕒 2026-03-01T08:30:00Z · #42 · 🖥️ runner-1") {
+		t.Errorf("Expected text message to end with the footer, got %q", text)
+	}
+}
+
+func TestFooterPublicModeHidesRunner(t *testing.T) {
+	setEnvFixture(t, map[string]string{"CI_MACHINE": "runner-1"})
+	mockHostname(t, "build-host", nil)
+	setPublicMode(true, nil)
+	defer setPublicMode(false, nil)
+
+	if hostname := getRunnerHostname(); hostname != "" {
+		t.Errorf("Expected no runner for public targets, got %q", hostname)
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+)
+
+// signGatewayRequest adds the egress gateway signature headers to req when
+// PLUGIN_GATEWAY_HMAC_KEY is set. The signature is the hex HMAC-SHA256 of the
+// exact request body.
+func signGatewayRequest(req *http.Request, body []byte) {
+	key := getEnvOrDefault("PLUGIN_GATEWAY_HMAC_KEY", "")
+	if key == "" {
+		return
+	}
+
+	h := hmac.New(sha256.New, []byte(key))
+	h.Write(body)
+
+	req.Header.Set(getEnvOrDefault("PLUGIN_GATEWAY_SIG_HEADER", "X-Gateway-Signature"), hex.EncodeToString(h.Sum(nil)))
+	req.Header.Set(getEnvOrDefault("PLUGIN_GATEWAY_TS_HEADER", "X-Gateway-Timestamp"), strconv.FormatInt(timeNow().Unix(), 10))
+}
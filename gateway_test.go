@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSendMessage_GatewaySignature(t *testing.T) {
+	key := "gateway-key"
+	verified := 0
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		// Recompute the signature the way the gateway does
+		h := hmac.New(sha256.New, []byte(key))
+		h.Write(body)
+		expected := hex.EncodeToString(h.Sum(nil))
+
+		if signature := r.Header.Get("X-Egress-Sig"); signature != expected {
+			t.Errorf("Expected signature %s, got %s", expected, signature)
+		} else {
+			verified++
+		}
+		if r.Header.Get("X-Egress-Ts") == "" {
+			t.Error("Expected a timestamp header")
+		}
+		w.Write([]byte(`{"code": 0}`))
+	}))
+	defer testServer.Close()
+
+	os.Setenv("PLUGIN_GATEWAY_HMAC_KEY", key)
+	os.Setenv("PLUGIN_GATEWAY_SIG_HEADER", "X-Egress-Sig")
+	os.Setenv("PLUGIN_GATEWAY_TS_HEADER", "X-Egress-Ts")
+	defer func() {
+		os.Unsetenv("PLUGIN_GATEWAY_HMAC_KEY")
+		os.Unsetenv("PLUGIN_GATEWAY_SIG_HEADER")
+		os.Unsetenv("PLUGIN_GATEWAY_TS_HEADER")
+	}()
+
+	// Each send is signed over its own body
+	if err := deliverMessage(context.Background(), testServer.URL+"/a", []byte(`{"msg_type":"text","content":{"text":"first"}}`), ""); err != nil {
+		t.Errorf("Expected delivery to succeed, got %v", err)
+	}
+	if err := deliverMessage(context.Background(), testServer.URL+"/b", []byte(`{"msg_type":"text","content":{"text":"second"},"sign":"x"}`), ""); err != nil {
+		t.Errorf("Expected delivery to succeed, got %v", err)
+	}
+
+	if verified != 2 {
+		t.Errorf("Expected 2 verified requests, got %d", verified)
+	}
+}
+
+func TestMain_GatewaySignatureMultipleTargets(t *testing.T) {
+	key := "gateway-key"
+	var paths []string
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		h := hmac.New(sha256.New, []byte(key))
+		h.Write(body)
+		if r.Header.Get("X-Gateway-Signature") != hex.EncodeToString(h.Sum(nil)) {
+			t.Errorf("Signature mismatch for %s", r.URL.Path)
+		}
+		paths = append(paths, r.URL.Path)
+		w.Write([]byte(`{"code": 0}`))
+	}))
+	defer testServer.Close()
+
+	os.Setenv("PLUGIN_WEBHOOK_URL", testServer.URL+"/a,"+testServer.URL+"/b")
+	os.Setenv("PLUGIN_GATEWAY_HMAC_KEY", key)
+	os.Setenv("PLUGIN_SECRET", "lark-secret")
+	defer func() {
+		os.Unsetenv("PLUGIN_WEBHOOK_URL")
+		os.Unsetenv("PLUGIN_GATEWAY_HMAC_KEY")
+		os.Unsetenv("PLUGIN_SECRET")
+	}()
+
+	main()
+
+	if len(paths) != 2 {
+		t.Errorf("Expected 2 signed requests, got %v", paths)
+	}
+}
+
+func TestSendMessage_NoGatewayKey(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Gateway-Signature") != "" || r.Header.Get("X-Gateway-Timestamp") != "" {
+			t.Error("Expected no gateway headers without a key")
+		}
+		w.Write([]byte(`{"code": 0}`))
+	}))
+	defer testServer.Close()
+
+	if err := deliverMessage(context.Background(), testServer.URL, []byte(`{}`), ""); err != nil {
+		t.Errorf("Expected delivery to succeed, got %v", err)
+	}
+}
+
+func TestPrintDebugInfo_RedactsSecrets(t *testing.T) {
+	os.Setenv("PLUGIN_GATEWAY_HMAC_KEY", "gateway-key-value")
+	os.Setenv("PLUGIN_SECRET", "lark-secret-value")
+	os.Setenv("PLUGIN_DEBUG", "true")
+	defer func() {
+		os.Unsetenv("PLUGIN_GATEWAY_HMAC_KEY")
+		os.Unsetenv("PLUGIN_SECRET")
+		os.Unsetenv("PLUGIN_DEBUG")
+	}()
+
+	output := captureStdout(t, func() {
+		printDebugInfo([]byte(`{}`))
+	})
+
+	if strings.Contains(output, "gateway-key-value") || strings.Contains(output, "lark-secret-value") {
+		t.Errorf("Expected secrets to be redacted, got:\n%s", output)
+	}
+	if !strings.Contains(output, "[REDACTED]") {
+		t.Error("Expected redaction marker in debug output")
+	}
+}
+
+func TestGatewaySignature_EveryRequest(t *testing.T) {
+	key := "gateway-key"
+	var paths []string
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		h := hmac.New(sha256.New, []byte(key))
+		h.Write(body)
+		if r.Header.Get("X-Gateway-Signature") != hex.EncodeToString(h.Sum(nil)) || r.Header.Get("X-Gateway-Timestamp") == "" {
+			t.Errorf("Expected a valid gateway signature for %s", r.URL.Path)
+		}
+		paths = append(paths, r.URL.Path)
+
+		switch r.URL.Path {
+		case "/open-apis/auth/v3/tenant_access_token/internal":
+			w.Write([]byte(`{"code": 0, "tenant_access_token": "t-1", "expire": 7200}`))
+		case "/wecom":
+			w.Write([]byte(`{"errcode": 0, "errmsg": "ok"}`))
+		default:
+			w.Write([]byte(`{"code": 0}`))
+		}
+	}))
+	defer testServer.Close()
+
+	originalOsExit := osExit
+	defer func() { osExit = originalOsExit }()
+	exitCode := 0
+	osExit = func(code int) { exitCode = code }
+
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_GATEWAY_HMAC_KEY": key,
+		"PLUGIN_API_BASE_URL":     testServer.URL,
+		"PLUGIN_APP_ID":           "cli_test",
+		"PLUGIN_APP_SECRET":       "app_secret",
+		"PLUGIN_CHAT_ID":          "oc_one",
+		"DRONE_BUILD_STATUS":      "success",
+	})
+
+	// The token and message requests of a chat target
+	captureOutput(t, main)
+	if exitCode != 0 {
+		t.Errorf("Expected exit code 0, got %d", exitCode)
+	}
+
+	// WeCom and DingTalk robots
+	if err := deliverErrcodeWebhook(context.Background(), "WeCom", testServer.URL+"/wecom", []byte(`{"msgtype":"text"}`)); err != nil {
+		t.Errorf("Expected delivery to succeed, got %v", err)
+	}
+
+	expected := []string{"/open-apis/auth/v3/tenant_access_token/internal", "/open-apis/im/v1/messages", "/wecom"}
+	if strings.Join(paths, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected signed requests to %v, got %v", expected, paths)
+	}
+}
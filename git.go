@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+const (
+	// gitTimeout bounds every git invocation
+	gitTimeout = 10 * time.Second
+	// maxGitOutput caps how much git output is read
+	maxGitOutput = 1 << 20
+)
+
+// cappedBuffer keeps the first limit bytes written to it and drops the rest
+type cappedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); room > 0 {
+		if len(p) > room {
+			b.Buffer.Write(p[:room])
+		} else {
+			b.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+func debugLog(format string, args ...any) {
+	logDebug(fmt.Sprintf(format, args...))
+}
+
+// runGit runs git in the workspace (CI_WORKSPACE, or the working directory)
+// and returns its output, capped at maxGitOutput
+func runGit(args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), gitTimeout)
+	defer cancel()
+
+	output := &cappedBuffer{limit: maxGitOutput}
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = getEnvOrDefault("CI_WORKSPACE", "")
+	cmd.Stdout = output
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+	return output.String(), nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newGitRepo creates an empty repository in a temporary directory and returns
+// it with helpers to run git and write files in it
+func newGitRepo(t *testing.T) (string, func(args ...string) string, func(name, content string)) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+
+	dir := t.TempDir()
+	git := func(args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+		return strings.TrimSpace(string(output))
+	}
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("init", "-q", "-b", "main")
+	return dir, git, write
+}
+
+// gitCommit commits all changes and returns the new SHA
+func gitCommit(git func(args ...string) string, message string) string {
+	git("add", ".")
+	git("commit", "-q", "--allow-empty", "-m", message)
+	return git("rev-parse", "HEAD")
+}
+
+func TestCappedBuffer(t *testing.T) {
+	buffer := &cappedBuffer{limit: 5}
+	buffer.Write([]byte("abc"))
+	if n, err := buffer.Write([]byte("defgh")); n != 5 || err != nil {
+		t.Errorf("Expected writes to report success, got %d, %v", n, err)
+	}
+	if buffer.String() != "abcde" {
+		t.Errorf("Expected capped output, got %q", buffer.String())
+	}
+}
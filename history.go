@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// historyRecord is one line of the notification history file
+type historyRecord struct {
+	Time          time.Time       `json:"time"`
+	Repo          string          `json:"repo"`
+	Pipeline      string          `json:"pipeline,omitempty"`
+	Status        string          `json:"status"`
+	Targets       []string        `json:"targets"`
+	Outcome       string          `json:"outcome"`
+	Reason        string          `json:"reason,omitempty"`
+	Skipped       []string        `json:"skipped,omitempty"`
+	Errors        []string        `json:"errors,omitempty"`
+	PayloadSHA256 string          `json:"payload_sha256,omitempty"`
+	Payload       json.RawMessage `json:"payload,omitempty"`
+}
+
+// historyFilePath resolves PLUGIN_HISTORY_FILE, relative paths are kept in the state dir
+func historyFilePath() string {
+	path := getEnvOrDefault("PLUGIN_HISTORY_FILE", "")
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	if stateDir := getEnvOrDefault("PLUGIN_STATE_DIR", ""); stateDir != "" {
+		return filepath.Join(stateDir, path)
+	}
+	return path
+}
+
+// webhookHost returns the host of a webhook URL, whose path holds the bot
+// token, or the chat id of a chat target
+func webhookHost(webhookURL string) string {
+	if chatID, ok := strings.CutPrefix(webhookURL, chatTargetPrefix); ok {
+		return "chat:" + chatID
+	}
+	if u, err := url.Parse(webhookURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return "invalid-url"
+}
+
+// redactPayload removes the Lark signature from a payload before it is stored
+func redactPayload(messageBytes []byte) json.RawMessage {
+	var message map[string]any
+	if err := json.Unmarshal(messageBytes, &message); err != nil {
+		return nil
+	}
+	if _, ok := message["sign"]; ok {
+		message["sign"] = "[REDACTED]"
+	}
+	redacted, err := json.Marshal(message)
+	if err != nil {
+		return nil
+	}
+	return redacted
+}
+
+// historyRun collects what notify did, for its history record
+type historyRun struct {
+	// Targets are the webhook URLs and chat targets of the run
+	Targets      []string
+	MessageBytes []byte
+	// Delivered tells whether the targets were sent to, SendErrors are the
+	// failed deliveries and Duplicates the targets skipped as duplicates
+	Delivered  bool
+	SendErrors []error
+	Duplicates []string
+	// SkipReason is why the run sent nothing: no webhook, a filtered status,
+	// quiet hours, a matrix leg waiting for the others or a dry run
+	SkipReason string
+	// Err stopped the run before anything was sent
+	Err error
+}
+
+func newHistoryRecord(run historyRun) historyRecord {
+	record := historyRecord{
+		Time:     timeNow().UTC(),
+		Repo:     getEnvOrDefault("CI_REPO", ""),
+		Pipeline: getPipelineNumber(),
+		Status:   getBuildStatus(),
+	}
+	if run.MessageBytes != nil {
+		sum := sha256.Sum256(run.MessageBytes)
+		record.PayloadSHA256 = hex.EncodeToString(sum[:])
+	}
+
+	for _, webhookURL := range run.Targets {
+		if webhookURL != "" {
+			record.Targets = append(record.Targets, webhookHost(webhookURL))
+		}
+	}
+	for _, webhookURL := range run.Duplicates {
+		record.Skipped = append(record.Skipped, webhookHost(webhookURL))
+	}
+
+	// Error messages may hold webhook URLs, whose path is the bot token
+	redact := func(err error) string {
+		message := err.Error()
+		for _, webhookURL := range run.Targets {
+			if webhookURL != "" {
+				message = strings.ReplaceAll(message, webhookURL, webhookHost(webhookURL))
+			}
+		}
+		return message
+	}
+	for _, err := range run.SendErrors {
+		record.Errors = append(record.Errors, redact(err))
+	}
+
+	attempted := len(run.Targets) - len(run.Duplicates)
+	switch {
+	case run.SkipReason != "":
+		record.Outcome = "skipped"
+		record.Reason = run.SkipReason
+	case !run.Delivered && run.Err != nil:
+		record.Outcome = "error"
+		record.Errors = append(record.Errors, redact(run.Err))
+	case attempted == 0:
+		record.Outcome = "skipped"
+		record.Reason = "duplicate notification"
+	case len(run.SendErrors) == 0:
+		record.Outcome = "delivered"
+	case len(run.SendErrors) < attempted:
+		record.Outcome = "partial"
+	default:
+		record.Outcome = "failed"
+	}
+
+	if getEnvOrDefault("PLUGIN_HISTORY_PAYLOAD", "false") == "true" && run.MessageBytes != nil {
+		record.Payload = redactPayload(run.MessageBytes)
+	}
+	return record
+}
+
+// appendHistory adds record to the history file as a single line. The line is
+// written with one append-mode write so a crash never interleaves records.
+func appendHistory(path string, record historyRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// recordHistory appends the outcome of this run when PLUGIN_HISTORY_FILE is set
+func recordHistory(run historyRun) {
+	path := historyFilePath()
+	if path == "" {
+		return
+	}
+	if err := appendHistory(path, newHistoryRecord(run)); err != nil {
+		logWarn(fmt.Sprintf("could not write history file: %v", err))
+	}
+}
+
+// readHistory parses a history file, skipping and counting corrupt lines
+func readHistory(r io.Reader) ([]historyRecord, int, error) {
+	var records []historyRecord
+	corrupt := 0
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var record historyRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			corrupt++
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, corrupt, scanner.Err()
+}
+
+// filterHistory returns the last limit records matching repo and status
+func filterHistory(records []historyRecord, repo, status string, limit int) []historyRecord {
+	var filtered []historyRecord
+	for _, record := range records {
+		if repo != "" && record.Repo != repo {
+			continue
+		}
+		if status != "" && !strings.EqualFold(record.Status, status) {
+			continue
+		}
+		filtered = append(filtered, record)
+	}
+
+	if limit > 0 && len(filtered) > limit {
+		filtered = filtered[len(filtered)-limit:]
+	}
+	return filtered
+}
+
+// runHistoryCommand implements the "history" subcommand and returns the exit code
+func runHistoryCommand(args []string) int {
+	flags := flag.NewFlagSet("history", flag.ContinueOnError)
+	limit := flags.Int("limit", 20, "number of records to show")
+	repo := flags.String("repo", "", "only show records for this repository")
+	status := flags.String("status", "", "only show records with this status")
+	asJSON := flags.Bool("json", false, "print records as JSON lines")
+	path := flags.String("file", historyFilePath(), "history file (default: PLUGIN_HISTORY_FILE)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *path == "" {
+		fmt.Fprintln(os.Stderr, "No history file: set PLUGIN_HISTORY_FILE or pass --file")
+		return 1
+	}
+
+	f, err := os.Open(*path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot read history file: %v\n", err)
+		return 1
+	}
+	defer f.Close()
+
+	records, corrupt, err := readHistory(f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot read history file: %v\n", err)
+		return 1
+	}
+	records = filterHistory(records, *repo, *status, *limit)
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		for _, record := range records {
+			encoder.Encode(record)
+		}
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TIME\tREPO\tPIPELINE\tSTATUS\tOUTCOME\tTARGETS\tPAYLOAD")
+		for _, record := range records {
+			outcome := record.Outcome
+			if record.Reason != "" {
+				outcome += " (" + record.Reason + ")"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%.12s\n",
+				formatTimestamp(record.Time),
+				record.Repo,
+				record.Pipeline,
+				record.Status,
+				outcome,
+				strings.Join(record.Targets, ","),
+				record.PayloadSHA256)
+		}
+		w.Flush()
+	}
+
+	if corrupt > 0 {
+		fmt.Fprintf(os.Stderr, "Skipped %d corrupt line(s)\n", corrupt)
+	}
+	return 0
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain_RecordsHistory(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		w.Write([]byte(`{"code": 0}`))
+	}))
+	defer testServer.Close()
+
+	originalOsExit := osExit
+	defer func() { osExit = originalOsExit }()
+	exitCode := 0
+	osExit = func(code int) { exitCode = code }
+
+	stateDir := t.TempDir()
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_WEBHOOK_URL":     testServer.URL + "/hook/secret-token," + testServer.URL + "/broken",
+		"PLUGIN_STATE_DIR":       stateDir,
+		"PLUGIN_HISTORY_FILE":    "history.jsonl",
+		"PLUGIN_HISTORY_PAYLOAD": "true",
+		"PLUGIN_SECRET":          "lark-secret",
+		"CI_REPO":                "octo/backend",
+		"CI_PIPELINE_NUMBER":     "42",
+		"DRONE_BUILD_STATUS":     "failure",
+	})
+
+	main()
+
+	if exitCode != exitPartial {
+		t.Errorf("Expected exit code 5 after a failed target, got %d", exitCode)
+	}
+
+	data, err := os.ReadFile(filepath.Join(stateDir, "history.jsonl"))
+	if err != nil {
+		t.Fatalf("Expected history file: %v", err)
+	}
+	if strings.Contains(string(data), "secret-token") {
+		t.Error("History must not contain the webhook token")
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Expected 1 history line, got %d", len(lines))
+	}
+
+	var record historyRecord
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("Invalid history line: %v", err)
+	}
+	if record.Repo != "octo/backend" || record.Pipeline != "42" || record.Status != "failure" {
+		t.Errorf("Unexpected record %+v", record)
+	}
+	if record.Outcome != "partial" || len(record.Errors) != 1 || len(record.Targets) != 2 {
+		t.Errorf("Expected a partial delivery to 2 targets, got %+v", record)
+	}
+	if len(record.PayloadSHA256) != 64 {
+		t.Errorf("Expected a sha256 digest, got '%s'", record.PayloadSHA256)
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(record.Payload, &payload); err != nil {
+		t.Fatalf("Expected stored payload: %v", err)
+	}
+	if payload["sign"] != "[REDACTED]" {
+		t.Errorf("Expected redacted signature, got %v", payload["sign"])
+	}
+}
+
+func TestNewHistoryRecord_RedactsWebhookInErrors(t *testing.T) {
+	webhookURL := "https://open.larksuite.com/open-apis/bot/v2/hook/secret-token"
+	record := newHistoryRecord(historyRun{
+		Targets:      []string{webhookURL},
+		MessageBytes: []byte(`{}`),
+		Delivered:    true,
+		SendErrors:   []error{errors.New(`error sending to Lark: Post "` + webhookURL + `": timeout`)},
+	})
+
+	if record.Outcome != "failed" {
+		t.Errorf("Expected outcome 'failed', got '%s'", record.Outcome)
+	}
+	if strings.Contains(record.Errors[0], "secret-token") {
+		t.Errorf("Expected webhook token to be redacted, got '%s'", record.Errors[0])
+	}
+	if record.Payload != nil {
+		t.Error("Expected no payload without PLUGIN_HISTORY_PAYLOAD")
+	}
+}
+
+func TestNewHistoryRecord_Outcomes(t *testing.T) {
+	first := "https://open.larksuite.com/open-apis/bot/v2/hook/first"
+	second := "https://open.feishu.cn/open-apis/bot/v2/hook/second"
+
+	tests := []struct {
+		name    string
+		run     historyRun
+		outcome string
+		reason  string
+	}{
+		{"Skipped run", historyRun{Targets: []string{first}, SkipReason: "matrix aggregation"}, "skipped", "matrix aggregation"},
+		{"Skip wins over an output file error", historyRun{Targets: []string{first}, SkipReason: "dry run", Err: errors.New("disk full")}, "skipped", "dry run"},
+		{"Error before sending", historyRun{Err: errors.New("invalid template")}, "error", ""},
+		{"Every target is a duplicate", historyRun{Targets: []string{first, second}, Delivered: true, Duplicates: []string{first, second}}, "skipped", "duplicate notification"},
+		{"One duplicate, one delivered", historyRun{Targets: []string{first, second}, Delivered: true, Duplicates: []string{first}}, "delivered", ""},
+		{"One duplicate, one failed", historyRun{Targets: []string{first, second}, Delivered: true, Duplicates: []string{first}, SendErrors: []error{errors.New("timeout")}}, "failed", ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			record := newHistoryRecord(tc.run)
+			if record.Outcome != tc.outcome || record.Reason != tc.reason {
+				t.Errorf("Expected %s (%s), got %s (%s)", tc.outcome, tc.reason, record.Outcome, record.Reason)
+			}
+			if record.PayloadSHA256 != "" {
+				t.Errorf("Expected no digest without a payload, got '%s'", record.PayloadSHA256)
+			}
+			if len(record.Skipped) != len(tc.run.Duplicates) {
+				t.Errorf("Expected skipped targets %v, got %v", tc.run.Duplicates, record.Skipped)
+			}
+		})
+	}
+}
+
+func TestMain_RecordsSkippedRuns(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Expected nothing to be sent, got a request to %s", r.URL.Path)
+	}))
+	defer testServer.Close()
+
+	originalOsExit := osExit
+	defer func() { osExit = originalOsExit }()
+	osExit = func(code int) {}
+
+	tests := []struct {
+		name   string
+		env    map[string]string
+		reason string
+	}{
+		{"No webhook for the status", map[string]string{"PLUGIN_WEBHOOK_URL_FAILURE": testServer.URL + "/hook"}, "no webhook for this status"},
+		{"Filtered status", map[string]string{"PLUGIN_WEBHOOK_URL": testServer.URL + "/hook", "PLUGIN_NOTIFY_ON": "failure"}, "status 'success' is not in PLUGIN_NOTIFY_ON (failure)"},
+		{"Dry run", map[string]string{"PLUGIN_WEBHOOK_URL": testServer.URL + "/hook", "PLUGIN_DRY_RUN": "true"}, "dry run"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			stateDir := t.TempDir()
+			tc.env["PLUGIN_STATE_DIR"] = stateDir
+			tc.env["PLUGIN_HISTORY_FILE"] = "history.jsonl"
+			tc.env["CI_REPO"] = "octo/backend"
+			tc.env["DRONE_BUILD_STATUS"] = "success"
+			setEnvFixture(t, tc.env)
+			captureOutput(t, main)
+
+			data, err := os.ReadFile(filepath.Join(stateDir, "history.jsonl"))
+			if err != nil {
+				t.Fatalf("Expected history file: %v", err)
+			}
+			var record historyRecord
+			if err := json.Unmarshal(data, &record); err != nil {
+				t.Fatalf("Invalid history line: %v", err)
+			}
+			if record.Outcome != "skipped" || record.Reason != tc.reason {
+				t.Errorf("Expected skipped (%s), got %s (%s)", tc.reason, record.Outcome, record.Reason)
+			}
+			if record.Repo != "octo/backend" || record.Status != "success" {
+				t.Errorf("Unexpected record %+v", record)
+			}
+		})
+	}
+}
+
+func TestReadHistory_SkipsCorruptLines(t *testing.T) {
+	input := `{"repo":"a","status":"success","outcome":"delivered"}
+{"repo":"b","status":
+not json at all
+
+{"repo":"c","status":"failure","outcome":"failed"}
+{"repo":"d","sta`
+
+	records, corrupt, err := readHistory(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(records) != 2 || records[0].Repo != "a" || records[1].Repo != "c" {
+		t.Errorf("Expected records a and c, got %+v", records)
+	}
+	if corrupt != 3 {
+		t.Errorf("Expected 3 corrupt lines, got %d", corrupt)
+	}
+}
+
+func TestFilterHistory(t *testing.T) {
+	records := []historyRecord{
+		{Repo: "a", Status: "success", Pipeline: "1"},
+		{Repo: "b", Status: "failure", Pipeline: "2"},
+		{Repo: "a", Status: "failure", Pipeline: "3"},
+		{Repo: "a", Status: "success", Pipeline: "4"},
+		{Repo: "a", Status: "failure", Pipeline: "5"},
+	}
+
+	tests := []struct {
+		name     string
+		repo     string
+		status   string
+		limit    int
+		expected []string
+	}{
+		{"No filters", "", "", 0, []string{"1", "2", "3", "4", "5"}},
+		{"Limit keeps the latest", "", "", 2, []string{"4", "5"}},
+		{"Repo", "a", "", 0, []string{"1", "3", "4", "5"}},
+		{"Status", "", "FAILURE", 0, []string{"2", "3", "5"}},
+		{"Repo, status and limit", "a", "failure", 1, []string{"5"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var pipelines []string
+			for _, record := range filterHistory(records, tc.repo, tc.status, tc.limit) {
+				pipelines = append(pipelines, record.Pipeline)
+			}
+			if strings.Join(pipelines, ",") != strings.Join(tc.expected, ",") {
+				t.Errorf("Expected %v, got %v", tc.expected, pipelines)
+			}
+		})
+	}
+}
+
+func TestRunHistoryCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	when := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	appendHistory(path, historyRecord{Time: when, Repo: "octo/backend", Pipeline: "41", Status: "success", Outcome: "delivered", Targets: []string{"open.larksuite.com"}})
+	appendHistory(path, historyRecord{Time: when, Repo: "octo/backend", Pipeline: "42", Status: "failure", Outcome: "failed", Targets: []string{"open.larksuite.com"}})
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("Expected 0600 permissions, got %o", perm)
+	}
+
+	var code int
+	output := captureStdout(t, func() {
+		code = runHistoryCommand([]string{"--file", path, "--status", "failure"})
+	})
+	if code != 0 {
+		t.Errorf("Expected exit code 0, got %d", code)
+	}
+	if !strings.Contains(output, "PIPELINE") || !strings.Contains(output, "42") || strings.Contains(output, "41") {
+		t.Errorf("Unexpected table output:\n%s", output)
+	}
+
+	output = captureStdout(t, func() {
+		code = runHistoryCommand([]string{"--file", path, "--json", "--limit", "1"})
+	})
+	var record historyRecord
+	if err := json.Unmarshal([]byte(strings.TrimSpace(output)), &record); err != nil {
+		t.Fatalf("Expected one JSON record, got %q", output)
+	}
+	if record.Pipeline != "42" {
+		t.Errorf("Expected latest record, got %+v", record)
+	}
+
+	appendHistory(path, historyRecord{Time: when, Repo: "octo/backend", Pipeline: "43", Status: "failure", Outcome: "skipped", Reason: "quiet hours"})
+	output = captureStdout(t, func() {
+		runHistoryCommand([]string{"--file", path, "--limit", "1"})
+	})
+	if !strings.Contains(output, "skipped (quiet hours)") {
+		t.Errorf("Expected the skip reason in the table, got:\n%s", output)
+	}
+
+	if code := runHistoryCommand([]string{"--file", filepath.Join(t.TempDir(), "missing.jsonl")}); code != 1 {
+		t.Errorf("Expected exit code 1 for a missing file, got %d", code)
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// defaultLocale is the language of the built-in labels
+const defaultLocale = "en"
+
+// larkLocales maps PLUGIN_LANG values to the locale keys Lark uses in i18n fields
+var larkLocales = map[string]string{
+	"en": "en_us",
+	"zh": "zh_cn",
+}
+
+// translations of the fixed labels, by PLUGIN_LANG value. Labels missing from
+// a locale are shown in English.
+var translations = map[string]map[string]string{
+	"zh": {
+		"Project":                          "项目",
+		"Branch":                           "分支",
+		"Author":                           "作者",
+		"Triggered by":                     "触发人",
+		"Author / Triggered by":            "作者 / 触发人",
+		"Version":                          "版本",
+		"Parent":                           "父流水线",
+		"Restarted from":                   "重启自",
+		"Duration":                         "耗时",
+		"Coverage":                         "覆盖率",
+		"Matrix":                           "矩阵",
+		"Changes":                          "变更",
+		"since":                            "自",
+		"Images":                           "镜像",
+		"Steps":                            "步骤",
+		"Commit Message":                   "提交信息",
+		"Message":                          "提交信息",
+		"Variables":                        "变量",
+		"Pipeline":                         "流水线",
+		"Pipeline Succeeded":               "流水线成功",
+		"Pipeline Failed":                  "流水线失败",
+		"Pipeline Fixed":                   "流水线已修复",
+		"Pipeline Still Failing":           "流水线仍然失败",
+		"Pipeline Errored":                 "流水线出错",
+		"Pipeline Killed":                  "流水线已终止",
+		"Pipeline Canceled":                "流水线已取消",
+		"Pipeline Declined":                "流水线已拒绝",
+		"Pipeline Skipped":                 "流水线已跳过",
+		"Pipeline Pending":                 "流水线等待中",
+		"Pipeline Running":                 "流水线运行中",
+		"Pipeline Pending Approval":        "流水线等待审批",
+		"View Pipeline":                    "查看流水线",
+		"Cron Job":                         "定时任务",
+		"Scheduled Pipeline Succeeded":     "定时流水线成功",
+		"Scheduled Pipeline Failed":        "定时流水线失败",
+		"Scheduled Pipeline Fixed":         "定时流水线已修复",
+		"Scheduled Pipeline Still Failing": "定时流水线仍然失败",
+		"Scheduled Pipeline Errored":       "定时流水线出错",
+		"Scheduled Pipeline Killed":        "定时流水线已终止",
+		"Scheduled Pipeline Canceled":      "定时流水线已取消",
+		"Scheduled Pipeline Skipped":       "定时流水线已跳过",
+		"Scheduled Pipeline Pending":       "定时流水线等待中",
+		"Scheduled Pipeline Running":       "定时流水线运行中",
+		"Deployment":                       "部署",
+		"Deploy Succeeded":                 "部署成功",
+		"Deploy Failed":                    "部署失败",
+		"Deploy Fixed":                     "部署已修复",
+		"Deploy Still Failing":             "部署仍然失败",
+		"Deploy Errored":                   "部署出错",
+		"Deploy Killed":                    "部署已终止",
+		"Deploy Canceled":                  "部署已取消",
+		"Deploy Declined":                  "部署已拒绝",
+		"Deploy Skipped":                   "部署已跳过",
+		"Deploy Pending":                   "部署等待中",
+		"Deploy Running":                   "部署进行中",
+		"Deploy Pending Approval":          "部署等待审批",
+		"View Deployment":                  "查看部署",
+		"Environment":                      "环境",
+		"View Commit":                      "查看提交",
+		"View Release":                     "查看发布",
+		"View Pull Request":                "查看合并请求",
+		"View Failed Step":                 "查看失败步骤",
+		"Failed Step":                      "失败步骤",
+		"View Parent":                      "查看父流水线",
+		"Failing for %d builds (since %s)": "已连续失败 %d 次（自 %s）",
+		"Fixed after 1 failed build":       "1 次失败后已修复",
+		"Fixed after %d failed builds":     "%d 次失败后已修复",
+		"1 day":                            "1 天",
+		"%d days":                          "%d 天",
+		"1 hour":                           "1 小时",
+		"%d hours":                         "%d 小时",
+		"1 minute":                         "1 分钟",
+		"%d minutes":                       "%d 分钟",
+	},
+}
+
+// currentLocale selects the translations while a message is being built
+var currentLocale = defaultLocale
+
+// tr translates a fixed label into currentLocale
+func tr(label string) string {
+	if translated, ok := translations[currentLocale][label]; ok {
+		return translated
+	}
+	return label
+}
+
+// getLocales returns the locales requested by PLUGIN_LANG, unknown values are
+// skipped with a warning
+func getLocales() []string {
+	var locales []string
+	for _, lang := range getListSetting("PLUGIN_LANG") {
+		lang = strings.ToLower(lang)
+		if _, ok := larkLocales[lang]; !ok {
+			logWarn(fmt.Sprintf("unsupported PLUGIN_LANG value '%s'", lang))
+			continue
+		}
+		locales = append(locales, lang)
+	}
+	if len(locales) == 0 {
+		return []string{defaultLocale}
+	}
+	return locales
+}
+
+// translateButtons translates the button labels. It runs after PLUGIN_BUTTONS
+// selection, so labels are translated once per card.
+func translateButtons(actions []map[string]any) []map[string]any {
+	for _, action := range actions {
+		if text, ok := action["text"].(map[string]any); ok {
+			if content, ok := text["content"].(string); ok {
+				text["content"] = tr(content)
+			}
+		}
+	}
+	return actions
+}
+
+func createLarkCard(config Config, projectVersion string) map[string]any {
+	defer func() { currentLocale = defaultLocale }()
+
+	locales := getLocales()
+	if len(locales) == 1 {
+		currentLocale = locales[0]
+		return applyCardLink(buildLarkCard(config, projectVersion))
+	}
+	return applyCardLink(createI18nLarkCard(config, projectVersion, locales))
+}
+
+// createI18nLarkCard builds the card once per locale and combines them into
+// i18n_elements and an i18n header title. The first locale is the fallback.
+func createI18nLarkCard(config Config, projectVersion string, locales []string) map[string]any {
+	var header map[string]any
+	titles := map[string]any{}
+	elements := map[string]any{}
+
+	for _, locale := range locales {
+		currentLocale = locale
+		card := buildLarkCard(config, projectVersion)["card"].(map[string]any)
+		// PLUGIN_SECTIONS can leave the header out
+		if localeHeader, ok := card["header"].(map[string]any); ok {
+			if header == nil {
+				header = localeHeader
+			}
+			titles[larkLocales[locale]] = localeHeader["title"].(map[string]any)["content"]
+		}
+		elements[larkLocales[locale]] = card["elements"]
+	}
+
+	card := map[string]any{
+		"i18n_elements": elements,
+	}
+	if header != nil {
+		header["title"].(map[string]any)["i18n"] = titles
+		card["header"] = header
+	}
+	return map[string]any{
+		"msg_type": "interactive",
+		"card":     card,
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCreateLarkCard_SingleLocale(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"CI_REPO":          "octo/backend",
+		"CI_REPO_NAME":     "backend",
+		"CI_COMMIT_BRANCH": "main",
+		"CI_PIPELINE_URL":  "https://ci.example.com/repos/1/pipeline/42",
+	})
+	config := Config{Status: "failure"}
+
+	setEnvFixture(t, map[string]string{"PLUGIN_LANG": ""})
+	defaultCard, _ := json.Marshal(createLarkCard(config, "v1.0.0"))
+	setEnvFixture(t, map[string]string{"PLUGIN_LANG": "en"})
+	englishCard, _ := json.Marshal(createLarkCard(config, "v1.0.0"))
+	if string(defaultCard) != string(englishCard) {
+		t.Errorf("Expected PLUGIN_LANG=en to match the default card, got %s and %s", englishCard, defaultCard)
+	}
+
+	setEnvFixture(t, map[string]string{"PLUGIN_LANG": "zh"})
+	chineseCard, _ := json.Marshal(createLarkCard(config, "v1.0.0"))
+	for _, label := range []string{"**项目:** octo/backend", "**分支:** main", "**版本:** v1.0.0", "**提交信息:**", "backend - 🚨 流水线失败", "查看流水线"} {
+		if !strings.Contains(string(chineseCard), label) {
+			t.Errorf("Expected %q in %s", label, chineseCard)
+		}
+	}
+}
+
+func TestCreateLarkCard_TwoLocales(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_LANG":      "en,zh",
+		"CI_REPO":          "octo/backend",
+		"CI_REPO_NAME":     "backend",
+		"CI_COMMIT_BRANCH": "main",
+	})
+
+	var message struct {
+		Card struct {
+			Header struct {
+				Title struct {
+					Content string            `json:"content"`
+					I18n    map[string]string `json:"i18n"`
+				} `json:"title"`
+				Template string `json:"template"`
+			} `json:"header"`
+			Elements     []any `json:"elements"`
+			I18nElements map[string][]struct {
+				Text struct {
+					Content string `json:"content"`
+				} `json:"text"`
+			} `json:"i18n_elements"`
+		} `json:"card"`
+	}
+	roundTrip(t, createLarkCard(Config{Status: "success"}, "v1.0.0"), &message)
+
+	title := message.Card.Header.Title
+	if title.Content != "backend - ✅ Pipeline Succeeded" || title.I18n["en_us"] != "backend - ✅ Pipeline Succeeded" || title.I18n["zh_cn"] != "backend - ✅ 流水线成功" {
+		t.Errorf("Unexpected header title %+v", title)
+	}
+	if message.Card.Header.Template != "green" {
+		t.Errorf("Expected green header, got '%s'", message.Card.Header.Template)
+	}
+	if message.Card.Elements != nil {
+		t.Error("Expected i18n_elements instead of elements")
+	}
+
+	english := message.Card.I18nElements["en_us"][0].Text.Content
+	chinese := message.Card.I18nElements["zh_cn"][0].Text.Content
+	if !strings.HasPrefix(english, "**Project:** octo/backend\n**Branch:** main") {
+		t.Errorf("Unexpected English metadata %q", english)
+	}
+	if !strings.HasPrefix(chinese, "**项目:** octo/backend\n**分支:** main") {
+		t.Errorf("Unexpected Chinese metadata %q", chinese)
+	}
+}
+
+func TestCreateLarkTextMessage_Chinese(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_LANG": "zh,en",
+		"CI_REPO":     "octo/backend",
+	})
+
+	text := createLarkTextMessage(Config{Status: "failure"}, "v1.0.0")["content"].(map[string]any)["text"].(string)
+	if !strings.HasPrefix(text, "🚨 流水线失败\n") || !strings.Contains(text, "📋 项目: octo/backend") {
+		t.Errorf("Expected Chinese labels, got %q", text)
+	}
+	if currentLocale != defaultLocale {
+		t.Errorf("Expected the locale to be reset, got '%s'", currentLocale)
+	}
+}
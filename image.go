@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// maxImageSize is the largest image Lark accepts for message images
+const maxImageSize = 10 << 20
+
+// cardImageKey is the image_key of the uploaded PLUGIN_IMAGE_FILE, set by
+// main before the card is built
+var cardImageKey string
+
+// uploadImage uploads a png or jpg image for use in messages and returns its
+// image_key
+func uploadImage(ctx context.Context, appID, appSecret, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxImageSize {
+		return "", fmt.Errorf("%s is %d bytes, Lark accepts images up to %d bytes", path, len(data), maxImageSize)
+	}
+	if contentType := http.DetectContentType(data); contentType != "image/png" && contentType != "image/jpeg" {
+		return "", fmt.Errorf("%s is %s, expected a png or jpg image", path, contentType)
+	}
+
+	var imageKey string
+	err = withTenantAccessToken(ctx, appID, appSecret, func(token string) error {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		form.WriteField("image_type", "message")
+		part, err := form.CreateFormFile("image", filepath.Base(path))
+		if err != nil {
+			return err
+		}
+		part.Write(data)
+		if err := form.Close(); err != nil {
+			return err
+		}
+
+		var result struct {
+			ImageKey string `json:"image_key"`
+		}
+		if err := sendOpenAPI(ctx, http.MethodPost, "/open-apis/im/v1/images", token, form.FormDataContentType(), body.Bytes(), &result); err != nil {
+			return err
+		}
+		if result.ImageKey == "" {
+			return fmt.Errorf("no image_key in the upload response")
+		}
+		imageKey = result.ImageKey
+		return nil
+	})
+	return imageKey, err
+}
+
+// resolveCardImageKey uploads PLUGIN_IMAGE_FILE for card messages. Failures
+// only warn, the card is then sent without the image.
+func resolveCardImageKey(ctx context.Context, config Config) string {
+	path := getEnvOrDefault("PLUGIN_IMAGE_FILE", "")
+	if path == "" || !config.UseCard || config.Compact || isMinimalDetail(config.Status) || config.Provider != providerLark {
+		return ""
+	}
+	if config.DryRun {
+		logInfo("dry run, not uploading PLUGIN_IMAGE_FILE", "path", path)
+		return ""
+	}
+
+	imageKey, err := uploadImage(ctx, getEnvOrDefault("PLUGIN_APP_ID", ""), getEnvOrDefault("PLUGIN_APP_SECRET", ""), path)
+	if err != nil {
+		logWarn(fmt.Sprintf("could not upload PLUGIN_IMAGE_FILE, sending the card without it: %v", err))
+		return ""
+	}
+	return imageKey
+}
+
+// createImageElements returns the img element of the uploaded image, or nil
+func createImageElements() []map[string]any {
+	if cardImageKey == "" {
+		return nil
+	}
+	return []map[string]any{
+		{
+			"tag":     "img",
+			"img_key": cardImageKey,
+			"alt": map[string]any{
+				"tag":     "plain_text",
+				"content": getEnvOrDefault("PLUGIN_IMAGE_ALT", ""),
+			},
+		},
+	}
+}
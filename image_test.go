@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// pngHeader is enough of a png file for content sniffing
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+// setupImageServer serves the token and image upload endpoints; uploadCode is
+// the code of the upload response
+func setupImageServer(t *testing.T, uploadCode int) *[]byte {
+	var uploaded []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/open-apis/auth/v3/tenant_access_token/internal":
+			json.NewEncoder(w).Encode(map[string]any{"code": 0, "tenant_access_token": "t-1", "expire": 7200})
+		case "/open-apis/im/v1/images":
+			if r.Header.Get("Authorization") != "Bearer t-1" {
+				t.Errorf("Unexpected upload authorization %q", r.Header.Get("Authorization"))
+			}
+			if err := r.ParseMultipartForm(maxImageSize); err != nil {
+				t.Fatalf("Expected a multipart upload: %v", err)
+			}
+			if imageType := r.FormValue("image_type"); imageType != "message" {
+				t.Errorf("Expected image_type message, got %q", imageType)
+			}
+			file, _, err := r.FormFile("image")
+			if err != nil {
+				t.Fatalf("Expected an image file: %v", err)
+			}
+			uploaded, _ = io.ReadAll(file)
+			if uploadCode != 0 {
+				json.NewEncoder(w).Encode(map[string]any{"code": uploadCode, "msg": "no permission"})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{"code": 0, "data": map[string]any{"image_key": "img_v2_abc"}})
+		default:
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	originalAPIClient := openAPIClient
+	t.Cleanup(func() {
+		openAPIClient = originalAPIClient
+		cardImageKey = ""
+	})
+
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_API_BASE_URL": server.URL,
+		"PLUGIN_APP_ID":       "cli_test",
+		"PLUGIN_APP_SECRET":   "app_secret",
+		"PLUGIN_IMAGE_ALT":    "Coverage badge",
+		"CI_REPO":             "octocat/hello-world",
+	})
+	return &uploaded
+}
+
+func writeImageFile(t *testing.T, data []byte) string {
+	path := filepath.Join(t.TempDir(), "badge.png")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCardImage(t *testing.T) {
+	uploaded := setupImageServer(t, 0)
+	t.Setenv("PLUGIN_IMAGE_FILE", writeImageFile(t, pngHeader))
+
+	cardImageKey = resolveCardImageKey(context.Background(), Config{Provider: providerLark, UseCard: true})
+	if cardImageKey != "img_v2_abc" {
+		t.Fatalf("Expected image_key img_v2_abc, got %q", cardImageKey)
+	}
+	if !bytes.Equal(*uploaded, pngHeader) {
+		t.Errorf("Expected the file to be uploaded, got %q", *uploaded)
+	}
+
+	card := buildLarkCard(Config{}, "1.0.0")
+	elements := card["card"].(map[string]any)["elements"].([]map[string]any)
+	if tag := elements[1]["tag"]; tag != "img" {
+		t.Fatalf("Expected the image after the build details, got %v", elements[1])
+	}
+	cardJSON, _ := json.Marshal(card)
+	for _, expected := range []string{`"img_key":"img_v2_abc"`, `"content":"Coverage badge"`} {
+		if !strings.Contains(string(cardJSON), expected) {
+			t.Errorf("Expected %s in the card, got %s", expected, cardJSON)
+		}
+	}
+}
+
+func TestCardImage_Skipped(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       []byte
+		uploadCode int
+		config     Config
+		warning    string
+	}{
+		{
+			name:       "Upload fails",
+			data:       pngHeader,
+			uploadCode: 99991672,
+			config:     Config{Provider: providerLark, UseCard: true},
+			warning:    "could not upload PLUGIN_IMAGE_FILE",
+		},
+		{
+			name:    "Too large",
+			data:    append(append([]byte{}, pngHeader...), make([]byte, maxImageSize)...),
+			config:  Config{Provider: providerLark, UseCard: true},
+			warning: "Lark accepts images up to",
+		},
+		{
+			name:    "Not an image",
+			data:    []byte("coverage: 87%"),
+			config:  Config{Provider: providerLark, UseCard: true},
+			warning: "expected a png or jpg image",
+		},
+		{
+			name:   "Text message",
+			data:   pngHeader,
+			config: Config{Provider: providerLark, MsgType: msgTypeText},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupImageServer(t, tt.uploadCode)
+			t.Setenv("PLUGIN_IMAGE_FILE", writeImageFile(t, tt.data))
+
+			output := captureOutput(t, func() {
+				cardImageKey = resolveCardImageKey(context.Background(), tt.config)
+			})
+			if cardImageKey != "" {
+				t.Errorf("Expected no image, got %q", cardImageKey)
+			}
+			if !strings.Contains(output, tt.warning) {
+				t.Errorf("Expected %q in the output, got %q", tt.warning, output)
+			}
+			for _, element := range buildLarkCard(Config{}, "1.0.0")["card"].(map[string]any)["elements"].([]map[string]any) {
+				if element["tag"] == "img" {
+					t.Errorf("Expected no img element, got %v", element)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Card layouts selected by PLUGIN_LAYOUT
+const (
+	layoutList    = "list"
+	layoutColumns = "columns"
+)
+
+func isColumnsLayout() bool {
+	return strings.ToLower(getEnvOrDefault("PLUGIN_LAYOUT", layoutList)) == layoutColumns
+}
+
+// cardField renders a label/value pair as an entry of a div's fields array
+func cardField(content string, short bool) map[string]any {
+	return map[string]any{
+		"is_short": short,
+		"text": map[string]any{
+			"content": content,
+			"tag":     "lark_md",
+		},
+	}
+}
+
+// createColumnsMetadataElement renders the build metadata as two-column
+// fields: Project and Branch first, then Author and Version, then the rest.
+// Fields with empty values are left out.
+func createColumnsMetadataElement(projectVersion string) map[string]any {
+	pairs := [][2]string{
+		{tr("Project"), escapeMarkdown(getEnvOrDefault("CI_REPO", ""))},
+		{tr("Branch"), escapeMarkdown(getEnvOrDefault("CI_COMMIT_BRANCH", ""))},
+	}
+	var extra [][2]string
+	for i, field := range authorFields() {
+		if i == 0 {
+			pairs = append(pairs, [2]string{tr(field[0]), authorMentionValue(escapeMarkdown(field[1]), true)})
+			pairs = append(pairs, [2]string{tr("Version"), versionValue(projectVersion, true)})
+		} else {
+			extra = append(extra, [2]string{tr(field[0]), escapeMarkdown(field[1])})
+		}
+	}
+	pairs = append(pairs, extra...)
+	pairs = append(pairs, eventFields(true)...)
+	pairs = append(pairs, [2]string{tr("Duration"), getBuildDuration()})
+	for _, pipeline := range relatedPipelines() {
+		pairs = append(pairs, [2]string{tr(pipeline.Label), pipeline.markdown()})
+	}
+	pairs = append(pairs, [2]string{tr("Matrix"), escapeMarkdown(legMatrix())})
+
+	var fields []map[string]any
+	for _, pair := range pairs {
+		if pair[1] == "" {
+			continue
+		}
+		fields = append(fields, cardField(fmt.Sprintf("**%s:**\n%s", pair[0], pair[1]), true))
+	}
+
+	// Sentences rather than values stay full-width
+	if retry, ok := detectRetry(); ok {
+		fields = append(fields, cardField(retryLine(retry, true), false))
+	}
+	if streak := streakLine(failureStreak); streak != "" {
+		fields = append(fields, cardField(streak, false))
+	}
+
+	return map[string]any{
+		"tag":    "div",
+		"fields": fields,
+	}
+}
+
+// createColumnsVariablesElement renders PLUGIN_VARIABLES as two-column fields
+func createColumnsVariablesElement(variables []variableEntry, showValues bool) map[string]any {
+	var fields []map[string]any
+	for _, variable := range variables {
+		if !showValues {
+			fields = append(fields, cardField(variable.markdownTitle(), true))
+			continue
+		}
+		fields = append(fields, cardField(fmt.Sprintf("**%s:**\n%s", variable.textTitle(), variable.markdownValue()), true))
+	}
+
+	return map[string]any{
+		"tag": "div",
+		"text": map[string]any{
+			"content": fmt.Sprintf("**%s:**", tr("Variables")),
+			"tag":     "lark_md",
+		},
+		"fields": fields,
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCardLayoutList(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"CI_REPO":          "octo/backend",
+		"CI_COMMIT_BRANCH": "main",
+		"CI_COMMIT_AUTHOR": "alice",
+	})
+
+	elements := createLarkCard(Config{}, "v1.0.0")["card"].(map[string]any)["elements"].([]map[string]any)
+	expected := map[string]any{
+		"tag": "div",
+		"text": map[string]any{
+			"content": "**Project:** octo/backend\n**Branch:** main\n**Author:** alice\n**Version:** v1.0.0",
+			"tag":     "lark_md",
+		},
+	}
+	if !reflect.DeepEqual(elements[0], expected) {
+		t.Errorf("Expected %v, got %v", expected, elements[0])
+	}
+}
+
+func TestCardLayoutColumns(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_LAYOUT":     "columns",
+		"DEPLOY_ENV":        "staging",
+		"CI_REPO":           "octo/backend",
+		"CI_COMMIT_BRANCH":  "main",
+		"CI_COMMIT_MESSAGE": "Fix login\n\nDetails",
+	})
+
+	config := Config{Variables: []string{"DEPLOY_ENV", "EMPTY_VAR"}}
+	elements := createLarkCard(config, "v1.0.0")["card"].(map[string]any)["elements"].([]map[string]any)
+
+	// The author is empty and therefore omitted
+	expectedMetadata := map[string]any{
+		"tag": "div",
+		"fields": []map[string]any{
+			cardField("**Project:**\nocto/backend", true),
+			cardField("**Branch:**\nmain", true),
+			cardField("**Version:**\nv1.0.0", true),
+		},
+	}
+	if !reflect.DeepEqual(elements[0], expectedMetadata) {
+		t.Errorf("Expected %v, got %v", expectedMetadata, elements[0])
+	}
+
+	expectedCommit := map[string]any{
+		"tag": "div",
+		"text": map[string]any{
+			"content": "**Commit Message:**\nFix login",
+			"tag":     "lark_md",
+		},
+	}
+	if !reflect.DeepEqual(elements[2], expectedCommit) {
+		t.Errorf("Expected full-width commit message %v, got %v", expectedCommit, elements[2])
+	}
+
+	expectedVariables := map[string]any{
+		"tag": "div",
+		"text": map[string]any{
+			"content": "**Variables:**",
+			"tag":     "lark_md",
+		},
+		"fields": []map[string]any{
+			cardField("**DEPLOY_ENV:**\nstaging", true),
+		},
+	}
+	if !reflect.DeepEqual(elements[4], expectedVariables) {
+		t.Errorf("Expected %v, got %v", expectedVariables, elements[4])
+	}
+}
+
+func TestCardLayoutColumnsRowOrder(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_LAYOUT":             "columns",
+		"CI_REPO":                   "octo/backend",
+		"CI_COMMIT_BRANCH":          "main",
+		"CI_COMMIT_AUTHOR":          "alice",
+		"CI_PIPELINE_EVENT":         "deployment",
+		"CI_PIPELINE_DEPLOY_TARGET": "staging",
+	})
+
+	fields := createColumnsMetadataElement("v1.0.0")["fields"].([]map[string]any)
+	var contents []string
+	for _, field := range fields {
+		contents = append(contents, field["text"].(map[string]any)["content"].(string))
+	}
+	expected := []string{"**Project:**\nocto/backend", "**Branch:**\nmain", "**Author:**\nalice", "**Version:**\nv1.0.0", "**Environment:**\nstaging"}
+	if !reflect.DeepEqual(contents, expected) {
+		t.Errorf("Expected %q, got %q", expected, contents)
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Log formats selected by PLUGIN_LOG_FORMAT
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// logLevels maps PLUGIN_LOG_LEVEL values to slog levels
+var logLevels = map[string]slog.Level{
+	"debug":   slog.LevelDebug,
+	"info":    slog.LevelInfo,
+	"warn":    slog.LevelWarn,
+	"warning": slog.LevelWarn,
+	"error":   slog.LevelError,
+}
+
+// getLogLevel returns the PLUGIN_LOG_LEVEL level. PLUGIN_DEBUG=true selects
+// debug, as it did before log levels existed, and PLUGIN_QUIET=true warn.
+// Debug wins over quiet.
+func getLogLevel() slog.Level {
+	if getEnvOrDefault("PLUGIN_DEBUG", "false") == "true" {
+		return slog.LevelDebug
+	}
+	if isQuiet() {
+		return slog.LevelWarn
+	}
+	if level, ok := logLevels[strings.ToLower(getEnvOrDefault("PLUGIN_LOG_LEVEL", "info"))]; ok {
+		return level
+	}
+	return slog.LevelInfo
+}
+
+// isQuiet reports whether PLUGIN_QUIET hides the informational output. It has
+// no effect with PLUGIN_DEBUG.
+func isQuiet() bool {
+	return getEnvOrDefault("PLUGIN_QUIET", "false") == "true" && getEnvOrDefault("PLUGIN_DEBUG", "false") != "true"
+}
+
+// logger returns a logger for the current settings. Errors go to stderr and
+// everything else to stdout, or to stderr as well in quiet mode.
+func logger() *slog.Logger {
+	level := getLogLevel()
+	var out io.Writer = os.Stdout
+	if isQuiet() {
+		out = os.Stderr
+	}
+	if strings.ToLower(getEnvOrDefault("PLUGIN_LOG_FORMAT", logFormatText)) == logFormatJSON {
+		opts := &slog.HandlerOptions{Level: level}
+		return slog.New(&splitHandler{
+			out: slog.NewJSONHandler(out, opts),
+			err: slog.NewJSONHandler(os.Stderr, opts),
+		})
+	}
+	return slog.New(&textHandler{level: level, out: out, err: os.Stderr})
+}
+
+func logDebug(msg string, args ...any) { logger().Debug(msg, args...) }
+func logInfo(msg string, args ...any)  { logger().Info(msg, args...) }
+func logWarn(msg string, args ...any)  { logger().Warn(msg, args...) }
+func logError(msg string, args ...any) { logger().Error(msg, args...) }
+
+// deliveryAttrs returns the log fields describing a failed delivery
+func deliveryAttrs(target string, err error) []any {
+	attrs := []any{"target", webhookHost(target)}
+	var responseErr *webhookResponseError
+	var apiErr *larkAPIError
+	var providerErr *providerResponseError
+	switch {
+	case errors.As(err, &responseErr):
+		attrs = append(attrs, "http_status", responseErr.StatusCode)
+		if responseErr.Code != 0 {
+			attrs = append(attrs, "lark_code", responseErr.Code)
+		}
+	case errors.As(err, &apiErr):
+		attrs = append(attrs, "lark_code", apiErr.Code)
+	case errors.As(err, &providerErr):
+		attrs = append(attrs, "http_status", providerErr.StatusCode)
+		if providerErr.Code != 0 {
+			attrs = append(attrs, "errcode", providerErr.Code)
+		}
+	}
+	return attrs
+}
+
+// splitHandler sends errors to one handler and all other records to another
+type splitHandler struct {
+	out, err slog.Handler
+}
+
+func (h *splitHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.out.Enabled(ctx, level)
+}
+
+func (h *splitHandler) Handle(ctx context.Context, record slog.Record) error {
+	if record.Level >= slog.LevelError {
+		return h.err.Handle(ctx, record)
+	}
+	return h.out.Handle(ctx, record)
+}
+
+func (h *splitHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &splitHandler{out: h.out.WithAttrs(attrs), err: h.err.WithAttrs(attrs)}
+}
+
+func (h *splitHandler) WithGroup(name string) slog.Handler {
+	return &splitHandler{out: h.out.WithGroup(name), err: h.err.WithGroup(name)}
+}
+
+// textHandler writes the human readable format: the message behind a level
+// prefix ("Warning: ", "Error: "), followed by key=value fields. Multi-line
+// values, such as payloads, are written below the message.
+type textHandler struct {
+	level    slog.Level
+	out, err io.Writer
+	attrs    []slog.Attr
+}
+
+// textLevelPrefixes are the message prefixes of the text format
+var textLevelPrefixes = map[slog.Level]string{
+	slog.LevelDebug: "Debug: ",
+	slog.LevelWarn:  "Warning: ",
+	slog.LevelError: "Error: ",
+}
+
+func (h *textHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *textHandler) Handle(_ context.Context, record slog.Record) error {
+	var line, blocks strings.Builder
+	line.WriteString(textLevelPrefixes[record.Level] + record.Message)
+
+	writeAttr := func(attr slog.Attr) bool {
+		value := attr.Value.Resolve().String()
+		if raw, ok := attr.Value.Any().(json.RawMessage); ok {
+			value = string(raw)
+		}
+		switch {
+		case strings.Contains(value, "\n"):
+			blocks.WriteString("\n" + strings.TrimRight(value, "\n"))
+		case value == "" || strings.ContainsAny(value, " \"="):
+			fmt.Fprintf(&line, " %s=%q", attr.Key, value)
+		default:
+			fmt.Fprintf(&line, " %s=%s", attr.Key, value)
+		}
+		return true
+	}
+	for _, attr := range h.attrs {
+		writeAttr(attr)
+	}
+	record.Attrs(writeAttr)
+
+	w := h.out
+	if record.Level >= slog.LevelError {
+		w = h.err
+	}
+	_, err := fmt.Fprintln(w, line.String()+blocks.String())
+	return err
+}
+
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &textHandler{level: h.level, out: h.out, err: h.err, attrs: append(append([]slog.Attr{}, h.attrs...), attrs...)}
+}
+
+// WithGroup is not used by the plugin; the text format ignores groups
+func (h *textHandler) WithGroup(string) slog.Handler {
+	return h
+}
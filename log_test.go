@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// parseJSONLogs decodes JSON log output, one record per line
+func parseJSONLogs(t *testing.T, output string) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Expected a JSON log line, got %q", line)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestMain_JSONLogs(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code": 19021, "msg": "sign match fail"}`))
+	}))
+	defer testServer.Close()
+
+	originalOsExit := osExit
+	originalStderr := os.Stderr
+	defer func() {
+		osExit = originalOsExit
+		os.Stderr = originalStderr
+	}()
+	osExit = func(code int) {}
+
+	stderrFile, err := os.CreateTemp(t.TempDir(), "stderr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stderr = stderrFile
+
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_WEBHOOK_URL": testServer.URL + "/hook/secret-token",
+		"PLUGIN_LOG_FORMAT":  "json",
+		"CI_REPO":            "octo/backend",
+		"DRONE_BUILD_STATUS": "failure",
+	})
+	stdout := captureStdout(t, main)
+	stderr, _ := os.ReadFile(stderrFile.Name())
+
+	host := strings.TrimPrefix(testServer.URL, "http://")
+	var buildInfo, sending map[string]any
+	for _, record := range parseJSONLogs(t, stdout) {
+		switch record["msg"] {
+		case "Build info":
+			buildInfo = record
+		case "Sending to Lark...":
+			sending = record
+		}
+		if record["level"] == "ERROR" {
+			t.Errorf("Expected errors on stderr only, got %v", record)
+		}
+	}
+	if buildInfo["status"] != "failure" || buildInfo["project"] != "octo/backend" {
+		t.Errorf("Expected build info fields, got %v", buildInfo)
+	}
+	if sending["target"] != host {
+		t.Errorf("Expected the webhook host, got %v", sending)
+	}
+
+	errorRecords := parseJSONLogs(t, string(stderr))
+	if len(errorRecords) != 3 {
+		t.Fatalf("Expected the delivery error, the final error and the exit code, got %s", stderr)
+	}
+	if exit := errorRecords[2]["msg"]; exit != "exiting with code 4 (rejected by the chat service)" {
+		t.Errorf("Expected the exit code and its category, got %v", exit)
+	}
+	delivery := errorRecords[0]
+	if delivery["level"] != "ERROR" || delivery["target"] != host || delivery["http_status"] != 400.0 || delivery["lark_code"] != 19021.0 {
+		t.Errorf("Expected the delivery error fields, got %v", delivery)
+	}
+	if !strings.Contains(delivery["msg"].(string), "sign match fail") {
+		t.Errorf("Expected the Lark response in the error, got %v", delivery)
+	}
+	if strings.Contains(stdout+string(stderr), "secret-token") {
+		t.Error("Expected the webhook token not to be logged")
+	}
+}
+
+func TestLogLevels(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected []string
+		hidden   []string
+	}{
+		{
+			name:     "Default",
+			env:      map[string]string{},
+			expected: []string{"info message", "Warning: warn message"},
+			hidden:   []string{"debug message"},
+		},
+		{
+			name:     "Warn",
+			env:      map[string]string{"PLUGIN_LOG_LEVEL": "warn"},
+			expected: []string{"Warning: warn message"},
+			hidden:   []string{"debug message", "info message"},
+		},
+		{
+			name:     "PLUGIN_DEBUG",
+			env:      map[string]string{"PLUGIN_DEBUG": "true", "PLUGIN_LOG_LEVEL": "error"},
+			expected: []string{"Debug: debug message key=value", "info message", "Warning: warn message"},
+		},
+		{
+			name:     "Quiet",
+			env:      map[string]string{"PLUGIN_QUIET": "true"},
+			expected: []string{"Warning: warn message"},
+			hidden:   []string{"debug message", "info message"},
+		},
+		{
+			name:     "Debug wins over quiet",
+			env:      map[string]string{"PLUGIN_QUIET": "true", "PLUGIN_DEBUG": "true"},
+			expected: []string{"Debug: debug message key=value", "info message"},
+		},
+		{
+			name:     "Unknown level",
+			env:      map[string]string{"PLUGIN_LOG_LEVEL": "verbose"},
+			expected: []string{"info message"},
+			hidden:   []string{"debug message"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnvFixture(t, tt.env)
+			output := captureOutput(t, func() {
+				logDebug("debug message", "key", "value")
+				logInfo("info message")
+				logWarn("warn message")
+			})
+
+			for _, expected := range tt.expected {
+				if !strings.Contains(output, expected) {
+					t.Errorf("Expected %q in %q", expected, output)
+				}
+			}
+			for _, hidden := range tt.hidden {
+				if strings.Contains(output, hidden) {
+					t.Errorf("Expected %q to be filtered from %q", hidden, output)
+				}
+			}
+		})
+	}
+}
+
+func TestMain_Quiet(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code": 0}`))
+	}))
+	defer testServer.Close()
+
+	originalOsExit := osExit
+	defer func() { osExit = originalOsExit }()
+	exitCode := 0
+	osExit = func(code int) { exitCode = code }
+
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_WEBHOOK_URL": testServer.URL,
+		"PLUGIN_QUIET":       "true",
+		"PLUGIN_TIMEZONE":    "",
+		"CI_REPO":            "octo/backend",
+		"DRONE_BUILD_STATUS": "success",
+	})
+
+	if stdout := captureStdout(t, main); stdout != "" {
+		t.Errorf("Expected no output in quiet mode, got:\n%s", stdout)
+	}
+	if exitCode != 0 {
+		t.Errorf("Expected exit code 0, got %d", exitCode)
+	}
+
+	// Warnings still go out, to stderr
+	os.Setenv("PLUGIN_TIMEZONE", "Mars/Olympus")
+	if stdout := captureStdout(t, main); stdout != "" {
+		t.Errorf("Expected warnings on stderr only, got stdout:\n%s", stdout)
+	}
+	if output := captureOutput(t, main); !strings.Contains(output, "Warning: invalid PLUGIN_TIMEZONE") {
+		t.Errorf("Expected the warning in the output, got:\n%s", output)
+	}
+}
+
+func TestTextHandler(t *testing.T) {
+	output := captureOutput(t, func() {
+		logInfo("Lark message JSON", "payload", json.RawMessage("{\n  \"msg_type\": \"text\"\n}"), "target", "open.feishu.cn", "note", "two words")
+	})
+	expected := "Lark message JSON target=open.feishu.cn note=\"two words\"\n{\n  \"msg_type\": \"text\"\n}\n"
+	if output != expected {
+		t.Errorf("Expected %q, got %q", expected, output)
+	}
+}
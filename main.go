@@ -1,207 +1,448 @@
 package main
 
 import (
-	"bytes"
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/base64"
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"io"
+	"maps"
 	"net/http"
 	"os"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
+
+	"ci-lark-notification/pkg/lark"
 )
 
 // osExit is a variable for os.Exit that can be overridden in tests
 var osExit = os.Exit
 
 func main() {
-	webhookURL := getEnvOrDefault("PLUGIN_WEBHOOK_URL", "")
-	if webhookURL == "" {
-		fmt.Println("Need to set Lark Webhook URL")
-		osExit(1)
+	if len(os.Args) > 1 && os.Args[1] == "history" {
+		osExit(runHistoryCommand(os.Args[2:]))
+		return
+	}
+	if err := parseFlags(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		printError(err)
+		osExit(2)
+		return
+	}
+	if getEnvOrDefault("PLUGIN_PRINT_VERSION", "false") == "true" {
+		fmt.Println(versionString())
+		return
+	}
+
+	err := run()
+	if resultErr := writeResults(); resultErr != nil {
+		logWarn(resultErr.Error())
+	}
+	if err != nil {
+		printError(err)
+		code, category := exitCode(err)
+		if getEnvOrDefault("PLUGIN_FAIL_ON_ERROR", "true") == "true" {
+			logError(fmt.Sprintf("exiting with code %d (%v)", code, category))
+			osExit(code)
+			return
+		}
+		logWarn(fmt.Sprintf("the notification failed (%v), but PLUGIN_FAIL_ON_ERROR is false so the pipeline continues", category))
+	}
+}
+
+// printError prints each error joined into err on its own line
+func printError(err error) {
+	if classified, ok := err.(*classifiedError); ok {
+		printError(classified.err)
+		return
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, err := range joined.Unwrap() {
+			printError(err)
+		}
+		return
+	}
+	logError(err.Error())
+}
+
+// run sends the notification. Errors that notify did not classify as delivery
+// failures are configuration errors.
+func run() error {
+	err := notify()
+	if err == nil || errors.Is(err, errPartialDelivery) || errors.Is(err, errRejected) || errors.Is(err, errNetwork) {
+		return err
+	}
+	return classify(err, errConfiguration)
+}
+
+// notify validates the settings, then builds and sends the message. Delivery
+// errors are printed as they happen; main decides whether they fail the step.
+func notify() (err error) {
+	deliveryResults = nil
+	quietMentionsMuted = false
+	applyCIEnvironment()
+	if err := loadFileSettings(); err != nil {
+		return err
+	}
+	loadEnvFile()
+
+	// Every run from here on is recorded, including the skipped ones
+	var run historyRun
+	defer func() {
+		run.Err = err
+		recordHistory(run)
+	}()
+
+	config, err := LoadConfig(func(name string) string { return getEnvOrDefault(name, "") })
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	provider := getProvider(config)
+	warnWebhookURLs(config)
+	warnUnknownButtons(config.Buttons)
+
+	if config.Quiet && config.Debug {
+		logDebug("PLUGIN_QUIET is ignored because PLUGIN_DEBUG is enabled")
+	}
+
+	if err := configureHTTPClients(); err != nil {
+		return err
 	}
 
 	projectVersion := getProjectVersion()
 
-	// Check if using signature verification
-	secret := getEnvOrDefault("PLUGIN_SECRET", "")
-	useCard := getEnvOrDefault("PLUGIN_USE_CARD", "true") == "true"
+	// Content files must all be readable in strict mode
+	if config.Strict {
+		if _, errs := loadContentSections(); len(errs) > 0 {
+			return errors.Join(errs...)
+		}
+		if err := checkCustomMessage(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := parseCustomButtons(); err != nil {
+		return err
+	}
+	if _, err := parseCustomFields(); err != nil {
+		return err
+	}
 
-	var message map[string]any
-	if useCard {
-		message = createLarkCard(projectVersion)
-	} else {
-		message = createLarkTextMessage(projectVersion)
+	if err := loadTitleTemplate(); err != nil {
+		return err
 	}
 
-	// Add signature if secret is provided
-	if secret != "" {
-		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
-		sign := generateSignature(timestamp, secret)
-		message["timestamp"] = timestamp
-		message["sign"] = sign
+	if err := loadCardTemplate(); err != nil {
+		return err
 	}
 
-	messageBytes, err := json.Marshal(message)
+	if err := loadTextTemplate(); err != nil {
+		return err
+	}
+
+	prebuiltMessage, err := loadPayloadFile()
 	if err != nil {
-		fmt.Printf("Error creating message JSON: %v\n", err)
-		osExit(1)
+		return err
 	}
 
-	if getEnvOrDefault("PLUGIN_DEBUG", "false") == "true" {
-		printDebugInfo(messageBytes)
+	failureStreak = updateFailureStreak()
+	buildCoverage = loadCoverage()
+	buildDiffStat = loadDiffStat()
+	buildChangelog = loadChangelog()
+	buildImages = loadBuildImages()
+	buildSteps = loadPipelineSteps()
+
+	// Routed once the status and its transition are known
+	webhookURLs := append(phaseWebhookURLs(routeWebhookURLs(config)), chatTargets()...)
+	if len(webhookURLs) == 0 && !config.DryRun {
+		printBuildInfo(projectVersion)
+		logInfo("Skipping notification: no webhook for this status", "status", getBuildStatus(), "setting", statusWebhookPrefix+statusSettingSuffix(getBuildStatus()))
+		run.SkipReason = "no webhook for this status"
+		return nil
+	}
+	if len(webhookURLs) == 0 {
+		// A dry run without targets still builds the message once
+		webhookURLs = []string{""}
+	}
+	run.Targets = webhookURLs
+
+	// A dry run shows this leg without taking part in the aggregation
+	if !config.DryRun {
+		send, err := aggregateLegs()
+		if err != nil {
+			return err
+		}
+		if !send {
+			skipTargets(webhookURLs, "matrix aggregation")
+			run.SkipReason = "matrix aggregation"
+			return nil
+		}
+	}
+	// Combined matrix notifications report the status of all legs
+	config.Status = getBuildStatus()
+
+	reason := notifySkipReason()
+	if reason == "" {
+		reason = branchSkipReason()
+	}
+	if reason == "" {
+		reason, quietMentionsMuted = quietHoursDecision(timeNow())
+	}
+	if reason != "" {
+		printBuildInfo(projectVersion)
+		logInfo("Skipping notification: "+reason, "status", getBuildStatus())
+		skipTargets(webhookURLs, reason)
+		run.SkipReason = reason
+		return nil
+	}
+
+	if quietMentionsMuted {
+		logInfo("Sending without mentions during quiet hours", "status", getBuildStatus())
+	}
+	authorOpenID = resolveAuthorOpenID(ctx)
+	cardImageKey = resolveCardImageKey(ctx, config)
+	noteBuilderTemplateOverrides()
+
+	// Public targets get their own build of the message
+	targetURLs := make([]string, len(webhookURLs))
+	targetPublic := make([]bool, len(webhookURLs))
+	payloads := map[bool][]byte{}
+	for i, entry := range webhookURLs {
+		targetURLs[i], targetPublic[i] = parseWebhookTarget(entry)
+		if _, ok := payloads[targetPublic[i]]; ok {
+			continue
+		}
+
+		setPublicMode(targetPublic[i], config.Variables)
+		message, err := provider.buildMessage(config, projectVersion, prebuiltMessage)
+		setPublicMode(false, nil)
+		if err != nil {
+			return err
+		}
+
+		messageBytes, err := json.Marshal(message)
+		if err != nil {
+			return fmt.Errorf("creating message JSON: %v", err)
+		}
+		payloads[targetPublic[i]] = messageBytes
+	}
+	messageBytes := payloads[targetPublic[0]]
+
+	// What each target is sent, signed with its own secret
+	signedAt := timeNow()
+	sent := make([][]byte, len(targetURLs))
+	for i, target := range targetURLs {
+		sent[i] = provider.payloadFor(target, payloads[targetPublic[i]], signedAt)
+	}
+	run.Targets, run.MessageBytes = targetURLs, sent[0]
+
+	printDebugInfo(messageBytes)
+	if public, ok := payloads[true]; ok && !targetPublic[0] {
+		logDebug("Lark message JSON (public targets)", "payload", json.RawMessage(public))
 	}
 
 	printBuildInfo(projectVersion)
-	
-	// Only send message if webhook URL is provided
-	if webhookURL != "" {
-		sendMessage(webhookURL, messageBytes)
+
+	outputErr := writeOutputFiles(sent)
+	if config.DryRun {
+		printDryRun(targetURLs, targetPublic, sent)
+		skipTargets(targetURLs, "dry run")
+		run.SkipReason = "dry run"
+		return outputErr
+	}
+
+	loadPhaseState()
+	loadDedupeState()
+	var sendErrors []error
+	for i, webhookURL := range targetURLs {
+		if isDuplicateNotification(webhookURL) {
+			logInfo("duplicate notification suppressed", "target", webhookHost(webhookURL))
+			skipTargets([]string{webhookURL}, "duplicate notification")
+			run.Duplicates = append(run.Duplicates, webhookURL)
+			continue
+		}
+		deliveryAttempts = 0
+		start := time.Now()
+		err := provider.deliver(ctx, webhookURL, payloads[targetPublic[i]])
+		deliveryResults = append(deliveryResults, newDeliveryResult(webhookURL, sent[i], time.Since(start), err))
+		if err != nil {
+			logError(err.Error(), deliveryAttrs(webhookURL, err)...)
+			sendErrors = append(sendErrors, err)
+			continue
+		}
+		recordNotification(webhookURL)
+	}
+
+	saveDedupeState()
+	run.Delivered, run.SendErrors = true, sendErrors
+	if err := savePhaseState(); err != nil {
+		outputErr = errors.Join(outputErr, err)
+	}
+
+	if len(sendErrors) > 0 {
+		deliveryErr := fmt.Errorf("delivery failed for %d of %d targets", len(sendErrors), len(targetURLs))
+		return classify(errors.Join(outputErr, deliveryErr), deliveryFailureCategory(sendErrors, len(targetURLs)))
+	}
+	return outputErr
+}
+
+// buildMessage builds the message in the form selected by the configuration:
+// the prebuilt payload, a card builder template, a card template, the
+// built-in card, a rich text post or a text message
+func buildMessage(config Config, projectVersion string, prebuilt map[string]any) (map[string]any, error) {
+	switch {
+	case prebuilt != nil:
+		// Copied so that each payload gets its own signature
+		return maps.Clone(prebuilt), nil
+	case getCardTemplateID() != "":
+		return createBuilderTemplateCard(config, projectVersion), nil
+	case config.UseCard && cardTemplate != nil:
+		return renderTemplateCard(config, projectVersion)
+	case config.UseCard:
+		return createLarkCard(config, projectVersion), nil
+	case config.MsgType == msgTypePost:
+		return createLarkPostMessage(config, projectVersion), nil
+	default:
+		return createLarkTextMessage(config, projectVersion), nil
 	}
 }
 
+// signMessage adds the timestamp of now and the signature when a secret is configured
+func signMessage(message map[string]any, secret string, now time.Time) {
+	if secret == "" {
+		return
+	}
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	message["timestamp"] = timestamp
+	message["sign"] = generateSignature(timestamp, secret)
+}
+
 func generateSignature(timestamp, secret string) string {
-	stringToSign := fmt.Sprintf("%s\n%s", timestamp, secret)
-	h := hmac.New(sha256.New, []byte(stringToSign))
-	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+	return lark.Sign(timestamp, secret)
 }
 
+// defaultSHALength is how many characters of the commit SHA are shown
+const defaultSHALength = 7
+
+// unknownVersion is shown when nothing identifies the build
+const unknownVersion = "unknown"
+
+// getProjectVersion returns what identifies the build: the tag, the
+// PLUGIN_VERSION override, the short commit SHA, the pipeline number or
+// unknownVersion
 func getProjectVersion() string {
 	if tag := getEnvOrDefault("CI_COMMIT_TAG", ""); tag != "" {
 		return tag
 	}
-	if sha := getEnvOrDefault("CI_COMMIT_SHA", ""); sha != "" {
-		return sha[:7]
+	if version := getEnvOrDefault("PLUGIN_VERSION", ""); version != "" {
+		return version
+	}
+	if sha := shortSHA(getEnvOrDefault("CI_COMMIT_SHA", "")); sha != "" {
+		return sha
+	}
+	if number := getPipelineNumber(); number != "" {
+		return "build #" + number
 	}
-	return ""
+	return unknownVersion
 }
 
-func createLarkCard(projectVersion string) map[string]any {
-	// Allow overriding build status via plugin settings
-	status := getEnvOrDefault("PLUGIN_STATUS", getEnvOrDefault("DRONE_BUILD_STATUS", ""))
-
-	var headerColor, statusIcon, statusText string
-	if status == "failure" {
-		headerColor = "red"
-		statusIcon = "🚨"
-		statusText = "Pipeline Failed"
-	} else {
-		headerColor = "green"
-		statusIcon = "✅"
-		statusText = "Pipeline Succeeded"
+// shortSHA returns the first PLUGIN_SHA_LENGTH characters of sha, or "" when
+// sha is not a hexadecimal commit id
+func shortSHA(sha string) string {
+	sha = strings.TrimSpace(sha)
+	if sha == "" || strings.Trim(strings.ToLower(sha), "0123456789abcdef") != "" {
+		return ""
 	}
+	return sha[:min(getSHALength(), len(sha))]
+}
 
-	elements := []map[string]any{
-		{
-			"tag": "div",
-			"text": map[string]any{
-				"content": fmt.Sprintf("**Project:** %s\n**Branch:** %s\n**Author:** %s\n**Version:** %s",
-					getEnvOrDefault("CI_REPO", ""),
-					getEnvOrDefault("CI_COMMIT_BRANCH", ""),
-					getEnvOrDefault("CI_COMMIT_AUTHOR", ""),
-					projectVersion),
-				"tag": "lark_md",
-			},
-		},
-		{
-			"tag": "hr",
-		},
-		{
-			"tag": "div",
-			"text": map[string]any{
-				"content": fmt.Sprintf("**Commit Message:**\n%s",
-					strings.Split(getEnvOrDefault("CI_COMMIT_MESSAGE", ""), "\n")[0]),
-				"tag": "lark_md",
-			},
-		},
+func getSHALength() int {
+	value := getEnvOrDefault("PLUGIN_SHA_LENGTH", "")
+	if value == "" {
+		return defaultSHALength
 	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		logWarn(fmt.Sprintf("invalid PLUGIN_SHA_LENGTH %q, using %d", value, defaultSHALength))
+		return defaultSHALength
+	}
+	return n
+}
 
-	// Add variables if specified
-	if variables := getEnvOrDefault("PLUGIN_VARIABLES", ""); variables != "" {
-		elements = append(elements, map[string]any{
-			"tag": "hr",
-		})
-
-		varContent := "**Variables:**\n"
-		for _, varName := range strings.Split(variables, ",") {
-			varName = strings.TrimSpace(varName)
-			varContent += fmt.Sprintf("• `%s`: %s\n", varName, getEnvOrDefault(varName, ""))
-		}
-
-		elements = append(elements, map[string]any{
-			"tag": "div",
-			"text": map[string]any{
-				"content": varContent,
-				"tag": "lark_md",
-			},
-		})
+// getBuildStatus returns the pipeline status, allowing an override via plugin
+// settings. The start phase reports the pipeline as running, and combined
+// matrix notifications the status of all legs.
+func getBuildStatus() string {
+	if isAggregated() {
+		return aggregateStatus(aggregatedLegs)
 	}
+	return resolveStatus(func(name string) string { return getEnvOrDefault(name, "") })
+}
 
-	// Add action buttons
-	actions := createActionButtons()
-	if len(actions) > 0 {
-		elements = append(elements, map[string]any{
-			"tag": "action",
-			"actions": actions,
-		})
+// resolveStatus returns PLUGIN_STATUS, "running" in the start phase or
+// DRONE_BUILD_STATUS, read through getenv
+func resolveStatus(getenv func(string) string) string {
+	status := getenv("PLUGIN_STATUS")
+	if status == "" && getenv("PLUGIN_PHASE") == phaseStart {
+		return "running"
 	}
+	if status == "" {
+		status = getenv("DRONE_BUILD_STATUS")
+	}
+	// GitHub Actions spells job.status "cancelled"
+	if status == "cancelled" {
+		return "canceled"
+	}
+	return status
+}
 
-	projectName := getEnvOrDefault("CI_REPO_NAME", "")
-	headerTitle := fmt.Sprintf("%s - %s %s", projectName, statusIcon, statusText)
+// buildLarkCard builds the card for currentLocale
+func buildLarkCard(config Config, projectVersion string) map[string]any {
+	return cardMessage(lark.NewBuildCard(buildInfo(config, projectVersion)))
+}
 
-	return map[string]any{
-		"msg_type": "interactive",
-		"card": map[string]any{
-			"header": map[string]any{
-				"title": map[string]any{
-					"content": headerTitle,
-					"tag": "plain_text",
-				},
-				"template": headerColor,
-			},
-			"elements": elements,
-		},
+// cardHeaderTitle returns the header title of full and minimal cards
+func cardHeaderTitle(config Config, projectVersion, statusIcon, statusText string) string {
+	projectName := headerProjectName()
+	headerTitle := fmt.Sprintf("%s%s - %s%s%s", retryBadge(), projectName, iconText(statusIcon, statusText), eventTitleSuffix(), matrixTitleSuffix())
+	if target := deployTarget(); target != "" {
+		headerTitle = fmt.Sprintf("%s%s %s%s", retryBadge(), deployHeaderProject(projectName, target), iconText(statusIcon, statusText), matrixTitleSuffix())
+	}
+	if title, ok := customTitle(config, projectVersion, statusText); ok {
+		headerTitle = title
 	}
+	return truncateRunes(headerTitle, maxHeaderTitleLength)
 }
 
-func createLarkTextMessage(projectVersion string) map[string]any {
-	// Allow overriding build status via plugin settings
-	status := getEnvOrDefault("PLUGIN_STATUS", getEnvOrDefault("DRONE_BUILD_STATUS", ""))
+func createLarkTextMessage(config Config, projectVersion string) map[string]any {
+	style := getStatusStyle(config.Status)
+	currentLocale = getLocales()[0]
+	defer func() { currentLocale = defaultLocale }()
 
-	var statusIcon, statusText string
-	if status == "failure" {
-		statusIcon = "🚨"
-		statusText = "PIPELINE FAILED"
-	} else {
-		statusIcon = "✅"
-		statusText = "PIPELINE SUCCEEDED"
-	}
-
-	message := fmt.Sprintf("%s %s\n\n", statusIcon, statusText)
-	message += fmt.Sprintf("📋 Project: %s\n", getEnvOrDefault("CI_REPO", ""))
-	message += fmt.Sprintf("🌿 Branch: %s\n", getEnvOrDefault("CI_COMMIT_BRANCH", ""))
-	message += fmt.Sprintf("👤 Author: %s\n", getEnvOrDefault("CI_COMMIT_AUTHOR", ""))
-	message += fmt.Sprintf("🏷️ Version: %s\n", projectVersion)
-	message += fmt.Sprintf("💬 Message: %s\n", strings.Split(getEnvOrDefault("CI_COMMIT_MESSAGE", ""), "\n")[0])
-
-	// Add variables if specified
-	if variables := getEnvOrDefault("PLUGIN_VARIABLES", ""); variables != "" {
-		message += "\n📊 Variables:\n"
-		for _, varName := range strings.Split(variables, ",") {
-			varName = strings.TrimSpace(varName)
-			message += fmt.Sprintf("• %s: %s\n", varName, getEnvOrDefault(varName, ""))
+	statusIcon, statusText := style.Icon, style.upperStatusText()
+
+	if text, ok := customText(config, projectVersion); ok {
+		return map[string]any{
+			"msg_type": "text",
+			"content": map[string]any{
+				"text": text,
+			},
 		}
 	}
-
-	// Add links
-	if pipelineURL := getEnvOrDefault("CI_PIPELINE_URL", ""); pipelineURL != "" {
-		message += fmt.Sprintf("\n🔗 Pipeline: %s", pipelineURL)
+	if config.Compact {
+		return createCompactLarkTextMessage(config, projectVersion, statusIcon, statusText)
 	}
+	if isMinimalDetail(config.Status) {
+		return createMinimalLarkTextMessage(config, projectVersion, statusIcon, statusText)
+	}
+
+	message := composeText(sectionInput{Config: config, ProjectVersion: projectVersion, Style: style, MentionAuthor: true}, false)
 
 	return map[string]any{
 		"msg_type": "text",
@@ -211,28 +452,55 @@ func createLarkTextMessage(projectVersion string) map[string]any {
 	}
 }
 
-func createActionButtons() []map[string]any {
-	var actions []map[string]any
+// textMessageTitle returns the first line of text and post messages
+func textMessageTitle(config Config, projectVersion, statusIcon, statusText string) string {
+	if title, ok := customTitle(config, projectVersion, statusText); ok {
+		return title
+	}
+	return fmt.Sprintf("%s%s%s%s", retryBadge(), iconText(statusIcon, statusText), eventTitleSuffix(), matrixTitleSuffix())
+}
+
+// textMessageDetails returns the build details of text and post messages, one
+// per line. mentionAuthor adds the at-tag of the author, which post messages
+// add as an element instead.
+func textMessageDetails(config Config, projectVersion string, mentionAuthor bool) string {
+	return composeText(sectionInput{Config: config, ProjectVersion: projectVersion, Style: getStatusStyle(config.Status), MentionAuthor: mentionAuthor}, true)
+}
+
+// createActionButtons returns the card buttons selected and ordered by
+// PLUGIN_BUTTONS, all of them by default
+func createActionButtons(config Config) []map[string]any {
+	return selectButtons(config.Buttons, append(builtinButtons(config.Status), customButtonActions()...))
+}
+
+// builtinButtons returns the built-in buttons that apply to a build with
+// status, in their default order
+func builtinButtons(status string) []actionButton {
+	var buttons []actionButton
 
 	// Pipeline button
 	if pipelineURL := getEnvOrDefault("CI_PIPELINE_URL", ""); pipelineURL != "" {
-		actions = append(actions, map[string]any{
+		buttons = append(buttons, actionButton{ID: buttonPipeline, Action: map[string]any{
 			"tag": "button",
 			"text": map[string]any{
-				"content": "View Pipeline",
+				"content": deploymentLabel("View Pipeline"),
 				"tag": "plain_text",
 			},
 			"type": "primary",
 			"url": pipelineURL,
-		})
+		}})
+	}
+
+	if button := createFailedStepButton(status); button != nil {
+		buttons = append(buttons, actionButton{ID: buttonFailedStep, Action: button})
 	}
 
 	// Commit/Release button
 	if tag := getEnvOrDefault("CI_COMMIT_TAG", ""); tag != "" {
 		// Release button
 		if repoURL := getEnvOrDefault("CI_REPO_URL", ""); repoURL != "" {
-			releaseURL := fmt.Sprintf("%s/releases/tag/%s", repoURL, tag)
-			actions = append(actions, map[string]any{
+			releaseURL := forgeReleaseURL(repoURL, tag)
+			buttons = append(buttons, actionButton{ID: buttonRelease, Action: map[string]any{
 				"tag": "button",
 				"text": map[string]any{
 					"content": "View Release",
@@ -240,12 +508,12 @@ func createActionButtons() []map[string]any {
 				},
 				"type": "default",
 				"url": releaseURL,
-			})
+			}})
 		}
 	} else {
 		// Commit button
 		if commitURL := getEnvOrDefault("CI_PIPELINE_FORGE_URL", ""); commitURL != "" {
-			actions = append(actions, map[string]any{
+			buttons = append(buttons, actionButton{ID: buttonCommit, Action: map[string]any{
 				"tag": "button",
 				"text": map[string]any{
 					"content": "View Commit",
@@ -253,96 +521,139 @@ func createActionButtons() []map[string]any {
 				},
 				"type": "default",
 				"url": commitURL,
-			})
+			}})
 		}
 	}
 
-	// Filter buttons based on PLUGIN_BUTTONS if specified
-	requestedButtons := getEnvOrDefault("PLUGIN_BUTTONS", "")
-	if requestedButtons != "" {
-		var filteredActions []map[string]any
-		buttonNames := strings.Split(requestedButtons, ",")
-
-		for _, name := range buttonNames {
-			name = strings.TrimSpace(name)
-			for _, action := range actions {
-				if text, ok := action["text"].(map[string]any); ok {
-					if content, ok := text["content"].(string); ok {
-						if (name == "pipeline" && strings.Contains(content, "Pipeline")) ||
-						   (name == "commit" && strings.Contains(content, "Commit")) ||
-						   (name == "release" && strings.Contains(content, "Release")) {
-							filteredActions = append(filteredActions, action)
-							break
-						}
-					}
-				}
-			}
+	// Pull request button
+	if getPipelineEvent() == "pull_request" {
+		if _, prURL := pullRequestRef(); prURL != "" {
+			buttons = append(buttons, actionButton{ID: buttonPullRequest, Action: map[string]any{
+				"tag": "button",
+				"text": map[string]any{
+					"content": "View Pull Request",
+					"tag":     "plain_text",
+				},
+				"type": "default",
+				"url":  prURL,
+			}})
 		}
-		return filteredActions
 	}
 
-	return actions
+	// Parent pipeline button
+	if _, parentURL := getParentPipeline(); parentURL != "" {
+		buttons = append(buttons, actionButton{ID: buttonParent, Action: map[string]any{
+			"tag": "button",
+			"text": map[string]any{
+				"content": "View Parent",
+				"tag":     "plain_text",
+			},
+			"type": "default",
+			"url":  parentURL,
+		}})
+	}
+
+	return buttons
 }
 
 func printBuildInfo(projectVersion string) {
-	fmt.Println("\nBuild Info:")
-	fmt.Printf(" PROJECT: %s\n", getEnvOrDefault("CI_REPO", ""))
-	fmt.Printf(" BRANCH:  %s\n", getEnvOrDefault("CI_COMMIT_BRANCH", ""))
-	fmt.Printf(" VERSION: %s\n", projectVersion)
-	fmt.Printf(" STATUS:  %s\n", getEnvOrDefault("DRONE_BUILD_STATUS", ""))
-	fmt.Printf(" DATE:    %s\n", time.Now().UTC().Format(time.RFC3339))
+	logInfo("Build info",
+		"project", getEnvOrDefault("CI_REPO", ""),
+		"branch", getEnvOrDefault("CI_COMMIT_BRANCH", ""),
+		"version", projectVersion,
+		"status", getEnvOrDefault("DRONE_BUILD_STATUS", ""),
+		"date", formatTimestamp(timeNow()))
 }
 
-func sendMessage(webhookURL string, messageBytes []byte) {
-	fmt.Println("\nSending to Lark...")
-
-	resp, err := http.Post(webhookURL, "application/json", bytes.NewReader(messageBytes))
-	if err != nil {
-		fmt.Printf("Error sending to Lark: %v\n", err)
-		osExit(1)
-	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("Error response from Lark: %s\n", string(body))
-		osExit(1)
+// webhookResponseError is a webhook response with an HTTP error status or a
+// non-zero Lark code
+type webhookResponseError = lark.ResponseError
+
+// deliverMessage posts the message to a webhook with webhookClient, signed
+// with the secret of the webhook or secret, and reports any transport, HTTP or
+// Lark API error. Cancelling ctx aborts the request.
+func deliverMessage(ctx context.Context, webhookURL string, messageBytes []byte, secret string) error {
+	logInfo("Sending to Lark...", "target", webhookHost(webhookURL))
+
+	client := &lark.Client{HTTPClient: webhookClient, PrepareRequest: signGatewayRequest}
+	deliveryAttempts++
+	payload, signed := signPayload(webhookURL, messageBytes, secret, timeNow())
+	err := client.Send(ctx, webhookURL, payload)
+	var responseErr *webhookResponseError
+	if errors.As(err, &responseErr) && responseErr.IsSignatureError() && signed {
+		logWarn("Lark rejected the signature, retrying with a fresh timestamp", "target", webhookHost(webhookURL))
+		deliveryAttempts++
+		payload, _ = signPayload(webhookURL, messageBytes, secret, timeNow())
+		err = client.Send(ctx, webhookURL, payload)
 	}
-
-	// Parse response to check if successful
-	var response map[string]any
-	if err := json.Unmarshal(body, &response); err == nil {
-		if code, ok := response["code"].(float64); ok && code != 0 {
-			fmt.Printf("Lark API error: %v\n", response)
-			osExit(1)
+	if err != nil {
+		if errors.As(err, &responseErr) {
+			if responseErr.IsSignatureError() {
+				return &signatureError{err: responseErr, signed: signed}
+			}
+			return err
 		}
+		return fmt.Errorf("error sending to Lark: %w", err)
 	}
 
-	fmt.Println("Done!")
+	logInfo("Done!", "target", webhookHost(webhookURL), "http_status", http.StatusOK)
+	return nil
 }
 
 func getEnvOrDefault(key, defaultValue string) string {
+	if publicMode && isPublicHidden(key) {
+		return defaultValue
+	}
+	if value := flagSettings[key]; value != "" {
+		return value
+	}
+	if value := fileSettings[key]; value != "" {
+		return value
+	}
+	if value := envFile[key]; value != "" && isEnvFileOverride() {
+		return value
+	}
+	if value := ciEnv[key]; value != "" {
+		return value
+	}
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
+	if value := actionInput(key); value != "" {
+		return value
+	}
+	if value := envFile[key]; value != "" {
+		return value
+	}
+	if alias := settingAliases[key]; alias != "" {
+		return getEnvOrDefault(alias, defaultValue)
+	}
 	return defaultValue
 }
 
+// printDebugInfo logs the message at debug level. PLUGIN_DEBUG adds the
+// environment, with secrets redacted, and the proxy in use.
 func printDebugInfo(messageBytes []byte) {
-	fmt.Println("\n** DEBUG ENABLED **")
-	fmt.Println("\nEnvironment Variables:")
-
-	envVars := os.Environ()
-	sort.Strings(envVars)
-
-	for _, env := range envVars {
-		parts := strings.SplitN(env, "=", 2)
-		if len(parts) == 2 {
-			fmt.Printf(" %-30s = %s\n", parts[0], parts[1])
+	if getEnvOrDefault("PLUGIN_DEBUG", "false") == "true" {
+		logDebug("** DEBUG ENABLED **")
+
+		envVars := os.Environ()
+		sort.Strings(envVars)
+
+		for _, env := range envVars {
+			parts := strings.SplitN(env, "=", 2)
+			if len(parts) == 2 {
+				if isSensitiveSetting(parts[0]) || isMaskedVariable(parts[0]) {
+					parts[1] = "[REDACTED]"
+				} else if strings.HasSuffix(strings.ToUpper(parts[0]), "_PROXY") {
+					parts[1] = redactProxyURL(parts[1])
+				}
+				logDebug("Environment variable", "name", parts[0], "value", parts[1])
+			}
 		}
+
+		logDebug("Proxy: " + proxyDescription())
 	}
 
-	fmt.Println("\nLark Message JSON:")
-	fmt.Println(string(messageBytes))
+	logDebug("Lark message JSON", "payload", json.RawMessage(messageBytes))
 }
@@ -1,14 +1,32 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 )
 
+func TestMain(m *testing.M) {
+	// main parses the plugin flags from os.Args, which must not see the test flags
+	flag.Parse()
+	os.Args = os.Args[:1]
+	// The CI running the tests must not be mistaken for the build they describe
+	for _, entry := range os.Environ() {
+		if name, _, _ := strings.Cut(entry, "="); isCIProviderVariable(name) {
+			os.Unsetenv(name)
+		}
+	}
+	os.Exit(m.Run())
+}
+
 func TestGetEnvOrDefault(t *testing.T) {
 	// Test with existing env var
 	os.Setenv("TEST_VAR", "test_value")
@@ -90,7 +108,7 @@ func TestCreateLarkCard_StatusOverride(t *testing.T) {
 			}()
 			
 			// Call the function
-			card := createLarkCard("v1.0.0")
+			card := createLarkCard(Config{Status: resolveStatus(os.Getenv)}, "v1.0.0")
 			
 			// Extract and verify the header color
 			cardObj, ok := card["card"].(map[string]any)
@@ -170,7 +188,7 @@ func TestCreateLarkTextMessage_StatusOverride(t *testing.T) {
 			}()
 			
 			// Call the function
-			message := createLarkTextMessage("v1.0.0")
+			message := createLarkTextMessage(Config{Status: resolveStatus(os.Getenv)}, "v1.0.0")
 			
 			// Extract and verify the message content
 			contentObj, ok := message["content"].(map[string]any)
@@ -212,8 +230,41 @@ func TestGetProjectVersion(t *testing.T) {
 	
 	// Test with no env vars
 	os.Unsetenv("CI_COMMIT_SHA")
-	if version := getProjectVersion(); version != "" {
-		t.Errorf("Expected empty string, got '%s'", version)
+	if version := getProjectVersion(); version != "unknown" {
+		t.Errorf("Expected 'unknown', got '%s'", version)
+	}
+}
+
+func TestGetProjectVersion_Fallbacks(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected string
+	}{
+		{"Tag wins", map[string]string{"CI_COMMIT_TAG": "v1.0.0", "PLUGIN_VERSION": "2.0", "CI_COMMIT_SHA": "abcdef1234"}, "v1.0.0"},
+		{"Override", map[string]string{"PLUGIN_VERSION": "2.0", "CI_COMMIT_SHA": "abcdef1234"}, "2.0"},
+		{"Short SHA", map[string]string{"CI_COMMIT_SHA": "abc"}, "abc"},
+		{"SHA length", map[string]string{"CI_COMMIT_SHA": "abcdef1234567890", "PLUGIN_SHA_LENGTH": "10"}, "abcdef1234"},
+		{"Invalid SHA length", map[string]string{"CI_COMMIT_SHA": "abcdef1234567890", "PLUGIN_SHA_LENGTH": "0"}, "abcdef1"},
+		{"Malformed SHA", map[string]string{"CI_COMMIT_SHA": "unknown", "CI_PIPELINE_NUMBER": "42"}, "build #42"},
+		{"Pipeline number", map[string]string{"CI_PIPELINE_NUMBER": "42"}, "build #42"},
+		{"Nothing", map[string]string{}, "unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := map[string]string{
+				"CI_COMMIT_TAG": "", "PLUGIN_VERSION": "", "CI_COMMIT_SHA": "", "PLUGIN_SHA_LENGTH": "",
+				"CI_PIPELINE_NUMBER": "", "DRONE_BUILD_NUMBER": "",
+			}
+			for key, value := range tt.env {
+				env[key] = value
+			}
+			setEnvFixture(t, env)
+
+			if version := getProjectVersion(); version != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, version)
+			}
+		})
 	}
 }
 
@@ -226,29 +277,26 @@ func TestCreateActionButtons(t *testing.T) {
 		os.Unsetenv("CI_PIPELINE_URL")
 		os.Unsetenv("CI_COMMIT_TAG")
 		os.Unsetenv("CI_REPO_URL")
-		os.Unsetenv("PLUGIN_BUTTONS")
 	}()
 	
 	// Test with all buttons
-	actions := createActionButtons()
+	actions := createActionButtons(Config{})
 	if len(actions) != 2 {
 		t.Errorf("Expected 2 buttons, got %d", len(actions))
 	}
 	
 	// Test with filtered buttons
-	os.Setenv("PLUGIN_BUTTONS", "pipeline")
-	actions = createActionButtons()
+	actions = createActionButtons(Config{Buttons: []string{"pipeline"}})
 	if len(actions) != 1 {
 		t.Errorf("Expected 1 button, got %d", len(actions))
 	}
 	
 	// Test with commit instead of tag
 	os.Unsetenv("CI_COMMIT_TAG")
-	os.Unsetenv("PLUGIN_BUTTONS")
 	os.Setenv("CI_PIPELINE_FORGE_URL", "https://github.com/user/repo/commit/abc123")
 	defer os.Unsetenv("CI_PIPELINE_FORGE_URL")
 	
-	actions = createActionButtons()
+	actions = createActionButtons(Config{})
 	if len(actions) != 2 {
 		t.Errorf("Expected 2 buttons, got %d", len(actions))
 	}
@@ -277,6 +325,33 @@ func TestPrintDebugInfo(t *testing.T) {
 	printDebugInfo(messageBytes)
 }
 
+func TestPrintDebugInfo_RedactsActionInputs(t *testing.T) {
+	secrets := map[string]string{
+		"INPUT_WEBHOOK_URL":         "https://open.feishu.cn/open-apis/bot/v2/hook/bot-token-1",
+		"INPUT_WEBHOOK-URL_FAILURE": "https://open.feishu.cn/open-apis/bot/v2/hook/bot-token-2",
+		"INPUT_APP_SECRET":          "app-secret-3",
+		"INPUT_SECRET":              "sign-secret-4",
+	}
+	env := map[string]string{"PLUGIN_DEBUG": "true"}
+	for key, value := range secrets {
+		env[key] = value
+	}
+	setEnvFixture(t, env)
+
+	output := captureOutput(t, func() {
+		printDebugInfo([]byte(`{}`))
+	})
+
+	for key, value := range secrets {
+		if strings.Contains(output, value) {
+			t.Errorf("Expected %s to be redacted, got %s", key, output)
+		}
+	}
+	if !strings.Contains(output, "INPUT_WEBHOOK_URL") || !strings.Contains(output, "[REDACTED]") {
+		t.Errorf("Expected INPUT_WEBHOOK_URL to be listed as redacted, got %s", output)
+	}
+}
+
 func TestMain_MissingWebhookURL(t *testing.T) {
 	// Save original osExit and restore it after the test
 	originalOsExit := osExit
@@ -300,8 +375,8 @@ func TestMain_MissingWebhookURL(t *testing.T) {
 	if !exitCalled {
 		t.Error("Expected os.Exit to be called")
 	}
-	if exitCode != 1 {
-		t.Errorf("Expected exit code 1, got %d", exitCode)
+	if exitCode != exitConfiguration {
+		t.Errorf("Expected exit code 2, got %d", exitCode)
 	}
 }
 
@@ -347,69 +422,145 @@ func TestMain_WithWebhook(t *testing.T) {
 	}
 }
 
+// roundTripFunc stubs the transport of an http.Client
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// stubWebhook makes webhookClient answer every request with fn, without sockets
+func stubWebhook(t *testing.T, fn roundTripFunc) {
+	t.Helper()
+	originalClient := webhookClient
+	webhookClient = &http.Client{Transport: fn}
+	t.Cleanup(func() { webhookClient = originalClient })
+}
+
+// larkResponse returns a webhook response with the status and body
+func larkResponse(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
 func TestSendMessage(t *testing.T) {
-	// Create a test server that mimics Lark API
-	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Verify that the request is a POST
-		if r.Method != http.MethodPost {
-			t.Errorf("Expected POST request, got %s", r.Method)
+	messageBytes := []byte(`{"msg_type":"text","content":{"text":"Test message"}}`)
+
+	t.Run("success", func(t *testing.T) {
+		stubWebhook(t, func(r *http.Request) (*http.Response, error) {
+			if r.Method != http.MethodPost {
+				t.Errorf("Expected POST request, got %s", r.Method)
+			}
+			if contentType := r.Header.Get("Content-Type"); contentType != "application/json" {
+				t.Errorf("Expected Content-Type application/json, got %s", contentType)
+			}
+			body, _ := io.ReadAll(r.Body)
+			var requestData map[string]interface{}
+			if err := json.Unmarshal(body, &requestData); err != nil {
+				t.Errorf("Request body is not valid JSON: %v", err)
+			}
+			return larkResponse(http.StatusOK, `{"code": 0, "message": "success"}`), nil
+		})
+
+		if err := deliverMessage(context.Background(), "https://open.larksuite.com/hook/x", messageBytes, ""); err != nil {
+			t.Errorf("Expected delivery to succeed, got %v", err)
 		}
+	})
 
-		// Verify that the content type is application/json
-		contentType := r.Header.Get("Content-Type")
-		if contentType != "application/json" && contentType != "" {
-			t.Errorf("Expected Content-Type application/json, got %s", contentType)
+	t.Run("HTTP error", func(t *testing.T) {
+		stubWebhook(t, func(r *http.Request) (*http.Response, error) {
+			return larkResponse(http.StatusBadRequest, `{"code": 1, "message": "error"}`), nil
+		})
+
+		// The error is returned to main, which decides whether to exit
+		err := deliverMessage(context.Background(), "https://open.larksuite.com/hook/x", messageBytes, "")
+		if err == nil || !strings.Contains(err.Error(), `{"code": 1, "message": "error"}`) {
+			t.Errorf("Expected an error with the response body, got %v", err)
 		}
+	})
+
+	t.Run("Lark error code", func(t *testing.T) {
+		stubWebhook(t, func(r *http.Request) (*http.Response, error) {
+			return larkResponse(http.StatusOK, `{"code": 19021, "msg": "sign match fail"}`), nil
+		})
 
-		// Read the request body
-		body, err := io.ReadAll(r.Body)
-		if err != nil {
-			t.Fatalf("Failed to read request body: %v", err)
+		err := deliverMessage(context.Background(), "https://open.larksuite.com/hook/x", messageBytes, "")
+		var responseErr *webhookResponseError
+		if !errors.As(err, &responseErr) || responseErr.Code != 19021 {
+			t.Errorf("Expected a webhook response error with code 19021, got %v", err)
 		}
+	})
+
+	t.Run("DNS failure", func(t *testing.T) {
+		stubWebhook(t, func(r *http.Request) (*http.Response, error) {
+			return nil, &net.DNSError{Err: "no such host", Name: r.URL.Host, IsNotFound: true}
+		})
 
-		// Verify that the body is valid JSON
-		var requestData map[string]interface{}
-		if err := json.Unmarshal(body, &requestData); err != nil {
-			t.Fatalf("Request body is not valid JSON: %v", err)
+		err := deliverMessage(context.Background(), "https://open.larksuite.com/hook/x", messageBytes, "")
+		if err == nil || !strings.Contains(err.Error(), "error sending to Lark") || !strings.Contains(err.Error(), "no such host") {
+			t.Errorf("Expected a transport error, got %v", err)
 		}
+	})
 
-		// Return a successful response
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"code": 0, "message": "success"}`)) // Lark API success response
-	}))
-	defer testServer.Close()
+	t.Run("malformed body", func(t *testing.T) {
+		stubWebhook(t, func(r *http.Request) (*http.Response, error) {
+			return larkResponse(http.StatusBadGateway, `<html>Bad Gateway</html>`), nil
+		})
 
-	// Test with success response
-	messageBytes := []byte(`{"msg_type":"text","content":{"text":"Test message"}}`)
-	sendMessage(testServer.URL, messageBytes)
+		err := deliverMessage(context.Background(), "https://open.larksuite.com/hook/x", messageBytes, "")
+		if err == nil || !strings.Contains(err.Error(), "<html>Bad Gateway</html>") {
+			t.Errorf("Expected an error with the raw body, got %v", err)
+		}
+	})
 
-	// Test with error response
-	errorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(`{"code": 1, "message": "error"}`)) // Lark API error response
-	}))
-	defer errorServer.Close()
+	t.Run("canceled", func(t *testing.T) {
+		stubWebhook(t, func(r *http.Request) (*http.Response, error) {
+			<-r.Context().Done()
+			return nil, r.Context().Err()
+		})
 
-	// We need to use a custom exit function to prevent the test from exiting
-	originalOsExit := osExit
-	defer func() { osExit = originalOsExit }()
-	
-	exitCalled := false
-	osExit = func(code int) {
-		exitCalled = true
-		if code != 1 {
-			t.Errorf("Expected exit code 1, got %d", code)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := deliverMessage(ctx, "https://open.larksuite.com/hook/x", messageBytes, "")
+		if err == nil || !strings.Contains(err.Error(), "context canceled") {
+			t.Errorf("Expected the request to be canceled, got %v", err)
 		}
+	})
+}
+
+// captureStdout returns everything fn prints to stdout
+func captureStdout(t *testing.T, fn func()) string {
+	originalStdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
 	}
+	os.Stdout = w
 
-	// This should call osExit(1) due to the error response
-	sendMessage(errorServer.URL, messageBytes)
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		output <- string(data)
+	}()
 
-	if !exitCalled {
-		t.Error("Expected os.Exit to be called")
-	}
+	defer func() { os.Stdout = originalStdout }()
+	fn()
+	w.Close()
+	return <-output
+}
+
+// captureOutput returns everything fn prints to stdout and stderr, such as
+// errors next to the messages leading up to them
+func captureOutput(t *testing.T, fn func()) string {
+	originalStderr := os.Stderr
+	defer func() { os.Stderr = originalStderr }()
+	return captureStdout(t, func() {
+		os.Stderr = os.Stdout
+		fn()
+	})
 }
 
 // Helper function for Go versions before 1.21 which don't have min in standard library
@@ -419,3 +570,65 @@ func min(a, b int) int {
 	}
 	return b
 }
+
+func TestMain_FailOnError(t *testing.T) {
+	failingServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code": 19021, "msg": "sign match fail or timestamp is not within one hour from current time"}`))
+	}))
+	defer failingServer.Close()
+
+	originalOsExit := osExit
+	defer func() { osExit = originalOsExit }()
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		exitCode int
+		output   []string
+	}{
+		{
+			name:     "Delivery error fails by default",
+			env:      map[string]string{"PLUGIN_WEBHOOK_URL": failingServer.URL},
+			exitCode: exitRejected,
+			output:   []string{"sign match fail", "Error: delivery failed for 1 of 1 targets", "Error: exiting with code 4 (rejected by the chat service)"},
+		},
+		{
+			name:     "Delivery error tolerated",
+			env:      map[string]string{"PLUGIN_WEBHOOK_URL": failingServer.URL, "PLUGIN_FAIL_ON_ERROR": "false"},
+			exitCode: 0,
+			output:   []string{"sign match fail", "Error: delivery failed for 1 of 1 targets", "PLUGIN_FAIL_ON_ERROR is false"},
+		},
+		{
+			name:     "Missing webhook fails by default",
+			env:      map[string]string{},
+			exitCode: exitConfiguration,
+			output:   []string{"Error: Need to set Lark Webhook URL", "Error: exiting with code 2 (configuration error)"},
+		},
+		{
+			name:     "Missing webhook tolerated",
+			env:      map[string]string{"PLUGIN_FAIL_ON_ERROR": "false"},
+			exitCode: 0,
+			output:   []string{"Error: Need to set Lark Webhook URL", "PLUGIN_FAIL_ON_ERROR is false"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnvFixture(t, tt.env)
+			exitCode := 0
+			osExit = func(code int) { exitCode = code }
+
+			output := captureOutput(t, main)
+
+			if exitCode != tt.exitCode {
+				t.Errorf("Expected exit code %d, got %d", tt.exitCode, exitCode)
+			}
+			for _, expected := range tt.output {
+				if !strings.Contains(output, expected) {
+					t.Errorf("Expected output to contain %q, got %q", expected, output)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// markdownEscaper replaces the characters lark_md interprets with HTML entities
+var markdownEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	"*", "&#42;",
+	"_", "&#95;",
+	"~", "&#126;",
+	"`", "&#96;",
+	"[", "&#91;",
+	"]", "&#93;",
+)
+
+// atTagOpenPattern matches the opening of an <at> or </at> mention tag
+var atTagOpenPattern = regexp.MustCompile(`(?i)<(/?)at\b`)
+
+// escapeText neutralizes mention tags in a user-controlled value so that, for
+// example, a commit message cannot ping everyone
+func escapeText(s string) string {
+	return atTagOpenPattern.ReplaceAllString(s, "<${1}\u200bat")
+}
+
+// escapeMarkdown makes a user-controlled value safe to embed in lark_md. With
+// PLUGIN_RAW_MARKDOWN=true markdown is kept, but mention tags never are.
+func escapeMarkdown(s string) string {
+	if getEnvOrDefault("PLUGIN_RAW_MARKDOWN", "false") == "true" {
+		return escapeText(s)
+	}
+	return markdownEscaper.Replace(s)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const hostileCommitMessage = `**bold** __under__ ~~strike~~ ` + "`code`" + ` [link](https://evil.example.com) <at id=all></at> <AT user_id="all">All</at> <font color='red'>x</font> & done`
+
+func TestEscapeMarkdown(t *testing.T) {
+	setEnvFixture(t, map[string]string{})
+
+	escaped := escapeMarkdown(hostileCommitMessage)
+	for _, sequence := range []string{"**", "__", "~~", "`", "[", "]", "<at", "<AT", "</at", "<font", "& "} {
+		if strings.Contains(escaped, sequence) {
+			t.Errorf("Expected %q to be escaped in %q", sequence, escaped)
+		}
+	}
+	if !strings.Contains(escaped, "&#42;&#42;bold&#42;&#42;") || !strings.Contains(escaped, "&amp; done") {
+		t.Errorf("Unexpected escaping %q", escaped)
+	}
+}
+
+func TestEscapeMarkdownRaw(t *testing.T) {
+	setEnvFixture(t, map[string]string{"PLUGIN_RAW_MARKDOWN": "true"})
+
+	escaped := escapeMarkdown(hostileCommitMessage)
+	if !strings.Contains(escaped, "**bold**") || !strings.Contains(escaped, "[link](https://evil.example.com)") {
+		t.Errorf("Expected markdown to be kept, got %q", escaped)
+	}
+	if strings.Contains(strings.ToLower(escaped), "<at") || strings.Contains(strings.ToLower(escaped), "</at") {
+		t.Errorf("Expected mention tags to be neutralized, got %q", escaped)
+	}
+}
+
+func TestHostileCommitMessageInCardAndText(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"CI_COMMIT_MESSAGE": hostileCommitMessage,
+		"CI_COMMIT_AUTHOR":  "*mallory*",
+		"CI_COMMIT_BRANCH":  "feat/<at id=all></at>",
+		"DEPLOY_NOTE":       "[click](https://evil.example.com)",
+	})
+
+	config := Config{Variables: []string{"DEPLOY_NOTE"}}
+	card := createLarkCard(config, "v1.0.0")["card"].(map[string]any)
+	elements := card["elements"].([]map[string]any)
+	for _, element := range elements {
+		text, ok := element["text"].(map[string]any)
+		if !ok {
+			continue
+		}
+		content := text["content"].(string)
+		if strings.Contains(strings.ToLower(content), "<at") || strings.Contains(content, "[link]") ||
+			strings.Contains(content, "[click]") || strings.Contains(content, "*mallory*") {
+			t.Errorf("Unescaped user content in card: %q", content)
+		}
+	}
+	// The plugin's own formatting is kept
+	metadata := elements[0]["text"].(map[string]any)["content"].(string)
+	if !strings.HasPrefix(metadata, "**Project:**") || !strings.Contains(metadata, "**Author:** &#42;mallory&#42;") {
+		t.Errorf("Unexpected metadata %q", metadata)
+	}
+
+	text := createLarkTextMessage(config, "v1.0.0")["content"].(map[string]any)["text"].(string)
+	if strings.Contains(strings.ToLower(text), "<at") || strings.Contains(strings.ToLower(text), "</at") {
+		t.Errorf("Expected mention tags to be neutralized in text, got %q", text)
+	}
+	if !strings.Contains(text, "**bold**") {
+		t.Errorf("Expected plain text to keep markdown characters, got %q", text)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+// maskedValue replaces values of sensitive-looking variables
+const maskedValue = "••••"
+
+// defaultMaskPatterns mark variable names whose values are never shown.
+// PLUGIN_MASK_PATTERNS adds to them.
+var defaultMaskPatterns = []string{"TOKEN", "SECRET", "PASSWORD", "KEY", "CREDENTIAL"}
+
+// isMaskedVariable reports whether a variable's value must be masked: its name
+// contains one of the mask patterns and is not listed in PLUGIN_NO_MASK
+func isMaskedVariable(name string) bool {
+	upper := strings.ToUpper(name)
+	if slices.ContainsFunc(getListSetting("PLUGIN_NO_MASK"), func(allowed string) bool {
+		return strings.EqualFold(allowed, name)
+	}) {
+		return false
+	}
+
+	for _, pattern := range slices.Concat(defaultMaskPatterns, getListSetting("PLUGIN_MASK_PATTERNS")) {
+		if strings.Contains(upper, strings.ToUpper(pattern)) {
+			return true
+		}
+	}
+	return false
+}
+
+// variableValue returns the value of a PLUGIN_VARIABLES entry, masked when
+// the name looks sensitive. Empty values stay empty.
+func variableValue(name string) string {
+	value := getEnvOrDefault(name, "")
+	if value != "" && isMaskedVariable(name) {
+		return maskedValue
+	}
+	return value
+}
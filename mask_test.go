@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestIsMaskedVariable(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns string
+		noMask   string
+		variable string
+		expected bool
+	}{
+		{"Token", "", "", "DEPLOY_TOKEN", true},
+		{"Password", "", "", "db_password", true},
+		{"Secret", "", "", "ClientSecret", true},
+		{"Key", "", "", "SSH_KEY", true},
+		{"Credential", "", "", "GCP_CREDENTIALS", true},
+		{"Ordinary variable", "", "", "DEPLOY_ENV", false},
+		{"Custom pattern", "DSN,cookie", "", "SENTRY_DSN", true},
+		{"Custom pattern ignores case", "DSN,cookie", "", "SESSION_COOKIE", true},
+		{"Custom patterns extend the defaults", "DSN", "", "API_TOKEN", true},
+		{"Allow-listed name", "", "MONKEY_NAME,deploy_key_id", "MONKEY_NAME", false},
+		{"Allow-list ignores case", "", "MONKEY_NAME,deploy_key_id", "DEPLOY_KEY_ID", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setEnvFixture(t, map[string]string{
+				"PLUGIN_MASK_PATTERNS": tc.patterns,
+				"PLUGIN_NO_MASK":       tc.noMask,
+			})
+
+			if masked := isMaskedVariable(tc.variable); masked != tc.expected {
+				t.Errorf("Expected %v for %s, got %v", tc.expected, tc.variable, masked)
+			}
+		})
+	}
+}
+
+func TestMaskedVariablesInMessages(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"DEPLOY_ENV":   "staging",
+		"DEPLOY_TOKEN": "super-secret-token",
+		"PLUGIN_DEBUG": "true",
+	})
+
+	config := Config{Variables: []string{"DEPLOY_ENV", "DEPLOY_TOKEN", "EMPTY_PASSWORD"}}
+	cardBytes, err := json.Marshal(createLarkCard(config, "v1.0.0"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	textBytes, err := json.Marshal(createLarkTextMessage(config, "v1.0.0"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	card, text := string(cardBytes), string(textBytes)
+
+	for _, output := range []string{card, text} {
+		if strings.Contains(output, "super-secret-token") {
+			t.Errorf("Secret leaked: %s", output)
+		}
+		if !strings.Contains(output, "staging") || !strings.Contains(output, maskedValue) {
+			t.Errorf("Expected the plain and the masked value, got %s", output)
+		}
+	}
+
+	debug := captureStdout(t, func() { printDebugInfo([]byte(card)) })
+	if strings.Contains(debug, "super-secret-token") {
+		t.Errorf("Secret leaked through debug output: %s", debug)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// matrixAxis is one dimension of a matrix build
+type matrixAxis struct {
+	Name  string
+	Value string
+}
+
+// getMatrixAxes reads the matrix from PLUGIN_MATRIX (key=value pairs) or, when
+// that is unset, from the variables named in PLUGIN_MATRIX_VARS. The workflow
+// (Drone stage) name always comes first, as it tells workflows of a pipeline apart.
+func getMatrixAxes() []matrixAxis {
+	var axes []matrixAxis
+	if workflow := getEnvOrDefault("CI_WORKFLOW_NAME", getEnvOrDefault("DRONE_STAGE_NAME", "")); workflow != "" {
+		axes = append(axes, matrixAxis{Name: "workflow", Value: workflow})
+	}
+
+	if pairs := getListSetting("PLUGIN_MATRIX"); len(pairs) > 0 {
+		for _, pair := range pairs {
+			name, value, found := strings.Cut(pair, "=")
+			name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+			if !found || name == "" {
+				logWarn(fmt.Sprintf("ignoring matrix entry %q, expected key=value", pair))
+				continue
+			}
+			if value != "" {
+				axes = append(axes, matrixAxis{Name: name, Value: value})
+			}
+		}
+		return axes
+	}
+
+	for _, name := range getListSetting("PLUGIN_MATRIX_VARS") {
+		if value := getEnvOrDefault(name, ""); value != "" {
+			axes = append(axes, matrixAxis{Name: name, Value: value})
+		}
+	}
+	return axes
+}
+
+// matrixString renders the matrix as "go=1.22, platform=linux/arm64". It
+// identifies the matrix leg, so it also tells parallel legs apart.
+func matrixString() string {
+	var parts []string
+	for _, axis := range getMatrixAxes() {
+		parts = append(parts, axis.Name+"="+axis.Value)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// matrixTitleSuffix returns the values of the matrix, like " (go1.22, arm64)",
+// for titles when PLUGIN_MATRIX_IN_TITLE is on. Combined notifications of
+// all legs have none.
+func matrixTitleSuffix() string {
+	if getEnvOrDefault("PLUGIN_MATRIX_IN_TITLE", "false") != "true" || isAggregated() {
+		return ""
+	}
+
+	var values []string
+	for _, axis := range getMatrixAxes() {
+		values = append(values, axis.Value)
+	}
+	if len(values) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(values, ", ") + ")"
+}
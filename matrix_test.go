@@ -0,0 +1,134 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatrixString(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected string
+	}{
+		{"Unset", map[string]string{}, ""},
+		{"Pairs", map[string]string{"PLUGIN_MATRIX": "go=1.22, platform=linux/arm64"}, "go=1.22, platform=linux/arm64"},
+		{"JSON pairs", map[string]string{"PLUGIN_MATRIX": `["go=1.22","platform=linux/arm64"]`}, "go=1.22, platform=linux/arm64"},
+		{"Malformed and empty pairs are skipped", map[string]string{"PLUGIN_MATRIX": "go=1.22,broken,=x,tags="}, "go=1.22"},
+		{
+			name:     "Variables",
+			env:      map[string]string{"PLUGIN_MATRIX_VARS": "GO_VERSION,TARGETARCH,UNSET_AXIS", "GO_VERSION": "1.22", "TARGETARCH": "arm64"},
+			expected: "GO_VERSION=1.22, TARGETARCH=arm64",
+		},
+		{
+			name:     "Pairs take precedence over variables",
+			env:      map[string]string{"PLUGIN_MATRIX": "go=1.21", "PLUGIN_MATRIX_VARS": "GO_VERSION", "GO_VERSION": "1.22"},
+			expected: "go=1.21",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setEnvFixture(t, tc.env)
+
+			if result := matrixString(); result != tc.expected {
+				t.Errorf("Expected '%s', got '%s'", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestCreateLarkCard_Matrix(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"CI_REPO_NAME":  "backend",
+		"PLUGIN_MATRIX": "go=1.22,platform=linux/arm64",
+	})
+
+	config := Config{Status: "failure"}
+	card := createLarkCard(config, "v1.0.0")
+	header := card["card"].(map[string]any)["header"].(map[string]any)
+	title := header["title"].(map[string]any)["content"].(string)
+	if title != "backend - 🚨 Pipeline Failed" {
+		t.Errorf("Expected title without matrix, got '%s'", title)
+	}
+
+	elements := card["card"].(map[string]any)["elements"].([]map[string]any)
+	content := elements[0]["text"].(map[string]any)["content"].(string)
+	if !strings.HasSuffix(content, "\n**Matrix:** go=1.22, platform=linux/arm64") {
+		t.Errorf("Expected matrix line, got %q", content)
+	}
+
+	setEnvFixture(t, map[string]string{"PLUGIN_MATRIX_IN_TITLE": "true"})
+	card = createLarkCard(config, "v1.0.0")
+	header = card["card"].(map[string]any)["header"].(map[string]any)
+	title = header["title"].(map[string]any)["content"].(string)
+	if title != "backend - 🚨 Pipeline Failed (1.22, linux/arm64)" {
+		t.Errorf("Unexpected title '%s'", title)
+	}
+
+	message := createLarkTextMessage(config, "v1.0.0")
+	text := message["content"].(map[string]any)["text"].(string)
+	if !strings.HasPrefix(text, "🚨 PIPELINE FAILED (1.22, linux/arm64)\n") {
+		t.Errorf("Unexpected text message %q", text)
+	}
+
+	setEnvFixture(t, map[string]string{"PLUGIN_LANG": "zh"})
+	text = createLarkTextMessage(config, "v1.0.0")["content"].(map[string]any)["text"].(string)
+	if !strings.Contains(text, "矩阵: go=1.22, platform=linux/arm64\n") {
+		t.Errorf("Expected the translated matrix label, got %q", text)
+	}
+}
+
+func TestMatrixWorkflowName(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected string
+	}{
+		{"Woodpecker workflow", map[string]string{"CI_WORKFLOW_NAME": "test"}, "workflow=test"},
+		{"Drone stage", map[string]string{"DRONE_STAGE_NAME": "linux-arm64"}, "workflow=linux-arm64"},
+		{
+			name:     "Workflow before variables",
+			env:      map[string]string{"CI_WORKFLOW_NAME": "test", "PLUGIN_MATRIX_VARS": "GO_VERSION,TARGETARCH", "GO_VERSION": "go1.22", "TARGETARCH": "arm64"},
+			expected: "workflow=test, GO_VERSION=go1.22, TARGETARCH=arm64",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setEnvFixture(t, tc.env)
+
+			if result := matrixString(); result != tc.expected {
+				t.Errorf("Expected '%s', got '%s'", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestMatrixTitleSuffix(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"CI_REPO_NAME":           "backend",
+		"PLUGIN_MATRIX_IN_TITLE": "true",
+		"PLUGIN_MATRIX_VARS":     "GO_VERSION,TARGETARCH,UNSET_AXIS",
+		"GO_VERSION":             "go1.22",
+		"TARGETARCH":             "arm64",
+	})
+
+	card := createLarkCard(Config{}, "v1.0.0")
+	title := card["card"].(map[string]any)["header"].(map[string]any)["title"].(map[string]any)["content"].(string)
+	if title != "backend - ✅ Pipeline Succeeded (go1.22, arm64)" {
+		t.Errorf("Unexpected title '%s'", title)
+	}
+
+	text := createLarkTextMessage(Config{}, "v1.0.0")["content"].(map[string]any)["text"].(string)
+	if !strings.HasPrefix(text, "✅ PIPELINE SUCCEEDED (go1.22, arm64)\n") || !strings.Contains(text, "🧩 Matrix: GO_VERSION=go1.22, TARGETARCH=arm64\n") {
+		t.Errorf("Unexpected text message %q", text)
+	}
+
+	t.Setenv("TARGETARCH", strings.Repeat("arm64-", 30))
+	card = createLarkCard(Config{}, "v1.0.0")
+	title = card["card"].(map[string]any)["header"].(map[string]any)["title"].(map[string]any)["content"].(string)
+	if length := len([]rune(title)); length > maxHeaderTitleLength+1 {
+		t.Errorf("Expected title capped at %d runes, got %d", maxHeaderTitleLength, length)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// mentionAll is the PLUGIN_MENTION_USERS entry that mentions everyone in the group
+const mentionAll = "all"
+
+// mentionStatusMatches reports whether status or its transition is listed in
+// PLUGIN_MENTION_ON (default "failure"), outside muted quiet hours
+func mentionStatusMatches(status string) bool {
+	if quietMentionsMuted {
+		return false
+	}
+	mentionOn := getListSetting("PLUGIN_MENTION_ON")
+	if len(mentionOn) == 0 {
+		mentionOn = []string{"failure"}
+	}
+	for _, wanted := range mentionOn {
+		for _, matched := range notifyStatuses(status) {
+			if strings.EqualFold(wanted, matched) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mentionUsers returns the PLUGIN_MENTION_USERS ids to mention in a build
+// with status
+func mentionUsers(status string) []string {
+	users := getListSetting("PLUGIN_MENTION_USERS")
+	if len(users) == 0 || !mentionStatusMatches(status) {
+		return nil
+	}
+	return users
+}
+
+// cardMentionLine returns the lark_md at-tags for the users to mention, or ""
+func cardMentionLine(status string) string {
+	var tags []string
+	for _, id := range mentionUsers(status) {
+		tags = append(tags, fmt.Sprintf("<at id=%s></at>", id))
+	}
+	return strings.Join(tags, " ")
+}
+
+// textMentionLine returns the text message at-tags for the users to mention, or ""
+func textMentionLine(status string) string {
+	var tags []string
+	for _, id := range mentionUsers(status) {
+		if id == mentionAll {
+			tags = append(tags, `<at user_id="all">All</at>`)
+		} else {
+			tags = append(tags, fmt.Sprintf(`<at user_id="%s"></at>`, id))
+		}
+	}
+	return strings.Join(tags, " ")
+}
+
+// mentionElement returns the card element holding the mentions, or nil
+func mentionElement(status string) map[string]any {
+	line := cardMentionLine(status)
+	if line == "" {
+		return nil
+	}
+	return map[string]any{
+		"tag": "div",
+		"text": map[string]any{
+			"content": line,
+			"tag":     "lark_md",
+		},
+	}
+}
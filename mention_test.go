@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMentions_Card(t *testing.T) {
+	setEnvFixture(t, map[string]string{"PLUGIN_MENTION_USERS": "ou_123, ,ou_456,all,"})
+
+	var message struct {
+		Card struct {
+			Elements []struct {
+				Tag  string `json:"tag"`
+				Text struct {
+					Content string `json:"content"`
+					Tag     string `json:"tag"`
+				} `json:"text"`
+			} `json:"elements"`
+		} `json:"card"`
+	}
+	roundTrip(t, createLarkCard(Config{Status: "failure"}, "v1.0.0"), &message)
+
+	expected := "<at id=ou_123></at> <at id=ou_456></at> <at id=all></at>"
+	found := false
+	for _, element := range message.Card.Elements {
+		if element.Tag == "div" && element.Text.Tag == "lark_md" && element.Text.Content == expected {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected a lark_md div with %q, got %+v", expected, message.Card.Elements)
+	}
+}
+
+func TestMentions_Text(t *testing.T) {
+	setEnvFixture(t, map[string]string{"PLUGIN_MENTION_USERS": "ou_123,all", "PLUGIN_USE_CARD": "false"})
+
+	var message struct {
+		Content struct {
+			Text string `json:"text"`
+		} `json:"content"`
+	}
+	roundTrip(t, createLarkTextMessage(Config{Status: "failure"}, "v1.0.0"), &message)
+
+	expected := `<at user_id="ou_123"></at> <at user_id="all">All</at>`
+	if !strings.Contains(message.Content.Text, expected) {
+		t.Errorf("Expected mentions %s in %q", expected, message.Content.Text)
+	}
+}
+
+// roundTrip marshals a message the way main does and decodes it into v
+func roundTrip(t *testing.T, message map[string]any, v any) {
+	t.Helper()
+	data, err := json.Marshal(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMentions_Unset(t *testing.T) {
+	for _, value := range []string{"", " , "} {
+		setEnvFixture(t, map[string]string{"PLUGIN_MENTION_USERS": value})
+
+		card, _ := json.Marshal(createLarkCard(Config{Status: "failure"}, "v1.0.0"))
+		text, _ := json.Marshal(createLarkTextMessage(Config{Status: "failure"}, "v1.0.0"))
+		if strings.Contains(string(card), `\u003cat`) || strings.Contains(string(text), `\u003cat`) {
+			t.Errorf("Expected no mentions for %q, got %s and %s", value, card, text)
+		}
+	}
+}
+
+func TestMentions_Compact(t *testing.T) {
+	setEnvFixture(t, map[string]string{"PLUGIN_MENTION_USERS": "ou_123"})
+
+	if line := cardMentionLine("failure"); line != "<at id=ou_123></at>" {
+		t.Errorf("Unexpected card mention line '%s'", line)
+	}
+	card := createLarkCard(Config{Status: "failure", Compact: true}, "v1.0.0")["card"].(map[string]any)
+	found := false
+	for _, element := range card["elements"].([]map[string]any) {
+		if text, ok := element["text"].(map[string]any); ok && text["content"] == "<at id=ou_123></at>" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected the compact card to keep the mentions")
+	}
+}
+
+func TestMentionOn(t *testing.T) {
+	tests := []struct {
+		name       string
+		mentionOn  string
+		status     string
+		prevStatus string
+		expected   string
+	}{
+		{"Success is not mentioned by default", "", "success", "", ""},
+		{"Failure is mentioned by default", "", "failure", "", "<at id=ou_123></at>"},
+		{"Mention everyone on failure", "", "failure", "", "<at id=all></at>"},
+		{"Fixed transition", "fixed", "success", "failure", "<at id=ou_123></at>"},
+		{"Still failing only", "still_failing", "failure", "success", ""},
+		{"Case insensitive list", " SUCCESS ,failure", "success", "", "<at id=ou_123></at>"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			users := "ou_123"
+			if strings.Contains(tc.expected, "all") {
+				users = "all"
+			}
+			setEnvFixture(t, map[string]string{
+				"PLUGIN_MENTION_USERS":    users,
+				"PLUGIN_MENTION_ON":       tc.mentionOn,
+				"CI_PREV_PIPELINE_STATUS": tc.prevStatus,
+			})
+
+			if line := cardMentionLine(tc.status); line != tc.expected {
+				t.Errorf("Expected '%s', got '%s'", tc.expected, line)
+			}
+		})
+	}
+}
+
+func TestMentionOn_StatusOverride(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_MENTION_USERS": "ou_123",
+		"DRONE_BUILD_STATUS":   "success",
+		"PLUGIN_STATUS":        "failure",
+	})
+
+	if line := textMentionLine(resolveStatus(os.Getenv)); line != `<at user_id="ou_123"></at>` {
+		t.Errorf("Expected the PLUGIN_STATUS override to trigger the mention, got '%s'", line)
+	}
+}
+
+func TestMentionOn_NoMatchIsByteIdentical(t *testing.T) {
+	setEnvFixture(t, map[string]string{"CI_REPO_NAME": "backend"})
+	for _, useCard := range []bool{true, false} {
+		build := func() []byte {
+			var message map[string]any
+			if useCard {
+				message = createLarkCard(Config{Status: "success"}, "v1.0.0")
+			} else {
+				message = createLarkTextMessage(Config{Status: "success"}, "v1.0.0")
+			}
+			data, err := json.Marshal(message)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return data
+		}
+
+		setEnvFixture(t, map[string]string{"PLUGIN_MENTION_USERS": "", "PLUGIN_MENTION_ON": ""})
+		without := build()
+		setEnvFixture(t, map[string]string{"PLUGIN_MENTION_USERS": "ou_123,all", "PLUGIN_MENTION_ON": "failure"})
+		with := build()
+
+		if string(with) != string(without) {
+			t.Errorf("Expected identical payloads, got %s and %s", with, without)
+		}
+	}
+}
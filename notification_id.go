@@ -0,0 +1,28 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// notificationIDLength is the number of hex characters of a generated ID
+const notificationIDLength = 12
+
+// notificationID identifies the notification of this pipeline run: a short
+// hash of the repository, pipeline number, event and phase, or
+// PLUGIN_NOTIFICATION_ID. Re-running the step gives the same ID, so it can
+// be used to tell duplicate messages apart and as a dedupe key.
+func notificationID() string {
+	if id := strings.TrimSpace(getEnvOrDefault("PLUGIN_NOTIFICATION_ID", "")); id != "" {
+		return id
+	}
+	key := strings.Join([]string{
+		getEnvOrDefault("CI_REPO", ""),
+		getPipelineNumber(),
+		getPipelineEvent(),
+		getPhase(),
+	}, "\x00")
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])[:notificationIDLength]
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNotificationID(t *testing.T) {
+	base := map[string]string{
+		"CI_REPO":            "octo/backend",
+		"CI_PIPELINE_NUMBER": "42",
+		"CI_PIPELINE_EVENT":  "push",
+		"CI_COMMIT_SHA":      "abcdef1",
+		"PLUGIN_STATUS":      "success",
+	}
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{"Fixed inputs", map[string]string{}, "efc6e10ad100"},
+		{"The commit and status do not change it", map[string]string{"CI_COMMIT_SHA": "0123456", "PLUGIN_STATUS": "failure"}, "efc6e10ad100"},
+		{"Another pipeline", map[string]string{"CI_PIPELINE_NUMBER": "43"}, "d74836629ee1"},
+		{"Override", map[string]string{"PLUGIN_NOTIFICATION_ID": " release-1.2 "}, "release-1.2"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setEnvFixture(t, base)
+			setEnvFixture(t, tc.env)
+
+			if got := notificationID(); got != tc.want {
+				t.Errorf("Expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestNotificationID_DiffersByEventAndPhase(t *testing.T) {
+	setEnvFixture(t, map[string]string{"CI_REPO": "octo/backend", "CI_PIPELINE_NUMBER": "42", "CI_PIPELINE_EVENT": "push"})
+	push := notificationID()
+
+	setEnvFixture(t, map[string]string{"CI_PIPELINE_EVENT": "tag"})
+	tag := notificationID()
+
+	setEnvFixture(t, map[string]string{"PLUGIN_PHASE": phaseFinish})
+	if push == tag || tag == notificationID() || len(push) != notificationIDLength {
+		t.Errorf("Expected distinct IDs per event and phase, got %q, %q and %q", push, tag, notificationID())
+	}
+}
+
+func TestNotificationID_InTextAndResults(t *testing.T) {
+	var text string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Content struct {
+				Text string `json:"text"`
+			} `json:"content"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		text = body.Content.Text
+		w.Write([]byte(`{"code": 0}`))
+	}))
+	defer server.Close()
+
+	results, _, _ := readResults(t, map[string]string{
+		"PLUGIN_WEBHOOK_URL":     server.URL,
+		"PLUGIN_USE_CARD":        "false",
+		"PLUGIN_NOTIFICATION_ID": "deploy-42",
+	})
+
+	if !strings.Contains(text, "🔖 Notification ID: deploy-42") {
+		t.Errorf("Expected the ID in the text message, got %q", text)
+	}
+	if len(results) != 1 || results[0].NotificationID != "deploy-42" {
+		t.Errorf("Expected the ID in the results, got %+v", results)
+	}
+}
+
+func TestDedupeFingerprint_PrefersNotificationID(t *testing.T) {
+	setEnvFixture(t, map[string]string{"CI_REPO": "octo/backend", "CI_COMMIT_SHA": "abcdef1", "PLUGIN_NOTIFICATION_ID": "deploy-42"})
+	base := dedupeFingerprint("https://example.com/hook/a")
+
+	setEnvFixture(t, map[string]string{"CI_COMMIT_SHA": "0123456"})
+	if dedupeFingerprint("https://example.com/hook/a") != base {
+		t.Error("Expected PLUGIN_NOTIFICATION_ID to replace the commit in the fingerprint")
+	}
+	setEnvFixture(t, map[string]string{"PLUGIN_NOTIFICATION_ID": "deploy-43"})
+	if dedupeFingerprint("https://example.com/hook/a") == base {
+		t.Error("Expected another ID to be another notification")
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// notifyStatuses returns the statuses a build with status matches in
+// PLUGIN_NOTIFY_ON: the status itself and its transition, if known. A
+// successful build ending a failure streak is also "fixed".
+func notifyStatuses(status string) []string {
+	status = strings.ToLower(status)
+	statuses := []string{status}
+	transition := getTransition(status)
+	if transition == "" && status == "success" && failureStreak.FixedAfter > 0 {
+		transition = transitionFixed
+	}
+	if transition != "" {
+		statuses = append(statuses, transition)
+	}
+	return statuses
+}
+
+// notifySkipReason returns why PLUGIN_NOTIFY_ON suppresses this notification,
+// or an empty string when it should be sent. Scheduled pipelines are filtered
+// by PLUGIN_CRON_NOTIFY_ON instead, PLUGIN_NOTIFY_ON does not apply to them.
+func notifySkipReason() string {
+	setting, notifyOn := "PLUGIN_NOTIFY_ON", getListSetting("PLUGIN_NOTIFY_ON")
+	if isCron() {
+		if len(getListSetting("PLUGIN_CRON_NOTIFY_ON")) == 0 {
+			return cronDefaultSkipReason()
+		}
+		setting, notifyOn = "PLUGIN_CRON_NOTIFY_ON", cronNotifyOn()
+	}
+	if len(notifyOn) == 0 {
+		return ""
+	}
+
+	statuses := notifyStatuses(getBuildStatus())
+	for _, wanted := range notifyOn {
+		for _, status := range statuses {
+			if strings.EqualFold(wanted, status) {
+				return ""
+			}
+		}
+	}
+	return fmt.Sprintf("status '%s' is not in %s (%s)", strings.Join(statuses, "/"), setting, strings.Join(notifyOn, ","))
+}
+
+// branchSkipReason returns why PLUGIN_BRANCH_FILTER suppresses this
+// notification. Patterns use path.Match globs, so "*" does not cross "/";
+// "!pattern" excludes and exclusions win. Tag builds are never filtered.
+func branchSkipReason() string {
+	filter := getListSetting("PLUGIN_BRANCH_FILTER")
+	if len(filter) == 0 || getEnvOrDefault("CI_COMMIT_TAG", "") != "" {
+		return ""
+	}
+
+	branch := getEnvOrDefault("CI_COMMIT_BRANCH", "")
+	included, hasIncludes := false, false
+	for _, pattern := range filter {
+		if exclude, ok := strings.CutPrefix(pattern, "!"); ok {
+			if matched, _ := path.Match(exclude, branch); matched {
+				return fmt.Sprintf("branch %s excluded by filter %s", branch, pattern)
+			}
+			continue
+		}
+		hasIncludes = true
+		if matched, _ := path.Match(pattern, branch); matched {
+			included = true
+		}
+	}
+
+	if hasIncludes && !included {
+		return fmt.Sprintf("branch %s excluded by filter %s", branch, strings.Join(filter, ","))
+	}
+	return ""
+}
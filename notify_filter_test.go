@@ -0,0 +1,124 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNotifySkipReason(t *testing.T) {
+	tests := []struct {
+		name       string
+		notifyOn   string
+		status     string
+		prevStatus string
+		send       bool
+	}{
+		{"Default always sends", "", "success", "", true},
+		{"Failure only skips success", "failure", "success", "", false},
+		{"Failure only sends failure", "failure", "failure", "", true},
+		{"Case and whitespace are ignored", " Success , FAILURE ", "failure", "", true},
+		{"Fixed matches success after a failure", "failure,fixed", "success", "failure", true},
+		{"Fixed does not match a plain success", "failure,fixed", "success", "success", false},
+		{"JSON list", `["success"]`, "success", "", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setEnvFixture(t, map[string]string{
+				"PLUGIN_NOTIFY_ON":        tc.notifyOn,
+				"PLUGIN_STATUS":           tc.status,
+				"CI_PREV_PIPELINE_STATUS": tc.prevStatus,
+			})
+
+			if reason := notifySkipReason(); (reason == "") != tc.send {
+				t.Errorf("Expected send=%v, got skip reason '%s'", tc.send, reason)
+			}
+		})
+	}
+}
+
+func TestNotifySkipReason_FixedFromStreak(t *testing.T) {
+	setEnvFixture(t, map[string]string{"PLUGIN_NOTIFY_ON": "fixed", "PLUGIN_STATUS": "success"})
+	defer func() { failureStreak = streakInfo{} }()
+
+	failureStreak = streakInfo{FixedAfter: 2}
+	if reason := notifySkipReason(); reason != "" {
+		t.Errorf("Expected a fixed build to be sent, got '%s'", reason)
+	}
+}
+
+func TestMain_NotifyOnSkips(t *testing.T) {
+	requests := 0
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"code": 0}`))
+	}))
+	defer testServer.Close()
+
+	originalOsExit := osExit
+	defer func() { osExit = originalOsExit }()
+	exitCode := 0
+	osExit = func(code int) { exitCode = code }
+
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_WEBHOOK_URL": testServer.URL,
+		"PLUGIN_NOTIFY_ON":   "failure",
+		"DRONE_BUILD_STATUS": "success",
+	})
+
+	output := captureStdout(t, main)
+
+	if exitCode != 0 {
+		t.Errorf("Expected exit code 0, got %d", exitCode)
+	}
+	if requests != 0 {
+		t.Errorf("Expected no request, got %d", requests)
+	}
+	if !strings.Contains(output, "Build info") || !strings.Contains(output, "Skipping notification: status 'success'") {
+		t.Errorf("Unexpected output:\n%s", output)
+	}
+}
+
+func TestBranchSkipReason(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		branch string
+		tag    string
+		send   bool
+	}{
+		{"No filter", "", "feature/x", "", true},
+		{"Exact match", "main", "main", "", true},
+		{"Exact mismatch", "main", "develop", "", false},
+		{"Wildcard match", "main,release/*,hotfix/*", "release/1.2", "", true},
+		{"Wildcard does not cross slashes", "release/*", "release/1.2/rc", "", false},
+		{"Wildcard mismatch", "main,release/*", "feature/login", "", false},
+		{"Exclusion only", "!wip/*", "feature/login", "", true},
+		{"Exclusion only matches", "!wip/*", "wip/spike", "", false},
+		{"Exclusion wins over inclusion", "*,!wip/*", "wip/spike", "", false},
+		{"Exclusion wins regardless of order", "!release/old,release/*", "release/old", "", false},
+		{"Tag build with empty branch", "main", "", "v1.0.0", true},
+		{"Tag build is never filtered", "main", "release/1.0", "v1.0.0", true},
+		{"Empty branch without tag", "main", "", "", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setEnvFixture(t, map[string]string{
+				"PLUGIN_BRANCH_FILTER": tc.filter,
+				"CI_COMMIT_BRANCH":     tc.branch,
+				"CI_COMMIT_TAG":        tc.tag,
+			})
+
+			reason := branchSkipReason()
+			if (reason == "") != tc.send {
+				t.Errorf("Expected send=%v, got skip reason '%s'", tc.send, reason)
+			}
+			if reason != "" && !strings.HasPrefix(reason, "branch "+tc.branch+" excluded by filter ") {
+				t.Errorf("Unexpected reason '%s'", reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// openAPIResponse is the envelope of every Lark OpenAPI response
+type openAPIResponse struct {
+	Code int             `json:"code"`
+	Msg  string          `json:"msg"`
+	Data json.RawMessage `json:"data"`
+}
+
+// callOpenAPI sends an authenticated JSON request to the Lark OpenAPI and
+// decodes the "data" field of the response into result, which may be nil.
+// A non-zero code is returned as a *larkAPIError. Cancelling ctx aborts the
+// request.
+func callOpenAPI(ctx context.Context, method, path, token string, body, result any) error {
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	return sendOpenAPI(ctx, method, path, token, "application/json; charset=utf-8", reqBody, result)
+}
+
+// sendOpenAPI is callOpenAPI for a body that is already encoded as contentType,
+// such as a multipart upload
+func sendOpenAPI(ctx context.Context, method, path, token, contentType string, body []byte, result any) error {
+	req, err := http.NewRequestWithContext(ctx, method, getOpenAPIBaseURL()+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", contentType)
+	signGatewayRequest(req, body)
+
+	resp, err := openAPIClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("calling Lark OpenAPI: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+
+	var response openAPIResponse
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		return fmt.Errorf("unexpected Lark OpenAPI response (HTTP %d): %s", resp.StatusCode, string(respBody))
+	}
+	if response.Code != 0 {
+		return &larkAPIError{Code: response.Code, Msg: response.Msg}
+	}
+	if result != nil && len(response.Data) > 0 {
+		if err := json.Unmarshal(response.Data, result); err != nil {
+			return fmt.Errorf("unexpected Lark OpenAPI data: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// setupOpenAPIServer serves the token endpoint and an echo endpoint that
+// returns the request's Authorization header and body as data
+func setupOpenAPIServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/open-apis/auth/v3/tenant_access_token/internal":
+			json.NewEncoder(w).Encode(map[string]any{"code": 0, "tenant_access_token": "t-test", "expire": 7200})
+		case "/open-apis/echo":
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			json.NewEncoder(w).Encode(map[string]any{
+				"code": 0,
+				"data": map[string]string{"authorization": r.Header.Get("Authorization"), "text": body["text"]},
+			})
+		case "/open-apis/fail":
+			json.NewEncoder(w).Encode(map[string]any{"code": 230001, "msg": "invalid request"})
+		default:
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+	}))
+	t.Cleanup(server.Close)
+	setEnvFixture(t, map[string]string{"PLUGIN_API_BASE_URL": server.URL, "PLUGIN_STATE_DIR": ""})
+	return server
+}
+
+func TestFetchTenantAccessToken(t *testing.T) {
+	setupOpenAPIServer(t)
+
+	token, err := fetchTenantAccessToken(context.Background(), "cli_test", "app_secret")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if token.Token != "t-test" {
+		t.Errorf("Expected token 't-test', got '%s'", token.Token)
+	}
+}
+
+func TestCallOpenAPI(t *testing.T) {
+	setupOpenAPIServer(t)
+
+	var data struct {
+		Authorization string `json:"authorization"`
+		Text          string `json:"text"`
+	}
+	err := withTenantAccessToken(context.Background(), "cli_test", "app_secret", func(token string) error {
+		return callOpenAPI(context.Background(), http.MethodPost, "/open-apis/echo", token, map[string]string{"text": "hello"}, &data)
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if data.Authorization != "Bearer t-test" || data.Text != "hello" {
+		t.Errorf("Unexpected response data %+v", data)
+	}
+}
+
+func TestCallOpenAPI_APIError(t *testing.T) {
+	setupOpenAPIServer(t)
+
+	err := callOpenAPI(context.Background(), http.MethodPost, "/open-apis/fail", "t-test", map[string]string{}, nil)
+	var apiErr *larkAPIError
+	if !errors.As(err, &apiErr) || apiErr.Code != 230001 {
+		t.Errorf("Expected Lark API error 230001, got %v", err)
+	}
+}
+
+func TestOpenAPI_CanceledContext(t *testing.T) {
+	setupOpenAPIServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := fetchTenantAccessToken(ctx, "cli_test", "app_secret"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the token request to be canceled, got %v", err)
+	}
+	err := callOpenAPI(ctx, http.MethodPost, "/open-apis/echo", "t-test", map[string]string{"text": "hello"}, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the API call to be canceled, got %v", err)
+	}
+	if err := deliverToTarget(ctx, chatTargetPrefix+"oc_test", []byte(`{"msg_type":"text","content":{"text":"hello"}}`), ""); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the chat delivery to be canceled, got %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// outputFilePath returns the file for the payload of target i. When targets
+// get different payloads, public ones or ones signed with another secret, each
+// one is written to its own file, with the 1-based target index before the
+// extension.
+func outputFilePath(path string, i int, perTarget bool) string {
+	if !perTarget {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s.%d%s", strings.TrimSuffix(path, ext), i+1, ext)
+}
+
+// writeOutputFile writes a payload with 0600 permissions, indented when
+// PLUGIN_OUTPUT_PRETTY is true
+func writeOutputFile(path string, payload []byte) error {
+	data := payload
+	if getEnvOrDefault("PLUGIN_OUTPUT_PRETTY", "false") == "true" {
+		var pretty bytes.Buffer
+		if err := json.Indent(&pretty, payload, "", "  "); err != nil {
+			return err
+		}
+		data = pretty.Bytes()
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		return err
+	}
+	// WriteFile keeps the mode of an existing file
+	return os.Chmod(path, 0600)
+}
+
+// writeOutputFiles archives the payloads sent to the targets to
+// PLUGIN_OUTPUT_FILE. Errors are warnings unless PLUGIN_FAIL_ON_ERROR is
+// explicitly true.
+func writeOutputFiles(sent [][]byte) error {
+	path := getEnvOrDefault("PLUGIN_OUTPUT_FILE", "")
+	if path == "" {
+		return nil
+	}
+
+	perTarget := false
+	for _, payload := range sent[1:] {
+		if !bytes.Equal(payload, sent[0]) {
+			perTarget = true
+		}
+	}
+	var errs []error
+	for i, payload := range sent {
+		if i > 0 && !perTarget {
+			break
+		}
+		file := outputFilePath(path, i, perTarget)
+		if err := writeOutputFile(file, payload); err != nil {
+			errs = append(errs, fmt.Errorf("writing PLUGIN_OUTPUT_FILE %s: %v", file, err))
+		}
+	}
+
+	if len(errs) > 0 && getEnvOrDefault("PLUGIN_FAIL_ON_ERROR", "") != "true" {
+		for _, err := range errs {
+			logWarn(err.Error())
+		}
+		return nil
+	}
+	return errors.Join(errs...)
+}
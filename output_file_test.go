@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMain_OutputFile(t *testing.T) {
+	received := map[string][]byte{}
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received[r.URL.Path], _ = io.ReadAll(r.Body)
+		w.Write([]byte(`{"code": 0}`))
+	}))
+	defer testServer.Close()
+
+	originalOsExit := osExit
+	defer func() { osExit = originalOsExit }()
+	exitCode := 0
+	osExit = func(code int) { exitCode = code }
+
+	t.Run("Single payload", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "artifacts", "lark.json")
+		setEnvFixture(t, map[string]string{
+			"PLUGIN_WEBHOOK_URL": testServer.URL + "/a," + testServer.URL + "/b",
+			"PLUGIN_SECRET":      "lark-secret",
+			"PLUGIN_OUTPUT_FILE": path,
+			"CI_REPO":            "octo/backend",
+		})
+		main()
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Expected output file: %v", err)
+		}
+		if !bytes.Equal(bytes.TrimSuffix(data, []byte("\n")), received["/a"]) || !bytes.Equal(received["/a"], received["/b"]) {
+			t.Errorf("Expected the file to match the sent payload, got %s and %s", data, received["/a"])
+		}
+		if !bytes.Contains(data, []byte(`"sign":`)) {
+			t.Errorf("Expected the signature in the file, got %s", data)
+		}
+		if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+			t.Errorf("Expected mode 0600, got %v", info.Mode().Perm())
+		}
+	})
+
+	t.Run("Pretty payload per target", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "lark.json")
+		setEnvFixture(t, map[string]string{
+			"PLUGIN_WEBHOOK_URL":   testServer.URL + "/private," + testServer.URL + "/public#public",
+			"PLUGIN_OUTPUT_FILE":   path,
+			"PLUGIN_OUTPUT_PRETTY": "true",
+			"CI_REPO":              "octo/backend",
+			"CI_RUNNER_HOSTNAME":   "runner-1",
+		})
+		main()
+
+		for file, target := range map[string]string{"lark.1.json": "/private", "lark.2.json": "/public"} {
+			data, err := os.ReadFile(filepath.Join(filepath.Dir(path), file))
+			if err != nil {
+				t.Fatalf("Expected %s: %v", file, err)
+			}
+			var compact bytes.Buffer
+			json.Compact(&compact, data)
+			if !bytes.Equal(compact.Bytes(), received[target]) {
+				t.Errorf("Expected %s to match the payload sent to %s", file, target)
+			}
+			if !strings.Contains(string(data), "\n  \"msg_type\"") {
+				t.Errorf("Expected indented JSON in %s, got %s", file, data)
+			}
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected no unsuffixed file, got %v", err)
+		}
+	})
+
+	t.Run("Write errors", func(t *testing.T) {
+		blocker := filepath.Join(t.TempDir(), "file")
+		os.WriteFile(blocker, nil, 0600)
+
+		// The setting is read like any other, including from action inputs
+		for _, tc := range []struct {
+			setting     map[string]string
+			failOnError bool
+		}{
+			{map[string]string{"PLUGIN_FAIL_ON_ERROR": ""}, false},
+			{map[string]string{"PLUGIN_FAIL_ON_ERROR": "true"}, true},
+			{map[string]string{"PLUGIN_FAIL_ON_ERROR": "", "INPUT_FAIL_ON_ERROR": "true"}, true},
+		} {
+			setEnvFixture(t, map[string]string{
+				"PLUGIN_WEBHOOK_URL":  testServer.URL + "/a",
+				"PLUGIN_OUTPUT_FILE":  filepath.Join(blocker, "lark.json"),
+				"INPUT_FAIL_ON_ERROR": "",
+			})
+			setEnvFixture(t, tc.setting)
+			exitCode = 0
+			output := captureOutput(t, main)
+
+			if !tc.failOnError && (exitCode != 0 || !strings.Contains(output, "Warning: writing PLUGIN_OUTPUT_FILE")) {
+				t.Errorf("Expected a warning, got exit code %d and %q", exitCode, output)
+			}
+			if tc.failOnError && (exitCode != exitConfiguration || !strings.Contains(output, "Error: writing PLUGIN_OUTPUT_FILE")) {
+				t.Errorf("Expected an error, got exit code %d and %q", exitCode, output)
+			}
+			if !strings.Contains(output, "Done!") {
+				t.Errorf("Expected the message to be sent anyway, got %q", output)
+			}
+		}
+	})
+}
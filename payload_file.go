@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// payloadStdin is read when PLUGIN_PAYLOAD_FILE is "-", overridable in tests
+var payloadStdin io.Reader = os.Stdin
+
+// loadPayloadFile reads the prebuilt message of PLUGIN_PAYLOAD_FILE, a local
+// path, an https:// URL or - for stdin, or nil when the setting is empty. The
+// message is only signed. With PLUGIN_SECRET set, existing sign or timestamp
+// fields are an error unless PLUGIN_FORCE_SIGN is true, which replaces them.
+func loadPayloadFile() (map[string]any, error) {
+	path := getEnvOrDefault("PLUGIN_PAYLOAD_FILE", "")
+	if path == "" {
+		return nil, nil
+	}
+
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(payloadStdin)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read PLUGIN_PAYLOAD_FILE: %w", err)
+		}
+	} else if data, err = loadTemplateSource("PLUGIN_PAYLOAD_FILE", path); err != nil {
+		return nil, err
+	}
+
+	var message map[string]any
+	if err := json.Unmarshal(data, &message); err != nil {
+		return nil, fmt.Errorf("PLUGIN_PAYLOAD_FILE is not a JSON object: %v", err)
+	}
+	if msgType, _ := message["msg_type"].(string); msgType == "" {
+		return nil, fmt.Errorf(`PLUGIN_PAYLOAD_FILE has no msg_type, expected a complete Lark message such as {"msg_type": "interactive", "card": {...}}`)
+	}
+
+	if getEnvOrDefault("PLUGIN_SECRET", "") != "" && getEnvOrDefault("PLUGIN_FORCE_SIGN", "false") != "true" {
+		for _, field := range []string{"sign", "timestamp"} {
+			if _, ok := message[field]; ok {
+				return nil, fmt.Errorf("PLUGIN_PAYLOAD_FILE already has a %s field, set PLUGIN_FORCE_SIGN=true to sign it again with PLUGIN_SECRET", field)
+			}
+		}
+	}
+	return message, nil
+}
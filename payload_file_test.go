@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMain_PayloadFile(t *testing.T) {
+	var received map[string]any
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = nil
+		json.Unmarshal(body, &received)
+		w.Write([]byte(`{"code": 0}`))
+	}))
+	defer testServer.Close()
+
+	originalOsExit := osExit
+	originalStdin := payloadStdin
+	defer func() {
+		osExit = originalOsExit
+		payloadStdin = originalStdin
+	}()
+
+	dir := t.TempDir()
+	writePayload := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(content), 0600)
+		return path
+	}
+	card := `{"msg_type": "interactive", "card": {"elements": [{"tag": "div", "text": {"tag": "plain_text", "content": "prebuilt"}}]}}`
+	signed := `{"msg_type": "text", "content": {"text": "hi"}, "timestamp": "1", "sign": "old"}`
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		stdin    string
+		exitCode int
+		output   string
+		check    func(t *testing.T, received map[string]any)
+	}{
+		{
+			name:  "File",
+			env:   map[string]string{"PLUGIN_PAYLOAD_FILE": writePayload("card.json", card)},
+			check: expectPrebuiltCard,
+		},
+		{
+			name:  "Stdin",
+			env:   map[string]string{"PLUGIN_PAYLOAD_FILE": "-"},
+			stdin: card,
+			check: expectPrebuiltCard,
+		},
+		{
+			name: "Signature injected",
+			env:  map[string]string{"PLUGIN_PAYLOAD_FILE": writePayload("card.json", card), "PLUGIN_SECRET": "lark-secret"},
+			check: func(t *testing.T, received map[string]any) {
+				expectPrebuiltCard(t, received)
+				timestamp, _ := received["timestamp"].(string)
+				if timestamp == "" || received["sign"] != generateSignature(timestamp, "lark-secret") {
+					t.Errorf("Expected a valid signature, got %v", received)
+				}
+			},
+		},
+		{
+			name:     "Existing signature rejected",
+			env:      map[string]string{"PLUGIN_PAYLOAD_FILE": writePayload("signed.json", signed), "PLUGIN_SECRET": "lark-secret"},
+			exitCode: exitConfiguration,
+			output:   "Error: PLUGIN_PAYLOAD_FILE already has a sign field, set PLUGIN_FORCE_SIGN=true",
+		},
+		{
+			name: "Existing signature replaced",
+			env:  map[string]string{"PLUGIN_PAYLOAD_FILE": writePayload("signed.json", signed), "PLUGIN_SECRET": "lark-secret", "PLUGIN_FORCE_SIGN": "true"},
+			check: func(t *testing.T, received map[string]any) {
+				if received["sign"] == "old" || received["timestamp"] == "1" {
+					t.Errorf("Expected a new signature, got %v", received)
+				}
+			},
+		},
+		{
+			name:     "Invalid JSON",
+			env:      map[string]string{"PLUGIN_PAYLOAD_FILE": writePayload("broken.json", `{"msg_type": `)},
+			exitCode: exitConfiguration,
+			output:   "Error: PLUGIN_PAYLOAD_FILE is not a JSON object",
+		},
+		{
+			name:     "Missing msg_type",
+			env:      map[string]string{"PLUGIN_PAYLOAD_FILE": writePayload("untyped.json", `{"card": {}}`)},
+			exitCode: exitConfiguration,
+			output:   "Error: PLUGIN_PAYLOAD_FILE has no msg_type",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.env["PLUGIN_WEBHOOK_URL"] = testServer.URL
+			tt.env["CI_REPO"] = "octo/backend"
+			setEnvFixture(t, tt.env)
+			payloadStdin = strings.NewReader(tt.stdin)
+			received = nil
+			exitCode := 0
+			osExit = func(code int) { exitCode = code }
+
+			output := captureOutput(t, main)
+
+			if exitCode != tt.exitCode {
+				t.Errorf("Expected exit code %d, got %d", tt.exitCode, exitCode)
+			}
+			if !strings.Contains(output, tt.output) {
+				t.Errorf("Expected output to contain %q, got %q", tt.output, output)
+			}
+			if tt.check != nil {
+				tt.check(t, received)
+			} else if received != nil {
+				t.Errorf("Expected nothing to be sent, got %v", received)
+			}
+		})
+	}
+}
+
+// expectPrebuiltCard checks that the message was sent without being rebuilt
+func expectPrebuiltCard(t *testing.T, received map[string]any) {
+	t.Helper()
+	card, _ := json.Marshal(received["card"])
+	if received["msg_type"] != "interactive" || string(card) != `{"elements":[{"tag":"div","text":{"content":"prebuilt","tag":"plain_text"}}]}` {
+		t.Errorf("Expected the prebuilt card, got %v", received)
+	}
+}
+
+func TestLoadPayloadFile_URL(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"msg_type": "text", "content": {"text": "remote"}}`))
+	}))
+	t.Cleanup(server.Close)
+	originalClient := templateFetchClient
+	templateFetchClient = server.Client()
+	t.Cleanup(func() { templateFetchClient = originalClient })
+
+	setEnvFixture(t, map[string]string{"PLUGIN_PAYLOAD_FILE": server.URL + "/card.json"})
+	message, err := loadPayloadFile()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if message["msg_type"] != "text" {
+		t.Errorf("Expected the downloaded message, got %v", message)
+	}
+
+	setEnvFixture(t, map[string]string{"PLUGIN_PAYLOAD_FILE": "http://example.com/card.json"})
+	if _, err := loadPayloadFile(); err == nil || !strings.Contains(err.Error(), "PLUGIN_PAYLOAD_FILE must use https") {
+		t.Errorf("Expected plain http to be refused, got %v", err)
+	}
+}
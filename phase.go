@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// Phases selected by PLUGIN_PHASE. In the start phase a running card is sent
+// to the chats and its message ids are stored; the finish phase updates those
+// cards with the final status.
+const (
+	phaseStart  = "start"
+	phaseFinish = "finish"
+)
+
+// defaultPhaseStateFile is the state file name, relative to the workspace
+const defaultPhaseStateFile = ".lark-notify-state"
+
+// phaseState is the content of the state file written by the start phase
+type phaseState struct {
+	// Messages maps chat ids to the message ids of their running cards
+	Messages map[string]string `json:"messages"`
+}
+
+// phaseMessages holds the message ids sent in the start phase or read for the
+// finish phase
+var phaseMessages map[string]string
+
+func getPhase() string {
+	return getEnvOrDefault("PLUGIN_PHASE", "")
+}
+
+// checkPhase reports an unknown phase, or a phase without the app bot
+// credentials and chats needed to update messages
+func checkPhase(phase string, canUseChats bool) error {
+	switch phase {
+	case "":
+		return nil
+	case phaseStart, phaseFinish:
+	default:
+		return fmt.Errorf("PLUGIN_PHASE must be %s or %s, got %q", phaseStart, phaseFinish, phase)
+	}
+	if !canUseChats {
+		return fmt.Errorf("PLUGIN_PHASE=%s needs PLUGIN_APP_ID, PLUGIN_APP_SECRET and PLUGIN_CHAT_ID: only messages sent by the app bot can be updated, not webhook messages", phase)
+	}
+	return nil
+}
+
+// phaseWebhookURLs returns the webhook targets of the current phase. Webhook
+// messages cannot be updated, so they are only sent with the final status.
+func phaseWebhookURLs(webhookURLs []string) []string {
+	if getPhase() == phaseStart && len(webhookURLs) > 0 {
+		logInfo("Webhook targets are notified in the finish phase", "phase", phaseStart)
+		return nil
+	}
+	return webhookURLs
+}
+
+func phaseStatePath() string {
+	if path := getEnvOrDefault("PLUGIN_STATE_FILE", ""); path != "" {
+		return path
+	}
+	return filepath.Join(getEnvOrDefault("CI_WORKSPACE", ""), defaultPhaseStateFile)
+}
+
+// loadPhaseState reads the message ids of the start phase. Without them the
+// finish phase sends new messages.
+func loadPhaseState() {
+	phaseMessages = map[string]string{}
+	if getPhase() != phaseFinish {
+		return
+	}
+
+	path := phaseStatePath()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		logWarn("no start phase state found, sending new messages", "path", path)
+		return
+	}
+	var state phaseState
+	if err == nil {
+		err = json.Unmarshal(data, &state)
+	}
+	if err != nil {
+		logWarn(fmt.Sprintf("cannot read the start phase state, sending new messages: %v", err), "path", path)
+		return
+	}
+	if state.Messages != nil {
+		phaseMessages = state.Messages
+	}
+}
+
+// savePhaseState stores the message ids sent in the start phase
+func savePhaseState() error {
+	if getPhase() != phaseStart || len(phaseMessages) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(phaseState{Messages: phaseMessages})
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(phaseStatePath(), data, 0600); err != nil {
+		return fmt.Errorf("cannot write the start phase state: %w", err)
+	}
+	return nil
+}
+
+// shareCard sets config.update_multi on the cards sent in a phase. Lark only
+// lets PATCH im/v1/messages/{id} update shared cards, so both the running
+// card and its final version must be shared.
+func shareCard(body map[string]string) error {
+	if getPhase() == "" || body["msg_type"] != "interactive" {
+		return nil
+	}
+	var card map[string]any
+	if err := json.Unmarshal([]byte(body["content"]), &card); err != nil {
+		return err
+	}
+	config, _ := card["config"].(map[string]any)
+	if config == nil {
+		config = map[string]any{}
+	}
+	config["update_multi"] = true
+	card["config"] = config
+	content, err := json.Marshal(card)
+	if err != nil {
+		return err
+	}
+	body["content"] = string(content)
+	return nil
+}
+
+// updateChatMessage replaces the card of a message sent by the app bot
+func updateChatMessage(ctx context.Context, messageID string, messageBytes []byte) error {
+	body, err := openAPIMessage("", messageBytes)
+	if err != nil {
+		return err
+	}
+	if body["msg_type"] != "interactive" {
+		return fmt.Errorf("only cards can be updated, not %s messages", body["msg_type"])
+	}
+	if err := shareCard(body); err != nil {
+		return err
+	}
+
+	appID := getEnvOrDefault("PLUGIN_APP_ID", "")
+	appSecret := getEnvOrDefault("PLUGIN_APP_SECRET", "")
+	return withTenantAccessToken(ctx, appID, appSecret, func(token string) error {
+		return callOpenAPI(ctx, http.MethodPatch, "/open-apis/im/v1/messages/"+messageID, token, map[string]string{"content": body["content"]}, nil)
+	})
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// phaseRequest is a message request received by setupPhaseServer
+type phaseRequest struct {
+	Method string
+	Path   string
+	Body   map[string]string
+}
+
+// setupPhaseServer mimics the token, send and update endpoints. Sent messages
+// get the id om_1; updates of message ids in failing get an API error.
+func setupPhaseServer(t *testing.T, failing map[string]bool) *[]phaseRequest {
+	var mu sync.Mutex
+	var received []phaseRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/open-apis/auth/v3/tenant_access_token/internal" {
+			json.NewEncoder(w).Encode(map[string]any{"code": 0, "tenant_access_token": "t-1", "expire": 7200})
+			return
+		}
+
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		received = append(received, phaseRequest{r.Method, r.URL.Path, body})
+		mu.Unlock()
+
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/open-apis/im/v1/messages":
+			json.NewEncoder(w).Encode(map[string]any{"code": 0, "data": map[string]any{"message_id": "om_1"}})
+		case r.Method == http.MethodPatch && strings.HasPrefix(r.URL.Path, "/open-apis/im/v1/messages/"):
+			if failing[strings.TrimPrefix(r.URL.Path, "/open-apis/im/v1/messages/")] {
+				json.NewEncoder(w).Encode(map[string]any{"code": 230001, "msg": "message not found"})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{"code": 0})
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	originalOsExit := osExit
+	t.Cleanup(func() { osExit = originalOsExit })
+
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_API_BASE_URL": server.URL,
+		"PLUGIN_APP_ID":       "cli_test",
+		"PLUGIN_APP_SECRET":   "app_secret",
+		"PLUGIN_CHAT_ID":      "oc_one",
+		"PLUGIN_PHASE":        "",
+		"PLUGIN_WEBHOOK_URL":  "",
+		"PLUGIN_STATE_FILE":   filepath.Join(t.TempDir(), "state.json"),
+		"CI_REPO_NAME":        "backend",
+		"DRONE_BUILD_STATUS":  "failure",
+	})
+	return &received
+}
+
+// cardHeaderTemplate returns the header color of a card sent as OpenAPI content
+func cardHeaderTemplate(t *testing.T, content string) string {
+	t.Helper()
+	var card map[string]any
+	if err := json.Unmarshal([]byte(content), &card); err != nil {
+		t.Fatalf("Expected the card as a JSON string, got %q", content)
+	}
+	return card["header"].(map[string]any)["template"].(string)
+}
+
+// cardUpdateMulti returns config.update_multi of a card sent as OpenAPI content
+func cardUpdateMulti(t *testing.T, content string) any {
+	t.Helper()
+	var card struct {
+		Config map[string]any `json:"config"`
+	}
+	if err := json.Unmarshal([]byte(content), &card); err != nil {
+		t.Fatalf("Expected the card as a JSON string, got %q", content)
+	}
+	return card.Config["update_multi"]
+}
+
+func TestMain_PhaseStartAndFinish(t *testing.T) {
+	received := setupPhaseServer(t, nil)
+	exitCode := 0
+	osExit = func(code int) { exitCode = code }
+
+	os.Setenv("PLUGIN_PHASE", phaseStart)
+	main()
+
+	if exitCode != 0 {
+		t.Fatalf("Expected exit code 0 after the start phase, got %d", exitCode)
+	}
+	if len(*received) != 1 || (*received)[0].Method != http.MethodPost {
+		t.Fatalf("Expected one new message, got %v", *received)
+	}
+	if color := cardHeaderTemplate(t, (*received)[0].Body["content"]); color != "blue" {
+		t.Errorf("Expected a blue running card, got %s", color)
+	}
+	if shared := cardUpdateMulti(t, (*received)[0].Body["content"]); shared != true {
+		t.Errorf("Expected the running card to be shared with update_multi, got %v", shared)
+	}
+	data, err := os.ReadFile(os.Getenv("PLUGIN_STATE_FILE"))
+	if err != nil || !strings.Contains(string(data), `"oc_one":"om_1"`) {
+		t.Fatalf("Expected the message id in the state file, got %s (%v)", data, err)
+	}
+
+	os.Setenv("PLUGIN_PHASE", phaseFinish)
+	main()
+
+	if exitCode != 0 {
+		t.Fatalf("Expected exit code 0 after the finish phase, got %d", exitCode)
+	}
+	if len(*received) != 2 {
+		t.Fatalf("Expected an update after the start message, got %v", *received)
+	}
+	update := (*received)[1]
+	if update.Method != http.MethodPatch || update.Path != "/open-apis/im/v1/messages/om_1" {
+		t.Errorf("Expected PATCH of om_1, got %s %s", update.Method, update.Path)
+	}
+	if color := cardHeaderTemplate(t, update.Body["content"]); color != "red" {
+		t.Errorf("Expected the updated card to be red, got %s", color)
+	}
+	if shared := cardUpdateMulti(t, update.Body["content"]); shared != true {
+		t.Errorf("Expected the updated card to stay shared, got %v", shared)
+	}
+}
+
+func TestMain_PhaseFinishFallsBack(t *testing.T) {
+	tests := []struct {
+		name  string
+		state string
+	}{
+		{"missing state", ""},
+		{"failed update", `{"messages":{"oc_one":"om_gone"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received := setupPhaseServer(t, map[string]bool{"om_gone": true})
+			os.Setenv("PLUGIN_PHASE", phaseFinish)
+			if tt.state != "" {
+				os.WriteFile(os.Getenv("PLUGIN_STATE_FILE"), []byte(tt.state), 0600)
+			}
+			exitCode := 0
+			osExit = func(code int) { exitCode = code }
+
+			output := captureOutput(t, main)
+
+			if exitCode != 0 {
+				t.Errorf("Expected exit code 0, got %d", exitCode)
+			}
+			last := (*received)[len(*received)-1]
+			if last.Method != http.MethodPost || last.Body["receive_id"] != "oc_one" {
+				t.Errorf("Expected a new message to oc_one, got %v", *received)
+			}
+			if color := cardHeaderTemplate(t, last.Body["content"]); color != "red" {
+				t.Errorf("Expected a red card, got %s", color)
+			}
+			if !strings.Contains(output, "Warning: ") {
+				t.Errorf("Expected a warning about the fallback, got:\n%s", output)
+			}
+		})
+	}
+}
+
+func TestMain_PhaseStartSkipsWebhooks(t *testing.T) {
+	received := setupPhaseServer(t, nil)
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_PHASE":       phaseStart,
+		"PLUGIN_WEBHOOK_URL": "http://127.0.0.1:1/hook",
+	})
+	exitCode := 0
+	osExit = func(code int) { exitCode = code }
+
+	output := captureOutput(t, main)
+
+	if exitCode != 0 {
+		t.Errorf("Expected exit code 0, got %d:\n%s", exitCode, output)
+	}
+	if len(*received) != 1 {
+		t.Errorf("Expected only the chat message, got %v", *received)
+	}
+	if !strings.Contains(output, "Webhook targets are notified in the finish phase") {
+		t.Errorf("Expected a note about the webhook targets, got:\n%s", output)
+	}
+}
+
+func TestCheckPhase(t *testing.T) {
+	tests := []struct {
+		phase       string
+		canUseChats bool
+		wantErr     string
+	}{
+		{"", false, ""},
+		{phaseStart, true, ""},
+		{phaseFinish, true, ""},
+		{"begin", true, `PLUGIN_PHASE must be start or finish, got "begin"`},
+		{phaseStart, false, "needs PLUGIN_APP_ID, PLUGIN_APP_SECRET and PLUGIN_CHAT_ID"},
+	}
+	for _, tt := range tests {
+		err := checkPhase(tt.phase, tt.canUseChats)
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("checkPhase(%q, %v): expected no error, got %v", tt.phase, tt.canUseChats, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("checkPhase(%q, %v): expected %q, got %v", tt.phase, tt.canUseChats, tt.wantErr, err)
+		}
+	}
+}
+
+func TestMain_PhaseNeedsAppBot(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_PHASE":       phaseStart,
+		"PLUGIN_WEBHOOK_URL": "http://127.0.0.1:1/hook",
+		"PLUGIN_APP_ID":      "",
+	})
+	originalOsExit := osExit
+	defer func() { osExit = originalOsExit }()
+	exitCode := 0
+	osExit = func(code int) { exitCode = code }
+
+	output := captureOutput(t, main)
+
+	if exitCode != exitConfiguration || !strings.Contains(output, "only messages sent by the app bot can be updated") {
+		t.Errorf("Expected exit code 2 explaining the app bot requirement, got %d:\n%s", exitCode, output)
+	}
+}
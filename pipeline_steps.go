@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxPipelineSteps caps the steps listed for large pipelines
+const maxPipelineSteps = 10
+
+// ciAPIClient is the HTTP client used for CI server API calls
+var ciAPIClient = &http.Client{Timeout: 10 * time.Second}
+
+// woodpeckerStep is a step as returned by the Woodpecker API
+type woodpeckerStep struct {
+	PID      int    `json:"pid"`
+	Name     string `json:"name"`
+	State    string `json:"state"`
+	Started  int64  `json:"start_time"`
+	Finished int64  `json:"end_time"`
+}
+
+// woodpeckerPipeline is the part of a Woodpecker pipeline the plugin uses
+type woodpeckerPipeline struct {
+	Workflows []struct {
+		Name     string           `json:"name"`
+		Children []woodpeckerStep `json:"children"`
+	} `json:"workflows"`
+}
+
+// pipelineStep is a step of the current pipeline as shown in the Steps section
+type pipelineStep struct {
+	PID      int
+	Name     string
+	State    string
+	Duration time.Duration
+}
+
+// buildSteps is set by main when the steps could be fetched
+var buildSteps []pipelineStep
+
+// getCIAPI calls the CI server API and decodes the JSON response into result
+func getCIAPI(path string, result any) error {
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(getEnvOrDefault("CI_SYSTEM_URL", ""), "/")+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+getEnvOrDefault("PLUGIN_CI_TOKEN", ""))
+
+	resp, err := ciAPIClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d from %s", resp.StatusCode, path)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, result)
+}
+
+// getCIRepoID returns the Woodpecker id of the repository, from PLUGIN_CI_REPO_ID
+// or CI_REPO_ID, looking it up by name otherwise
+func getCIRepoID() (string, error) {
+	if id := getEnvOrDefault("PLUGIN_CI_REPO_ID", getEnvOrDefault("CI_REPO_ID", "")); id != "" {
+		return id, nil
+	}
+
+	var repo struct {
+		ID int64 `json:"id"`
+	}
+	if err := getCIAPI("/api/repos/lookup/"+getEnvOrDefault("CI_REPO", ""), &repo); err != nil {
+		return "", fmt.Errorf("looking up repository: %w", err)
+	}
+	return fmt.Sprint(repo.ID), nil
+}
+
+// loadPipelineSteps reads the steps of the current pipeline from the
+// Woodpecker API. It needs PLUGIN_CI_TOKEN, CI_SYSTEM_URL and the pipeline
+// number; any error only warns.
+func loadPipelineSteps() []pipelineStep {
+	number := getPipelineNumber()
+	if getEnvOrDefault("PLUGIN_CI_TOKEN", "") == "" || getEnvOrDefault("CI_SYSTEM_URL", "") == "" || number == "" {
+		return nil
+	}
+
+	repoID, err := getCIRepoID()
+	if err != nil {
+		logWarn(fmt.Sprintf("cannot fetch pipeline steps: %v", err))
+		return nil
+	}
+
+	var pipeline woodpeckerPipeline
+	if err := getCIAPI(fmt.Sprintf("/api/repos/%s/pipelines/%s", url.PathEscape(repoID), url.PathEscape(number)), &pipeline); err != nil {
+		logWarn(fmt.Sprintf("cannot fetch pipeline steps: %v", err))
+		return nil
+	}
+
+	multipleWorkflows := len(pipeline.Workflows) > 1
+	var steps []pipelineStep
+	for _, workflow := range pipeline.Workflows {
+		for _, child := range workflow.Children {
+			step := pipelineStep{PID: child.PID, Name: child.Name, State: child.State}
+			if multipleWorkflows {
+				step.Name = workflow.Name + "/" + child.Name
+			}
+			if child.Started > 0 && child.Finished >= child.Started {
+				step.Duration = time.Duration(child.Finished-child.Started) * time.Second
+			}
+			steps = append(steps, step)
+		}
+	}
+	return relevantSteps(steps)
+}
+
+// relevantSteps keeps at most maxPipelineSteps steps, preferring failed and
+// then slow ones, in pipeline order
+func relevantSteps(steps []pipelineStep) []pipelineStep {
+	if len(steps) <= maxPipelineSteps {
+		return steps
+	}
+
+	order := make([]int, len(steps))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		stepA, stepB := steps[order[a]], steps[order[b]]
+		if failedA, failedB := isFailedStatus(stepA.State), isFailedStatus(stepB.State); failedA != failedB {
+			return failedA
+		}
+		return stepA.Duration > stepB.Duration
+	})
+
+	keep := order[:maxPipelineSteps]
+	sort.Ints(keep)
+	var relevant []pipelineStep
+	for _, i := range keep {
+		relevant = append(relevant, steps[i])
+	}
+	return relevant
+}
+
+// stepLine renders a step with its status icon and duration
+func stepLine(step pipelineStep) string {
+	line := withIcon(classifyStatus(step.State).Icon, step.Name)
+	if step.Duration > 0 {
+		line += " · " + formatDuration(step.Duration)
+	}
+	return line
+}
+
+// createStepsElements returns the Steps section, with failed steps in bold
+func createStepsElements() []map[string]any {
+	if len(buildSteps) == 0 {
+		return nil
+	}
+
+	content := fmt.Sprintf("**%s:**", tr("Steps"))
+	for _, step := range buildSteps {
+		line := escapeMarkdown(stepLine(step))
+		if isFailedStatus(step.State) {
+			line = "**" + line + "**"
+		}
+		content += "\n" + line
+	}
+	return []map[string]any{
+		{
+			"tag": "hr",
+		},
+		{
+			"tag": "div",
+			"text": map[string]any{
+				"content": content,
+				"tag":     "lark_md",
+			},
+		},
+	}
+}
+
+// createStepsText lists the steps in text messages, marking failed ones
+func createStepsText() string {
+	if len(buildSteps) == 0 {
+		return ""
+	}
+
+	text := "\n" + withIcon("🪜", tr("Steps")+":\n")
+	for _, step := range buildSteps {
+		line := stepLine(step)
+		if isFailedStatus(step.State) {
+			line += " ← " + strings.ToUpper(step.State)
+		}
+		text += "• " + escapeText(line) + "\n"
+	}
+	return text
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const woodpeckerPipelineFixture = `{
+  "id": 812,
+  "number": 42,
+  "status": "failure",
+  "workflows": [
+    {
+      "id": 1530,
+      "name": "build",
+      "state": "failure",
+      "children": [
+        {"id": 1531, "pid": 2, "name": "clone", "state": "success", "start_time": 1700000000, "end_time": 1700000004},
+        {"id": 1532, "pid": 3, "name": "test", "state": "failure", "start_time": 1700000004, "end_time": 1700000066},
+        {"id": 1533, "pid": 4, "name": "notify", "state": "skipped", "start_time": 0, "end_time": 0}
+      ]
+    }
+  ]
+}`
+
+func TestLoadPipelineSteps(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.Header.Get("Authorization") != "Bearer ci-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/repos/lookup/octo/app":
+			fmt.Fprint(w, `{"id": 7, "full_name": "octo/app"}`)
+		case "/api/repos/7/pipelines/42":
+			fmt.Fprint(w, woodpeckerPipelineFixture)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_CI_TOKEN":    "ci-token",
+		"CI_SYSTEM_URL":      server.URL + "/",
+		"CI_REPO":            "octo/app",
+		"CI_PIPELINE_NUMBER": "42",
+	})
+
+	expected := []pipelineStep{
+		{PID: 2, Name: "clone", State: "success", Duration: 4 * time.Second},
+		{PID: 3, Name: "test", State: "failure", Duration: 62 * time.Second},
+		{PID: 4, Name: "notify", State: "skipped"},
+	}
+	if steps := loadPipelineSteps(); !reflect.DeepEqual(steps, expected) {
+		t.Errorf("Expected %v, got %v", expected, steps)
+	}
+	if !reflect.DeepEqual(paths, []string{"/api/repos/lookup/octo/app", "/api/repos/7/pipelines/42"}) {
+		t.Errorf("Unexpected requests: %v", paths)
+	}
+
+	t.Run("Repo id from the environment", func(t *testing.T) {
+		paths = nil
+		t.Setenv("CI_REPO_ID", "7")
+		if steps := loadPipelineSteps(); len(steps) != 3 {
+			t.Errorf("Expected 3 steps, got %v", steps)
+		}
+		if !reflect.DeepEqual(paths, []string{"/api/repos/7/pipelines/42"}) {
+			t.Errorf("Expected no lookup, got %v", paths)
+		}
+	})
+
+	t.Run("API error warns and skips", func(t *testing.T) {
+		t.Setenv("PLUGIN_CI_TOKEN", "wrong")
+		var steps []pipelineStep
+		output := captureStdout(t, func() { steps = loadPipelineSteps() })
+		if steps != nil {
+			t.Errorf("Expected no steps, got %v", steps)
+		}
+		if !strings.Contains(output, "HTTP 401 from") {
+			t.Errorf("Expected a warning, got %q", output)
+		}
+	})
+
+	t.Run("Not configured", func(t *testing.T) {
+		paths = nil
+		t.Setenv("PLUGIN_CI_TOKEN", "")
+		if steps := loadPipelineSteps(); steps != nil || paths != nil {
+			t.Errorf("Expected no request, got %v and %v", steps, paths)
+		}
+	})
+}
+
+func TestRelevantSteps(t *testing.T) {
+	var steps []pipelineStep
+	for i := 1; i <= 14; i++ {
+		steps = append(steps, pipelineStep{Name: fmt.Sprintf("step%d", i), State: "success", Duration: time.Duration(i) * time.Second})
+	}
+	steps[0].State = "failure"
+
+	var names []string
+	for _, step := range relevantSteps(steps) {
+		names = append(names, step.Name)
+	}
+	expected := []string{"step1", "step6", "step7", "step8", "step9", "step10", "step11", "step12", "step13", "step14"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected %v, got %v", expected, names)
+	}
+}
+
+func TestStepsSection(t *testing.T) {
+	setEnvFixture(t, map[string]string{"CI_REPO_NAME": "app"})
+	saved := buildSteps
+	defer func() { buildSteps = saved }()
+	buildSteps = []pipelineStep{
+		{Name: "clone", State: "success", Duration: 4 * time.Second},
+		{Name: "test<at>", State: "failure", Duration: 62 * time.Second},
+	}
+
+	var content string
+	for _, element := range createLarkCard(Config{}, "v1.0.0")["card"].(map[string]any)["elements"].([]map[string]any) {
+		if text, ok := element["text"].(map[string]any); ok && strings.HasPrefix(text["content"].(string), "**Steps:**") {
+			content = text["content"].(string)
+		}
+	}
+	expectedCard := "**Steps:**\n✅ clone · 4s\n**🚨 test&lt;at&gt; · 1m 2s**"
+	if content != expectedCard {
+		t.Errorf("Expected card section %q, got %q", expectedCard, content)
+	}
+
+	text := createLarkTextMessage(Config{}, "v1.0.0")["content"].(map[string]any)["text"].(string)
+	if !strings.Contains(text, "🪜 Steps:\n• ✅ clone · 4s\n• 🚨 test<\u200bat> · 1m 2s ← FAILURE\n") {
+		t.Errorf("Expected the steps in the text message, got %q", text)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+func getPipelineNumber() string {
+	return getEnvOrDefault("CI_PIPELINE_NUMBER", getEnvOrDefault("DRONE_BUILD_NUMBER", ""))
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// pipelineURLForNumber derives the URL of pipeline number from pipelineURL, the URL
+// of pipeline current. Woodpecker URLs look like …/repos/7/pipeline/123[/2], Drone
+// URLs like …/org/repo/123[/1/2]; any workflow/step suffix is dropped. It returns
+// "" when the number segment can't be identified.
+func pipelineURLForNumber(pipelineURL, current, number string) string {
+	u, err := url.Parse(pipelineURL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	index := -1
+
+	// Woodpecker: the number follows the "pipeline" segment
+	for i := 0; i < len(segments)-1; i++ {
+		if segments[i] == "pipeline" && isDigits(segments[i+1]) {
+			index = i + 1
+			break
+		}
+	}
+
+	// Drone: the first segment matching the current number
+	if index == -1 {
+		for i, segment := range segments {
+			if isDigits(segment) && (current == "" || segment == current) {
+				index = i
+				break
+			}
+		}
+	}
+
+	if index == -1 {
+		return ""
+	}
+
+	segments = append(segments[:index], number)
+	u.Path = "/" + strings.Join(segments, "/")
+	u.RawPath = ""
+	return u.String()
+}
+
+// relatedPipeline is another run of the repository shown in the details
+type relatedPipeline struct {
+	Label  string
+	Icon   string
+	Number string
+	URL    string
+}
+
+// markdown links the number to the pipeline when its URL is known
+func (p relatedPipeline) markdown() string {
+	if p.URL == "" {
+		return "#" + p.Number
+	}
+	return fmt.Sprintf("[#%s](%s)", p.Number, p.URL)
+}
+
+// text is the number followed by the URL when it is known
+func (p relatedPipeline) text() string {
+	if p.URL == "" {
+		return "#" + p.Number
+	}
+	return fmt.Sprintf("#%s %s", p.Number, p.URL)
+}
+
+// pipelineNumberSetting returns the pipeline number in setting, or "" when it
+// is unset, zero or not a number
+func pipelineNumberSetting(setting string) string {
+	number := getEnvOrDefault(setting, "")
+	if n, err := strconv.Atoi(number); err != nil || n <= 0 {
+		return ""
+	}
+	return number
+}
+
+// getParentPipeline returns the number and URL of the upstream pipeline that
+// spawned this child pipeline, PLUGIN_PARENT_PIPELINE. The URL is
+// PLUGIN_PARENT_URL or derived from CI_PIPELINE_URL, "" when it can't be.
+func getParentPipeline() (number, parentURL string) {
+	parent := pipelineNumberSetting("PLUGIN_PARENT_PIPELINE")
+	if parent == "" {
+		return "", ""
+	}
+
+	if override := getEnvOrDefault("PLUGIN_PARENT_URL", ""); override != "" {
+		return parent, override
+	}
+	return parent, pipelineURLForNumber(getEnvOrDefault("CI_PIPELINE_URL", ""), getPipelineNumber(), parent)
+}
+
+// getRestartedFrom returns the number and URL of the pipeline this one was
+// restarted from, CI_PIPELINE_PARENT. The URL is "" when it can't be derived.
+func getRestartedFrom() (number, restartedURL string) {
+	restarted := pipelineNumberSetting("CI_PIPELINE_PARENT")
+	if restarted == "" {
+		return "", ""
+	}
+	return restarted, pipelineURLForNumber(getEnvOrDefault("CI_PIPELINE_URL", ""), getPipelineNumber(), restarted)
+}
+
+// relatedPipelines returns the parent pipeline of a child pipeline and the
+// pipeline a restart was restarted from, each when known
+func relatedPipelines() []relatedPipeline {
+	var pipelines []relatedPipeline
+	if number, parentURL := getParentPipeline(); number != "" {
+		pipelines = append(pipelines, relatedPipeline{Label: "Parent", Icon: "⬆️", Number: number, URL: parentURL})
+	}
+	if number, restartedURL := getRestartedFrom(); number != "" {
+		pipelines = append(pipelines, relatedPipeline{Label: "Restarted from", Icon: "🔁", Number: number, URL: restartedURL})
+	}
+	return pipelines
+}
+
+// headerProjectName returns the project name of header titles followed by the
+// pipeline number, as in "backend #123"
+func headerProjectName() string {
+	name := getEnvOrDefault("CI_REPO_NAME", "")
+	number := getPipelineNumber()
+	switch {
+	case number == "":
+		return name
+	case name == "":
+		return "#" + number
+	default:
+		return name + " #" + number
+	}
+}
+
+// versionValue adds the pipeline number to the version, as in
+// "v1.2.3 (build #123)". In lark_md the number links to the pipeline.
+func versionValue(projectVersion string, markdown bool) string {
+	number := getPipelineNumber()
+	if number == "" {
+		return projectVersion
+	}
+	build := "#" + number
+	if pipelineURL := getEnvOrDefault("CI_PIPELINE_URL", ""); pipelineURL != "" && markdown {
+		build = fmt.Sprintf("[#%s](%s)", number, pipelineURL)
+	}
+	if projectVersion == "" || projectVersion == "build #"+number {
+		return "build " + build
+	}
+	return fmt.Sprintf("%s (build %s)", projectVersion, build)
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPipelineURLForNumber(t *testing.T) {
+	tests := []struct {
+		name        string
+		pipelineURL string
+		current     string
+		number      string
+		expected    string
+	}{
+		{
+			name:        "Woodpecker",
+			pipelineURL: "https://ci.example.com/repos/7/pipeline/123",
+			current:     "123",
+			number:      "456",
+			expected:    "https://ci.example.com/repos/7/pipeline/456",
+		},
+		{
+			name:        "Woodpecker with repo id equal to the pipeline number",
+			pipelineURL: "https://ci.example.com/repos/123/pipeline/123",
+			current:     "123",
+			number:      "99",
+			expected:    "https://ci.example.com/repos/123/pipeline/99",
+		},
+		{
+			name:        "Woodpecker step URL",
+			pipelineURL: "https://ci.example.com/repos/7/pipeline/123/2",
+			current:     "123",
+			number:      "456",
+			expected:    "https://ci.example.com/repos/7/pipeline/456",
+		},
+		{
+			name:        "Woodpecker under a path prefix",
+			pipelineURL: "https://example.com/woodpecker/repos/7/pipeline/123",
+			current:     "",
+			number:      "5",
+			expected:    "https://example.com/woodpecker/repos/7/pipeline/5",
+		},
+		{
+			name:        "Drone",
+			pipelineURL: "https://drone.example.com/octocat/hello-world/42",
+			current:     "42",
+			number:      "40",
+			expected:    "https://drone.example.com/octocat/hello-world/40",
+		},
+		{
+			name:        "Drone stage URL",
+			pipelineURL: "https://drone.example.com/octocat/hello-world/42/1/2",
+			current:     "42",
+			number:      "40",
+			expected:    "https://drone.example.com/octocat/hello-world/40",
+		},
+		{
+			name:        "Drone with numeric org",
+			pipelineURL: "https://drone.example.com/2024/hello-world/42",
+			current:     "42",
+			number:      "40",
+			expected:    "https://drone.example.com/2024/hello-world/40",
+		},
+		{
+			name:        "No number segment",
+			pipelineURL: "https://ci.example.com/builds/latest",
+			current:     "42",
+			number:      "40",
+			expected:    "",
+		},
+		{
+			name:        "Invalid URL",
+			pipelineURL: "not a url",
+			current:     "42",
+			number:      "40",
+			expected:    "",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := pipelineURLForNumber(tc.pipelineURL, tc.current, tc.number); result != tc.expected {
+				t.Errorf("Expected '%s', got '%s'", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestGetParentPipeline(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"CI_PIPELINE_URL":        "https://ci.example.com/repos/7/pipeline/123",
+		"CI_PIPELINE_NUMBER":     "123",
+		"PLUGIN_PARENT_PIPELINE": "0",
+	})
+
+	if parent, _ := getParentPipeline(); parent != "" {
+		t.Errorf("Expected no parent for 0, got '%s'", parent)
+	}
+
+	setEnvFixture(t, map[string]string{"PLUGIN_PARENT_PIPELINE": "456"})
+	parent, parentURL := getParentPipeline()
+	if parent != "456" || parentURL != "https://ci.example.com/repos/7/pipeline/456" {
+		t.Errorf("Unexpected parent %s (%s)", parent, parentURL)
+	}
+
+	setEnvFixture(t, map[string]string{"PLUGIN_PARENT_URL": "https://upstream.example.com/runs/456"})
+	if _, parentURL := getParentPipeline(); parentURL != "https://upstream.example.com/runs/456" {
+		t.Errorf("Expected override URL, got '%s'", parentURL)
+	}
+
+	card := createLarkCard(Config{}, "v1.0.0")
+	elements := card["card"].(map[string]any)["elements"].([]map[string]any)
+	content := elements[0]["text"].(map[string]any)["content"].(string)
+	if !strings.HasSuffix(content, "\n**Parent:** [#456](https://upstream.example.com/runs/456)") || strings.Contains(content, "Restarted from") {
+		t.Errorf("Expected parent line, got %q", content)
+	}
+
+	text := createLarkTextMessage(Config{}, "v1.0.0")["content"].(map[string]any)["text"].(string)
+	if !strings.Contains(text, "⬆️ Parent: #456 https://upstream.example.com/runs/456\n") {
+		t.Errorf("Expected parent line in the text message, got %q", text)
+	}
+
+	actions := createActionButtons(Config{Buttons: []string{"parent"}})
+	if len(actions) != 1 || actions[0]["url"] != "https://upstream.example.com/runs/456" {
+		t.Errorf("Expected only the parent button, got %v", actions)
+	}
+}
+
+func TestGetRestartedFrom(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"CI_PIPELINE_URL":    "https://ci.example.com/repos/7/pipeline/123",
+		"CI_PIPELINE_NUMBER": "123",
+		"CI_PIPELINE_PARENT": "0",
+		"PLUGIN_PARENT_URL":  "https://upstream.example.com/runs/456",
+	})
+
+	if restarted, _ := getRestartedFrom(); restarted != "" {
+		t.Errorf("Expected no restart for 0, got '%s'", restarted)
+	}
+
+	// PLUGIN_PARENT_URL belongs to the parent pipeline, not to restarts
+	setEnvFixture(t, map[string]string{"CI_PIPELINE_PARENT": "120"})
+	restarted, restartedURL := getRestartedFrom()
+	if restarted != "120" || restartedURL != "https://ci.example.com/repos/7/pipeline/120" {
+		t.Errorf("Unexpected restart %s (%s)", restarted, restartedURL)
+	}
+
+	card := createLarkCard(Config{}, "v1.0.0")
+	elements := card["card"].(map[string]any)["elements"].([]map[string]any)
+	content := elements[0]["text"].(map[string]any)["content"].(string)
+	if !strings.HasSuffix(content, "\n**Restarted from:** [#120](https://ci.example.com/repos/7/pipeline/120)") || strings.Contains(content, "Parent") {
+		t.Errorf("Expected restarted line, got %q", content)
+	}
+
+	// Restarts have no parent button
+	if actions := createActionButtons(Config{Buttons: []string{"parent"}}); len(actions) != 0 {
+		t.Errorf("Expected no parent button for a restart, got %v", actions)
+	}
+}
+
+func TestRelatedPipelines_RestartedChild(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"CI_PIPELINE_URL":        "https://ci.example.com/repos/7/pipeline/123",
+		"CI_PIPELINE_NUMBER":     "123",
+		"CI_PIPELINE_PARENT":     "120",
+		"PLUGIN_PARENT_PIPELINE": "456",
+		"PLUGIN_LANG":            "zh",
+	})
+
+	card := createLarkCard(Config{}, "v1.0.0")
+	elements := card["card"].(map[string]any)["elements"].([]map[string]any)
+	content := elements[0]["text"].(map[string]any)["content"].(string)
+	expected := "\n**父流水线:** [#456](https://ci.example.com/repos/7/pipeline/456)" +
+		"\n**重启自:** [#120](https://ci.example.com/repos/7/pipeline/120)"
+	if !strings.HasSuffix(content, expected) {
+		t.Errorf("Expected both lines with their own labels, got %q", content)
+	}
+}
+
+func TestPipelineNumber(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		version  string
+		title    string
+		markdown string
+		text     string
+	}{
+		{
+			name:     "No number",
+			env:      map[string]string{"CI_REPO_NAME": "backend"},
+			version:  "v1.2.3",
+			title:    "backend",
+			markdown: "v1.2.3",
+			text:     "v1.2.3",
+		},
+		{
+			name:     "Linked number",
+			env:      map[string]string{"CI_REPO_NAME": "backend", "CI_PIPELINE_NUMBER": "123", "CI_PIPELINE_URL": "https://ci.example.com/repos/7/pipeline/123"},
+			version:  "v1.2.3",
+			title:    "backend #123",
+			markdown: "v1.2.3 (build [#123](https://ci.example.com/repos/7/pipeline/123))",
+			text:     "v1.2.3 (build #123)",
+		},
+		{
+			name:     "Drone number without URL",
+			env:      map[string]string{"DRONE_BUILD_NUMBER": "9"},
+			version:  "build #9",
+			title:    "#9",
+			markdown: "build #9",
+			text:     "build #9",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnvFixture(t, tt.env)
+
+			if title := headerProjectName(); title != tt.title {
+				t.Errorf("Expected title %q, got %q", tt.title, title)
+			}
+			if value := versionValue(tt.version, true); value != tt.markdown {
+				t.Errorf("Expected %q, got %q", tt.markdown, value)
+			}
+			if value := versionValue(tt.version, false); value != tt.text {
+				t.Errorf("Expected %q, got %q", tt.text, value)
+			}
+		})
+	}
+}
+
+func TestCreateLarkTextMessage_PipelineNumber(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"CI_PIPELINE_URL":    "https://ci.example.com/repos/7/pipeline/123",
+		"CI_PIPELINE_NUMBER": "123",
+		"CI_PIPELINE_PARENT": "120",
+	})
+
+	text := createLarkTextMessage(Config{}, "v1.2.3")["content"].(map[string]any)["text"].(string)
+	for _, expected := range []string{"Version: v1.2.3 (build #123)\n", "Restarted from: #120 https://ci.example.com/repos/7/pipeline/120\n"} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("Expected %q in %q", expected, text)
+		}
+	}
+}
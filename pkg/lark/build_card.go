@@ -0,0 +1,92 @@
+package lark
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Field is a labeled value shown on a build card
+type Field struct {
+	Label string
+	Value string
+}
+
+// BuildInfo describes the build a card is sent for. Empty fields are left out.
+type BuildInfo struct {
+	// Project is the repository, such as "octo/backend"
+	Project       string
+	Branch        string
+	Author        string
+	Version       string
+	Status        string
+	CommitMessage string
+	PipelineURL   string
+	CommitURL     string
+	// Fields are shown below the commit message
+	Fields []Field
+
+	// Title and Color replace the header title and the status color
+	Title string
+	Color string
+	// Elements replace the body built from the build details, for callers
+	// that compose their own sections, as the plugin does
+	Elements []Element
+	// NoHeader leaves the header out
+	NoHeader bool
+}
+
+// NewBuildCard returns the plugin's standard card for a build: a header in
+// the status color, the build details, the commit message, the fields and
+// links to the pipeline and the commit
+func NewBuildCard(info BuildInfo) *Card {
+	status := StatusOf(info.Status)
+	card := &Card{
+		Title:    fmt.Sprintf("%s - %s %s", info.Project, status.Icon, status.Text),
+		Template: status.Color,
+		NoHeader: info.NoHeader,
+	}
+	if info.Title != "" {
+		card.Title = info.Title
+	}
+	if info.Color != "" {
+		card.Template = info.Color
+	}
+	if info.Elements != nil {
+		return card.Add(info.Elements...)
+	}
+
+	var details []string
+	for _, field := range []Field{
+		{"Project", info.Project},
+		{"Branch", info.Branch},
+		{"Author", info.Author},
+		{"Version", info.Version},
+	} {
+		if field.Value != "" {
+			details = append(details, fmt.Sprintf("**%s:** %s", field.Label, field.Value))
+		}
+	}
+	if len(details) > 0 {
+		card.Add(Markdown(strings.Join(details, "\n")))
+	}
+
+	if info.CommitMessage != "" {
+		card.Add(Markdown(fmt.Sprintf("**Commit Message:**\n%s", info.CommitMessage)))
+	}
+
+	if len(info.Fields) > 0 {
+		lines := []string{"**Variables:**"}
+		for _, field := range info.Fields {
+			lines = append(lines, fmt.Sprintf("• **%s:** %s", field.Label, field.Value))
+		}
+		card.Add(Markdown(strings.Join(lines, "\n")))
+	}
+
+	if info.PipelineURL != "" || info.CommitURL != "" {
+		card.Add(Actions(
+			Button{Text: "View Pipeline", URL: info.PipelineURL, Type: ButtonPrimary},
+			Button{Text: "View Commit", URL: info.CommitURL},
+		))
+	}
+	return card
+}
@@ -0,0 +1,64 @@
+package lark
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNewBuildCard(t *testing.T) {
+	card := NewBuildCard(BuildInfo{
+		Project:       "octo/backend",
+		Branch:        "main",
+		Status:        "failure",
+		CommitMessage: "Fix the build",
+		PipelineURL:   "https://ci.example.com/1",
+		Fields:        []Field{{"Region", "eu"}},
+	})
+
+	if card.Title != "octo/backend - 🚨 Pipeline Failed" {
+		t.Errorf("Expected the status in the title, got %s", card.Title)
+	}
+	if card.Template != "red" {
+		t.Errorf("Expected template red, got %s", card.Template)
+	}
+	if len(card.Elements) != 4 {
+		t.Fatalf("Expected 4 elements, got %d", len(card.Elements))
+	}
+
+	details := card.Elements[0]["text"].(map[string]any)["content"].(string)
+	if !strings.Contains(details, "**Branch:** main") || strings.Contains(details, "Author") {
+		t.Errorf("Expected the branch and no empty author, got %s", details)
+	}
+	fields := card.Elements[2]["text"].(map[string]any)["content"].(string)
+	if !strings.Contains(fields, "• **Region:** eu") {
+		t.Errorf("Expected the Region field, got %s", fields)
+	}
+	actions := card.Elements[3]["actions"].([]map[string]any)
+	if len(actions) != 1 || actions[0]["url"] != "https://ci.example.com/1" {
+		t.Errorf("Expected only the pipeline button, got %v", actions)
+	}
+}
+
+func TestNewBuildCard_ComposedSections(t *testing.T) {
+	card := NewBuildCard(BuildInfo{
+		Project:  "octo/backend",
+		Branch:   "main",
+		Status:   "failure",
+		Title:    "[retry] backend - 🚨 Pipeline Failed",
+		Color:    "orange",
+		Elements: []Element{Note("composed by the caller")},
+	})
+
+	if card.Title != "[retry] backend - 🚨 Pipeline Failed" || card.Template != "orange" {
+		t.Errorf("Expected the given title and color, got %q and %q", card.Title, card.Template)
+	}
+	if len(card.Elements) != 1 || card.Elements[0]["tag"] != "note" {
+		t.Errorf("Expected only the given elements, got %v", card.Elements)
+	}
+
+	data, _ := json.Marshal(NewBuildCard(BuildInfo{NoHeader: true, Elements: []Element{}}))
+	if string(data) != `{"elements":[]}` {
+		t.Errorf("Expected a card without header, got %s", data)
+	}
+}
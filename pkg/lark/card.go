@@ -0,0 +1,159 @@
+// Package lark builds Lark (Feishu) bot messages and sends them to custom bot
+// webhooks. It is the message layer of the ci-lark-notification plugin and
+// can be used by other tools that want to send the same build cards.
+package lark
+
+import "encoding/json"
+
+// Button types understood by Lark
+const (
+	ButtonDefault = "default"
+	ButtonPrimary = "primary"
+	ButtonDanger  = "danger"
+)
+
+// Element is a card element in Lark's JSON form, such as a div, hr, note or
+// action element. The constructors below cover the common ones; any other
+// element can be written as a map.
+type Element map[string]any
+
+// Button is a link button of an action element
+type Button struct {
+	Text string
+	URL  string
+	// Type is ButtonDefault, ButtonPrimary or ButtonDanger, ButtonDefault when empty
+	Type string
+}
+
+// Markdown returns a div element rendering content as lark_md
+func Markdown(content string) Element {
+	return Element{
+		"tag": "div",
+		"text": map[string]any{
+			"content": content,
+			"tag":     "lark_md",
+		},
+	}
+}
+
+// Divider returns a horizontal rule
+func Divider() Element {
+	return Element{"tag": "hr"}
+}
+
+// Note returns a note element with plain text, shown small and grey
+func Note(text string) Element {
+	return Element{
+		"tag": "note",
+		"elements": []map[string]any{
+			{
+				"content": text,
+				"tag":     "plain_text",
+			},
+		},
+	}
+}
+
+// Actions returns an action element with the buttons, skipping those
+// without a URL
+func Actions(buttons ...Button) Element {
+	var actions []map[string]any
+	for _, button := range buttons {
+		if button.URL == "" {
+			continue
+		}
+		buttonType := button.Type
+		if buttonType == "" {
+			buttonType = ButtonDefault
+		}
+		actions = append(actions, map[string]any{
+			"tag": "button",
+			"text": map[string]any{
+				"content": button.Text,
+				"tag":     "plain_text",
+			},
+			"type": buttonType,
+			"url":  button.URL,
+		})
+	}
+	return Element{"tag": "action", "actions": actions}
+}
+
+// Card is an interactive message card
+type Card struct {
+	// Title is shown in the header, in the color of Template
+	Title string
+	// Template is the header color, such as "green", "red" or "grey"
+	Template string
+	// NoHeader leaves the header out
+	NoHeader bool
+	Elements []Element
+}
+
+// Add appends elements to the card and returns it
+func (c *Card) Add(elements ...Element) *Card {
+	c.Elements = append(c.Elements, elements...)
+	return c
+}
+
+// MarshalJSON renders the card in Lark's card JSON
+func (c *Card) MarshalJSON() ([]byte, error) {
+	elements := c.Elements
+	if elements == nil {
+		elements = []Element{}
+	}
+	card := map[string]any{"elements": elements}
+	if !c.NoHeader {
+		card["header"] = map[string]any{
+			"title": map[string]any{
+				"content": c.Title,
+				"tag":     "plain_text",
+			},
+			"template": c.Template,
+		}
+	}
+	return json.Marshal(card)
+}
+
+// Message is a webhook message: a card or a text
+type Message struct {
+	Card *Card
+	Text string
+	// Timestamp and Signature are set by (*Message).Sign
+	Timestamp string
+	Signature string
+}
+
+// NewCardMessage returns an interactive message with the card
+func NewCardMessage(card *Card) *Message {
+	return &Message{Card: card}
+}
+
+// NewTextMessage returns a plain text message
+func NewTextMessage(text string) *Message {
+	return &Message{Text: text}
+}
+
+// Sign sets the timestamp and signature checked by webhooks that have
+// signature verification enabled
+func (m *Message) Sign(timestamp, secret string) {
+	m.Timestamp = timestamp
+	m.Signature = Sign(timestamp, secret)
+}
+
+// MarshalJSON renders the webhook request body
+func (m *Message) MarshalJSON() ([]byte, error) {
+	body := map[string]any{}
+	if m.Card != nil {
+		body["msg_type"] = "interactive"
+		body["card"] = m.Card
+	} else {
+		body["msg_type"] = "text"
+		body["content"] = map[string]any{"text": m.Text}
+	}
+	if m.Signature != "" {
+		body["timestamp"] = m.Timestamp
+		body["sign"] = m.Signature
+	}
+	return json.Marshal(body)
+}
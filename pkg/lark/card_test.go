@@ -0,0 +1,87 @@
+package lark
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCardMarshalJSON(t *testing.T) {
+	card := (&Card{Title: "Build", Template: "green"}).Add(Markdown("**Branch:** main"), Divider())
+
+	data, err := json.Marshal(card)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `{"elements":[{"tag":"div","text":{"content":"**Branch:** main","tag":"lark_md"}},{"tag":"hr"}],"header":{"template":"green","title":{"content":"Build","tag":"plain_text"}}}`
+	if string(data) != expected {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+}
+
+func TestCardMarshalJSONWithoutElements(t *testing.T) {
+	data, _ := json.Marshal(&Card{Title: "Build"})
+
+	var card map[string]any
+	json.Unmarshal(data, &card)
+	if elements, ok := card["elements"].([]any); !ok || len(elements) != 0 {
+		t.Errorf("Expected an empty elements array, got %v", card["elements"])
+	}
+}
+
+func TestActionsSkipsButtonsWithoutURL(t *testing.T) {
+	element := Actions(
+		Button{Text: "Pipeline", URL: "https://ci.example.com/1", Type: ButtonPrimary},
+		Button{Text: "Commit"},
+		Button{Text: "Docs", URL: "https://docs.example.com"},
+	)
+
+	actions := element["actions"].([]map[string]any)
+	if len(actions) != 2 {
+		t.Fatalf("Expected 2 buttons, got %d", len(actions))
+	}
+	if actions[0]["type"] != ButtonPrimary {
+		t.Errorf("Expected first button type %s, got %v", ButtonPrimary, actions[0]["type"])
+	}
+	if actions[1]["type"] != ButtonDefault {
+		t.Errorf("Expected second button type %s, got %v", ButtonDefault, actions[1]["type"])
+	}
+}
+
+func TestMessageMarshalJSON(t *testing.T) {
+	t.Run("card", func(t *testing.T) {
+		data, _ := json.Marshal(NewCardMessage(&Card{Title: "Build"}))
+
+		var message map[string]any
+		json.Unmarshal(data, &message)
+		if message["msg_type"] != "interactive" {
+			t.Errorf("Expected msg_type interactive, got %v", message["msg_type"])
+		}
+		if _, ok := message["card"].(map[string]any); !ok {
+			t.Errorf("Expected a card, got %v", message["card"])
+		}
+		if _, ok := message["sign"]; ok {
+			t.Error("Expected no sign on an unsigned message")
+		}
+	})
+
+	t.Run("signed text", func(t *testing.T) {
+		msg := NewTextMessage("hello")
+		msg.Sign("1700000000", "secret")
+		data, _ := json.Marshal(msg)
+
+		var message map[string]any
+		json.Unmarshal(data, &message)
+		if message["msg_type"] != "text" {
+			t.Errorf("Expected msg_type text, got %v", message["msg_type"])
+		}
+		if text := message["content"].(map[string]any)["text"]; text != "hello" {
+			t.Errorf("Expected text hello, got %v", text)
+		}
+		if message["timestamp"] != "1700000000" {
+			t.Errorf("Expected timestamp 1700000000, got %v", message["timestamp"])
+		}
+		if message["sign"] != Sign("1700000000", "secret") {
+			t.Errorf("Expected the signature of the timestamp, got %v", message["sign"])
+		}
+	})
+}
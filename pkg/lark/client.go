@@ -0,0 +1,107 @@
+package lark
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Sign returns the signature of a webhook message sent at timestamp (Unix
+// seconds) for a bot with signature verification enabled
+func Sign(timestamp, secret string) string {
+	stringToSign := fmt.Sprintf("%s\n%s", timestamp, secret)
+	h := hmac.New(sha256.New, []byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// CodeSignatureInvalid is the code of webhook responses rejecting the
+// signature, because it does not match the secret or the timestamp is more
+// than an hour off
+const CodeSignatureInvalid = 19021
+
+// ResponseError is a webhook response with an HTTP error status or a non-zero
+// Lark code
+type ResponseError struct {
+	StatusCode int
+	Code       int
+	Body       string
+}
+
+func (e *ResponseError) Error() string {
+	if e.StatusCode != http.StatusOK {
+		return fmt.Sprintf("Error response from Lark: %s", e.Body)
+	}
+	return fmt.Sprintf("Lark API error: %s", e.Body)
+}
+
+// IsSignatureError reports whether Lark rejected the signature or timestamp
+func (e *ResponseError) IsSignatureError() bool {
+	return e.Code == CodeSignatureInvalid
+}
+
+// Client sends messages to custom bot webhooks
+type Client struct {
+	// HTTPClient sends the requests, a client with a 30 second timeout when nil
+	HTTPClient *http.Client
+	// PrepareRequest, when set, is called with each request and its body
+	// before it is sent, for example to add headers for an egress gateway
+	PrepareRequest func(req *http.Request, body []byte)
+}
+
+var defaultHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// Send posts msg to the webhook. msg is a *Message, any other value that
+// marshals to a Lark message, or the JSON body itself as []byte or
+// json.RawMessage, which is sent unchanged. Transport errors are returned as
+// is, error responses as *ResponseError.
+func (c *Client) Send(ctx context.Context, webhookURL string, msg any) error {
+	var body []byte
+	switch m := msg.(type) {
+	case []byte:
+		body = m
+	case json.RawMessage:
+		body = m
+	default:
+		var err error
+		if body, err = json.Marshal(msg); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.PrepareRequest != nil {
+		c.PrepareRequest(req, body)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = defaultHTTPClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+
+	var response struct {
+		Code int `json:"code"`
+	}
+	json.Unmarshal(respBody, &response)
+	if resp.StatusCode != http.StatusOK || response.Code != 0 {
+		return &ResponseError{StatusCode: resp.StatusCode, Code: response.Code, Body: string(respBody)}
+	}
+	return nil
+}
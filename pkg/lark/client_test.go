@@ -0,0 +1,100 @@
+package lark
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSign(t *testing.T) {
+	// HMAC-SHA256 keyed with "timestamp\nsecret" over an empty message
+	expected := "fiWS2+gh28DOydAv7hzONH/mDn9+b1Y4Y5ivXWXy8vA="
+	if got := Sign("1700000000", "secret"); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+}
+
+func TestClientSend(t *testing.T) {
+	var body map[string]any
+	var header string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		json.Unmarshal(data, &body)
+		header = r.Header.Get("X-Test")
+		w.Write([]byte(`{"code":0}`))
+	}))
+	defer server.Close()
+
+	client := &Client{
+		HTTPClient:     server.Client(),
+		PrepareRequest: func(req *http.Request, _ []byte) { req.Header.Set("X-Test", "prepared") },
+	}
+	if err := client.Send(context.Background(), server.URL, NewTextMessage("hello")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if body["msg_type"] != "text" {
+		t.Errorf("Expected msg_type text, got %v", body["msg_type"])
+	}
+	if header != "prepared" {
+		t.Errorf("Expected PrepareRequest to be called, got header %q", header)
+	}
+}
+
+func TestClientSendRawJSON(t *testing.T) {
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		received = string(data)
+		w.Write([]byte(`{"code":0}`))
+	}))
+	defer server.Close()
+
+	raw := `{"msg_type":"text","content":{"text":"raw"}}`
+	if err := (&Client{}).Send(context.Background(), server.URL, json.RawMessage(raw)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if received != raw {
+		t.Errorf("Expected %s, got %s", raw, received)
+	}
+}
+
+func TestClientSendErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		wantCode   int
+		wantPrefix string
+	}{
+		{"http error", http.StatusBadRequest, `bad request`, 0, "Error response from Lark: "},
+		{"lark error", http.StatusOK, `{"code":19021,"msg":"sign match fail"}`, 19021, "Lark API error: "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			err := (&Client{}).Send(context.Background(), server.URL, NewTextMessage("hello"))
+			var responseErr *ResponseError
+			if !errors.As(err, &responseErr) {
+				t.Fatalf("Expected a *ResponseError, got %v", err)
+			}
+			if responseErr.StatusCode != tt.status || responseErr.Code != tt.wantCode {
+				t.Errorf("Expected status %d code %d, got %d %d", tt.status, tt.wantCode, responseErr.StatusCode, responseErr.Code)
+			}
+			if expected := tt.wantPrefix + tt.body; err.Error() != expected {
+				t.Errorf("Expected %q, got %q", expected, err.Error())
+			}
+			if responseErr.IsSignatureError() != (tt.wantCode == CodeSignatureInvalid) {
+				t.Errorf("Expected IsSignatureError to be %v", tt.wantCode == CodeSignatureInvalid)
+			}
+		})
+	}
+}
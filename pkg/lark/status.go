@@ -0,0 +1,38 @@
+package lark
+
+import "fmt"
+
+// Status is how a build status is presented: the header color, an icon and
+// the status text
+type Status struct {
+	Color string
+	Icon  string
+	Text  string
+}
+
+// Statuses are the presentations of the statuses reported by Woodpecker and
+// Drone
+var Statuses = map[string]Status{
+	"success":  {"green", "✅", "Pipeline Succeeded"},
+	"failure":  {"red", "🚨", "Pipeline Failed"},
+	"error":    {"red", "💥", "Pipeline Errored"},
+	"killed":   {"grey", "🛑", "Pipeline Killed"},
+	"canceled": {"grey", "⏹️", "Pipeline Canceled"},
+	"declined": {"grey", "🚫", "Pipeline Declined"},
+	"skipped":  {"grey", "⏭️", "Pipeline Skipped"},
+	"pending":  {"yellow", "⏳", "Pipeline Pending"},
+	"running":  {"blue", "🔄", "Pipeline Running"},
+	"blocked":  {"yellow", "✋", "Pipeline Pending Approval"},
+}
+
+// StatusOf returns the presentation of a build status. An empty status counts
+// as success; unknown values are grey and show the raw value.
+func StatusOf(status string) Status {
+	if status == "" {
+		status = "success"
+	}
+	if style, ok := Statuses[status]; ok {
+		return style
+	}
+	return Status{"grey", "❔", fmt.Sprintf("Pipeline Status: %s", status)}
+}
@@ -0,0 +1,15 @@
+package lark
+
+import "testing"
+
+func TestStatusOf(t *testing.T) {
+	if got := StatusOf(""); got != Statuses["success"] {
+		t.Errorf("Expected an empty status to be success, got %v", got)
+	}
+	if got := StatusOf("failure"); got.Color != "red" {
+		t.Errorf("Expected failure to be red, got %s", got.Color)
+	}
+	if got := StatusOf("unknown"); got.Color != "grey" || got.Text != "Pipeline Status: unknown" {
+		t.Errorf("Expected a neutral status showing the value, got %v", got)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import "strings"
+
+// Message types selected by PLUGIN_MSG_TYPE
+const (
+	msgTypeCard = "card"
+	msgTypeText = "text"
+	msgTypePost = "post"
+)
+
+// postText returns a rich text paragraph of plain text
+func postText(text string) []map[string]any {
+	return []map[string]any{{"tag": "text", "text": text}}
+}
+
+// createLarkPostMessage builds a rich text message for groups where cards are
+// disabled. It has the details of the text message, with the mentions as at
+// elements and the links as hyperlinks. Each PLUGIN_LANG locale gets its own
+// content.
+func createLarkPostMessage(config Config, projectVersion string) map[string]any {
+	defer func() { currentLocale = defaultLocale }()
+
+	style := getStatusStyle(config.Status)
+	post := map[string]any{}
+	for _, locale := range getLocales() {
+		currentLocale = locale
+		post[larkLocales[locale]] = createPostContent(config, projectVersion, style)
+	}
+
+	return map[string]any{
+		"msg_type": "post",
+		"content": map[string]any{
+			"post": post,
+		},
+	}
+}
+
+// createPostContent builds the title and paragraphs for currentLocale
+func createPostContent(config Config, projectVersion string, style statusStyle) map[string]any {
+	var paragraphs [][]map[string]any
+	for _, line := range strings.Split(strings.TrimRight(textMessageDetails(config, projectVersion, false), "\n"), "\n") {
+		paragraphs = append(paragraphs, postText(line))
+	}
+
+	var mentions []map[string]any
+	if authorOpenID != "" {
+		mentions = append(mentions, map[string]any{"tag": "at", "user_id": authorOpenID})
+	}
+	for _, id := range mentionUsers(config.Status) {
+		mentions = append(mentions, map[string]any{"tag": "at", "user_id": id})
+	}
+	if len(mentions) > 0 {
+		paragraphs = append(paragraphs, mentions)
+	}
+
+	var links []map[string]any
+	for _, action := range translateButtons(createActionButtons(config)) {
+		text, _ := action["text"].(map[string]any)
+		label, _ := text["content"].(string)
+		url, _ := action["url"].(string)
+		if url == "" {
+			continue
+		}
+		if len(links) > 0 {
+			links = append(links, map[string]any{"tag": "text", "text": " · "})
+		}
+		links = append(links, map[string]any{"tag": "a", "text": label, "href": url})
+	}
+	if len(links) > 0 {
+		paragraphs = append(paragraphs, links)
+	}
+
+	if custom := getCustomMessage(); custom != "" {
+		for _, line := range strings.Split(custom, "\n") {
+			paragraphs = append(paragraphs, postText(line))
+		}
+	}
+	if footer := footerLine(); footer != "" {
+		paragraphs = append(paragraphs, postText(footer))
+	}
+
+	return map[string]any{
+		"title":   textMessageTitle(config, projectVersion, style.Icon, tr(style.Text)),
+		"content": paragraphs,
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// checkPostSchema verifies a post locale against Lark's rich text schema: a
+// title and paragraphs of text, a and at elements with their required fields
+func checkPostSchema(t *testing.T, locale map[string]any) {
+	t.Helper()
+	if _, ok := locale["title"].(string); !ok {
+		t.Errorf("Expected a string title, got %v", locale["title"])
+	}
+	paragraphs, ok := locale["content"].([][]map[string]any)
+	if !ok || len(paragraphs) == 0 {
+		t.Fatalf("Expected paragraphs, got %v", locale["content"])
+	}
+	for _, paragraph := range paragraphs {
+		for _, element := range paragraph {
+			switch element["tag"] {
+			case "text":
+				if _, ok := element["text"].(string); !ok {
+					t.Errorf("Expected text in %v", element)
+				}
+			case "a":
+				if element["text"] == "" || element["href"] == "" {
+					t.Errorf("Expected text and href in %v", element)
+				}
+			case "at":
+				if element["user_id"] == "" {
+					t.Errorf("Expected user_id in %v", element)
+				}
+			default:
+				t.Errorf("Unexpected element %v", element)
+			}
+		}
+	}
+}
+
+// postElements returns the elements of a locale with the tag
+func postElements(locale map[string]any, tag string) []map[string]any {
+	var elements []map[string]any
+	for _, paragraph := range locale["content"].([][]map[string]any) {
+		for _, element := range paragraph {
+			if element["tag"] == tag {
+				elements = append(elements, element)
+			}
+		}
+	}
+	return elements
+}
+
+func TestCreateLarkPostMessage(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"CI_REPO":               "octo/backend",
+		"CI_COMMIT_BRANCH":      "main",
+		"CI_COMMIT_MESSAGE":     "Fix the build",
+		"CI_PIPELINE_URL":       "https://ci.example.com/octo/backend/42",
+		"CI_PIPELINE_FORGE_URL": "https://git.example.com/octo/backend/commit/abc",
+		"PLUGIN_LANG":           "en,zh",
+		"PLUGIN_MENTION_USERS":  "ou_123",
+		"PLUGIN_MENTION_ON":     "failure",
+		"PLUGIN_MESSAGE":        "Please have a look",
+	})
+
+	message := createLarkPostMessage(Config{Status: "failure"}, "v1.0.0")
+
+	if message["msg_type"] != "post" {
+		t.Errorf("Expected msg_type post, got %v", message["msg_type"])
+	}
+	post := message["content"].(map[string]any)["post"].(map[string]any)
+	if len(post) != 2 {
+		t.Fatalf("Expected en_us and zh_cn content, got %v", post)
+	}
+
+	en := post["en_us"].(map[string]any)
+	checkPostSchema(t, en)
+	if title := en["title"].(string); !strings.Contains(title, "Pipeline Failed") {
+		t.Errorf("Expected the status in the title, got %s", title)
+	}
+	links := postElements(en, "a")
+	if len(links) != 2 || links[0]["text"] != "View Pipeline" || links[0]["href"] != "https://ci.example.com/octo/backend/42" || links[1]["text"] != "View Commit" {
+		t.Errorf("Expected pipeline and commit links, got %v", links)
+	}
+	if mentions := postElements(en, "at"); len(mentions) != 1 || mentions[0]["user_id"] != "ou_123" {
+		t.Errorf("Expected a mention of ou_123, got %v", mentions)
+	}
+	var text string
+	for _, element := range postElements(en, "text") {
+		text += element["text"].(string) + "\n"
+	}
+	for _, expected := range []string{"Project: octo/backend", "Message: Fix the build", "Please have a look"} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("Expected %q in the post text:\n%s", expected, text)
+		}
+	}
+	if strings.Contains(text, "https://") {
+		t.Errorf("Expected links only as a elements, got:\n%s", text)
+	}
+
+	zh := post["zh_cn"].(map[string]any)
+	checkPostSchema(t, zh)
+	if title := zh["title"].(string); !strings.Contains(title, "流水线失败") {
+		t.Errorf("Expected a Chinese title, got %s", title)
+	}
+	if links := postElements(zh, "a"); len(links) == 0 || links[0]["text"] != "查看流水线" {
+		t.Errorf("Expected translated links, got %v", links)
+	}
+}
+
+func TestCreateLarkPostMessage_DefaultLocale(t *testing.T) {
+	setEnvFixture(t, map[string]string{"PLUGIN_LANG": "", "CI_REPO": "octo/backend"})
+
+	post := createLarkPostMessage(Config{}, "v1.0.0")["content"].(map[string]any)["post"].(map[string]any)
+	if _, ok := post["en_us"]; !ok || len(post) != 1 {
+		t.Errorf("Expected only en_us content, got %v", post)
+	}
+}
+
+func TestOpenAPIMessage_Post(t *testing.T) {
+	messageBytes := []byte(`{"msg_type":"post","content":{"post":{"en_us":{"title":"Build","content":[[{"tag":"text","text":"hi"}]]}}}}`)
+
+	body, err := openAPIMessage("oc_one", messageBytes)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var content map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(body["content"]), &content); err != nil || content["en_us"] == nil {
+		t.Errorf("Expected the locales as the content, got %s", body["content"])
+	}
+}
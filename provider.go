@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Providers selected by PLUGIN_PROVIDER
+const (
+	providerLark     = "lark"
+	providerWeCom    = "wecom"
+	providerDingTalk = "dingtalk"
+)
+
+// provider builds the native payload of a chat service and delivers it
+type provider interface {
+	// buildMessage returns the unsigned payload for the build
+	buildMessage(config Config, projectVersion string, prebuilt map[string]any) (map[string]any, error)
+	// payloadFor returns the payload as it is sent to target at now, which the
+	// dry run and output files record. Lark signs the body with the secret of
+	// the target; providers that sign the request URL return it as it is.
+	payloadFor(target string, messageBytes []byte, now time.Time) []byte
+	// deliver sends the payload to a target
+	deliver(ctx context.Context, target string, messageBytes []byte) error
+}
+
+// getProvider returns the PLUGIN_PROVIDER provider; LoadConfig has checked the name
+func getProvider(config Config) provider {
+	switch config.Provider {
+	case providerWeCom:
+		return weComProvider{}
+	case providerDingTalk:
+		return dingTalkProvider{secret: config.Secret}
+	default:
+		return larkProvider{secret: config.Secret}
+	}
+}
+
+// checkProvider reports an unknown provider, or Lark-only settings used with another one
+func checkProvider(config Config) error {
+	switch config.Provider {
+	case providerLark:
+		return nil
+	case providerWeCom, providerDingTalk:
+	default:
+		return fmt.Errorf("PLUGIN_PROVIDER must be %s, %s or %s, got %q", providerLark, providerWeCom, providerDingTalk, config.Provider)
+	}
+	if len(config.ChatIDs) > 0 || config.Phase != "" {
+		return fmt.Errorf("PLUGIN_CHAT_ID and PLUGIN_PHASE need the %s provider, not %s", providerLark, config.Provider)
+	}
+	return nil
+}
+
+// larkProvider sends cards, posts or text messages to Lark webhooks and chats.
+// With a secret the webhook payloads are signed.
+type larkProvider struct {
+	secret string
+}
+
+func (larkProvider) buildMessage(config Config, projectVersion string, prebuilt map[string]any) (map[string]any, error) {
+	return buildMessage(config, projectVersion, prebuilt)
+}
+
+func (p larkProvider) payloadFor(target string, messageBytes []byte, now time.Time) []byte {
+	signed, _ := signPayload(target, messageBytes, p.secret, now)
+	return signed
+}
+
+func (p larkProvider) deliver(ctx context.Context, target string, messageBytes []byte) error {
+	return deliverToTarget(ctx, target, messageBytes, p.secret)
+}
+
+// buildSummary is the build as shown by the markdown providers
+type buildSummary struct {
+	Style         statusStyle
+	Title         string
+	Fields        [][2]string
+	CommitMessage string
+	PipelineURL   string
+}
+
+// resolveBuildSummary collects the details shown by the markdown providers:
+// the build details, the variables, the commit message and the pipeline link.
+// Labels are in the first PLUGIN_LANG language.
+func resolveBuildSummary(config Config, projectVersion string) buildSummary {
+	currentLocale = getLocales()[0]
+	defer func() { currentLocale = defaultLocale }()
+
+	style := getStatusStyle(config.Status)
+	summary := buildSummary{
+		Style:       style,
+		Title:       textMessageTitle(config, projectVersion, style.Icon, tr(style.Text)),
+		PipelineURL: getEnvOrDefault("CI_PIPELINE_URL", ""),
+	}
+	add := func(label, value string) {
+		if value != "" {
+			summary.Fields = append(summary.Fields, [2]string{label, value})
+		}
+	}
+
+	add(tr("Project"), getEnvOrDefault("CI_REPO", ""))
+	add(tr("Branch"), getEnvOrDefault("CI_COMMIT_BRANCH", ""))
+	add(tr("Environment"), deployTarget())
+	for _, field := range authorFields() {
+		add(tr(field[0]), field[1])
+	}
+	add(tr("Version"), versionValue(projectVersion, false))
+	add(tr("Duration"), getBuildDuration())
+	if variables, showValues := variableEntries(config.Variables); showValues {
+		for _, variable := range variables {
+			add(variable.textTitle(), variable.Value)
+		}
+	}
+	if showCommitMessage() {
+		summary.CommitMessage = getCommitMessage()
+	}
+	return summary
+}
+
+// providerResponseError is an error response of a WeCom or DingTalk webhook
+type providerResponseError struct {
+	Provider   string
+	StatusCode int
+	Code       int
+	Msg        string
+}
+
+func (e *providerResponseError) Error() string {
+	if e.StatusCode != http.StatusOK {
+		return fmt.Sprintf("Error response from %s (HTTP %d): %s", e.Provider, e.StatusCode, e.Msg)
+	}
+	return fmt.Sprintf("%s API error %d: %s", e.Provider, e.Code, e.Msg)
+}
+
+// deliverErrcodeWebhook posts a payload to a webhook answering with
+// {"errcode": 0, "errmsg": "ok"}, as WeCom and DingTalk robots do
+func deliverErrcodeWebhook(ctx context.Context, providerName, webhookURL string, messageBytes []byte) error {
+	logInfo(fmt.Sprintf("Sending to %s...", providerName), "target", webhookHost(webhookURL))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(messageBytes))
+	if err != nil {
+		return fmt.Errorf("error sending to %s: %w", providerName, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	signGatewayRequest(req, messageBytes)
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending to %s: %w", providerName, err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	var response struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &providerResponseError{Provider: providerName, StatusCode: resp.StatusCode, Msg: string(body)}
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return &providerResponseError{Provider: providerName, StatusCode: resp.StatusCode, Code: -1, Msg: string(body)}
+	}
+	if response.ErrCode != 0 {
+		return &providerResponseError{Provider: providerName, StatusCode: resp.StatusCode, Code: response.ErrCode, Msg: response.ErrMsg}
+	}
+
+	logInfo("Done!", "target", webhookHost(webhookURL), "http_status", resp.StatusCode)
+	return nil
+}
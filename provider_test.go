@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// setupProviderServer records the request bodies and URLs of a WeCom or
+// DingTalk robot and answers with response
+func setupProviderServer(t *testing.T, response string) (*httptest.Server, *[]map[string]any, *[]string) {
+	var bodies []map[string]any
+	var urls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var body map[string]any
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Errorf("Expected a JSON body, got %s", data)
+		}
+		bodies = append(bodies, body)
+		urls = append(urls, r.URL.String())
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+
+	originalOsExit := osExit
+	t.Cleanup(func() { osExit = originalOsExit })
+	return server, &bodies, &urls
+}
+
+// providerFixture is a failed build with a pipeline link
+var providerFixture = map[string]string{
+	"CI_REPO":                 "octo/backend",
+	"CI_COMMIT_BRANCH":        "main",
+	"CI_COMMIT_AUTHOR":        "octocat",
+	"CI_COMMIT_SHA":           "abcdef1234567890",
+	"CI_COMMIT_MESSAGE":       "Fix the build",
+	"CI_PIPELINE_URL":         "https://ci.example.com/octo/backend/42",
+	"DRONE_BUILD_STATUS":      "failure",
+	"PLUGIN_EMOJI":            "false",
+	"PLUGIN_SECRET":           "",
+	"PLUGIN_CHAT_ID":          "",
+	"CI_PIPELINE_STARTED":     "",
+	"CI_PREV_PIPELINE_STATUS": "",
+}
+
+func TestCheckProvider(t *testing.T) {
+	tests := []struct {
+		config  Config
+		wantErr string
+	}{
+		{Config{Provider: providerLark, ChatIDs: []string{"oc_one"}}, ""},
+		{Config{Provider: providerWeCom}, ""},
+		{Config{Provider: "slack"}, `PLUGIN_PROVIDER must be lark, wecom or dingtalk, got "slack"`},
+		{Config{Provider: providerDingTalk, Phase: phaseStart}, "PLUGIN_CHAT_ID and PLUGIN_PHASE need the lark provider, not dingtalk"},
+	}
+	for _, tt := range tests {
+		err := checkProvider(tt.config)
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%s: expected no error, got %v", tt.config.Provider, err)
+		}
+		if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+			t.Errorf("%s: expected %q, got %v", tt.config.Provider, tt.wantErr, err)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// parseProxySetting validates PLUGIN_PROXY. Parse errors never echo the value,
+// which may hold credentials.
+func parseProxySetting() (*url.URL, error) {
+	raw := strings.TrimSpace(getEnvOrDefault("PLUGIN_PROXY", ""))
+	if raw == "" {
+		return nil, nil
+	}
+
+	proxyURL, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("PLUGIN_PROXY is not a valid URL")
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("PLUGIN_PROXY must be an http, https or socks5 URL, got scheme '%s'", proxyURL.Scheme)
+	}
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("PLUGIN_PROXY has no host")
+	}
+	return proxyURL, nil
+}
+
+// bypassProxy reports whether host matches a PLUGIN_NO_PROXY entry: "*", a
+// host name (which also matches its subdomains), a ".domain" suffix, an IP
+// address or a CIDR range
+func bypassProxy(host string, noProxy []string) bool {
+	host = strings.ToLower(host)
+	ip := net.ParseIP(host)
+	for _, entry := range noProxy {
+		entry = strings.ToLower(entry)
+		switch {
+		case entry == "*":
+			return true
+		case strings.Contains(entry, "/"):
+			if _, network, err := net.ParseCIDR(entry); err == nil && ip != nil && network.Contains(ip) {
+				return true
+			}
+		case strings.HasPrefix(entry, "."):
+			if strings.HasSuffix(host, entry) {
+				return true
+			}
+		case host == entry || strings.HasSuffix(host, "."+entry):
+			return true
+		}
+	}
+	return false
+}
+
+// proxyFunc routes requests through proxyURL unless their host is in noProxy
+func proxyFunc(proxyURL *url.URL, noProxy []string) func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		if bypassProxy(req.URL.Hostname(), noProxy) {
+			return nil, nil
+		}
+		return proxyURL, nil
+	}
+}
+
+// redactProxyURL hides the password of a proxy URL
+func redactProxyURL(raw string) string {
+	proxyURL, err := url.Parse(raw)
+	if err != nil {
+		return "[REDACTED]"
+	}
+	return proxyURL.Redacted()
+}
+
+// proxyDescription describes the selected proxy for debug output
+func proxyDescription() string {
+	proxyURL, err := parseProxySetting()
+	if err != nil || proxyURL == nil {
+		return "none"
+	}
+	description := proxyURL.Redacted()
+	if noProxy := getListSetting("PLUGIN_NO_PROXY"); len(noProxy) > 0 {
+		description += fmt.Sprintf(" (bypassed for %s)", strings.Join(noProxy, ", "))
+	}
+	return description
+}
@@ -0,0 +1,79 @@
+package main
+
+import "strings"
+
+// publicTargetSuffix marks a webhook URL entry as a public target
+const publicTargetSuffix = "#public"
+
+// publicMode is set while a message for a public target is being built. While
+// it is set getEnvOrDefault hides sensitive content, so no part of the message
+// (templates included) can show it.
+var publicMode bool
+
+// publicVariables holds the PLUGIN_VARIABLES names whose values are hidden in public mode
+var publicVariables map[string]bool
+
+// publicHiddenSettings are hidden in public mode: commit content, author email,
+// runner details and forge URLs that may point at internal hosts
+var publicHiddenSettings = map[string]bool{
+	"CI_COMMIT_MESSAGE":           true,
+	"CI_COMMIT_AUTHOR_EMAIL":      true,
+	"CI_PREV_COMMIT_MESSAGE":      true,
+	"CI_PREV_COMMIT_AUTHOR_EMAIL": true,
+	"DRONE_COMMIT_MESSAGE":        true,
+	"DRONE_COMMIT_AUTHOR_EMAIL":   true,
+	"CI_SYSTEM_HOST":              true,
+	"DRONE_SYSTEM_HOST":           true,
+	"CI_MACHINE":                  true,
+	"DRONE_MACHINE":               true,
+	"CI_FORGE_URL":                true,
+	"CI_REPO_URL":                 true,
+	"CI_PIPELINE_FORGE_URL":       true,
+	"CI_PREV_PIPELINE_FORGE_URL":  true,
+}
+
+// publicHiddenPrefixes hide whole groups of settings in public mode
+var publicHiddenPrefixes = []string{"CI_RUNNER_", "DRONE_RUNNER_", "CI_PIPELINE_FILES"}
+
+// parseWebhookTarget strips the #public suffix from a webhook URL entry
+func parseWebhookTarget(entry string) (string, bool) {
+	if strings.HasSuffix(entry, publicTargetSuffix) {
+		return strings.TrimSuffix(entry, publicTargetSuffix), true
+	}
+	return entry, getEnvOrDefault("PLUGIN_PUBLIC_MODE", "false") == "true"
+}
+
+// setPublicMode switches content resolution in or out of public mode
+func setPublicMode(enabled bool, variables []string) {
+	publicMode = false
+	publicVariables = map[string]bool{}
+	if enabled {
+		for _, variable := range parseVariables(variables) {
+			publicVariables[variable.Name] = true
+		}
+	}
+	publicMode = enabled
+}
+
+func isPublicHidden(key string) bool {
+	if publicHiddenSettings[key] || publicVariables[key] {
+		return true
+	}
+	for _, prefix := range publicHiddenPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// visibleVariables returns the variables to list and whether their values may be shown
+func visibleVariables(entries []string) ([]displayVariable, bool) {
+	if !publicMode {
+		return parseVariables(entries), true
+	}
+	if getEnvOrDefault("PLUGIN_PUBLIC_SHOW_VAR_NAMES", "false") == "true" {
+		return parseVariables(entries), false
+	}
+	return nil, false
+}
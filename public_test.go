@@ -0,0 +1,111 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParseWebhookTarget(t *testing.T) {
+	setEnvFixture(t, map[string]string{"PLUGIN_PUBLIC_MODE": ""})
+
+	if url, public := parseWebhookTarget("https://hook/a#public"); url != "https://hook/a" || !public {
+		t.Errorf("Expected public target https://hook/a, got %s (%v)", url, public)
+	}
+	if url, public := parseWebhookTarget("https://hook/b"); url != "https://hook/b" || public {
+		t.Errorf("Expected private target https://hook/b, got %s (%v)", url, public)
+	}
+
+	setEnvFixture(t, map[string]string{"PLUGIN_PUBLIC_MODE": "true"})
+	if _, public := parseWebhookTarget("https://hook/b"); !public {
+		t.Error("Expected PLUGIN_PUBLIC_MODE to make every target public")
+	}
+}
+
+func TestMain_PublicTargetOmitsSensitiveContent(t *testing.T) {
+	var mu sync.Mutex
+	bodies := map[string]string{}
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies[r.URL.Path] = string(body)
+		mu.Unlock()
+		w.Write([]byte(`{"code": 0}`))
+	}))
+	defer testServer.Close()
+
+	originalOsExit := osExit
+	defer func() { osExit = originalOsExit }()
+	osExit = func(code int) {}
+
+	suppressed := []string{
+		"Rotate credentials for db-internal.corp",
+		"dev@example.com",
+		"runner-17.internal",
+		"hunter2-value",
+		"git.internal.corp",
+	}
+
+	for _, useCard := range []string{"true", "false"} {
+		t.Run("use_card="+useCard, func(t *testing.T) {
+			setEnvFixture(t, map[string]string{
+				"PLUGIN_WEBHOOK_URL":     testServer.URL + "/private," + testServer.URL + "/public#public",
+				"PLUGIN_USE_CARD":        useCard,
+				"PLUGIN_VARIABLES":       "DEPLOY_HOST",
+				"DEPLOY_HOST":            "hunter2-value",
+				"CI_REPO":                "octo/backend",
+				"CI_REPO_NAME":           "backend",
+				"CI_REPO_URL":            "https://git.internal.corp/octo/backend",
+				"CI_COMMIT_TAG":          "v1.2.3",
+				"CI_COMMIT_MESSAGE":      "Rotate credentials for db-internal.corp",
+				"CI_COMMIT_AUTHOR_EMAIL": "dev@example.com",
+				"CI_RUNNER_HOSTNAME":     "runner-17.internal",
+				"CI_PIPELINE_URL":        "https://ci.example.com/repos/1/pipeline/42",
+				"DRONE_BUILD_STATUS":     "success",
+			})
+
+			main()
+
+			public := bodies["/public"]
+			if public == "" {
+				t.Fatal("Expected a message for the public target")
+			}
+			for _, value := range suppressed {
+				if strings.Contains(public, value) {
+					t.Errorf("Public payload contains %q: %s", value, public)
+				}
+			}
+			for _, value := range []string{"backend", "v1.2.3", "https://ci.example.com/repos/1/pipeline/42"} {
+				if !strings.Contains(public, value) {
+					t.Errorf("Public payload is missing %q: %s", value, public)
+				}
+			}
+
+			if private := bodies["/private"]; !strings.Contains(private, "Rotate credentials") || !strings.Contains(private, "hunter2-value") {
+				t.Errorf("Expected the private target to get the full message, got %s", private)
+			}
+		})
+	}
+}
+
+func TestPublicModeShowVariableNames(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_PUBLIC_SHOW_VAR_NAMES": "true",
+		"PLUGIN_TEMPLATE_ENV_ALLOW":    "DEPLOY_*",
+		"DEPLOY_HOST":                  "hunter2-value",
+	})
+	config := Config{Variables: []string{"DEPLOY_HOST"}}
+	setPublicMode(true, config.Variables)
+	defer setPublicMode(false, nil)
+
+	text := createLarkTextMessage(config, "v1.0.0")["content"].(map[string]any)["text"].(string)
+	if !strings.Contains(text, "DEPLOY_HOST") || strings.Contains(text, "hunter2-value") {
+		t.Errorf("Expected the variable name without its value, got %q", text)
+	}
+	if value, _ := expandTemplateEnv("${DEPLOY_HOST}"); value != "" {
+		t.Errorf("Expected templates to see an empty value, got %q", value)
+	}
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Behaviors selected by PLUGIN_QUIET_HOURS_MODE. Without a mode, successful
+// builds are skipped and failures are sent without mentions.
+const (
+	quietModeSkipAll       = "skip-all"
+	quietModeSkipSuccess   = "skip-success"
+	quietModeStripMentions = "strip-mentions"
+)
+
+const (
+	minutesPerDay  = 24 * 60
+	minutesPerWeek = 7 * minutesPerDay
+)
+
+// quietMentionsMuted is set by main when quiet hours strip the mentions
+var quietMentionsMuted bool
+
+// quietWindow is a span of minutes of the week, counted from Sunday 00:00,
+// with start < end <= minutesPerWeek
+type quietWindow struct {
+	start, end int
+}
+
+// checkQuietHoursSettings reports invalid quiet hours settings
+func checkQuietHoursSettings(getenv func(string) string) []error {
+	var problems []error
+	if _, err := parseQuietHours(getenv("PLUGIN_QUIET_HOURS")); err != nil {
+		problems = append(problems, err)
+	}
+	switch mode := getenv("PLUGIN_QUIET_HOURS_MODE"); mode {
+	case "", quietModeSkipAll, quietModeSkipSuccess, quietModeStripMentions:
+	default:
+		problems = append(problems, fmt.Errorf("PLUGIN_QUIET_HOURS_MODE must be %s, %s or %s, got %q", quietModeSkipAll, quietModeSkipSuccess, quietModeStripMentions, mode))
+	}
+	return problems
+}
+
+// parseQuietHours parses windows such as "22:00-08:00" or
+// "Sat,Sun=00:00-24:00", separated by commas or semicolons or given as a JSON
+// array. Weekdays may be ranges such as Mon-Fri; a window that ends before it
+// starts runs into the next day. The windows are merged where they overlap.
+func parseQuietHours(value string) ([]quietWindow, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	items := []string{value}
+	if strings.HasPrefix(value, "[") {
+		items = nil
+		if err := json.Unmarshal([]byte(value), &items); err != nil {
+			return nil, fmt.Errorf("PLUGIN_QUIET_HOURS starts with '[' but is not a JSON string array")
+		}
+	}
+
+	var windows []quietWindow
+	for _, item := range items {
+		var days []string
+		for _, token := range strings.FieldsFunc(item, func(r rune) bool { return r == ',' || r == ';' }) {
+			token = strings.TrimSpace(token)
+			dayPart, span, hasDays := strings.Cut(token, "=")
+			if !hasDays && !strings.Contains(token, ":") {
+				// A weekday listed before the one carrying the window
+				days = append(days, token)
+				continue
+			}
+			fragment := token
+			if hasDays {
+				days = append(days, strings.TrimSpace(dayPart))
+				fragment = strings.Join(days, ",") + "=" + span
+			} else if span = token; len(days) > 0 {
+				return nil, fmt.Errorf("PLUGIN_QUIET_HOURS window %q: weekdays %q must be followed by =HH:MM-HH:MM", fragment, strings.Join(days, ","))
+			}
+
+			parsed, err := parseQuietWindow(days, span)
+			if err != nil {
+				return nil, fmt.Errorf("PLUGIN_QUIET_HOURS window %q: %v", fragment, err)
+			}
+			windows = append(windows, parsed...)
+			days = nil
+		}
+		if len(days) > 0 {
+			return nil, fmt.Errorf("PLUGIN_QUIET_HOURS window %q: expected HH:MM-HH:MM", strings.Join(days, ","))
+		}
+	}
+	return mergeQuietWindows(windows), nil
+}
+
+// parseQuietWindow returns the span "HH:MM-HH:MM" on each of days, every day
+// when days is empty
+func parseQuietWindow(days []string, span string) ([]quietWindow, error) {
+	from, to, ok := strings.Cut(strings.TrimSpace(span), "-")
+	if !ok {
+		return nil, fmt.Errorf("expected HH:MM-HH:MM")
+	}
+	start, err := parseClock(from, false)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseClock(to, true)
+	if err != nil {
+		return nil, err
+	}
+	if start == end {
+		return nil, fmt.Errorf("the window is empty, use 00:00-24:00 for a whole day")
+	}
+	if end < start {
+		end += minutesPerDay
+	}
+
+	weekdays := []int{0, 1, 2, 3, 4, 5, 6}
+	if len(days) > 0 {
+		weekdays = nil
+		for _, day := range days {
+			parsed, err := parseWeekdays(day)
+			if err != nil {
+				return nil, err
+			}
+			weekdays = append(weekdays, parsed...)
+		}
+	}
+
+	var windows []quietWindow
+	for _, day := range weekdays {
+		offset := day * minutesPerDay
+		if offset+end <= minutesPerWeek {
+			windows = append(windows, quietWindow{offset + start, offset + end})
+			continue
+		}
+		// Saturday night runs into Sunday morning
+		windows = append(windows, quietWindow{offset + start, minutesPerWeek}, quietWindow{0, offset + end - minutesPerWeek})
+	}
+	return windows, nil
+}
+
+// parseClock parses HH:MM into minutes of the day; 24:00 is only an end
+func parseClock(value string, isEnd bool) (int, error) {
+	value = strings.TrimSpace(value)
+	hours, minutes, ok := strings.Cut(value, ":")
+	h, hErr := strconv.Atoi(hours)
+	m, mErr := strconv.Atoi(minutes)
+	if !ok || hErr != nil || mErr != nil || len(minutes) != 2 || h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && (m != 0 || !isEnd)) {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return h*60 + m, nil
+}
+
+// parseWeekdays parses a weekday such as Sat or Saturday, or a range such as
+// Mon-Fri or Fri-Mon, into time.Weekday numbers
+func parseWeekdays(value string) ([]int, error) {
+	from, to, isRange := strings.Cut(value, "-")
+	first, err := parseWeekday(from)
+	if err != nil {
+		return nil, err
+	}
+	if !isRange {
+		return []int{first}, nil
+	}
+	last, err := parseWeekday(to)
+	if err != nil {
+		return nil, err
+	}
+	days := []int{first}
+	for day := first; day != last; {
+		day = (day + 1) % 7
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+func parseWeekday(value string) (int, error) {
+	name := strings.ToLower(strings.TrimSpace(value))
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if len(name) >= 3 && strings.HasPrefix(strings.ToLower(day.String()), name) {
+			return int(day), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown weekday %q", strings.TrimSpace(value))
+}
+
+// mergeQuietWindows sorts the windows and joins those that overlap or touch
+func mergeQuietWindows(windows []quietWindow) []quietWindow {
+	sort.Slice(windows, func(i, j int) bool { return windows[i].start < windows[j].start })
+	var merged []quietWindow
+	for _, window := range windows {
+		if last := len(merged) - 1; last >= 0 && window.start <= merged[last].end {
+			merged[last].end = max(merged[last].end, window.end)
+			continue
+		}
+		merged = append(merged, window)
+	}
+	return merged
+}
+
+// activeQuietWindow returns the window containing now, joined across the end
+// of the week, and whether there is one
+func activeQuietWindow(windows []quietWindow, now time.Time) (quietWindow, bool) {
+	minute := int(now.Weekday())*minutesPerDay + now.Hour()*60 + now.Minute()
+	for i, window := range windows {
+		if minute < window.start || minute >= window.end {
+			continue
+		}
+		last := windows[len(windows)-1]
+		switch {
+		case window.start == 0 && last.end == minutesPerWeek && i != len(windows)-1:
+			window.start = last.start - minutesPerWeek
+		case window.end == minutesPerWeek && windows[0].start == 0 && i != 0:
+			window.end = minutesPerWeek + windows[0].end
+		}
+		return window, true
+	}
+	return quietWindow{}, false
+}
+
+// String renders the window as "Sat 22:00-Sun 08:00"
+func (w quietWindow) String() string {
+	return weekMinute(w.start) + "-" + weekMinute(w.end)
+}
+
+func weekMinute(minute int) string {
+	minute = (minute%minutesPerWeek + minutesPerWeek) % minutesPerWeek
+	day := time.Weekday(minute / minutesPerDay).String()[:3]
+	return fmt.Sprintf("%s %02d:%02d", day, minute%minutesPerDay/60, minute%60)
+}
+
+// quietHoursDecision applies PLUGIN_QUIET_HOURS at now, in PLUGIN_TIMEZONE.
+// It returns why the notification is skipped, or whether it is sent with the
+// mentions stripped. Outside the windows it returns "" and false.
+func quietHoursDecision(now time.Time) (string, bool) {
+	windows, _ := parseQuietHours(getEnvOrDefault("PLUGIN_QUIET_HOURS", ""))
+	window, ok := activeQuietWindow(windows, now.In(getTimezone()))
+	if !ok {
+		return "", false
+	}
+
+	failed := isFailedStatus(getBuildStatus())
+	switch getEnvOrDefault("PLUGIN_QUIET_HOURS_MODE", "") {
+	case quietModeSkipAll:
+		return fmt.Sprintf("quiet hours %s", window), false
+	case quietModeSkipSuccess:
+		if !failed {
+			return fmt.Sprintf("quiet hours %s for status '%s'", window, getBuildStatus()), false
+		}
+		return "", false
+	case quietModeStripMentions:
+		return "", true
+	default:
+		if !failed {
+			return fmt.Sprintf("quiet hours %s for status '%s'", window, getBuildStatus()), false
+		}
+		return "", true
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubClock makes timeNow return now until the test ends
+func stubClock(t *testing.T, now time.Time) {
+	t.Helper()
+	originalTimeNow := timeNow
+	timeNow = func() time.Time { return now }
+	t.Cleanup(func() { timeNow = originalTimeNow })
+}
+
+func TestParseQuietHours(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"Empty", "", ""},
+		{"Weekdays share a window", "Sat,Sun=00:00-24:00", "Sun 00:00-Mon 00:00|Sat 00:00-Sun 00:00"},
+		{"Weekday range", "Mon-Wed=09:00-10:00", "Mon 09:00-Mon 10:00|Tue 09:00-Tue 10:00|Wed 09:00-Wed 10:00"},
+		{"Overlapping windows merge", "Mon=08:00-12:00;Mon=11:00-13:00", "Mon 08:00-Mon 13:00"},
+		{"Crossing midnight runs into the next day", "Fri=22:00-02:00,Sat=01:00-03:00", "Fri 22:00-Sat 03:00"},
+		{"Saturday night wraps to Sunday", "Saturday=23:00-01:00", "Sun 00:00-Sun 01:00|Sat 23:00-Sun 00:00"},
+		{"JSON list", `["Sat,Sun=10:00-11:00", "Mon=10:00-11:00"]`, "Sun 10:00-Sun 11:00|Mon 10:00-Mon 11:00|Sat 10:00-Sat 11:00"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			windows, err := parseQuietHours(tc.value)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var got []string
+			for _, window := range windows {
+				got = append(got, window.String())
+			}
+			if strings.Join(got, "|") != tc.want {
+				t.Errorf("Expected %q, got %q", tc.want, strings.Join(got, "|"))
+			}
+		})
+	}
+}
+
+func TestParseQuietHours_Daily(t *testing.T) {
+	windows, err := parseQuietHours("22:00-08:00")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Every night joins the next morning, and Saturday night wraps to Sunday
+	if len(windows) != 8 || windows[0].String() != "Sun 00:00-Sun 08:00" || windows[1].String() != "Sun 22:00-Mon 08:00" {
+		t.Errorf("Unexpected windows %v", windows)
+	}
+}
+
+func TestParseQuietHours_Invalid(t *testing.T) {
+	tests := []struct {
+		value    string
+		fragment string
+	}{
+		{"25:00-08:00", `"25:00-08:00"`},
+		{"22:00-08:00,Sat,Sun=00:00-24:70", `"Sat,Sun=00:00-24:70"`},
+		{"Funday=10:00-11:00", `"Funday=10:00-11:00"`},
+		{"10:00", `"10:00"`},
+		{"10:00-10:00", `"10:00-10:00"`},
+		{"24:00-08:00", `"24:00-08:00"`},
+		{"Sat,Sun", `"Sat,Sun"`},
+		{"Sat,10:00-11:00", `"10:00-11:00"`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.value, func(t *testing.T) {
+			_, err := parseQuietHours(tc.value)
+			if err == nil || !strings.Contains(err.Error(), tc.fragment) {
+				t.Errorf("Expected an error quoting %s, got %v", tc.fragment, err)
+			}
+		})
+	}
+}
+
+func TestCheckQuietHoursSettings(t *testing.T) {
+	problems := checkQuietHoursSettings(mapGetenv(map[string]string{
+		"PLUGIN_QUIET_HOURS":      "22:00-8",
+		"PLUGIN_QUIET_HOURS_MODE": "silent",
+	}))
+	if len(problems) != 2 || !strings.Contains(problems[0].Error(), `"22:00-8"`) || !strings.Contains(problems[1].Error(), `"silent"`) {
+		t.Errorf("Expected the window and the mode to be reported, got %v", problems)
+	}
+}
+
+func TestQuietHoursDecision(t *testing.T) {
+	// Saturday 23:30 in Shanghai, Saturday 15:30 UTC
+	now := time.Date(2026, 10, 17, 15, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		hours  string
+		mode   string
+		status string
+		skip   bool
+		muted  bool
+	}{
+		{"Outside the windows", "08:00-22:00", "", "failure", false, false},
+		{"Default skips success", "22:00-08:00", "", "success", true, false},
+		{"Default mutes failures", "22:00-08:00", "", "failure", false, true},
+		{"Skip all", "Sat=23:00-24:00", quietModeSkipAll, "failure", true, false},
+		{"Skip success keeps failure mentions", "22:00-08:00", quietModeSkipSuccess, "failure", false, false},
+		{"Skip success", "22:00-08:00", quietModeSkipSuccess, "success", true, false},
+		{"Strip mentions sends success", "22:00-08:00", quietModeStripMentions, "success", false, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setEnvFixture(t, map[string]string{
+				"PLUGIN_QUIET_HOURS":      tc.hours,
+				"PLUGIN_QUIET_HOURS_MODE": tc.mode,
+				"PLUGIN_TIMEZONE":         "Asia/Shanghai",
+				"PLUGIN_STATUS":           tc.status,
+			})
+
+			reason, muted := quietHoursDecision(now)
+			if (reason != "") != tc.skip || muted != tc.muted {
+				t.Errorf("Expected skip=%v muted=%v, got reason '%s' muted=%v", tc.skip, tc.muted, reason, muted)
+			}
+		})
+	}
+}
+
+func TestQuietHoursDecision_ReportsMergedWindow(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_QUIET_HOURS":      "Sat=22:00-23:00,Sat=22:30-02:00",
+		"PLUGIN_QUIET_HOURS_MODE": quietModeSkipAll,
+		"PLUGIN_TIMEZONE":         "UTC",
+	})
+
+	reason, _ := quietHoursDecision(time.Date(2026, 10, 18, 1, 0, 0, 0, time.UTC))
+	if reason != "quiet hours Sat 22:00-Sun 02:00" {
+		t.Errorf("Expected the merged window across the week, got '%s'", reason)
+	}
+}
+
+func TestMain_QuietHoursStripsMentions(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		json.NewDecoder(r.Body).Decode(&payload)
+		data, _ := json.Marshal(payload)
+		body = string(data)
+		w.Write([]byte(`{"code": 0}`))
+	}))
+	defer server.Close()
+	stubClock(t, time.Date(2026, 10, 17, 23, 0, 0, 0, time.UTC))
+	t.Cleanup(func() { quietMentionsMuted = false })
+
+	results, exitCode, _ := readResults(t, map[string]string{
+		"PLUGIN_WEBHOOK_URL":   server.URL,
+		"PLUGIN_STATUS":        "failure",
+		"PLUGIN_MENTION_USERS": "ou_123",
+		"PLUGIN_QUIET_HOURS":   "22:00-08:00",
+		"PLUGIN_TIMEZONE":      "UTC",
+		"PLUGIN_USE_CARD":      "false",
+	})
+
+	if exitCode != 0 || body == "" {
+		t.Fatalf("Expected the failure to be sent, got exit code %d", exitCode)
+	}
+	if strings.Contains(body, "ou_123") {
+		t.Errorf("Expected no mentions during quiet hours, got %s", body)
+	}
+	if len(results) != 1 || !results[0].Success || results[0].Reason != "mentions stripped during quiet hours" {
+		t.Errorf("Unexpected results %+v", results)
+	}
+}
+
+func TestMain_QuietHoursSkipsSuccess(t *testing.T) {
+	stubClock(t, time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC))
+
+	results, exitCode, _ := readResults(t, map[string]string{
+		"PLUGIN_WEBHOOK_URL": "http://localhost/open-apis/bot/v2/hook/token",
+		"PLUGIN_STATUS":      "success",
+		"PLUGIN_QUIET_HOURS": "Sat,Sun=00:00-24:00",
+		"PLUGIN_TIMEZONE":    "UTC",
+	})
+
+	if exitCode != 0 {
+		t.Errorf("Expected exit code 0, got %d", exitCode)
+	}
+	if len(results) != 1 || !results[0].Skipped || results[0].Reason != "quiet hours Sat 00:00-Mon 00:00 for status 'success'" {
+		t.Errorf("Unexpected results %+v", results)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// deliveryResult is the outcome for one target, as written to
+// PLUGIN_RESULT_FILE. Target is the host, never the webhook URL.
+type deliveryResult struct {
+	Target         string `json:"target"`
+	NotificationID string `json:"notification_id"`
+	Success        bool   `json:"success"`
+	Skipped        bool   `json:"skipped"`
+	Reason         string `json:"reason,omitempty"`
+	HTTPStatus     int    `json:"http_status"`
+	LarkCode       int    `json:"lark_code"`
+	LarkMsg        string `json:"lark_msg,omitempty"`
+	Error          string `json:"error,omitempty"`
+	Attempts       int    `json:"attempts"`
+	DurationMS     int64  `json:"duration_ms"`
+	PayloadBytes   int    `json:"payload_bytes"`
+}
+
+// deliveryResults collects the outcome of every target during run
+var deliveryResults []deliveryResult
+
+// deliveryAttempts counts the requests of the current delivery, which are
+// more than one when a request is retried
+var deliveryAttempts int
+
+// checkResultSettings reports an invalid PLUGIN_RESULT_FORMAT
+func checkResultSettings(getenv func(string) string) error {
+	switch format := getenv("PLUGIN_RESULT_FORMAT"); format {
+	case "", "text", "json":
+		return nil
+	default:
+		return fmt.Errorf("PLUGIN_RESULT_FORMAT must be text or json, got %q", format)
+	}
+}
+
+// skipTargets records every target as skipped for reason
+func skipTargets(targets []string, reason string) {
+	for _, entry := range targets {
+		if entry == "" {
+			continue
+		}
+		target, _ := parseWebhookTarget(entry)
+		deliveryResults = append(deliveryResults, deliveryResult{Target: webhookHost(target), NotificationID: notificationID(), Skipped: true, Reason: reason})
+	}
+}
+
+// newDeliveryResult describes a delivery to target that took duration and
+// ended with err
+func newDeliveryResult(target string, payload []byte, duration time.Duration, err error) deliveryResult {
+	result := deliveryResult{
+		Target:         webhookHost(target),
+		NotificationID: notificationID(),
+		Success:        err == nil,
+		Attempts:       max(deliveryAttempts, 1),
+		DurationMS:     duration.Milliseconds(),
+		PayloadBytes:   len(payload),
+	}
+	if quietMentionsMuted {
+		result.Reason = "mentions stripped during quiet hours"
+	}
+	if err == nil {
+		result.HTTPStatus = 200
+		return result
+	}
+
+	result.Error = err.Error()
+	var responseErr *webhookResponseError
+	var apiErr *larkAPIError
+	var providerErr *providerResponseError
+	switch {
+	case errors.As(err, &responseErr):
+		result.HTTPStatus, result.LarkCode = responseErr.StatusCode, responseErr.Code
+		var response struct {
+			Msg string `json:"msg"`
+		}
+		if json.Unmarshal([]byte(responseErr.Body), &response) == nil {
+			result.LarkMsg = response.Msg
+		}
+	case errors.As(err, &apiErr):
+		result.LarkCode, result.LarkMsg = apiErr.Code, apiErr.Msg
+	case errors.As(err, &providerErr):
+		result.HTTPStatus, result.LarkCode, result.LarkMsg = providerErr.StatusCode, providerErr.Code, providerErr.Msg
+	}
+	return result
+}
+
+// writeResults writes deliveryResults to PLUGIN_RESULT_FILE and, with
+// PLUGIN_RESULT_FORMAT=json, prints them as the last line of stdout. It runs
+// after every run, whether the notification was sent, skipped or failed.
+func writeResults() error {
+	results := deliveryResults
+	if results == nil {
+		results = []deliveryResult{}
+	}
+	data, err := json.Marshal(results)
+	if err != nil {
+		return err
+	}
+
+	if getEnvOrDefault("PLUGIN_RESULT_FORMAT", "") == "json" {
+		fmt.Println(string(data))
+	}
+	path := getEnvOrDefault("PLUGIN_RESULT_FILE", "")
+	if path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("cannot write PLUGIN_RESULT_FILE: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("cannot write PLUGIN_RESULT_FILE: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readResults runs main and parses PLUGIN_RESULT_FILE
+func readResults(t *testing.T, env map[string]string) ([]deliveryResult, int, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "results", "notify.json")
+	setEnvFixture(t, env)
+	setEnvFixture(t, map[string]string{"PLUGIN_RESULT_FILE": path})
+
+	originalOsExit := osExit
+	t.Cleanup(func() { osExit = originalOsExit })
+	exitCode := 0
+	osExit = func(code int) { exitCode = code }
+
+	output := captureStdout(t, main)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected PLUGIN_RESULT_FILE to be written: %v", err)
+	}
+	var results []deliveryResult
+	if err := json.Unmarshal(data, &results); err != nil {
+		t.Fatalf("Expected a JSON array, got %s: %v", data, err)
+	}
+	return results, exitCode, output
+}
+
+func TestResultFile_SuccessAndAPIError(t *testing.T) {
+	okServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":0,"msg":"success"}`))
+	}))
+	defer okServer.Close()
+	failingServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":9499,"msg":"Bad Request"}`))
+	}))
+	defer failingServer.Close()
+
+	results, exitCode, _ := readResults(t, map[string]string{
+		"PLUGIN_WEBHOOK_URL": okServer.URL + "," + failingServer.URL,
+		"PLUGIN_STATUS":      "failure",
+	})
+
+	if exitCode != exitPartial {
+		t.Errorf("Expected exit code 5, got %d", exitCode)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected one result per target, got %+v", results)
+	}
+
+	ok := results[0]
+	if !ok.Success || ok.Skipped || ok.HTTPStatus != 200 || ok.LarkCode != 0 || ok.Attempts != 1 || ok.PayloadBytes == 0 || ok.Error != "" {
+		t.Errorf("Unexpected success result %+v", ok)
+	}
+	if ok.Target != strings.TrimPrefix(okServer.URL, "http://") {
+		t.Errorf("Expected the target host, got %q", ok.Target)
+	}
+
+	failed := results[1]
+	if failed.Success || failed.HTTPStatus != 200 || failed.LarkCode != 9499 || failed.LarkMsg != "Bad Request" || failed.Attempts != 1 {
+		t.Errorf("Unexpected API error result %+v", failed)
+	}
+	if !strings.Contains(failed.Error, "Bad Request") {
+		t.Errorf("Expected the error message, got %q", failed.Error)
+	}
+}
+
+func TestResultFile_SignatureRetryAttempts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":19021,"msg":"sign match fail or timestamp is not within one hour from current time"}`))
+	}))
+	defer server.Close()
+
+	results, _, _ := readResults(t, map[string]string{
+		"PLUGIN_WEBHOOK_URL": server.URL,
+		"PLUGIN_SECRET":      "secret",
+	})
+
+	if len(results) != 1 || results[0].Attempts != 2 || results[0].LarkCode != 19021 || results[0].Success {
+		t.Errorf("Expected two failed attempts, got %+v", results)
+	}
+}
+
+func TestResultFile_Skipped(t *testing.T) {
+	results, exitCode, output := readResults(t, map[string]string{
+		"PLUGIN_WEBHOOK_URL":   "http://localhost/open-apis/bot/v2/hook/token",
+		"PLUGIN_STATUS":        "success",
+		"PLUGIN_NOTIFY_ON":     "failure",
+		"PLUGIN_RESULT_FORMAT": "json",
+	})
+
+	if exitCode != 0 {
+		t.Errorf("Expected exit code 0, got %d", exitCode)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Expected one result, got %+v", results)
+	}
+	if result := results[0]; !result.Skipped || result.Success || result.Target != "localhost" || !strings.Contains(result.Reason, "PLUGIN_NOTIFY_ON") {
+		t.Errorf("Unexpected skipped result %+v", result)
+	}
+
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	var printed []deliveryResult
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &printed); err != nil || len(printed) != 1 || !printed[0].Skipped {
+		t.Errorf("Expected the results as the last stdout line, got %q", lines[len(lines)-1])
+	}
+}
+
+func TestResultFile_ConfigurationError(t *testing.T) {
+	results, exitCode, _ := readResults(t, map[string]string{"PLUGIN_WEBHOOK_URL": ""})
+
+	if exitCode != exitConfiguration || len(results) != 0 {
+		t.Errorf("Expected an empty result list and exit code 2, got %+v and %d", results, exitCode)
+	}
+}
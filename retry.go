@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// retryInfo describes a pipeline that re-runs a previous, failed one
+type retryInfo struct {
+	Attempt    int
+	PrevNumber string
+	PrevStatus string
+	PrevURL    string
+}
+
+func isFailedStatus(status string) bool {
+	switch status {
+	case "failure", "error", "killed":
+		return true
+	}
+	return false
+}
+
+// detectRetry reports whether the current pipeline retries a failed one.
+// Consecutive pipeline numbers alone prove nothing, so without an explicit
+// PLUGIN_ATTEMPT or CI_PIPELINE_RETRY the previous pipeline must have failed
+// on the same commit.
+func detectRetry() (retryInfo, bool) {
+	var info retryInfo
+
+	current, currentErr := strconv.Atoi(getPipelineNumber())
+	prevNumber := getEnvOrDefault("CI_PREV_PIPELINE_NUMBER", "")
+	prev, prevErr := strconv.Atoi(prevNumber)
+	prevStatus := getEnvOrDefault("CI_PREV_PIPELINE_STATUS", "")
+	sha := getEnvOrDefault("CI_COMMIT_SHA", "")
+
+	prevIsFailedRun := currentErr == nil && prevErr == nil && prev > 0 && prev < current &&
+		isFailedStatus(prevStatus) &&
+		sha != "" && getEnvOrDefault("CI_PREV_COMMIT_SHA", "") == sha
+
+	if prevIsFailedRun {
+		info.PrevNumber = prevNumber
+		info.PrevStatus = prevStatus
+		info.PrevURL = pipelineURLForNumber(getEnvOrDefault("CI_PIPELINE_URL", ""), getPipelineNumber(), prevNumber)
+	}
+
+	if attempt := getEnvOrDefault("PLUGIN_ATTEMPT", ""); attempt != "" {
+		n, err := strconv.Atoi(attempt)
+		if err != nil || n < 2 {
+			return retryInfo{}, false
+		}
+		info.Attempt = n
+		return info, true
+	}
+
+	// CI_PIPELINE_RETRY counts the re-runs of this pipeline
+	if retries, err := strconv.Atoi(getEnvOrDefault("CI_PIPELINE_RETRY", "")); err == nil && retries > 0 {
+		info.Attempt = retries + 1
+		return info, true
+	}
+
+	return info, prevIsFailedRun
+}
+
+// retryStatusWord turns a failed status into the wording used in the retry line
+func retryStatusWord(status string) string {
+	switch status {
+	case "failure":
+		return "failed"
+	case "error":
+		return "errored"
+	}
+	return status
+}
+
+// retryLine describes the retry as lark_md, or plain text when markdown is false
+func retryLine(info retryInfo, markdown bool) string {
+	if info.PrevNumber == "" {
+		return withIcon("♻️", fmt.Sprintf("Attempt %d of this pipeline", info.Attempt))
+	}
+
+	status := retryStatusWord(info.PrevStatus)
+	switch {
+	case info.PrevURL != "" && markdown:
+		return withIcon("♻️", fmt.Sprintf("Retry of [#%s](%s) (%s)", info.PrevNumber, info.PrevURL, status))
+	case info.PrevURL != "":
+		return withIcon("♻️", fmt.Sprintf("Retry of #%s (%s) %s", info.PrevNumber, status, info.PrevURL))
+	default:
+		return withIcon("♻️", fmt.Sprintf("Retry of #%s (%s)", info.PrevNumber, status))
+	}
+}
+
+// retryBadge returns the header prefix for retried pipelines when PLUGIN_RETRY_BADGE is on
+func retryBadge() string {
+	if getEnvOrDefault("PLUGIN_RETRY_BADGE", "false") != "true" {
+		return ""
+	}
+	if _, ok := detectRetry(); ok && emojiEnabled() {
+		return "♻️ "
+	}
+	return ""
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectRetry(t *testing.T) {
+	base := map[string]string{
+		"CI_PIPELINE_URL":    "https://ci.example.com/repos/7/pipeline/123",
+		"CI_PIPELINE_NUMBER": "123",
+		"CI_COMMIT_SHA":      "abcdef1234567890",
+	}
+
+	tests := []struct {
+		name          string
+		env           map[string]string
+		expectedRetry bool
+		expectedLine  string
+	}{
+		{
+			name:          "No previous pipeline",
+			env:           map[string]string{},
+			expectedRetry: false,
+		},
+		{
+			name:          "Consecutive number after a success",
+			env:           map[string]string{"CI_PREV_PIPELINE_NUMBER": "122", "CI_PREV_PIPELINE_STATUS": "success", "CI_PREV_COMMIT_SHA": "abcdef1234567890"},
+			expectedRetry: false,
+		},
+		{
+			name:          "Consecutive failure on another commit",
+			env:           map[string]string{"CI_PREV_PIPELINE_NUMBER": "122", "CI_PREV_PIPELINE_STATUS": "failure", "CI_PREV_COMMIT_SHA": "0123456789abcdef"},
+			expectedRetry: false,
+		},
+		{
+			name:          "Failure without a previous commit",
+			env:           map[string]string{"CI_PREV_PIPELINE_NUMBER": "122", "CI_PREV_PIPELINE_STATUS": "failure"},
+			expectedRetry: false,
+		},
+		{
+			name:          "Failure on the same commit",
+			env:           map[string]string{"CI_PREV_PIPELINE_NUMBER": "122", "CI_PREV_PIPELINE_STATUS": "failure", "CI_PREV_COMMIT_SHA": "abcdef1234567890"},
+			expectedRetry: true,
+			expectedLine:  "♻️ Retry of [#122](https://ci.example.com/repos/7/pipeline/122) (failed)",
+		},
+		{
+			name:          "Previous number not lower",
+			env:           map[string]string{"CI_PREV_PIPELINE_NUMBER": "124", "CI_PREV_PIPELINE_STATUS": "failure", "CI_PREV_COMMIT_SHA": "abcdef1234567890"},
+			expectedRetry: false,
+		},
+		{
+			name:          "Explicit attempt",
+			env:           map[string]string{"PLUGIN_ATTEMPT": "2"},
+			expectedRetry: true,
+			expectedLine:  "♻️ Attempt 2 of this pipeline",
+		},
+		{
+			name:          "Re-run of this pipeline",
+			env:           map[string]string{"CI_PIPELINE_RETRY": "2"},
+			expectedRetry: true,
+			expectedLine:  "♻️ Attempt 3 of this pipeline",
+		},
+		{
+			name:          "First run of this pipeline",
+			env:           map[string]string{"CI_PIPELINE_RETRY": "0"},
+			expectedRetry: false,
+		},
+		{
+			name:          "Explicit attempt with previous failure",
+			env:           map[string]string{"PLUGIN_ATTEMPT": "3", "CI_PREV_PIPELINE_NUMBER": "122", "CI_PREV_PIPELINE_STATUS": "error", "CI_PREV_COMMIT_SHA": "abcdef1234567890"},
+			expectedRetry: true,
+			expectedLine:  "♻️ Retry of [#122](https://ci.example.com/repos/7/pipeline/122) (errored)",
+		},
+		{
+			name:          "Explicit first attempt wins over heuristics",
+			env:           map[string]string{"PLUGIN_ATTEMPT": "1", "CI_PREV_PIPELINE_NUMBER": "122", "CI_PREV_PIPELINE_STATUS": "failure", "CI_PREV_COMMIT_SHA": "abcdef1234567890"},
+			expectedRetry: false,
+		},
+		{
+			name:          "Invalid attempt",
+			env:           map[string]string{"PLUGIN_ATTEMPT": "second"},
+			expectedRetry: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setEnvFixture(t, base)
+			setEnvFixture(t, tc.env)
+
+			info, ok := detectRetry()
+			if ok != tc.expectedRetry {
+				t.Fatalf("Expected retry=%v, got %v", tc.expectedRetry, ok)
+			}
+			if ok {
+				if line := retryLine(info, true); line != tc.expectedLine {
+					t.Errorf("Expected '%s', got '%s'", tc.expectedLine, line)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateLarkCard_RetryBadge(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"CI_REPO_NAME":            "backend",
+		"CI_PIPELINE_NUMBER":      "123",
+		"CI_COMMIT_SHA":           "abcdef1234567890",
+		"CI_PREV_PIPELINE_NUMBER": "122",
+		"CI_PREV_PIPELINE_STATUS": "failure",
+		"CI_PREV_COMMIT_SHA":      "abcdef1234567890",
+	})
+
+	card := createLarkCard(Config{}, "v1.0.0")
+	title := card["card"].(map[string]any)["header"].(map[string]any)["title"].(map[string]any)["content"].(string)
+	if strings.HasPrefix(title, "♻️") {
+		t.Errorf("Expected no badge without PLUGIN_RETRY_BADGE, got '%s'", title)
+	}
+
+	setEnvFixture(t, map[string]string{"PLUGIN_RETRY_BADGE": "true"})
+	card = createLarkCard(Config{}, "v1.0.0")
+	title = card["card"].(map[string]any)["header"].(map[string]any)["title"].(map[string]any)["content"].(string)
+	if title != "♻️ backend #123 - 🎉 Pipeline Fixed" {
+		t.Errorf("Unexpected title '%s'", title)
+	}
+
+	message := createLarkTextMessage(Config{}, "v1.0.0")
+	text := message["content"].(map[string]any)["text"].(string)
+	if !strings.Contains(text, "♻️ Retry of #122 (failed)\n") {
+		t.Errorf("Expected retry line, got %q", text)
+	}
+}
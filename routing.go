@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"slices"
+	"strings"
+)
+
+// Prefixes of the status-scoped webhook and secret settings, such as
+// PLUGIN_WEBHOOK_URL_FAILURE and PLUGIN_SECRET_FAILURE
+const (
+	statusWebhookPrefix = "PLUGIN_WEBHOOK_URL_"
+	statusSecretPrefix  = "PLUGIN_SECRET_"
+)
+
+// webhookSecrets maps the routed status-scoped targets to their secret
+var webhookSecrets map[string]string
+
+// statusWebhookSettings returns the names of the PLUGIN_WEBHOOK_URL_<STATUS>
+// settings in the environment, sorted
+func statusWebhookSettings() []string {
+	var names []string
+	for _, entry := range os.Environ() {
+		name, _, _ := strings.Cut(entry, "=")
+		status, ok := strings.CutPrefix(name, statusWebhookPrefix)
+		if !ok || status == "" || status == "FILE" {
+			continue
+		}
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// isStatusScopedSetting tells whether name is a status-scoped webhook or secret
+func isStatusScopedSetting(name string) bool {
+	return strings.HasPrefix(name, statusWebhookPrefix) || strings.HasPrefix(name, statusSecretPrefix)
+}
+
+// statusSettingSuffix turns a status or transition such as still_failing into
+// the suffix of its settings, STILL_FAILING
+func statusSettingSuffix(status string) string {
+	return strings.ToUpper(strings.ReplaceAll(status, "-", "_"))
+}
+
+// routeWebhookURLs returns the webhook targets of the resolved status. The
+// PLUGIN_WEBHOOK_URL_<STATUS> list of the transition, such as FIXED, wins over
+// that of the status; without one PLUGIN_WEBHOOK_URL is used. Routed targets
+// are signed with PLUGIN_SECRET_<STATUS>, or PLUGIN_SECRET. With
+// PLUGIN_ROUTE_ADDITIVE=true PLUGIN_WEBHOOK_URL receives the message as well.
+func routeWebhookURLs(config Config) []string {
+	webhookSecrets = map[string]string{}
+
+	statuses := notifyStatuses(getBuildStatus())
+	slices.Reverse(statuses)
+	for _, status := range statuses {
+		suffix := statusSettingSuffix(status)
+		routed := configList(func(name string) string { return getEnvOrDefault(name, "") }, statusWebhookPrefix+suffix)
+		if len(routed) == 0 {
+			continue
+		}
+
+		secret := getEnvOrDefault(statusSecretPrefix+suffix, config.Secret)
+		for i, entry := range routed {
+			routed[i] = cleanWebhookURL(entry)
+			webhookSecrets[strings.TrimSuffix(routed[i], publicTargetSuffix)] = secret
+			logInfo("Routing notification", "status", status, "target", webhookHost(routed[i]))
+		}
+		if getEnvOrDefault("PLUGIN_ROUTE_ADDITIVE", "false") == "true" {
+			return append(routed, config.WebhookURLs...)
+		}
+		return routed
+	}
+	return config.WebhookURLs
+}
+
+// webhookSecret returns the secret to sign requests to target with: that of
+// its status when it was routed by status, fallback otherwise
+func webhookSecret(target, fallback string) string {
+	if secret, ok := webhookSecrets[target]; ok {
+		return secret
+	}
+	return fallback
+}
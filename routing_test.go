@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// routingServer records the path and body of every request
+func routingServer(t *testing.T) (*httptest.Server, *[]string, map[string]map[string]any) {
+	t.Helper()
+	var paths []string
+	bodies := map[string]map[string]any{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		paths = append(paths, r.URL.Path)
+		bodies[r.URL.Path] = body
+		w.Write([]byte(`{"code": 0}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &paths, bodies
+}
+
+func TestMain_StatusRouting(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     string
+		prevStatus string
+		additive   string
+		want       string
+	}{
+		{"Failure goes to the on-call group", "failure", "", "", "/failure"},
+		{"Success goes to the announcements group", "success", "", "", "/success"},
+		{"Unmapped status falls back to PLUGIN_WEBHOOK_URL", "killed", "", "", "/generic"},
+		{"Transition wins over the status", "success", "failure", "", "/fixed"},
+		{"Additive also sends to PLUGIN_WEBHOOK_URL", "failure", "", "true", "/failure /generic"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server, paths, _ := routingServer(t)
+			originalOsExit := osExit
+			defer func() { osExit = originalOsExit }()
+			osExit = func(code int) { t.Errorf("Unexpected exit code %d", code) }
+
+			setEnvFixture(t, map[string]string{
+				"PLUGIN_WEBHOOK_URL":         server.URL + "/generic",
+				"PLUGIN_WEBHOOK_URL_FAILURE": server.URL + "/failure",
+				"PLUGIN_WEBHOOK_URL_SUCCESS": server.URL + "/success",
+				"PLUGIN_WEBHOOK_URL_FIXED":   server.URL + "/fixed",
+				"PLUGIN_ROUTE_ADDITIVE":      tc.additive,
+				"PLUGIN_STATUS":              tc.status,
+				"CI_PREV_PIPELINE_STATUS":    tc.prevStatus,
+			})
+
+			output := captureOutput(t, main)
+			if got := strings.Join(*paths, " "); got != tc.want {
+				t.Errorf("Expected requests to %q, got %q", tc.want, got)
+			}
+			if tc.want != "/generic" && !strings.Contains(output, "Routing notification") {
+				t.Errorf("Expected the routing to be logged, got %q", output)
+			}
+		})
+	}
+}
+
+func TestMain_StatusRoutingWithoutFallback(t *testing.T) {
+	server, paths, _ := routingServer(t)
+	originalOsExit := osExit
+	defer func() { osExit = originalOsExit }()
+	osExit = func(code int) { t.Errorf("Unexpected exit code %d", code) }
+
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_WEBHOOK_URL_FAILURE": server.URL + "/failure",
+		"PLUGIN_STATUS":              "success",
+	})
+
+	output := captureOutput(t, main)
+	if len(*paths) != 0 {
+		t.Errorf("Expected no requests, got %v", *paths)
+	}
+	if !strings.Contains(output, "no webhook for this status") || !strings.Contains(output, "PLUGIN_WEBHOOK_URL_SUCCESS") {
+		t.Errorf("Expected the skip to be logged, got %q", output)
+	}
+}
+
+func TestMain_StatusSecrets(t *testing.T) {
+	server, _, bodies := routingServer(t)
+	originalOsExit := osExit
+	defer func() { osExit = originalOsExit }()
+	osExit = func(code int) { t.Errorf("Unexpected exit code %d", code) }
+
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_WEBHOOK_URL":         server.URL + "/generic",
+		"PLUGIN_SECRET":              "shared",
+		"PLUGIN_WEBHOOK_URL_FAILURE": server.URL + "/failure",
+		"PLUGIN_SECRET_FAILURE":      "on-call",
+		"PLUGIN_ROUTE_ADDITIVE":      "true",
+		"PLUGIN_STATUS":              "failure",
+	})
+	captureOutput(t, main)
+
+	for path, secret := range map[string]string{"/failure": "on-call", "/generic": "shared"} {
+		body := bodies[path]
+		timestamp, _ := body["timestamp"].(string)
+		if body == nil || body["sign"] != generateSignature(timestamp, secret) {
+			t.Errorf("Expected %s to be signed with %q, got %v", path, secret, body)
+		}
+	}
+}
+
+func TestRouteWebhookURLs_SecretFallback(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_WEBHOOK_URL_SUCCESS": "https://open.larksuite.com/open-apis/bot/v2/hook/success",
+		"PLUGIN_STATUS":              "success",
+	})
+	defer func() { webhookSecrets = nil }()
+
+	routed := routeWebhookURLs(Config{Secret: "shared"})
+	if len(routed) != 1 || webhookSecret(routed[0], "") != "shared" {
+		t.Errorf("Expected the shared secret for %v, got %q", routed, webhookSecret(routed[0], ""))
+	}
+}
+
+func TestLoadConfig_StatusWebhookURLs(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_WEBHOOK_URL_FAILURE": "http://open.larksuite.com/open-apis/bot/v2/hook/failure",
+	})
+
+	_, err := LoadConfig(func(name string) string { return getEnvOrDefault(name, "") })
+	if err == nil || strings.Contains(err.Error(), "Need to set Lark Webhook URL") || !strings.Contains(err.Error(), "https") {
+		t.Errorf("Expected the status webhook to be checked and accepted as a target, got %v", err)
+	}
+}
+
+func TestMain_StatusSecretsRecorded(t *testing.T) {
+	server, _, bodies := routingServer(t)
+	originalOsExit := osExit
+	originalTimeNow := timeNow
+	defer func() { osExit, timeNow = originalOsExit, originalTimeNow }()
+	osExit = func(code int) { t.Errorf("Unexpected exit code %d", code) }
+	now := time.Unix(1700000000, 0)
+	timeNow = func() time.Time { return now }
+	timestamp := "1700000000"
+
+	path := filepath.Join(t.TempDir(), "lark.json")
+	env := map[string]string{
+		"PLUGIN_WEBHOOK_URL":         server.URL + "/generic",
+		"PLUGIN_SECRET":              "shared",
+		"PLUGIN_WEBHOOK_URL_FAILURE": server.URL + "/failure",
+		"PLUGIN_SECRET_FAILURE":      "on-call",
+		"PLUGIN_ROUTE_ADDITIVE":      "true",
+		"PLUGIN_STATUS":              "failure",
+		"PLUGIN_OUTPUT_FILE":         path,
+	}
+
+	t.Run("Output file per secret", func(t *testing.T) {
+		setEnvFixture(t, env)
+		captureOutput(t, main)
+
+		for file, target := range map[string]string{"lark.1.json": "/failure", "lark.2.json": "/generic"} {
+			data, err := os.ReadFile(filepath.Join(filepath.Dir(path), file))
+			if err != nil {
+				t.Fatalf("Expected %s: %v", file, err)
+			}
+			var recorded map[string]any
+			json.Unmarshal(data, &recorded)
+			if recorded["sign"] == nil || recorded["sign"] != bodies[target]["sign"] {
+				t.Errorf("Expected %s to hold the signature sent to %s, got %v", file, target, recorded["sign"])
+			}
+		}
+	})
+
+	t.Run("Dry run", func(t *testing.T) {
+		env["PLUGIN_DRY_RUN"] = "true"
+		env["PLUGIN_OUTPUT_FILE"] = ""
+		setEnvFixture(t, env)
+		output := captureOutput(t, main)
+
+		for _, secret := range []string{"on-call", "shared"} {
+			if !strings.Contains(output, generateSignature(timestamp, secret)) {
+				t.Errorf("Expected the payload signed with %q in the dry run, got %s", secret, output)
+			}
+		}
+	})
+}
@@ -0,0 +1,464 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Sections of the full card and the text message, as named in PLUGIN_SECTIONS
+const (
+	sectionHeader       = "header"
+	sectionMetadata     = "metadata"
+	sectionCoverage     = "coverage"
+	sectionImage        = "image"
+	sectionCommit       = "commit"
+	sectionVariables    = "variables"
+	sectionCustomFields = "customFields"
+	sectionDiffStat     = "diffStat"
+	sectionAggregate    = "aggregate"
+	sectionSteps        = "steps"
+	sectionChangelog    = "changelog"
+	sectionImages       = "images"
+	sectionContentFiles = "contentFiles"
+	sectionMessage      = "message"
+	sectionMentions     = "mentions"
+	sectionActions      = "actions"
+	sectionFooter       = "footer"
+)
+
+// defaultCardSections is the order of the card sections without
+// PLUGIN_SECTIONS
+var defaultCardSections = []string{
+	sectionHeader, sectionMetadata, sectionCoverage, sectionImage, sectionCommit, sectionVariables,
+	sectionCustomFields, sectionDiffStat, sectionAggregate, sectionSteps, sectionChangelog,
+	sectionImages, sectionContentFiles, sectionMessage, sectionMentions, sectionActions, sectionFooter,
+}
+
+// defaultTextSections is the order of the text message sections without
+// PLUGIN_SECTIONS: the custom message comes after the links, just before the
+// footer
+var defaultTextSections = []string{
+	sectionHeader, sectionMetadata, sectionCoverage, sectionImage, sectionCommit, sectionVariables,
+	sectionCustomFields, sectionDiffStat, sectionAggregate, sectionSteps, sectionChangelog,
+	sectionImages, sectionContentFiles, sectionMentions, sectionActions, sectionMessage, sectionFooter,
+}
+
+// sectionInput is the resolved build the sections are rendered from
+type sectionInput struct {
+	Config         Config
+	ProjectVersion string
+	Style          statusStyle
+	// MentionAuthor adds the at-tag of the author to the text details; post
+	// messages add it as an element instead
+	MentionAuthor bool
+}
+
+// messageSection renders one part of the full card and of the text message
+type messageSection struct {
+	// card returns the card elements; the header is set on the card instead
+	card func(in sectionInput) []map[string]any
+	// text returns the lines of the text message, nil when the section has
+	// none or shows as part of another section
+	text func(in sectionInput) string
+	// detail sections make up the details that post messages share
+	detail bool
+}
+
+// messageSections is the section registry, by name
+var messageSections = map[string]messageSection{
+	sectionHeader: {
+		text: func(in sectionInput) string {
+			return textMessageTitle(in.Config, in.ProjectVersion, in.Style.Icon, in.Style.upperStatusText()) + "\n\n"
+		},
+	},
+	sectionMetadata:  {card: metadataElements, text: metadataText, detail: true},
+	sectionCoverage:  {card: coverageElements, text: coverageText, detail: true},
+	sectionImage:     {card: func(sectionInput) []map[string]any { return createImageElements() }, detail: true},
+	sectionCommit:    {card: commitElements, text: commitText, detail: true},
+	sectionVariables: {card: variablesElements, text: variablesText, detail: true},
+	sectionCustomFields: {
+		card:   func(sectionInput) []map[string]any { return createCustomFieldElements() },
+		text:   func(sectionInput) string { return createCustomFieldText() },
+		detail: true,
+	},
+	// Text messages show the diff stat with the metadata
+	sectionDiffStat: {card: func(sectionInput) []map[string]any { return createDiffStatElements() }, detail: true},
+	sectionAggregate: {
+		card:   func(sectionInput) []map[string]any { return createAggregateElements() },
+		text:   func(sectionInput) string { return createAggregateText() },
+		detail: true,
+	},
+	sectionSteps: {
+		card:   func(sectionInput) []map[string]any { return createStepsElements() },
+		text:   func(sectionInput) string { return createStepsText() },
+		detail: true,
+	},
+	sectionChangelog: {
+		card:   func(sectionInput) []map[string]any { return createChangelogElements() },
+		text:   func(sectionInput) string { return createChangelogText() },
+		detail: true,
+	},
+	sectionImages: {
+		card:   func(sectionInput) []map[string]any { return createImagesElements() },
+		text:   func(sectionInput) string { return createImagesText() },
+		detail: true,
+	},
+	sectionContentFiles: {
+		card:   func(sectionInput) []map[string]any { return createContentFileElements() },
+		text:   func(sectionInput) string { return createContentFileText() },
+		detail: true,
+	},
+	sectionMessage: {
+		card: func(sectionInput) []map[string]any { return createCustomMessageElements() },
+		text: func(sectionInput) string {
+			if custom := getCustomMessage(); custom != "" {
+				return "\n\n" + custom
+			}
+			return ""
+		},
+	},
+	sectionMentions: {
+		card: func(in sectionInput) []map[string]any {
+			if mention := mentionElement(in.Config.Status); mention != nil {
+				return []map[string]any{mention}
+			}
+			return nil
+		},
+		text: func(in sectionInput) string {
+			if mentions := textMentionLine(in.Config.Status); mentions != "" {
+				return "\n" + mentions + "\n"
+			}
+			return ""
+		},
+	},
+	sectionActions: {card: actionsElements, text: linksText},
+	sectionFooter: {
+		card: func(sectionInput) []map[string]any {
+			if footer := footerElement(); footer != nil {
+				return []map[string]any{footer}
+			}
+			return nil
+		},
+		text: func(sectionInput) string {
+			if footer := footerLine(); footer != "" {
+				return "\n\n" + footer
+			}
+			return ""
+		},
+	},
+}
+
+// checkSectionsSetting reports PLUGIN_SECTIONS entries that name no section
+func checkSectionsSetting(getenv func(string) string) error {
+	if !isValidList(getenv("PLUGIN_SECTIONS")) {
+		return nil
+	}
+	var unknown []string
+	for _, name := range parseList("PLUGIN_SECTIONS", getenv("PLUGIN_SECTIONS")) {
+		if canonicalSection(name) == "" {
+			unknown = append(unknown, fmt.Sprintf("%q", name))
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("PLUGIN_SECTIONS has unknown sections %s, expected some of %s", strings.Join(unknown, ", "), strings.Join(defaultCardSections, ", "))
+	}
+	return nil
+}
+
+// canonicalSection returns the registry name of a PLUGIN_SECTIONS entry,
+// ignoring case, or "" for unknown names
+func canonicalSection(name string) string {
+	for _, section := range defaultCardSections {
+		if strings.EqualFold(section, name) {
+			return section
+		}
+	}
+	return ""
+}
+
+// selectedSections returns the sections named by PLUGIN_SECTIONS, in that
+// order and each once, or defaults when it is unset
+func selectedSections(defaults []string) []string {
+	names := getListSetting("PLUGIN_SECTIONS")
+	if len(names) == 0 {
+		return defaults
+	}
+	var sections []string
+	for _, name := range names {
+		if section := canonicalSection(name); section != "" && !slices.Contains(sections, section) {
+			sections = append(sections, section)
+		}
+	}
+	return sections
+}
+
+// isSectionSelected tells whether the text message shows section
+func isSectionSelected(section string) bool {
+	return slices.Contains(selectedSections(defaultTextSections), section)
+}
+
+// composeCard returns the header title and the elements of the full card
+// from the selected sections, and whether the header is selected
+func composeCard(in sectionInput) (string, bool, []map[string]any) {
+	title, header := "", false
+	elements := []map[string]any{}
+	for _, name := range selectedSections(defaultCardSections) {
+		if name == sectionHeader {
+			title, header = cardHeaderTitle(in.Config, in.ProjectVersion, in.Style.Icon, tr(in.Style.Text)), true
+			continue
+		}
+		elements = append(elements, messageSections[name].card(in)...)
+	}
+	return title, header, elements
+}
+
+// composeText joins the text of the selected sections, each starting on a
+// new line; details limits it to the detail sections
+func composeText(in sectionInput, details bool) string {
+	var message string
+	for _, name := range selectedSections(defaultTextSections) {
+		section := messageSections[name]
+		if section.text == nil || (details && !section.detail) {
+			continue
+		}
+		text := section.text(in)
+		if text != "" && message != "" && !strings.HasSuffix(message, "\n") && !strings.HasPrefix(text, "\n") {
+			message += "\n"
+		}
+		message += text
+	}
+	return message
+}
+
+// metadataElements returns the build details: a list, or fields with
+// PLUGIN_LAYOUT=columns
+func metadataElements(in sectionInput) []map[string]any {
+	if isColumnsLayout() {
+		return []map[string]any{createColumnsMetadataElement(in.ProjectVersion)}
+	}
+
+	metadata := fmt.Sprintf("**%s:** %s\n**%s:** %s\n",
+		tr("Project"), escapeMarkdown(getEnvOrDefault("CI_REPO", "")), tr("Branch"),
+		escapeMarkdown(getEnvOrDefault("CI_COMMIT_BRANCH", "")))
+	for _, field := range eventFields(true) {
+		metadata += fmt.Sprintf("**%s:** %s\n", field[0], field[1])
+	}
+	for i, field := range authorFields() {
+		value := escapeMarkdown(field[1])
+		if i == 0 {
+			value = authorMentionValue(value, true)
+		}
+		metadata += fmt.Sprintf("**%s:** %s\n", tr(field[0]), value)
+	}
+	metadata += fmt.Sprintf("**%s:** %s", tr("Version"), versionValue(in.ProjectVersion, true))
+	if duration := getBuildDuration(); duration != "" {
+		metadata += fmt.Sprintf("\n**%s:** %s", tr("Duration"), duration)
+	}
+	for _, pipeline := range relatedPipelines() {
+		metadata += fmt.Sprintf("\n**%s:** %s", tr(pipeline.Label), pipeline.markdown())
+	}
+	if retry, ok := detectRetry(); ok {
+		metadata += "\n" + retryLine(retry, true)
+	}
+	if matrix := legMatrix(); matrix != "" {
+		matrix = escapeMarkdown(matrix)
+		metadata += fmt.Sprintf("\n**%s:** %s", tr("Matrix"), matrix)
+	}
+	if streak := streakLine(failureStreak); streak != "" {
+		metadata += "\n" + streak
+	}
+
+	return []map[string]any{
+		{
+			"tag": "div",
+			"text": map[string]any{
+				"content": metadata,
+				"tag":     "lark_md",
+			},
+		},
+	}
+}
+
+// metadataText returns the build details one per line, with the diff stat
+// when that section is selected
+func metadataText(in sectionInput) string {
+	message := withIcon("📋", fmt.Sprintf("%s: %s\n", tr("Project"), escapeText(getEnvOrDefault("CI_REPO", ""))))
+	message += withIcon("🌿", fmt.Sprintf("%s: %s\n", tr("Branch"), escapeText(getEnvOrDefault("CI_COMMIT_BRANCH", ""))))
+	for _, field := range eventFields(false) {
+		message += withIcon(pipelineEvents[getPipelineEvent()].Icon, fmt.Sprintf("%s: %s\n", field[0], field[1]))
+	}
+	authorIcon := "👤"
+	if cronJob() != "" {
+		authorIcon = pipelineEvents["cron"].Icon
+	}
+	for i, field := range authorFields() {
+		value := escapeText(field[1])
+		if i == 0 && in.MentionAuthor {
+			value = authorMentionValue(value, false)
+		}
+		message += withIcon(authorIcon, fmt.Sprintf("%s: %s\n", tr(field[0]), value))
+	}
+	message += withIcon("🏷️", fmt.Sprintf("%s: %s\n", tr("Version"), versionValue(in.ProjectVersion, false)))
+	if duration := getBuildDuration(); duration != "" {
+		message += withIcon("⏱️", fmt.Sprintf("%s: %s\n", tr("Duration"), duration))
+	}
+	if isSectionSelected(sectionDiffStat) {
+		message += createDiffStatText()
+	}
+	for _, pipeline := range relatedPipelines() {
+		message += withIcon(pipeline.Icon, fmt.Sprintf("%s: %s\n", tr(pipeline.Label), pipeline.text()))
+	}
+	if retry, ok := detectRetry(); ok {
+		message += retryLine(retry, false) + "\n"
+	}
+	if matrix := legMatrix(); matrix != "" {
+		matrix = escapeText(matrix)
+		message += withIcon("🧩", fmt.Sprintf("%s: %s\n", tr("Matrix"), matrix))
+	}
+	if streak := streakLine(failureStreak); streak != "" {
+		message += streak + "\n"
+	}
+	return message
+}
+
+// coverageElements returns the PLUGIN_COVERAGE_FILE coverage, as a field in
+// the columns layout
+func coverageElements(sectionInput) []map[string]any {
+	coverage := coverageValue()
+	if coverage == "" {
+		return nil
+	}
+	if isColumnsLayout() {
+		return []map[string]any{{
+			"tag":    "div",
+			"fields": []map[string]any{cardField(fmt.Sprintf("**%s:**\n%s", tr("Coverage"), coverage), true)},
+		}}
+	}
+	return []map[string]any{
+		{
+			"tag": "div",
+			"text": map[string]any{
+				"content": fmt.Sprintf("**%s:** %s", tr("Coverage"), coverage),
+				"tag":     "lark_md",
+			},
+		},
+	}
+}
+
+func coverageText(sectionInput) string {
+	if coverage := coverageValue(); coverage != "" {
+		return withIcon("📈", fmt.Sprintf("%s: %s\n", tr("Coverage"), coverage))
+	}
+	return ""
+}
+
+// commitElements returns the commit message; public targets never see it
+func commitElements(sectionInput) []map[string]any {
+	if !showCommitMessage() {
+		return nil
+	}
+	return []map[string]any{
+		{
+			"tag": "hr",
+		},
+		{
+			"tag": "div",
+			"text": map[string]any{
+				"content": fmt.Sprintf("**%s:**\n%s", tr("Commit Message"),
+					escapeMarkdown(getCommitMessage())),
+				"tag": "lark_md",
+			},
+		},
+	}
+}
+
+func commitText(sectionInput) string {
+	if !showCommitMessage() {
+		return ""
+	}
+	// A full commit message starts on its own line
+	commitMessage := escapeText(getCommitMessage())
+	if strings.Contains(commitMessage, "\n") {
+		return withIcon("💬", fmt.Sprintf("%s:\n%s\n", tr("Message"), commitMessage))
+	}
+	return withIcon("💬", fmt.Sprintf("%s: %s\n", tr("Message"), commitMessage))
+}
+
+// variablesElements returns the PLUGIN_VARIABLES section
+func variablesElements(in sectionInput) []map[string]any {
+	variables, showValues := variableEntries(in.Config.Variables)
+	if len(variables) == 0 {
+		return nil
+	}
+
+	elements := []map[string]any{
+		{
+			"tag": "hr",
+		},
+	}
+	if isColumnsLayout() {
+		return append(elements, createColumnsVariablesElement(variables, showValues))
+	}
+
+	varContent := fmt.Sprintf("**%s:**\n", tr("Variables"))
+	for _, variable := range variables {
+		if showValues {
+			varContent += fmt.Sprintf("• %s: %s\n", variable.markdownTitle(), variable.markdownValue())
+		} else {
+			varContent += fmt.Sprintf("• %s\n", variable.markdownTitle())
+		}
+	}
+	return append(elements, map[string]any{
+		"tag": "div",
+		"text": map[string]any{
+			"content": varContent,
+			"tag":     "lark_md",
+		},
+	})
+}
+
+func variablesText(in sectionInput) string {
+	variables, showValues := variableEntries(in.Config.Variables)
+	if len(variables) == 0 {
+		return ""
+	}
+
+	message := "\n" + withIcon("📊", tr("Variables")+":\n")
+	for _, variable := range variables {
+		if showValues {
+			message += fmt.Sprintf("• %s: %s\n", variable.textTitle(), escapeText(variable.Value))
+		} else {
+			message += fmt.Sprintf("• %s\n", variable.textTitle())
+		}
+	}
+	return message
+}
+
+// actionsElements returns the buttons selected by PLUGIN_BUTTONS
+func actionsElements(in sectionInput) []map[string]any {
+	actions := translateButtons(createActionButtons(in.Config))
+	if len(actions) == 0 {
+		return nil
+	}
+	return []map[string]any{
+		{
+			"tag":     "action",
+			"actions": actions,
+		},
+	}
+}
+
+// linksText lists the links of the text message and the notification ID
+func linksText(in sectionInput) string {
+	var message string
+	if pipelineURL := getEnvOrDefault("CI_PIPELINE_URL", ""); pipelineURL != "" {
+		message += "\n" + withIcon("🔗", fmt.Sprintf("%s: %s", tr(deploymentLabel("Pipeline")), pipelineURL))
+	}
+	if stepURL := failedStepURL(in.Config.Status); stepURL != "" {
+		message += "\n" + withIcon("🔗", fmt.Sprintf("%s: %s", tr("Failed Step"), stepURL))
+	}
+	message += createCustomButtonText(in.Config.Buttons)
+	message += "\n" + withIcon("🔖", fmt.Sprintf("Notification ID: %s", notificationID()))
+	return message
+}
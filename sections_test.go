@@ -0,0 +1,121 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// sectionsFixture is a build that fills most sections
+var sectionsFixture = map[string]string{
+	"CI_REPO":                "octo/backend",
+	"CI_COMMIT_BRANCH":       "main",
+	"CI_COMMIT_MESSAGE":      "Fix the thing",
+	"CI_COMMIT_AUTHOR":       "alice",
+	"CI_PIPELINE_NUMBER":     "42",
+	"CI_PIPELINE_URL":        "https://ci.example.com/repos/1/pipeline/42",
+	"CI_MACHINE":             "runner-1",
+	"DEPLOY_ENV":             "prod",
+	"PLUGIN_CUSTOM_FIELDS":   `{"Region":"eu"}`,
+	"PLUGIN_MESSAGE":         "Deployed by the nightly job",
+	"PLUGIN_MENTION_USERS":   "ou_1",
+	"PLUGIN_NOTIFICATION_ID": "deploy-42",
+}
+
+// sectionsConfig is the failed build of sectionsFixture showing DEPLOY_ENV
+var sectionsConfig = Config{Status: "failure", Variables: []string{"DEPLOY_ENV"}}
+
+// elementTags returns the tag of each card element
+func elementTags(card map[string]any) []string {
+	var tags []string
+	for _, element := range card["card"].(map[string]any)["elements"].([]map[string]any) {
+		tags = append(tags, element["tag"].(string))
+	}
+	return tags
+}
+
+func textContent(message map[string]any) string {
+	return message["content"].(map[string]any)["text"].(string)
+}
+
+func TestSections_DefaultOrder(t *testing.T) {
+	setEnvFixture(t, sectionsFixture)
+	mockFooterClock(t)
+	card, text := createLarkCard(sectionsConfig, "v1.0.0"), textContent(createLarkTextMessage(sectionsConfig, "v1.0.0"))
+
+	// metadata, commit, variables, custom fields, message, mentions, actions, footer
+	want := []string{"div", "hr", "div", "hr", "div", "hr", "div", "hr", "div", "div", "action", "note"}
+	if got := elementTags(card); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected elements %v, got %v", want, got)
+	}
+
+	// Listing every section in the default order changes nothing
+	setEnvFixture(t, map[string]string{"PLUGIN_SECTIONS": strings.Join(defaultCardSections, ",")})
+	if explicit := createLarkCard(sectionsConfig, "v1.0.0"); !reflect.DeepEqual(explicit, card) {
+		t.Errorf("Expected the default card, got %v", explicit)
+	}
+	setEnvFixture(t, map[string]string{"PLUGIN_SECTIONS": strings.Join(defaultTextSections, ",")})
+	if explicit := textContent(createLarkTextMessage(sectionsConfig, "v1.0.0")); explicit != text {
+		t.Errorf("Expected the default text\n%s\ngot\n%s", text, explicit)
+	}
+}
+
+func TestSections_CustomOrder(t *testing.T) {
+	setEnvFixture(t, sectionsFixture)
+	setEnvFixture(t, map[string]string{"PLUGIN_SECTIONS": "header, actions,Commit,commit"})
+
+	card := createLarkCard(sectionsConfig, "v1.0.0")
+	if got, want := elementTags(card), []string{"action", "hr", "div"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected elements %v, got %v", want, got)
+	}
+	if _, ok := card["card"].(map[string]any)["header"]; !ok {
+		t.Error("Expected the header to be kept")
+	}
+
+	text := textContent(createLarkTextMessage(sectionsConfig, "v1.0.0"))
+	want := "🚨 PIPELINE FAILED\n\n" +
+		"\n🔗 Pipeline: https://ci.example.com/repos/1/pipeline/42" +
+		"\n🔖 Notification ID: deploy-42" +
+		"\n💬 Message: Fix the thing\n"
+	if text != want {
+		t.Errorf("Expected text\n%q\ngot\n%q", want, text)
+	}
+}
+
+func TestSections_WithoutHeader(t *testing.T) {
+	setEnvFixture(t, sectionsFixture)
+	setEnvFixture(t, map[string]string{"PLUGIN_SECTIONS": "metadata", "PLUGIN_LANG": "en,zh"})
+
+	card := createLarkCard(sectionsConfig, "v1.0.0")["card"].(map[string]any)
+	if _, ok := card["header"]; ok {
+		t.Errorf("Expected no header, got %v", card["header"])
+	}
+	if elements := card["i18n_elements"].(map[string]any); len(elements) != 2 {
+		t.Errorf("Expected elements for both languages, got %v", elements)
+	}
+}
+
+func TestSections_DiffStatInTextDetails(t *testing.T) {
+	setEnvFixture(t, sectionsFixture)
+	defer func() { buildDiffStat = nil }()
+	buildDiffStat = &diffStat{Files: 2, Insertions: 10, Deletions: 3}
+
+	if text := textMessageDetails(sectionsConfig, "v1.0.0", false); !strings.Contains(text, "📝 Changes: ") {
+		t.Errorf("Expected the diff stat in the details, got %q", text)
+	}
+	setEnvFixture(t, map[string]string{"PLUGIN_SECTIONS": "header,metadata"})
+	if text := textMessageDetails(sectionsConfig, "v1.0.0", false); strings.Contains(text, "Changes") {
+		t.Errorf("Expected no diff stat without its section, got %q", text)
+	}
+}
+
+func TestCheckSectionsSetting(t *testing.T) {
+	if err := checkSectionsSetting(mapGetenv(map[string]string{"PLUGIN_SECTIONS": "Header,customfields,footer"})); err != nil {
+		t.Errorf("Expected known sections in any case to be accepted, got %v", err)
+	}
+
+	err := checkSectionsSetting(mapGetenv(map[string]string{"PLUGIN_SECTIONS": "header,tests,logTail"}))
+	if err == nil || !strings.Contains(err.Error(), `"tests", "logTail"`) || !strings.Contains(err.Error(), strings.Join(defaultCardSections, ", ")) {
+		t.Errorf("Expected the unknown sections and the valid names, got %v", err)
+	}
+}
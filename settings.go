@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// sensitiveSettings are never printed, not even in debug mode
+var sensitiveSettings = map[string]bool{
+	"PLUGIN_SECRET":           true,
+	"PLUGIN_APP_SECRET":       true,
+	"PLUGIN_GATEWAY_HMAC_KEY": true,
+	"PLUGIN_CI_TOKEN":         true,
+	"PLUGIN_WEBHOOK_URL":      true,
+}
+
+func isSensitiveSetting(name string) bool {
+	name = pluginSettingName(name)
+	return sensitiveSettings[name] || isStatusScopedSetting(name)
+}
+
+// pluginSettingName maps a GitHub Actions input such as INPUT_WEBHOOK_URL or
+// INPUT_APP-SECRET to the PLUGIN_* setting it stands for. Other names are
+// returned unchanged.
+func pluginSettingName(name string) string {
+	input, ok := strings.CutPrefix(name, "INPUT_")
+	if !ok {
+		return name
+	}
+	return "PLUGIN_" + strings.ToUpper(strings.ReplaceAll(input, "-", "_"))
+}
+
+// settingAliases are the other names a setting can be given, read when the
+// setting itself is unset
+var settingAliases = map[string]string{
+	"PLUGIN_TEMPLATE_FILE": "PLUGIN_CARD_TEMPLATE_FILE",
+	"PLUGIN_PAYLOAD_FILE":  "PLUGIN_CARD_JSON_FILE",
+}
+
+// fileSettingNames are the settings that can also be read from the file
+// named by <NAME>_FILE, for credentials mounted as files
+var fileSettingNames = []string{"PLUGIN_WEBHOOK_URL", "PLUGIN_SECRET"}
+
+// fileSettings holds the values read by loadFileSettings. They take
+// precedence over the environment.
+var fileSettings map[string]string
+
+// loadFileSettings reads the _FILE variants of fileSettingNames, trimming
+// trailing whitespace. A file wins over the plain setting, with a warning.
+func loadFileSettings() error {
+	fileSettings = map[string]string{}
+	for _, name := range fileSettingNames {
+		path := getEnvOrDefault(name+"_FILE", "")
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("cannot read %s_FILE: %w", name, err)
+		}
+		if getEnvOrDefault(name, "") != "" {
+			logWarn(fmt.Sprintf("both %s and %s_FILE are set, using %s_FILE", name, name, name))
+		}
+		fileSettings[name] = strings.TrimRight(string(data), " \t\r\n")
+	}
+	return nil
+}
+
+// getListSetting reads a list-typed setting. Woodpecker delivers YAML lists as
+// JSON arrays (["FOO","BAR"]), plain strings are split on commas. Elements are
+// trimmed and empty elements are dropped.
+func getListSetting(key string) []string {
+	return parseList(key, getEnvOrDefault(key, ""))
+}
+
+func parseList(key, value string) []string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+
+	var items []string
+	if strings.HasPrefix(value, "[") {
+		if err := json.Unmarshal([]byte(value), &items); err != nil {
+			logWarn(fmt.Sprintf("%s starts with '[' but is not a JSON string array, splitting on commas instead", key))
+			items = strings.Split(value, ",")
+		}
+	} else {
+		items = strings.Split(value, ",")
+	}
+
+	var result []string
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseList(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected []string
+	}{
+		{"Empty", "", nil},
+		{"Comma separated", "FOO,BAR", []string{"FOO", "BAR"}},
+		{"Comma separated with spaces", " FOO , BAR ,", []string{"FOO", "BAR"}},
+		{"JSON array", `["FOO","BAR"]`, []string{"FOO", "BAR"}},
+		{"JSON array with spaces", ` [ "FOO" , " BAR " ] `, []string{"FOO", "BAR"}},
+		{"JSON array with commas in values", `["https://a.example.com/hook?x=1,2","https://b.example.com"]`, []string{"https://a.example.com/hook?x=1,2", "https://b.example.com"}},
+		{"Bracket but not JSON", "[beta],stable", []string{"[beta]", "stable"}},
+		{"JSON array of numbers", "[1,2]", []string{"[1", "2]"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := parseList("PLUGIN_TEST", tc.value); !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("Expected %q, got %q", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestCreateLarkCard_JSONArrayVariables(t *testing.T) {
+	os.Setenv("FOO", "foo-value")
+	os.Setenv("BAR", "bar-value")
+	defer func() {
+		os.Unsetenv("FOO")
+		os.Unsetenv("BAR")
+	}()
+
+	config, err := LoadConfig(mapGetenv(map[string]string{
+		"PLUGIN_WEBHOOK_URL": "https://example.com/hook",
+		"PLUGIN_VARIABLES":   `["FOO","BAR"]`,
+	}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	card := createLarkCard(config, "v1.0.0")
+	elements := card["card"].(map[string]any)["elements"].([]map[string]any)
+	content := elements[4]["text"].(map[string]any)["content"]
+
+	expected := "**Variables:**\n• `FOO`: foo-value\n• `BAR`: bar-value\n"
+	if content != expected {
+		t.Errorf("Expected %q, got %q", expected, content)
+	}
+}
+
+func TestMain_MultipleWebhooks(t *testing.T) {
+	received := 0
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received++
+		w.Write([]byte(`{"code": 0}`))
+	}))
+	defer testServer.Close()
+
+	os.Setenv("PLUGIN_WEBHOOK_URL", `["`+testServer.URL+`/a","`+testServer.URL+`/b"]`)
+	defer os.Unsetenv("PLUGIN_WEBHOOK_URL")
+
+	main()
+
+	if received != 2 {
+		t.Errorf("Expected 2 requests, got %d", received)
+	}
+}
+
+func TestMain_FileSettings(t *testing.T) {
+	var received map[string]any
+	var requests int
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		received = nil
+		json.NewDecoder(r.Body).Decode(&received)
+		w.Write([]byte(`{"code": 0}`))
+	}))
+	defer testServer.Close()
+
+	originalOsExit := osExit
+	defer func() { osExit = originalOsExit }()
+	exitCode := 0
+	osExit = func(code int) { exitCode = code }
+
+	dir := t.TempDir()
+	webhookFile := filepath.Join(dir, "webhook")
+	secretFile := filepath.Join(dir, "secret")
+	os.WriteFile(webhookFile, []byte(testServer.URL+"/hook/file-token\n"), 0600)
+	os.WriteFile(secretFile, []byte("file-secret\n"), 0600)
+
+	t.Run("Read from files", func(t *testing.T) {
+		setEnvFixture(t, map[string]string{
+			"PLUGIN_WEBHOOK_URL_FILE": webhookFile,
+			"PLUGIN_SECRET_FILE":      secretFile,
+			"PLUGIN_SECRET":           "env-secret",
+			"PLUGIN_DEBUG":            "true",
+		})
+		exitCode, requests = 0, 0
+		output := captureStdout(t, main)
+
+		if exitCode != 0 || requests != 1 {
+			t.Fatalf("Expected one successful request, got exit code %d and %d requests", exitCode, requests)
+		}
+		timestamp, _ := received["timestamp"].(string)
+		if received["sign"] != generateSignature(timestamp, "file-secret") {
+			t.Errorf("Expected the message to be signed with the file secret, got %v", received)
+		}
+		if !strings.Contains(output, "Warning: both PLUGIN_SECRET and PLUGIN_SECRET_FILE are set, using PLUGIN_SECRET_FILE") {
+			t.Errorf("Expected a warning about the overridden setting, got %q", output)
+		}
+		for _, secret := range []string{"file-secret", "env-secret", "file-token"} {
+			if strings.Contains(output, secret) {
+				t.Errorf("Expected %q not to be printed, got %q", secret, output)
+			}
+		}
+	})
+
+	t.Run("Unreadable file", func(t *testing.T) {
+		missing := filepath.Join(dir, "missing")
+		setEnvFixture(t, map[string]string{"PLUGIN_SECRET_FILE": missing, "PLUGIN_WEBHOOK_URL": testServer.URL})
+		exitCode, requests = 0, 0
+		output := captureOutput(t, main)
+
+		if exitCode != exitConfiguration || requests != 0 {
+			t.Errorf("Expected a startup error, got exit code %d and %d requests", exitCode, requests)
+		}
+		if !strings.Contains(output, "Error: cannot read PLUGIN_SECRET_FILE: open "+missing) {
+			t.Errorf("Expected the error to name the file, got %q", output)
+		}
+	})
+}
+
+func TestGetEnvOrDefault_Aliases(t *testing.T) {
+	setEnvFixture(t, map[string]string{"PLUGIN_CARD_TEMPLATE_FILE": "card.tmpl", "PLUGIN_CARD_JSON_FILE": "card.json"})
+	if value := getEnvOrDefault("PLUGIN_TEMPLATE_FILE", ""); value != "card.tmpl" {
+		t.Errorf("Expected PLUGIN_CARD_TEMPLATE_FILE, got '%s'", value)
+	}
+	if value := getEnvOrDefault("PLUGIN_PAYLOAD_FILE", ""); value != "card.json" {
+		t.Errorf("Expected PLUGIN_CARD_JSON_FILE, got '%s'", value)
+	}
+
+	setEnvFixture(t, map[string]string{"PLUGIN_TEMPLATE_FILE": "main.tmpl"})
+	if value := getEnvOrDefault("PLUGIN_TEMPLATE_FILE", ""); value != "main.tmpl" {
+		t.Errorf("Expected PLUGIN_TEMPLATE_FILE to win over its alias, got '%s'", value)
+	}
+}
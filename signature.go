@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// signPayload signs a webhook payload for target at now, with the secret
+// routed to its status or fallback, and tells whether it was signed. This is
+// the one place payloads are signed: for the dry run and output files, and
+// again before every request so that each attempt carries a current
+// timestamp. Chat targets, which take no signature, payloads without a secret
+// and bodies that are not a JSON object are returned as they are.
+func signPayload(target string, messageBytes []byte, fallback string, now time.Time) ([]byte, bool) {
+	secret := webhookSecret(target, fallback)
+	if secret == "" || strings.HasPrefix(target, chatTargetPrefix) {
+		return messageBytes, false
+	}
+	var message map[string]any
+	if json.Unmarshal(messageBytes, &message) != nil {
+		return messageBytes, false
+	}
+	signMessage(message, secret, now)
+	signed, err := json.Marshal(message)
+	if err != nil {
+		return messageBytes, false
+	}
+	return signed, true
+}
+
+// signatureError is a signature rejection that was not solved by a retry
+// with a fresh timestamp. It names the likely causes instead of showing the
+// raw response.
+type signatureError struct {
+	err    *webhookResponseError
+	signed bool
+}
+
+func (e *signatureError) Error() string {
+	var response struct {
+		Msg string `json:"msg"`
+	}
+	json.Unmarshal([]byte(e.err.Body), &response)
+	reason := fmt.Sprintf("code %d", e.err.Code)
+	if response.Msg != "" {
+		reason += ", " + response.Msg
+	}
+
+	if !e.signed {
+		return fmt.Sprintf("Lark rejected the message (%s): the bot has signature verification enabled, set PLUGIN_SECRET to its signing secret", reason)
+	}
+	return fmt.Sprintf("Lark rejected the signature (%s) again with a fresh timestamp: check that PLUGIN_SECRET matches the signing secret of the bot and that the runner clock is not off by more than an hour", reason)
+}
+
+func (e *signatureError) Unwrap() error {
+	return e.err
+}
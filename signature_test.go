@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeliverMessage_RetriesSignatureRejection(t *testing.T) {
+	originalTimeNow := timeNow
+	defer func() { timeNow = originalTimeNow }()
+	now := time.Unix(1700000000, 0)
+	timeNow = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	tests := []struct {
+		name      string
+		secret    string
+		responses []string
+		requests  int
+		wantErr   string
+	}{
+		{
+			name:      "fresh timestamp is accepted",
+			secret:    "lark-secret",
+			responses: []string{`{"code":19021,"msg":"sign match fail or timestamp is not within one hour from current time"}`, `{"code":0}`},
+			requests:  2,
+		},
+		{
+			name:      "rejected twice",
+			secret:    "wrong-secret",
+			responses: []string{`{"code":19021,"msg":"sign match fail"}`, `{"code":19021,"msg":"sign match fail"}`},
+			requests:  2,
+			wantErr:   "Lark rejected the signature (code 19021, sign match fail) again with a fresh timestamp: check that PLUGIN_SECRET matches",
+		},
+		{
+			name:      "no secret is not retried",
+			responses: []string{`{"code":19021,"msg":"sign match fail"}`},
+			requests:  1,
+			wantErr:   "the bot has signature verification enabled, set PLUGIN_SECRET",
+		},
+		{
+			name:      "other errors are not retried",
+			secret:    "lark-secret",
+			responses: []string{`{"code":9499,"msg":"Bad Request"}`},
+			requests:  1,
+			wantErr:   "Lark API error: ",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var received []map[string]any
+			stubWebhook(t, func(r *http.Request) (*http.Response, error) {
+				body, _ := io.ReadAll(r.Body)
+				var message map[string]any
+				json.Unmarshal(body, &message)
+				received = append(received, message)
+				return larkResponse(http.StatusOK, tc.responses[len(received)-1]), nil
+			})
+
+			message := map[string]any{"msg_type": "text", "content": map[string]any{"text": "hello"}}
+			signMessage(message, tc.secret, timeNow())
+			messageBytes, _ := json.Marshal(message)
+
+			var err error
+			captureOutput(t, func() {
+				err = deliverMessage(context.Background(), "https://lark.example.com/hook", messageBytes, tc.secret)
+			})
+
+			if len(received) != tc.requests {
+				t.Fatalf("Expected %d requests, got %d", tc.requests, len(received))
+			}
+			if tc.wantErr == "" && err != nil {
+				t.Errorf("Expected success, got %v", err)
+			}
+			if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Errorf("Expected an error containing %q, got %v", tc.wantErr, err)
+			}
+			if tc.secret == "" {
+				return
+			}
+
+			seen := map[string]bool{}
+			for _, body := range received {
+				timestamp, _ := body["timestamp"].(string)
+				if body["sign"] != generateSignature(timestamp, tc.secret) {
+					t.Errorf("Expected a valid signature for timestamp %s, got %v", timestamp, body["sign"])
+				}
+				if seen[timestamp] {
+					t.Errorf("Expected every request to carry a fresh timestamp, got %s twice", timestamp)
+				}
+				seen[timestamp] = true
+			}
+			if timestamp := message["timestamp"]; seen[timestamp.(string)] {
+				t.Errorf("Expected the requests to be signed again, not with the build timestamp %s", timestamp)
+			}
+		})
+	}
+}
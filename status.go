@@ -0,0 +1,31 @@
+package main
+
+import "ci-lark-notification/pkg/lark"
+
+// classifyStatus returns the presentation of a build status, see
+// lark.StatusOf. Failure and success icons can be overridden, see statusIcon.
+func classifyStatus(status string) statusStyle {
+	if status == "" {
+		status = "success"
+	}
+	style := statusStyle(lark.StatusOf(status))
+	switch status {
+	case "success":
+		style.Icon = statusIcon(style.Icon, false)
+	case "failure", "error":
+		style.Icon = statusIcon(style.Icon, true)
+	default:
+		style.Icon = emoji(style.Icon)
+	}
+	return style
+}
+
+// isTransitionStatus reports whether a status takes part in transitions
+// against the previous pipeline; canceled or pending builds do not
+func isTransitionStatus(status string) bool {
+	switch status {
+	case "", "success", "failure", "error":
+		return true
+	}
+	return false
+}
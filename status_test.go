@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClassifyStatus(t *testing.T) {
+	tests := []struct {
+		status string
+		color  string
+		icon   string
+		text   string
+	}{
+		{"", "green", "✅", "Pipeline Succeeded"},
+		{"success", "green", "✅", "Pipeline Succeeded"},
+		{"failure", "red", "🚨", "Pipeline Failed"},
+		{"error", "red", "💥", "Pipeline Errored"},
+		{"killed", "grey", "🛑", "Pipeline Killed"},
+		{"canceled", "grey", "⏹️", "Pipeline Canceled"},
+		{"declined", "grey", "🚫", "Pipeline Declined"},
+		{"skipped", "grey", "⏭️", "Pipeline Skipped"},
+		{"pending", "yellow", "⏳", "Pipeline Pending"},
+		{"running", "blue", "🔄", "Pipeline Running"},
+		{"blocked", "yellow", "✋", "Pipeline Pending Approval"},
+		{"exploded", "grey", "❔", "Pipeline Status: exploded"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.status, func(t *testing.T) {
+			setEnvFixture(t, map[string]string{})
+
+			style := getStatusStyle(tc.status)
+			if style.Color != tc.color || style.Icon != tc.icon || style.Text != tc.text {
+				t.Errorf("Expected {%s %s %s}, got %+v", tc.color, tc.icon, tc.text, style)
+			}
+		})
+	}
+}
+
+func TestStatusSharedByCardAndText(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"CI_REPO_NAME": "backend",
+	})
+
+	config := Config{Status: "blocked"}
+	card := createLarkCard(config, "v1.0.0")["card"].(map[string]any)
+	header := card["header"].(map[string]any)
+	if header["template"] != "yellow" || header["title"].(map[string]any)["content"] != "backend - ✋ Pipeline Pending Approval" {
+		t.Errorf("Unexpected card header %v", header)
+	}
+
+	text := createLarkTextMessage(config, "v1.0.0")["content"].(map[string]any)["text"].(string)
+	if expected := "✋ PIPELINE PENDING APPROVAL\n\n"; !strings.HasPrefix(text, expected) {
+		t.Errorf("Expected text to start with %q, got %q", expected, text)
+	}
+}
+
+func TestCanceledStatusHasNoTransition(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"CI_PREV_PIPELINE_STATUS": "failure",
+	})
+
+	if transition := getTransition("canceled"); transition != "" {
+		t.Errorf("Expected no transition for a canceled pipeline, got %q", transition)
+	}
+	if style := getStatusStyle("canceled"); style.Text != "Pipeline Canceled" {
+		t.Errorf("Expected canceled style, got %+v", style)
+	}
+}
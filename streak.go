@@ -0,0 +1,170 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// streakState is the persisted failure streak of a repository branch.
+// LastNumber is the pipeline that last changed it, so that notifying the same
+// pipeline again (a second plugin step, a matrix leg) does not count it twice,
+// and FixedAfter is the streak that pipeline ended.
+type streakState struct {
+	Count       int       `json:"count"`
+	FirstNumber string    `json:"first_number,omitempty"`
+	FirstTime   time.Time `json:"first_time,omitempty"`
+	LastNumber  string    `json:"last_number,omitempty"`
+	FixedAfter  int       `json:"fixed_after,omitempty"`
+}
+
+// streakInfo is the streak as it applies to the current build
+type streakInfo struct {
+	Failing     int
+	FixedAfter  int
+	FirstNumber string
+	FirstTime   time.Time
+}
+
+// failureStreak is set by main once the streak state has been updated
+var failureStreak streakInfo
+
+func streakStatePath() string {
+	stateDir := getEnvOrDefault("PLUGIN_STATE_DIR", "")
+	if stateDir == "" {
+		return ""
+	}
+	key := sha256.Sum256([]byte(getEnvOrDefault("CI_REPO", "") + "\x00" + getEnvOrDefault("CI_COMMIT_BRANCH", "")))
+	return filepath.Join(stateDir, "streak_"+hex.EncodeToString(key[:8])+".json")
+}
+
+// readStreakState returns the stored streak; missing or corrupt state is an empty streak
+func readStreakState(path string) streakState {
+	var state streakState
+	if data, err := os.ReadFile(path); err == nil {
+		if json.Unmarshal(data, &state) != nil {
+			return streakState{}
+		}
+	}
+	return state
+}
+
+func writeStreakState(path string, state streakState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".streak_*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// updateFailureStreak counts consecutive failed builds of the current branch.
+// It runs before sending so the state is kept even when delivery fails. The
+// state is read and replaced while holding the lock file next to it, as
+// matrix legs of one pipeline finish at the same time.
+func updateFailureStreak() streakInfo {
+	path := streakStatePath()
+	if path == "" {
+		return streakInfo{}
+	}
+
+	unlock, err := lockFile(path + ".lock")
+	if err != nil {
+		logWarn(fmt.Sprintf("could not lock failure streak: %v", err))
+		return streakInfo{}
+	}
+	defer unlock()
+
+	state := readStreakState(path)
+	number := getPipelineNumber()
+	repeated := number != "" && number == state.LastNumber
+	var info streakInfo
+
+	switch status := getBuildStatus(); {
+	case isFailedStatus(status):
+		// A pipeline already counted as failed does not extend the streak
+		if !repeated || state.Count == 0 {
+			if state.Count == 0 {
+				state.FirstNumber = number
+				state.FirstTime = timeNow()
+			}
+			state.Count++
+			state.FixedAfter = 0
+		}
+		info = streakInfo{Failing: state.Count, FirstNumber: state.FirstNumber, FirstTime: state.FirstTime}
+	case status == "success":
+		// A pipeline notified again reports what it reported the first time
+		if repeated {
+			return streakInfo{FixedAfter: state.FixedAfter}
+		}
+		info = streakInfo{FixedAfter: state.Count}
+		state = streakState{FixedAfter: state.Count}
+	default:
+		// Canceled, skipped and similar builds neither extend nor break a streak
+		return streakInfo{}
+	}
+
+	// A dry run shows the streak without recording this build
+	if isDryRun() {
+		return info
+	}
+	state.LastNumber = number
+	if err := writeStreakState(path, state); err != nil {
+		logWarn(fmt.Sprintf("could not update failure streak: %v", err))
+	}
+	return info
+}
+
+// humanizeAge renders a duration as "2 days", "5 hours" or "12 minutes"
+func humanizeAge(d time.Duration) string {
+	plural := func(n int, one, many string) string {
+		if n == 1 {
+			return tr(one)
+		}
+		return fmt.Sprintf(tr(many), n)
+	}
+
+	switch {
+	case d >= 24*time.Hour:
+		return plural(int(d/(24*time.Hour)), "1 day", "%d days")
+	case d >= time.Hour:
+		return plural(int(d/time.Hour), "1 hour", "%d hours")
+	default:
+		return plural(int(d/time.Minute), "1 minute", "%d minutes")
+	}
+}
+
+// streakLine describes the streak, or returns "" when there is nothing to say
+func streakLine(info streakInfo) string {
+	switch {
+	case info.Failing > 1:
+		since := humanizeAge(timeNow().Sub(info.FirstTime))
+		if info.FirstNumber != "" {
+			since = fmt.Sprintf("#%s, %s", info.FirstNumber, since)
+		}
+		return withIcon("❌", fmt.Sprintf(tr("Failing for %d builds (since %s)"), info.Failing, since))
+	case info.FixedAfter == 1:
+		return withIcon("✅", tr("Fixed after 1 failed build"))
+	case info.FixedAfter > 1:
+		return withIcon("✅", fmt.Sprintf(tr("Fixed after %d failed builds"), info.FixedAfter))
+	}
+	return ""
+}
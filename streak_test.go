@@ -0,0 +1,177 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setupStreakTest(t *testing.T, now *time.Time) {
+	originalTimeNow := timeNow
+	timeNow = func() time.Time { return *now }
+	t.Cleanup(func() {
+		timeNow = originalTimeNow
+		failureStreak = streakInfo{}
+	})
+
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_STATE_DIR": t.TempDir(),
+		"CI_REPO":          "octo/backend",
+		"CI_COMMIT_BRANCH": "main",
+	})
+}
+
+func runBuild(t *testing.T, number, status string) streakInfo {
+	setEnvFixture(t, map[string]string{"CI_PIPELINE_NUMBER": number, "DRONE_BUILD_STATUS": status})
+	return updateFailureStreak()
+}
+
+func TestUpdateFailureStreak(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	setupStreakTest(t, &now)
+
+	// Missing state starts a new streak
+	if info := runBuild(t, "118", "failure"); info.Failing != 1 || info.FirstNumber != "118" {
+		t.Errorf("Expected a new streak at #118, got %+v", info)
+	}
+
+	now = now.Add(24 * time.Hour)
+	runBuild(t, "119", "error")
+	runBuild(t, "120", "killed")
+
+	// Canceled builds leave the streak alone
+	if info := runBuild(t, "121", "canceled"); info != (streakInfo{}) {
+		t.Errorf("Expected no streak info for a canceled build, got %+v", info)
+	}
+
+	now = now.Add(24 * time.Hour)
+	info := runBuild(t, "122", "failure")
+	if info.Failing != 4 || info.FirstNumber != "118" {
+		t.Errorf("Expected 4 failures since #118, got %+v", info)
+	}
+	if line := streakLine(info); line != "❌ Failing for 4 builds (since #118, 2 days)" {
+		t.Errorf("Unexpected streak line '%s'", line)
+	}
+
+	info = runBuild(t, "123", "success")
+	if info.FixedAfter != 4 {
+		t.Errorf("Expected fixed after 4 failures, got %+v", info)
+	}
+	if line := streakLine(info); line != "✅ Fixed after 4 failed builds" {
+		t.Errorf("Unexpected fixed line '%s'", line)
+	}
+
+	// The streak was reset
+	if info := runBuild(t, "124", "success"); info.FixedAfter != 0 || streakLine(info) != "" {
+		t.Errorf("Expected no streak after a reset, got %+v", info)
+	}
+}
+
+func TestUpdateFailureStreak_PerBranchAndCorruptState(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	setupStreakTest(t, &now)
+
+	runBuild(t, "1", "failure")
+	runBuild(t, "2", "failure")
+
+	setEnvFixture(t, map[string]string{"CI_COMMIT_BRANCH": "feature"})
+	if info := runBuild(t, "3", "failure"); info.Failing != 1 {
+		t.Errorf("Expected an independent streak per branch, got %+v", info)
+	}
+
+	os.WriteFile(streakStatePath(), []byte("garbage"), 0600)
+	if info := runBuild(t, "4", "failure"); info.Failing != 1 || info.FirstNumber != "4" {
+		t.Errorf("Expected corrupt state to start a new streak, got %+v", info)
+	}
+}
+
+func TestUpdateFailureStreak_SamePipelineTwice(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	setupStreakTest(t, &now)
+
+	runBuild(t, "41", "failure")
+	first := runBuild(t, "42", "failure")
+	if again := runBuild(t, "42", "failure"); again != first || again.Failing != 2 {
+		t.Errorf("Expected the second notification of #42 to report %+v, got %+v", first, again)
+	}
+
+	fixed := runBuild(t, "43", "success")
+	if again := runBuild(t, "43", "success"); again != fixed || again.FixedAfter != 2 {
+		t.Errorf("Expected the second notification of #43 to report %+v, got %+v", fixed, again)
+	}
+
+	// A failed leg of a pipeline that already reported success starts a streak
+	if info := runBuild(t, "43", "failure"); info.Failing != 1 || info.FirstNumber != "43" {
+		t.Errorf("Expected a new streak at #43, got %+v", info)
+	}
+	if info := runBuild(t, "43", "success"); info.FixedAfter != 0 {
+		t.Errorf("Expected a failed pipeline not to be fixed by its own legs, got %+v", info)
+	}
+	if _, err := os.Stat(streakStatePath() + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("Expected the lock to be released, got %v", err)
+	}
+}
+
+func TestUpdateFailureStreak_NoStateDir(t *testing.T) {
+	setEnvFixture(t, map[string]string{"DRONE_BUILD_STATUS": "failure"})
+
+	if info := updateFailureStreak(); info != (streakInfo{}) {
+		t.Errorf("Expected no streak without a state dir, got %+v", info)
+	}
+}
+
+func TestStreakLine(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	setupStreakTest(t, &now)
+
+	tests := []struct {
+		info     streakInfo
+		expected string
+	}{
+		{streakInfo{}, ""},
+		{streakInfo{Failing: 1, FirstNumber: "5", FirstTime: now}, ""},
+		{streakInfo{Failing: 7, FirstNumber: "118", FirstTime: now.Add(-49 * time.Hour)}, "❌ Failing for 7 builds (since #118, 2 days)"},
+		{streakInfo{Failing: 2, FirstTime: now.Add(-90 * time.Minute)}, "❌ Failing for 2 builds (since 1 hour)"},
+		{streakInfo{FixedAfter: 1}, "✅ Fixed after 1 failed build"},
+	}
+
+	for _, tc := range tests {
+		if line := streakLine(tc.info); line != tc.expected {
+			t.Errorf("Expected '%s', got '%s'", tc.expected, line)
+		}
+	}
+
+	currentLocale = "zh"
+	defer func() { currentLocale = defaultLocale }()
+	if line := streakLine(streakInfo{Failing: 7, FirstNumber: "118", FirstTime: now.Add(-49 * time.Hour)}); line != "❌ 已连续失败 7 次（自 #118, 2 天）" {
+		t.Errorf("Unexpected translated streak line '%s'", line)
+	}
+	if line := streakLine(streakInfo{FixedAfter: 3}); line != "✅ 3 次失败后已修复" {
+		t.Errorf("Unexpected translated fixed line '%s'", line)
+	}
+}
+
+func TestMain_StreakUpdatedWhenSendFails(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	setupStreakTest(t, &now)
+
+	originalOsExit := osExit
+	defer func() { osExit = originalOsExit }()
+	osExit = func(code int) {}
+
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_WEBHOOK_URL": "http://127.0.0.1:1/unreachable",
+		"DRONE_BUILD_STATUS": "failure",
+		"CI_PIPELINE_NUMBER": "7",
+	})
+
+	output := captureOutput(t, main)
+	if !strings.Contains(output, "error sending to Lark") {
+		t.Fatalf("Expected the send to fail, got:\n%s", output)
+	}
+
+	if state := readStreakState(streakStatePath()); state.Count != 1 || state.FirstNumber != "7" {
+		t.Errorf("Expected streak state to be saved, got %+v", state)
+	}
+}
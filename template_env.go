@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"text/template"
+)
+
+// defaultTemplateEnvAllow lists the variables templates may read by default
+var defaultTemplateEnvAllow = []string{"CI_*", "DRONE_*", "PLUGIN_*"}
+
+// templateEnvDenyPatterns mark variable names that templates can never read,
+// whatever PLUGIN_TEMPLATE_ENV_ALLOW says. Webhook URLs embed the bot token
+// and proxy URLs may carry credentials.
+var templateEnvDenyPatterns = []string{"SECRET", "TOKEN", "PASSWORD", "KEY", "WEBHOOK", "PROXY"}
+
+// warnedTemplateEnv remembers blocked variables that were already reported
+var warnedTemplateEnv = map[string]bool{}
+
+func isTemplateEnvDenied(name string) bool {
+	upper := strings.ToUpper(name)
+	for _, pattern := range templateEnvDenyPatterns {
+		if strings.Contains(upper, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+func isTemplateEnvAllowed(name string) bool {
+	if isTemplateEnvDenied(name) {
+		return false
+	}
+	// PLUGIN_ENV_FILE was written for the notification
+	if _, ok := envFile[name]; ok {
+		return true
+	}
+
+	allow := getListSetting("PLUGIN_TEMPLATE_ENV_ALLOW")
+	if len(allow) == 0 {
+		allow = defaultTemplateEnvAllow
+	}
+	for _, pattern := range allow {
+		if matched, err := path.Match(pattern, name); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupTemplateEnv is the only way templates and ${VAR} interpolation read the
+// environment. Blocked variables read as empty with a warning, or fail in
+// strict mode.
+func lookupTemplateEnv(name string) (string, error) {
+	if isTemplateEnvAllowed(name) {
+		return getEnvOrDefault(name, ""), nil
+	}
+
+	if isStrictMode() {
+		return "", fmt.Errorf("template access to environment variable %s is not allowed", name)
+	}
+	if !warnedTemplateEnv[name] {
+		warnedTemplateEnv[name] = true
+		logWarn(fmt.Sprintf("template access to environment variable %s is not allowed, using an empty value", name))
+	}
+	return "", nil
+}
+
+// expandTemplateEnv replaces ${VAR} and $VAR references through lookupTemplateEnv
+func expandTemplateEnv(s string) (string, error) {
+	var expandErr error
+	expanded := os.Expand(s, func(name string) string {
+		value, err := lookupTemplateEnv(name)
+		if err != nil && expandErr == nil {
+			expandErr = err
+		}
+		return value
+	})
+	return expanded, expandErr
+}
+
+// templateJSON encodes a value for use inside JSON templates, e.g. {{json .CommitMessage}}
+func templateJSON(v any) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+// templateFuncs returns the functions available to user templates
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"env":  lookupTemplateEnv,
+		"json": templateJSON,
+	}
+}
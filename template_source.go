@@ -0,0 +1,137 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Limits for templates downloaded over HTTPS
+const (
+	maxTemplateSize      = 1 << 20
+	maxTemplateRedirects = 3
+)
+
+// templateFetchClient is the HTTP client used to download remote templates
+var templateFetchClient = &http.Client{Timeout: 10 * time.Second}
+
+// loadTemplateSource reads a template setting value, which is either a local
+// path or an https:// URL. setting is only used in error messages.
+func loadTemplateSource(setting, ref string) ([]byte, error) {
+	switch {
+	case strings.HasPrefix(ref, "https://"):
+		return loadRemoteTemplate(ref)
+	case strings.HasPrefix(ref, "http://"):
+		return nil, fmt.Errorf("%s must use https, got %s", setting, ref)
+	}
+
+	data, err := os.ReadFile(ref)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: %w", setting, err)
+	}
+	return data, nil
+}
+
+func templateCachePath(templateURL, checksum string) string {
+	stateDir := getEnvOrDefault("PLUGIN_STATE_DIR", "")
+	if stateDir == "" {
+		return ""
+	}
+	key := sha256.Sum256([]byte(templateURL + "\n" + checksum))
+	return filepath.Join(stateDir, "templates", hex.EncodeToString(key[:])+".tmpl")
+}
+
+func verifyTemplateChecksum(data []byte, checksum string) error {
+	if checksum == "" {
+		return nil
+	}
+	sum := sha256.Sum256(data)
+	if actual := hex.EncodeToString(sum[:]); !strings.EqualFold(actual, checksum) {
+		return fmt.Errorf("template checksum mismatch: expected %s, got %s", checksum, actual)
+	}
+	return nil
+}
+
+// loadRemoteTemplate downloads a template, verifies it against
+// PLUGIN_TEMPLATE_SHA256 and caches it in the state dir. When the download
+// fails the cached copy is used instead.
+func loadRemoteTemplate(templateURL string) ([]byte, error) {
+	checksum := strings.TrimSpace(getEnvOrDefault("PLUGIN_TEMPLATE_SHA256", ""))
+	if checksum == "" && isStrictMode() {
+		return nil, fmt.Errorf("PLUGIN_TEMPLATE_SHA256 is required for remote templates in strict mode")
+	}
+
+	cachePath := templateCachePath(templateURL, checksum)
+
+	data, fetchErr := fetchRemoteTemplate(templateURL)
+	if fetchErr != nil {
+		if cachePath != "" {
+			if cached, err := os.ReadFile(cachePath); err == nil && verifyTemplateChecksum(cached, checksum) == nil {
+				logWarn(fmt.Sprintf("%v, using cached template", fetchErr))
+				return cached, nil
+			}
+		}
+		return nil, fetchErr
+	}
+
+	if err := verifyTemplateChecksum(data, checksum); err != nil {
+		return nil, err
+	}
+
+	if cachePath != "" {
+		if err := writeTemplateCache(cachePath, data); err != nil {
+			logWarn(fmt.Sprintf("could not cache template: %v", err))
+		}
+	}
+	return data, nil
+}
+
+func fetchRemoteTemplate(templateURL string) ([]byte, error) {
+	client := *templateFetchClient
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) > maxTemplateRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxTemplateRedirects)
+		}
+		if req.URL.Scheme != "https" {
+			return errors.New("refusing to follow redirect to a non-https URL")
+		}
+		return nil
+	}
+
+	resp, err := client.Get(templateURL)
+	if err != nil {
+		return nil, fmt.Errorf("fetching template: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching template: HTTP %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxTemplateSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("fetching template: %w", err)
+	}
+	if len(data) > maxTemplateSize {
+		return nil, fmt.Errorf("fetching template: larger than %d bytes", maxTemplateSize)
+	}
+	return data, nil
+}
+
+func writeTemplateCache(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testTemplate = `{"elements":[{"tag":"div","text":{"tag":"lark_md","content":"{{.Repo}}"}}]}`
+
+func testTemplateChecksum() string {
+	sum := sha256.Sum256([]byte(testTemplate))
+	return hex.EncodeToString(sum[:])
+}
+
+// setupTemplateServer serves testTemplate over TLS until online is set to false
+func setupTemplateServer(t *testing.T, online *bool) *httptest.Server {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case !*online:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case r.URL.Path == "/redirect":
+			http.Redirect(w, r, "/redirect", http.StatusFound)
+		case r.URL.Path == "/huge":
+			w.Write([]byte(strings.Repeat("x", maxTemplateSize+1)))
+		default:
+			w.Write([]byte(testTemplate))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	originalClient := templateFetchClient
+	templateFetchClient = server.Client()
+	t.Cleanup(func() { templateFetchClient = originalClient })
+
+	setEnvFixture(t, map[string]string{"PLUGIN_STATE_DIR": t.TempDir()})
+	return server
+}
+
+func TestLoadTemplateSource_Fetch(t *testing.T) {
+	online := true
+	server := setupTemplateServer(t, &online)
+	setEnvFixture(t, map[string]string{"PLUGIN_TEMPLATE_SHA256": testTemplateChecksum()})
+
+	data, err := loadTemplateSource("PLUGIN_CARD_TEMPLATE_FILE", server.URL+"/card.tmpl")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(data) != testTemplate {
+		t.Errorf("Unexpected template %q", data)
+	}
+
+	cached, err := os.ReadFile(templateCachePath(server.URL+"/card.tmpl", testTemplateChecksum()))
+	if err != nil || string(cached) != testTemplate {
+		t.Errorf("Expected template to be cached, got %q (%v)", cached, err)
+	}
+}
+
+func TestLoadTemplateSource_ChecksumMismatch(t *testing.T) {
+	online := true
+	server := setupTemplateServer(t, &online)
+	setEnvFixture(t, map[string]string{"PLUGIN_TEMPLATE_SHA256": strings.Repeat("0", 64)})
+
+	_, err := loadTemplateSource("PLUGIN_CARD_TEMPLATE_FILE", server.URL+"/card.tmpl")
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("Expected checksum mismatch, got %v", err)
+	}
+	if _, err := os.Stat(templateCachePath(server.URL+"/card.tmpl", strings.Repeat("0", 64))); err == nil {
+		t.Error("A template failing verification must not be cached")
+	}
+}
+
+func TestLoadTemplateSource_CacheFallback(t *testing.T) {
+	online := true
+	server := setupTemplateServer(t, &online)
+	setEnvFixture(t, map[string]string{"PLUGIN_TEMPLATE_SHA256": testTemplateChecksum()})
+
+	if _, err := loadTemplateSource("PLUGIN_CARD_TEMPLATE_FILE", server.URL+"/card.tmpl"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	online = false
+	data, err := loadTemplateSource("PLUGIN_CARD_TEMPLATE_FILE", server.URL+"/card.tmpl")
+	if err != nil {
+		t.Fatalf("Expected cached template, got error: %v", err)
+	}
+	if string(data) != testTemplate {
+		t.Errorf("Unexpected template %q", data)
+	}
+
+	// Nothing cached for this URL
+	if _, err := loadTemplateSource("PLUGIN_CARD_TEMPLATE_FILE", server.URL+"/other.tmpl"); err == nil {
+		t.Error("Expected an error without a cached copy")
+	}
+}
+
+func TestLoadTemplateSource_Limits(t *testing.T) {
+	online := true
+	server := setupTemplateServer(t, &online)
+
+	if _, err := loadTemplateSource("PLUGIN_CARD_TEMPLATE_FILE", server.URL+"/redirect"); err == nil || !strings.Contains(err.Error(), "redirects") {
+		t.Errorf("Expected redirect limit error, got %v", err)
+	}
+	if _, err := loadTemplateSource("PLUGIN_CARD_TEMPLATE_FILE", server.URL+"/huge"); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Errorf("Expected size limit error, got %v", err)
+	}
+	if _, err := loadTemplateSource("PLUGIN_CARD_TEMPLATE_FILE", "http://example.com/card.tmpl"); err == nil {
+		t.Error("Expected plain http to be rejected")
+	}
+}
+
+func TestLoadTemplateSource_StrictRequiresChecksum(t *testing.T) {
+	online := true
+	server := setupTemplateServer(t, &online)
+	setEnvFixture(t, map[string]string{"PLUGIN_STRICT": "true"})
+
+	if _, err := loadTemplateSource("PLUGIN_CARD_TEMPLATE_FILE", server.URL+"/card.tmpl"); err == nil {
+		t.Error("Expected strict mode to require PLUGIN_TEMPLATE_SHA256")
+	}
+}
+
+func TestLoadTemplateSource_LocalFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "card.tmpl")
+	os.WriteFile(path, []byte(testTemplate), 0644)
+
+	data, err := loadTemplateSource("PLUGIN_CARD_TEMPLATE_FILE", path)
+	if err != nil || string(data) != testTemplate {
+		t.Errorf("Expected local template, got %q (%v)", data, err)
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// timeNow is a variable for time.Now that can be overridden in tests
+var timeNow = time.Now
+
+// openAPIClient is the HTTP client used for Lark OpenAPI calls
+var openAPIClient = &http.Client{Timeout: 10 * time.Second}
+
+// tokenRefreshMargin is the minimum remaining validity for a cached token to be reused
+const tokenRefreshMargin = 5 * time.Minute
+
+// Lark error codes signalling an invalid or expired tenant_access_token
+const (
+	larkCodeTokenInvalid = 99991663
+	larkCodeTokenExpired = 99991677
+)
+
+// larkAPIError is a non-zero code returned in a Lark OpenAPI response body
+type larkAPIError struct {
+	Code int
+	Msg  string
+}
+
+func (e *larkAPIError) Error() string {
+	return fmt.Sprintf("Lark API error %d: %s", e.Code, e.Msg)
+}
+
+func isTokenExpiredError(err error) bool {
+	var apiErr *larkAPIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code == larkCodeTokenInvalid || apiErr.Code == larkCodeTokenExpired
+	}
+	return false
+}
+
+type tenantToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func getOpenAPIBaseURL() string {
+	return strings.TrimSuffix(getEnvOrDefault("PLUGIN_API_BASE_URL", "https://open.larksuite.com"), "/")
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// tokenCachePath returns the cache file for appID, or "" when no state dir is configured
+func tokenCachePath(appID string) string {
+	stateDir := getEnvOrDefault("PLUGIN_STATE_DIR", "")
+	if stateDir == "" {
+		return ""
+	}
+	return filepath.Join(stateDir, "tenant_token_"+unsafeFileChars.ReplaceAllString(appID, "_")+".json")
+}
+
+// readCachedToken returns the cached token for appID if it is still valid for
+// longer than tokenRefreshMargin. Unreadable or corrupt cache files are a miss.
+func readCachedToken(appID string) (string, bool) {
+	path := tokenCachePath(appID)
+	if path == "" {
+		return "", false
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+
+	var cached tenantToken
+	if err := json.Unmarshal(data, &cached); err != nil || cached.Token == "" {
+		return "", false
+	}
+
+	if cached.ExpiresAt.Sub(timeNow()) <= tokenRefreshMargin {
+		return "", false
+	}
+	return cached.Token, true
+}
+
+func writeCachedToken(appID string, token tenantToken) error {
+	path := tokenCachePath(appID)
+	if path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so concurrent readers never see a partial token
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tenant_token_*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func invalidateCachedToken(appID string) {
+	if path := tokenCachePath(appID); path != "" {
+		os.Remove(path)
+	}
+}
+
+func fetchTenantAccessToken(ctx context.Context, appID, appSecret string) (tenantToken, error) {
+	reqBody, err := json.Marshal(map[string]string{
+		"app_id":     appID,
+		"app_secret": appSecret,
+	})
+	if err != nil {
+		return tenantToken{}, err
+	}
+
+	url := getOpenAPIBaseURL() + "/open-apis/auth/v3/tenant_access_token/internal"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(reqBody))
+	if err != nil {
+		return tenantToken{}, err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	signGatewayRequest(req, reqBody)
+
+	resp, err := openAPIClient.Do(req)
+	if err != nil {
+		return tenantToken{}, fmt.Errorf("requesting tenant_access_token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+
+	var response struct {
+		Code              int    `json:"code"`
+		Msg               string `json:"msg"`
+		TenantAccessToken string `json:"tenant_access_token"`
+		Expire            int    `json:"expire"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return tenantToken{}, fmt.Errorf("unexpected tenant_access_token response (HTTP %d): %s", resp.StatusCode, string(body))
+	}
+	if response.Code != 0 {
+		return tenantToken{}, &larkAPIError{Code: response.Code, Msg: response.Msg}
+	}
+	if response.TenantAccessToken == "" {
+		return tenantToken{}, fmt.Errorf("empty tenant_access_token in response (HTTP %d)", resp.StatusCode)
+	}
+
+	return tenantToken{
+		Token:     response.TenantAccessToken,
+		ExpiresAt: timeNow().Add(time.Duration(response.Expire) * time.Second),
+	}, nil
+}
+
+// getTenantAccessToken returns a cached token when possible and fetches a new one otherwise
+func getTenantAccessToken(ctx context.Context, appID, appSecret string) (string, error) {
+	if token, ok := readCachedToken(appID); ok {
+		return token, nil
+	}
+
+	token, err := fetchTenantAccessToken(ctx, appID, appSecret)
+	if err != nil {
+		return "", err
+	}
+
+	if err := writeCachedToken(appID, token); err != nil {
+		logWarn(fmt.Sprintf("could not cache tenant_access_token: %v", err))
+	}
+	return token.Token, nil
+}
+
+// withTenantAccessToken runs call with a tenant_access_token. If Lark reports the
+// token as invalid or expired, the cache is dropped and call is retried once
+// with a freshly fetched token.
+func withTenantAccessToken(ctx context.Context, appID, appSecret string, call func(token string) error) error {
+	token, err := getTenantAccessToken(ctx, appID, appSecret)
+	if err != nil {
+		return err
+	}
+
+	err = call(token)
+	if !isTokenExpiredError(err) {
+		return err
+	}
+
+	invalidateCachedToken(appID)
+	token, err = getTenantAccessToken(ctx, appID, appSecret)
+	if err != nil {
+		return err
+	}
+	return call(token)
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+// setupTokenTest points the plugin at a fake token endpoint, a temporary state dir
+// and a fake clock. It returns a pointer to the number of token requests served.
+func setupTokenTest(t *testing.T, now *time.Time) *int {
+	requests := 0
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/open-apis/auth/v3/tenant_access_token/internal" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+
+		var reqBody map[string]string
+		json.NewDecoder(r.Body).Decode(&reqBody)
+		if reqBody["app_id"] != "cli_test" || reqBody["app_secret"] != "app_secret" {
+			t.Errorf("Unexpected credentials %v", reqBody)
+		}
+
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"code":                0,
+			"msg":                 "ok",
+			"tenant_access_token": "t-" + string(rune('0'+requests)),
+			"expire":              7200,
+		})
+	}))
+	t.Cleanup(tokenServer.Close)
+
+	os.Setenv("PLUGIN_API_BASE_URL", tokenServer.URL)
+	os.Setenv("PLUGIN_STATE_DIR", t.TempDir())
+
+	originalTimeNow := timeNow
+	timeNow = func() time.Time { return *now }
+
+	t.Cleanup(func() {
+		os.Unsetenv("PLUGIN_API_BASE_URL")
+		os.Unsetenv("PLUGIN_STATE_DIR")
+		timeNow = originalTimeNow
+	})
+
+	return &requests
+}
+
+func TestGetTenantAccessToken_CachesToken(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	requests := setupTokenTest(t, &now)
+
+	token, err := getTenantAccessToken(context.Background(), "cli_test", "app_secret")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if token != "t-1" {
+		t.Errorf("Expected 't-1', got '%s'", token)
+	}
+
+	// A second call within the validity window must reuse the cached token
+	now = now.Add(time.Hour)
+	token, err = getTenantAccessToken(context.Background(), "cli_test", "app_secret")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if token != "t-1" {
+		t.Errorf("Expected cached 't-1', got '%s'", token)
+	}
+	if *requests != 1 {
+		t.Errorf("Expected 1 token request, got %d", *requests)
+	}
+
+	info, err := os.Stat(tokenCachePath("cli_test"))
+	if err != nil {
+		t.Fatalf("Expected cache file: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("Expected 0600 permissions, got %o", perm)
+	}
+}
+
+func TestGetTenantAccessToken_RefreshesNearExpiry(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	requests := setupTokenTest(t, &now)
+
+	getTenantAccessToken(context.Background(), "cli_test", "app_secret")
+
+	// Less than 5 minutes of validity left: fetch a new token
+	now = now.Add(2*time.Hour - 4*time.Minute)
+	token, err := getTenantAccessToken(context.Background(), "cli_test", "app_secret")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if token != "t-2" {
+		t.Errorf("Expected refreshed 't-2', got '%s'", token)
+	}
+	if *requests != 2 {
+		t.Errorf("Expected 2 token requests, got %d", *requests)
+	}
+}
+
+func TestGetTenantAccessToken_CorruptCacheIsMiss(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	requests := setupTokenTest(t, &now)
+
+	if err := os.WriteFile(tokenCachePath("cli_test"), []byte("{not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := getTenantAccessToken(context.Background(), "cli_test", "app_secret")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if token != "t-1" {
+		t.Errorf("Expected 't-1', got '%s'", token)
+	}
+	if *requests != 1 {
+		t.Errorf("Expected 1 token request, got %d", *requests)
+	}
+}
+
+func TestGetTenantAccessToken_NoStateDir(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	requests := setupTokenTest(t, &now)
+	os.Unsetenv("PLUGIN_STATE_DIR")
+
+	getTenantAccessToken(context.Background(), "cli_test", "app_secret")
+	getTenantAccessToken(context.Background(), "cli_test", "app_secret")
+
+	if *requests != 2 {
+		t.Errorf("Expected 2 token requests without a state dir, got %d", *requests)
+	}
+}
+
+func TestWithTenantAccessToken_RetriesOnExpiredToken(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	requests := setupTokenTest(t, &now)
+
+	// Seed the cache with a token the server no longer accepts
+	writeCachedToken("cli_test", tenantToken{Token: "stale", ExpiresAt: now.Add(time.Hour)})
+
+	var used []string
+	err := withTenantAccessToken(context.Background(), "cli_test", "app_secret", func(token string) error {
+		used = append(used, token)
+		if token == "stale" {
+			return &larkAPIError{Code: larkCodeTokenInvalid, Msg: "Invalid access token for authorization"}
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(used) != 2 || used[0] != "stale" || used[1] != "t-1" {
+		t.Errorf("Expected calls with 'stale' then 't-1', got %v", used)
+	}
+	if *requests != 1 {
+		t.Errorf("Expected 1 token request, got %d", *requests)
+	}
+	if token, ok := readCachedToken("cli_test"); !ok || token != "t-1" {
+		t.Errorf("Expected cache to hold 't-1', got '%s' (ok=%v)", token, ok)
+	}
+}
+
+func TestWithTenantAccessToken_RetriesOnlyOnce(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	setupTokenTest(t, &now)
+
+	calls := 0
+	err := withTenantAccessToken(context.Background(), "cli_test", "app_secret", func(token string) error {
+		calls++
+		return &larkAPIError{Code: larkCodeTokenInvalid, Msg: "Invalid access token for authorization"}
+	})
+
+	if !isTokenExpiredError(err) {
+		t.Errorf("Expected token error to be returned, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 calls, got %d", calls)
+	}
+}
+
+func TestFetchTenantAccessToken_APIError(t *testing.T) {
+	errorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code": 10003, "msg": "invalid param"}`))
+	}))
+	defer errorServer.Close()
+
+	os.Setenv("PLUGIN_API_BASE_URL", errorServer.URL)
+	defer os.Unsetenv("PLUGIN_API_BASE_URL")
+
+	_, err := fetchTenantAccessToken(context.Background(), "cli_test", "wrong")
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	if isTokenExpiredError(err) {
+		t.Error("Did not expect a token expiry error")
+	}
+}
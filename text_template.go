@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// textTemplate is the parsed PLUGIN_TEXT_TEMPLATE or PLUGIN_TEXT_TEMPLATE_FILE,
+// or nil for the built-in text message
+var textTemplate *template.Template
+
+// loadTextTemplate parses PLUGIN_TEXT_TEMPLATE, or the local path or https://
+// URL in PLUGIN_TEXT_TEMPLATE_FILE, and renders it once so that both syntax
+// errors and unknown fields are reported at startup. A literal \n in the
+// setting is a line break, for templates written on one line.
+func loadTextTemplate() error {
+	textTemplate = nil
+	setting := "PLUGIN_TEXT_TEMPLATE"
+	source := strings.ReplaceAll(getEnvOrDefault(setting, ""), `\n`, "\n")
+	if ref := getEnvOrDefault("PLUGIN_TEXT_TEMPLATE_FILE", ""); ref != "" {
+		if source != "" {
+			logWarn("both PLUGIN_TEXT_TEMPLATE and PLUGIN_TEXT_TEMPLATE_FILE are set, using PLUGIN_TEXT_TEMPLATE_FILE")
+		}
+		setting = "PLUGIN_TEXT_TEMPLATE_FILE"
+		data, err := loadTemplateSource(setting, ref)
+		if err != nil {
+			return err
+		}
+		source = string(data)
+	}
+	if source == "" {
+		return nil
+	}
+
+	// Missing .Env keys render as empty strings
+	tmpl, err := template.New("text").Funcs(templateFuncs()).Option("missingkey=zero").Parse(source)
+	if err != nil {
+		return fmt.Errorf("cannot parse %s: %w", setting, err)
+	}
+	textTemplate = tmpl
+	if _, err := renderTextTemplate(Config{}, ""); err != nil {
+		textTemplate = nil
+		return fmt.Errorf("cannot render %s: %w", setting, err)
+	}
+	return nil
+}
+
+func renderTextTemplate(config Config, projectVersion string) (string, error) {
+	var output strings.Builder
+	if err := textTemplate.Execute(&output, newCardTemplateContext(config, projectVersion)); err != nil {
+		return "", err
+	}
+	return strings.TrimRight(output.String(), "\n"), nil
+}
+
+// customText renders PLUGIN_TEXT_TEMPLATE, reporting false when it is not set
+// and the built-in text message applies
+func customText(config Config, projectVersion string) (string, bool) {
+	if textTemplate == nil {
+		return "", false
+	}
+	text, err := renderTextTemplate(config, projectVersion)
+	if err != nil {
+		logWarn(fmt.Sprintf("cannot render PLUGIN_TEXT_TEMPLATE: %v", err))
+		return "", false
+	}
+	return text, true
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTextTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{
+			name:     "Block scalar",
+			template: "{{.StatusIcon}} {{.Repo}} {{.Version}} {{.Status}}\n{{.Branch}} by {{.Author}}\n{{.PipelineURL}}\n",
+			expected: "✅ octocat/backend v1.0.0 success\nmain by octocat\nhttps://ci.example.com/repos/1/pipeline/42",
+		},
+		{
+			name:     "Escaped newlines",
+			template: `{{.Repo}}: {{.CommitMessage}}\nRunner: {{.Env.CI_MACHINE}}`,
+			expected: "octocat/backend: Fix the flaky test\nRunner: runner-1",
+		},
+		{
+			name:     "Missing env key",
+			template: `{{.Repo}}[{{.Env.CI_UNKNOWN}}]`,
+			expected: "octocat/backend[]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnvFixture(t, map[string]string{
+				"PLUGIN_TEXT_TEMPLATE": tt.template,
+				"CI_REPO":              "octocat/backend",
+				"CI_COMMIT_BRANCH":     "main",
+				"CI_COMMIT_AUTHOR":     "octocat",
+				"CI_COMMIT_MESSAGE":    "Fix the flaky test",
+				"CI_PIPELINE_URL":      "https://ci.example.com/repos/1/pipeline/42",
+				"CI_MACHINE":           "runner-1",
+			})
+			if err := loadTextTemplate(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			t.Cleanup(func() { textTemplate = nil })
+
+			message := createLarkTextMessage(Config{Status: "success"}, "v1.0.0")
+			if message["msg_type"] != "text" {
+				t.Errorf("Expected a text message, got %v", message["msg_type"])
+			}
+			if text := message["content"].(map[string]any)["text"]; text != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, text)
+			}
+		})
+	}
+}
+
+func TestTextTemplate_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{
+			name:     "Syntax error",
+			template: "{{.Repo}}\n{{if .Branch}}",
+			expected: "cannot parse PLUGIN_TEXT_TEMPLATE: template: text:2: unexpected EOF",
+		},
+		{
+			name:     "Unknown field",
+			template: "{{.Repo}}\n  {{.Commit}}",
+			expected: "cannot render PLUGIN_TEXT_TEMPLATE: template: text:2:4: executing",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnvFixture(t, map[string]string{"PLUGIN_TEXT_TEMPLATE": tt.template})
+			err := loadTextTemplate()
+			if err == nil || !strings.HasPrefix(err.Error(), tt.expected) {
+				t.Errorf("Expected %q, got %v", tt.expected, err)
+			}
+			if textTemplate != nil {
+				t.Error("Expected no template after an error")
+			}
+		})
+	}
+}
+
+func TestTextTemplate_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "text.tmpl")
+	os.WriteFile(path, []byte("{{.StatusIcon}} {{.Repo}}\n"), 0644)
+
+	online := true
+	server := setupTemplateServer(t, &online)
+
+	for name, ref := range map[string]string{"Local file": path, "HTTPS URL": server.URL + "/text.tmpl"} {
+		t.Run(name, func(t *testing.T) {
+			setEnvFixture(t, map[string]string{
+				"PLUGIN_TEXT_TEMPLATE_FILE": ref,
+				"PLUGIN_EMOJI":              "true",
+				"CI_REPO":                   "octo/backend",
+			})
+			if err := loadTextTemplate(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			t.Cleanup(func() { textTemplate = nil })
+
+			expected := "✅ octo/backend"
+			if ref != path {
+				expected = `{"elements":[{"tag":"div","text":{"tag":"lark_md","content":"octo/backend"}}]}`
+			}
+			if text, ok := customText(Config{Status: "success"}, ""); !ok || text != expected {
+				t.Errorf("Expected %q, got %q", expected, text)
+			}
+		})
+	}
+
+	setEnvFixture(t, map[string]string{"PLUGIN_TEXT_TEMPLATE_FILE": "http://example.com/text.tmpl"})
+	if err := loadTextTemplate(); err == nil || !strings.Contains(err.Error(), "PLUGIN_TEXT_TEMPLATE_FILE must use https") {
+		t.Errorf("Expected plain http to be refused, got %v", err)
+	}
+}
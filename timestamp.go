@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	// The plugin image has no zoneinfo files; embed them so that
+	// PLUGIN_TIMEZONE works everywhere
+	_ "time/tzdata"
+)
+
+// getTimezone returns the PLUGIN_TIMEZONE location, UTC when unset or unknown
+func getTimezone() *time.Location {
+	name := getEnvOrDefault("PLUGIN_TIMEZONE", "")
+	if name == "" {
+		return time.UTC
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		logWarn(fmt.Sprintf("invalid PLUGIN_TIMEZONE %q, using UTC: %v", name, err))
+		return time.UTC
+	}
+	return location
+}
+
+// formatTimestamp renders a time in PLUGIN_TIMEZONE with the PLUGIN_DATE_FORMAT
+// layout (default RFC3339). Every timestamp shown by the plugin goes through it.
+func formatTimestamp(t time.Time) string {
+	return t.In(getTimezone()).Format(getEnvOrDefault("PLUGIN_DATE_FORMAT", time.RFC3339))
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatTimestamp(t *testing.T) {
+	fixed := time.Date(2024, 6, 1, 10, 23, 45, 0, time.UTC)
+	tests := []struct {
+		name     string
+		timezone string
+		format   string
+		expected string
+	}{
+		{"Defaults", "", "", "2024-06-01T10:23:45Z"},
+		{"Shanghai", "Asia/Shanghai", "2006-01-02 15:04 MST", "2024-06-01 18:23 CST"},
+		{"New York RFC3339", "America/New_York", "", "2024-06-01T06:23:45-04:00"},
+		{"Invalid zone", "Mars/Olympus", "", "2024-06-01T10:23:45Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnvFixture(t, map[string]string{"PLUGIN_TIMEZONE": tt.timezone, "PLUGIN_DATE_FORMAT": tt.format})
+
+			var got string
+			output := captureStdout(t, func() { got = formatTimestamp(fixed) })
+
+			if got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+			if tt.name == "Invalid zone" && !strings.Contains(output, `Warning: invalid PLUGIN_TIMEZONE "Mars/Olympus", using UTC`) {
+				t.Errorf("Expected a warning about the zone, got:\n%s", output)
+			}
+		})
+	}
+}
+
+func TestFooterLine_Timezone(t *testing.T) {
+	originalTimeNow := timeNow
+	defer func() { timeNow = originalTimeNow }()
+	timeNow = func() time.Time { return time.Date(2024, 6, 1, 10, 23, 0, 0, time.UTC) }
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_TIMEZONE":    "Asia/Shanghai",
+		"PLUGIN_DATE_FORMAT": "2006-01-02 15:04 MST",
+		"PLUGIN_EMOJI":       "false",
+		"CI_MACHINE":         "",
+	})
+
+	if footer := footerLine(); !strings.HasPrefix(footer, "2024-06-01 18:23 CST") {
+		t.Errorf("Expected the footer to start with the local time, got %s", footer)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// maxHeaderTitleLength keeps custom titles within what Lark accepts in a card header
+const maxHeaderTitleLength = 100
+
+// titleTemplate is the parsed PLUGIN_TITLE_TEMPLATE, or nil for the built-in title
+var titleTemplate *template.Template
+
+// loadTitleTemplate parses PLUGIN_TITLE_TEMPLATE and renders it once so that
+// both syntax errors and unknown fields are reported at startup
+func loadTitleTemplate() error {
+	titleTemplate = nil
+	source := getEnvOrDefault("PLUGIN_TITLE_TEMPLATE", "")
+	if source == "" {
+		return nil
+	}
+
+	tmpl, err := template.New("title").Funcs(templateFuncs()).Option("missingkey=error").Parse(source)
+	if err != nil {
+		return fmt.Errorf("cannot parse PLUGIN_TITLE_TEMPLATE %q: %w", source, err)
+	}
+	titleTemplate = tmpl
+	if _, err := renderTitle(Config{}, "", ""); err != nil {
+		titleTemplate = nil
+		return fmt.Errorf("cannot render PLUGIN_TITLE_TEMPLATE %q: %w", source, err)
+	}
+	return nil
+}
+
+func renderTitle(config Config, projectVersion, statusText string) (string, error) {
+	context := newCardTemplateContext(config, projectVersion)
+	context.StatusText = statusText
+
+	var output strings.Builder
+	if err := titleTemplate.Execute(&output, context); err != nil {
+		return "", err
+	}
+	return truncateRunes(strings.TrimSpace(output.String()), maxHeaderTitleLength), nil
+}
+
+// customTitle renders PLUGIN_TITLE_TEMPLATE, reporting false when it is not
+// set and the built-in title applies
+func customTitle(config Config, projectVersion, statusText string) (string, bool) {
+	if titleTemplate == nil {
+		return "", false
+	}
+	title, err := renderTitle(config, projectVersion, statusText)
+	if err != nil {
+		logWarn(fmt.Sprintf("cannot render PLUGIN_TITLE_TEMPLATE: %v", err))
+		return "", false
+	}
+	return title, true
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func loadTitleFixture(t *testing.T, env map[string]string) {
+	t.Helper()
+	setEnvFixture(t, env)
+	if err := loadTitleTemplate(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	t.Cleanup(func() { titleTemplate = nil })
+}
+
+func TestTitleTemplateCardAndText(t *testing.T) {
+	loadTitleFixture(t, map[string]string{
+		"PLUGIN_TITLE_TEMPLATE":     ` [{{env "DEPLOY_ENV"}}] {{.RepoName}} {{.Event}} {{.StatusIcon}} `,
+		"PLUGIN_TEMPLATE_ENV_ALLOW": "CI_*,DEPLOY_ENV",
+		"DEPLOY_ENV":                "prod",
+		"CI_REPO_NAME":              "backend",
+		"CI_PIPELINE_EVENT":         "deployment",
+		"CI_COMMIT_BRANCH":          "main",
+	})
+
+	card := createLarkCard(Config{}, "v1.0.0")["card"].(map[string]any)
+	title := card["header"].(map[string]any)["title"].(map[string]any)["content"]
+	if title != "[prod] backend deployment ✅" {
+		t.Errorf("Expected custom card title, got %q", title)
+	}
+
+	text := createLarkTextMessage(Config{}, "v1.0.0")["content"].(map[string]any)["text"].(string)
+	if !strings.HasPrefix(text, "[prod] backend deployment ✅\n\n📋 Project:") {
+		t.Errorf("Expected custom first line, got %q", text)
+	}
+}
+
+func TestTitleTemplateStatusText(t *testing.T) {
+	loadTitleFixture(t, map[string]string{
+		"PLUGIN_TITLE_TEMPLATE": "{{.RepoName}}: {{.StatusText}}",
+		"CI_REPO_NAME":          "backend",
+	})
+
+	config := Config{Status: "failure", Compact: true}
+	card := createLarkCard(config, "v1.0.0")["card"].(map[string]any)
+	if title := card["header"].(map[string]any)["title"].(map[string]any)["content"]; title != "backend: Pipeline Failed" {
+		t.Errorf("Expected custom compact title, got %q", title)
+	}
+
+	text := createLarkTextMessage(config, "v1.0.0")["content"].(map[string]any)["text"].(string)
+	if !strings.HasPrefix(text, "backend: PIPELINE FAILED") {
+		t.Errorf("Expected custom compact first line, got %q", text)
+	}
+}
+
+func TestTitleTemplateTruncated(t *testing.T) {
+	loadTitleFixture(t, map[string]string{
+		"PLUGIN_TITLE_TEMPLATE": strings.Repeat("x", maxHeaderTitleLength+20),
+	})
+
+	title, ok := customTitle(Config{}, "v1.0.0", "Pipeline Succeeded")
+	if !ok {
+		t.Fatal("Expected the custom title to apply")
+	}
+	if length := len([]rune(title)); length != maxHeaderTitleLength+1 || !strings.HasSuffix(title, "…") {
+		t.Errorf("Expected title truncated to %d runes, got %d (%q)", maxHeaderTitleLength, length, title)
+	}
+}
+
+func TestTitleTemplateErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		err      string
+	}{
+		{"Syntax error", "{{.RepoName", `cannot parse PLUGIN_TITLE_TEMPLATE "{{.RepoName"`},
+		{"Unknown field", "{{.Repository}}", `cannot render PLUGIN_TITLE_TEMPLATE "{{.Repository}}"`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setEnvFixture(t, map[string]string{"PLUGIN_TITLE_TEMPLATE": tc.template})
+
+			err := loadTitleTemplate()
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("Expected error containing %q, got %v", tc.err, err)
+			}
+			if titleTemplate != nil {
+				t.Error("Expected no title template after an error")
+			}
+		})
+	}
+}
+
+func TestTitleTemplateUnset(t *testing.T) {
+	loadTitleFixture(t, map[string]string{"CI_REPO_NAME": "backend"})
+
+	card := createLarkCard(Config{}, "v1.0.0")["card"].(map[string]any)
+	if title := card["header"].(map[string]any)["title"].(map[string]any)["content"]; title != "backend - ✅ Pipeline Succeeded" {
+		t.Errorf("Expected the built-in title, got %q", title)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import "strings"
+
+// Status transitions relative to the previous pipeline
+const (
+	transitionSucceeded    = "succeeded"
+	transitionFixed        = "fixed"
+	transitionFailed       = "failed"
+	transitionStillFailing = "still_failing"
+)
+
+// statusStyle is how a build status is presented in the header
+type statusStyle struct {
+	Color string
+	Icon  string
+	Text  string
+}
+
+func getPrevBuildStatus() string {
+	return getEnvOrDefault("CI_PREV_PIPELINE_STATUS", getEnvOrDefault("DRONE_PREV_BUILD_STATUS", ""))
+}
+
+// getTransition compares status with the previous pipeline's. It
+// returns "" when the previous status is unknown or the current one is neither
+// a success nor a failure.
+func getTransition(status string) string {
+	prev := getPrevBuildStatus()
+	if prev == "" || !isTransitionStatus(status) {
+		return ""
+	}
+
+	failed := status == "failure" || status == "error"
+	prevFailed := isFailedStatus(prev)
+	switch {
+	case failed && prevFailed:
+		return transitionStillFailing
+	case failed:
+		return transitionFailed
+	case prevFailed:
+		return transitionFixed
+	default:
+		return transitionSucceeded
+	}
+}
+
+// getStatusStyle returns the header color, icon and text for status and its
+// transition, reworded for deployments and scheduled pipelines
+func getStatusStyle(status string) statusStyle {
+	var style statusStyle
+	switch getTransition(status) {
+	case transitionFixed:
+		style = statusStyle{"turquoise", statusIcon("🎉", false), "Pipeline Fixed"}
+	case transitionStillFailing:
+		style = statusStyle{"red", statusIcon("🔥", true), "Pipeline Still Failing"}
+	default:
+		style = classifyStatus(status)
+	}
+
+	return cronStyle(deploymentStyle(style, status))
+}
+
+// upperStatusText is the status text as shown in text messages
+func (s statusStyle) upperStatusText() string {
+	return strings.ToUpper(tr(s.Text))
+}
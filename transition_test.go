@@ -0,0 +1,89 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStatusTransitions(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     string
+		prevStatus string
+		transition string
+		color      string
+		title      string
+		textHeader string
+	}{
+		{"No previous status", "success", "", "", "green", "backend - ✅ Pipeline Succeeded", "✅ PIPELINE SUCCEEDED"},
+		{"No previous status, failure", "failure", "", "", "red", "backend - 🚨 Pipeline Failed", "🚨 PIPELINE FAILED"},
+		{"Succeeded", "success", "success", transitionSucceeded, "green", "backend - ✅ Pipeline Succeeded", "✅ PIPELINE SUCCEEDED"},
+		{"Fixed", "success", "failure", transitionFixed, "turquoise", "backend - 🎉 Pipeline Fixed", "🎉 PIPELINE FIXED"},
+		{"Fixed after an error", "success", "error", transitionFixed, "turquoise", "backend - 🎉 Pipeline Fixed", "🎉 PIPELINE FIXED"},
+		{"Failed", "failure", "success", transitionFailed, "red", "backend - 🚨 Pipeline Failed", "🚨 PIPELINE FAILED"},
+		{"Still failing", "failure", "failure", transitionStillFailing, "red", "backend - 🔥 Pipeline Still Failing", "🔥 PIPELINE STILL FAILING"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setEnvFixture(t, map[string]string{
+				"CI_REPO_NAME":            "backend",
+				"CI_PREV_PIPELINE_STATUS": tc.prevStatus,
+			})
+
+			if transition := getTransition(tc.status); transition != tc.transition {
+				t.Errorf("Expected transition '%s', got '%s'", tc.transition, transition)
+			}
+
+			header := createLarkCard(Config{Status: tc.status}, "v1.0.0")["card"].(map[string]any)["header"].(map[string]any)
+			if header["template"] != tc.color {
+				t.Errorf("Expected color '%s', got '%v'", tc.color, header["template"])
+			}
+			if title := header["title"].(map[string]any)["content"]; title != tc.title {
+				t.Errorf("Expected title '%s', got '%v'", tc.title, title)
+			}
+
+			text := createLarkTextMessage(Config{Status: tc.status}, "v1.0.0")["content"].(map[string]any)["text"].(string)
+			if !strings.HasPrefix(text, tc.textHeader+"\n") {
+				t.Errorf("Expected text to start with '%s', got '%s'", tc.textHeader, text)
+			}
+		})
+	}
+}
+
+func TestStatusTransitions_DroneFallback(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"DRONE_PREV_BUILD_STATUS": "failure",
+	})
+
+	if transition := getTransition("failure"); transition != transitionStillFailing {
+		t.Errorf("Expected '%s', got '%s'", transitionStillFailing, transition)
+	}
+}
+
+func TestNotifySkipReason_Transitions(t *testing.T) {
+	tests := []struct {
+		notifyOn   string
+		status     string
+		prevStatus string
+		send       bool
+	}{
+		{"failure,fixed", "success", "failure", true},
+		{"failure,fixed", "success", "success", false},
+		{"still_failing", "failure", "failure", true},
+		{"still_failing", "failure", "success", false},
+		{"failed", "failure", "success", true},
+	}
+
+	for _, tc := range tests {
+		setEnvFixture(t, map[string]string{
+			"PLUGIN_NOTIFY_ON":        tc.notifyOn,
+			"PLUGIN_STATUS":           tc.status,
+			"CI_PREV_PIPELINE_STATUS": tc.prevStatus,
+		})
+
+		if reason := notifySkipReason(); (reason == "") != tc.send {
+			t.Errorf("%s with %s after %s: expected send=%v, got skip reason '%s'", tc.notifyOn, tc.status, tc.prevStatus, tc.send, reason)
+		}
+	}
+}
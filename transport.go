@@ -0,0 +1,93 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// webhookTimeout bounds a webhook delivery, including reading the response
+const webhookTimeout = 30 * time.Second
+
+// webhookClient is the HTTP client used to deliver messages. Tests replace it
+// or its Transport to simulate Lark.
+var webhookClient = &http.Client{Timeout: webhookTimeout}
+
+// loadCACertPool returns the system roots plus PLUGIN_CA_CERT, which is either
+// PEM content or the path to a PEM file
+func loadCACertPool() (*x509.CertPool, error) {
+	caCert := strings.TrimSpace(getEnvOrDefault("PLUGIN_CA_CERT", ""))
+	if caCert == "" {
+		return nil, nil
+	}
+
+	pemData := []byte(caCert)
+	if !strings.HasPrefix(caCert, "-----BEGIN") {
+		data, err := os.ReadFile(caCert)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read PLUGIN_CA_CERT: %w", err)
+		}
+		pemData = data
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pemData) {
+		return nil, fmt.Errorf("PLUGIN_CA_CERT does not contain a valid PEM certificate")
+	}
+	return pool, nil
+}
+
+// loadTLSConfig builds the TLS settings for outgoing requests, nil keeps the defaults
+func loadTLSConfig() (*tls.Config, error) {
+	pool, err := loadCACertPool()
+	if err != nil {
+		return nil, err
+	}
+	insecure := getEnvOrDefault("PLUGIN_INSECURE_SKIP_VERIFY", "false") == "true"
+	if pool == nil && !insecure {
+		return nil, nil
+	}
+
+	if insecure {
+		logWarn("PLUGIN_INSECURE_SKIP_VERIFY is enabled, TLS certificates are NOT verified. Anyone on the network path can read and modify notifications. Use PLUGIN_CA_CERT instead.")
+	}
+	return &tls.Config{RootCAs: pool, InsecureSkipVerify: insecure}, nil
+}
+
+// configureHTTPClients applies the proxy and TLS settings to the webhook,
+// OpenAPI, CI API and template download clients. Invalid settings are reported before any message
+// is built.
+func configureHTTPClients() error {
+	proxyURL, err := parseProxySetting()
+	if err != nil {
+		return err
+	}
+	tlsConfig, err := loadTLSConfig()
+	if err != nil {
+		return err
+	}
+	if proxyURL == nil && tlsConfig == nil {
+		return nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if proxyURL != nil {
+		transport.Proxy = proxyFunc(proxyURL, getListSetting("PLUGIN_NO_PROXY"))
+	}
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	webhookClient = &http.Client{Timeout: webhookClient.Timeout, Transport: transport}
+	openAPIClient = &http.Client{Timeout: openAPIClient.Timeout, Transport: transport}
+	ciAPIClient = &http.Client{Timeout: ciAPIClient.Timeout, Transport: transport}
+	templateFetchClient = &http.Client{Timeout: templateFetchClient.Timeout, Transport: transport}
+	return nil
+}
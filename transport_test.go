@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// restoreHTTPClients puts back every client configureHTTPClients replaces
+// when the test ends, so proxy and TLS settings don't leak into other tests
+func restoreHTTPClients(t *testing.T) {
+	originalWebhook, originalAPI := webhookClient, openAPIClient
+	originalCIAPI, originalTemplate := ciAPIClient, templateFetchClient
+	t.Cleanup(func() {
+		webhookClient, openAPIClient = originalWebhook, originalAPI
+		ciAPIClient, templateFetchClient = originalCIAPI, originalTemplate
+	})
+}
+
+// setupTLSWebhook starts a TLS webhook and returns it with its certificate as PEM
+func setupTLSWebhook(t *testing.T) (*httptest.Server, string) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code": 0}`))
+	}))
+	t.Cleanup(server.Close)
+
+	restoreHTTPClients(t)
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	return server, string(certPEM)
+}
+
+func TestConfigureHTTPClients_CustomCA(t *testing.T) {
+	server, certPEM := setupTLSWebhook(t)
+	certPath := filepath.Join(t.TempDir(), "ca.pem")
+	os.WriteFile(certPath, []byte(certPEM), 0644)
+
+	setEnvFixture(t, map[string]string{"PLUGIN_CA_CERT": ""})
+	if err := configureHTTPClients(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := deliverMessage(context.Background(), server.URL, []byte(`{}`), ""); err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Errorf("Expected the default pool to reject the certificate, got %v", err)
+	}
+
+	for name, caCert := range map[string]string{"PEM content": certPEM, "PEM file": certPath} {
+		t.Run(name, func(t *testing.T) {
+			setEnvFixture(t, map[string]string{"PLUGIN_CA_CERT": caCert})
+			if err := configureHTTPClients(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if err := deliverMessage(context.Background(), server.URL, []byte(`{}`), ""); err != nil {
+				t.Errorf("Expected the custom pool to accept the certificate, got %v", err)
+			}
+			if _, err := fetchRemoteTemplate(server.URL); err != nil {
+				t.Errorf("Expected template downloads to use the custom pool, got %v", err)
+			}
+			if templateFetchClient.Timeout != 10*time.Second {
+				t.Errorf("Expected the template download timeout to be kept, got %s", templateFetchClient.Timeout)
+			}
+		})
+	}
+}
+
+func TestConfigureHTTPClients_InsecureSkipVerify(t *testing.T) {
+	server, _ := setupTLSWebhook(t)
+	setEnvFixture(t, map[string]string{"PLUGIN_INSECURE_SKIP_VERIFY": "true"})
+
+	output := captureStdout(t, func() {
+		if err := configureHTTPClients(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+	if !strings.Contains(output, "Warning: PLUGIN_INSECURE_SKIP_VERIFY is enabled") {
+		t.Errorf("Expected a warning, got %q", output)
+	}
+	if err := deliverMessage(context.Background(), server.URL, []byte(`{}`), ""); err != nil {
+		t.Errorf("Expected verification to be skipped, got %v", err)
+	}
+}
+
+func TestConfigureHTTPClients_InvalidCA(t *testing.T) {
+	invalidPath := filepath.Join(t.TempDir(), "invalid.pem")
+	os.WriteFile(invalidPath, []byte("not a certificate"), 0644)
+
+	tests := map[string]string{
+		"Missing file":    filepath.Join(t.TempDir(), "missing.pem"),
+		"Invalid file":    invalidPath,
+		"Invalid content": "-----BEGIN CERTIFICATE-----\nbroken\n-----END CERTIFICATE-----",
+	}
+	for name, caCert := range tests {
+		t.Run(name, func(t *testing.T) {
+			setEnvFixture(t, map[string]string{"PLUGIN_CA_CERT": caCert})
+			if err := configureHTTPClients(); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import "strings"
+
+// getTriggeredBy returns who started the pipeline: PLUGIN_TRIGGERED_BY, the
+// trigger reported by the CI system, or the deployer or creator of manual and
+// deployment pipelines. Drone's system triggers such as @hook and @cron are
+// not people and are ignored.
+func getTriggeredBy() string {
+	if triggeredBy := getEnvOrDefault("PLUGIN_TRIGGERED_BY", ""); triggeredBy != "" {
+		return triggeredBy
+	}
+	if trigger := getEnvOrDefault("CI_PIPELINE_TRIGGER", getEnvOrDefault("DRONE_BUILD_TRIGGER", "")); trigger != "" && !strings.HasPrefix(trigger, "@") {
+		return trigger
+	}
+
+	switch getEnvOrDefault("CI_PIPELINE_EVENT", "") {
+	case "manual", "deployment":
+		return getEnvOrDefault("CI_PIPELINE_DEPLOYER", getEnvOrDefault("CI_PIPELINE_CREATOR", ""))
+	}
+	return ""
+}
+
+// authorFields returns the label/value pairs naming the commit author and, when
+// it is someone else, the person who triggered the pipeline.
+func authorFields() [][2]string {
+	// Nobody pushed anything for a scheduled pipeline, the schedule replaces the author
+	if job := cronJob(); job != "" {
+		return [][2]string{{"Cron Job", job}}
+	}
+
+	author := getEnvOrDefault("CI_COMMIT_AUTHOR", "")
+	triggeredBy := getTriggeredBy()
+
+	switch {
+	case triggeredBy == "":
+		return [][2]string{{"Author", author}}
+	case strings.EqualFold(triggeredBy, author):
+		return [][2]string{{"Author / Triggered by", author}}
+	default:
+		return [][2]string{{"Author", author}, {"Triggered by", triggeredBy}}
+	}
+}
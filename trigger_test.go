@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAuthorFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected [][2]string
+	}{
+		{
+			name:     "Push event ignores the deployer",
+			env:      map[string]string{"CI_PIPELINE_EVENT": "push", "CI_COMMIT_AUTHOR": "alice", "CI_PIPELINE_DEPLOYER": "bob"},
+			expected: [][2]string{{"Author", "alice"}},
+		},
+		{
+			name:     "Manual event without trigger variables",
+			env:      map[string]string{"CI_PIPELINE_EVENT": "manual", "CI_COMMIT_AUTHOR": "alice"},
+			expected: [][2]string{{"Author", "alice"}},
+		},
+		{
+			name:     "Manual event by someone else",
+			env:      map[string]string{"CI_PIPELINE_EVENT": "manual", "CI_COMMIT_AUTHOR": "alice", "CI_PIPELINE_CREATOR": "bob"},
+			expected: [][2]string{{"Author", "alice"}, {"Triggered by", "bob"}},
+		},
+		{
+			name:     "Deployment prefers the deployer",
+			env:      map[string]string{"CI_PIPELINE_EVENT": "deployment", "CI_COMMIT_AUTHOR": "alice", "CI_PIPELINE_CREATOR": "bob", "CI_PIPELINE_DEPLOYER": "carol"},
+			expected: [][2]string{{"Author", "alice"}, {"Triggered by", "carol"}},
+		},
+		{
+			name:     "Restart by someone else",
+			env:      map[string]string{"CI_PIPELINE_EVENT": "push", "CI_COMMIT_AUTHOR": "alice", "CI_PIPELINE_TRIGGER": "bob"},
+			expected: [][2]string{{"Author", "alice"}, {"Triggered by", "bob"}},
+		},
+		{
+			name:     "Drone trigger",
+			env:      map[string]string{"CI_PIPELINE_EVENT": "push", "CI_COMMIT_AUTHOR": "alice", "DRONE_BUILD_TRIGGER": "carol"},
+			expected: [][2]string{{"Author", "alice"}, {"Triggered by", "carol"}},
+		},
+		{
+			name:     "Drone system trigger is ignored",
+			env:      map[string]string{"CI_PIPELINE_EVENT": "push", "CI_COMMIT_AUTHOR": "alice", "DRONE_BUILD_TRIGGER": "@hook"},
+			expected: [][2]string{{"Author", "alice"}},
+		},
+		{
+			name:     "Same person is collapsed",
+			env:      map[string]string{"CI_PIPELINE_EVENT": "manual", "CI_COMMIT_AUTHOR": "alice", "CI_PIPELINE_CREATOR": "Alice"},
+			expected: [][2]string{{"Author / Triggered by", "alice"}},
+		},
+		{
+			name:     "Explicit override",
+			env:      map[string]string{"CI_PIPELINE_EVENT": "push", "CI_COMMIT_AUTHOR": "alice", "PLUGIN_TRIGGERED_BY": "release-bot"},
+			expected: [][2]string{{"Author", "alice"}, {"Triggered by", "release-bot"}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setEnvFixture(t, tc.env)
+
+			if fields := authorFields(); !reflect.DeepEqual(fields, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, fields)
+			}
+		})
+	}
+}
+
+func TestCreateLarkCard_TriggeredBy(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"CI_PIPELINE_EVENT":   "manual",
+		"CI_COMMIT_AUTHOR":    "alice",
+		"CI_PIPELINE_CREATOR": "bob",
+	})
+
+	card := createLarkCard(Config{}, "v1.0.0")
+	elements := card["card"].(map[string]any)["elements"].([]map[string]any)
+	content := elements[0]["text"].(map[string]any)["content"].(string)
+
+	if !strings.Contains(content, "**Author:** alice\n**Triggered by:** bob\n") {
+		t.Errorf("Expected author and trigger lines, got %q", content)
+	}
+
+	message := createLarkTextMessage(Config{}, "v1.0.0")
+	text := message["content"].(map[string]any)["text"].(string)
+	if !strings.Contains(text, "👤 Author: alice\n👤 Triggered by: bob\n") {
+		t.Errorf("Expected author and trigger lines, got %q", text)
+	}
+
+	os.Unsetenv("CI_PIPELINE_CREATOR")
+	card = createLarkCard(Config{}, "v1.0.0")
+	elements = card["card"].(map[string]any)["elements"].([]map[string]any)
+	content = elements[0]["text"].(map[string]any)["content"].(string)
+	if strings.Contains(content, "Triggered by") {
+		t.Errorf("Expected no trigger line, got %q", content)
+	}
+}
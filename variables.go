@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// notSetValue is shown for unset variables when PLUGIN_VARIABLES_SKIP_EMPTY is false
+const notSetValue = "(not set)"
+
+// displayVariable is one PLUGIN_VARIABLES entry, "NAME" or "NAME=Label"
+type displayVariable struct {
+	Name  string
+	Label string
+}
+
+// warnedVariableLabels remembers duplicate labels that were already reported
+var warnedVariableLabels = map[string]bool{}
+
+// parseVariables parses the PLUGIN_VARIABLES entries. Entries reusing a label
+// are dropped with a warning.
+func parseVariables(entries []string) []displayVariable {
+	var variables []displayVariable
+	labels := map[string]bool{}
+	for _, entry := range entries {
+		name, label, _ := strings.Cut(entry, "=")
+		variable := displayVariable{Name: strings.TrimSpace(name), Label: strings.TrimSpace(label)}
+		if variable.Name == "" {
+			continue
+		}
+
+		if variable.Label != "" {
+			if labels[variable.Label] {
+				if !warnedVariableLabels[variable.Label] {
+					warnedVariableLabels[variable.Label] = true
+					logWarn(fmt.Sprintf("PLUGIN_VARIABLES uses the label %q more than once, skipping %s", variable.Label, variable.Name))
+				}
+				continue
+			}
+			labels[variable.Label] = true
+		}
+		variables = append(variables, variable)
+	}
+	return variables
+}
+
+// variableEntry is a variable as it is listed in a message
+type variableEntry struct {
+	displayVariable
+	// Value is empty when only names are shown
+	Value string
+}
+
+// variableEntries resolves the variables to list. Unset variables are left out,
+// or shown as "(not set)" when PLUGIN_VARIABLES_SKIP_EMPTY is false.
+func variableEntries(names []string) ([]variableEntry, bool) {
+	variables, showValues := visibleVariables(names)
+	skipEmpty := getEnvOrDefault("PLUGIN_VARIABLES_SKIP_EMPTY", "true") != "false"
+
+	var entries []variableEntry
+	for _, variable := range variables {
+		entry := variableEntry{displayVariable: variable}
+		if showValues {
+			entry.Value = variableValue(variable.Name)
+			if entry.Value == "" {
+				if skipEmpty {
+					continue
+				}
+				entry.Value = notSetValue
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, showValues
+}
+
+// markdownTitle is the entry's label in bold, or its name as code
+func (e variableEntry) markdownTitle() string {
+	if e.Label != "" {
+		return fmt.Sprintf("**%s**", e.Label)
+	}
+	return fmt.Sprintf("`%s`", e.Name)
+}
+
+// markdownValue is the entry's value escaped for lark_md
+func (e variableEntry) markdownValue() string {
+	if e.Value == notSetValue {
+		return e.Value
+	}
+	return escapeMarkdown(e.Value)
+}
+
+// textTitle is the entry's label, or its name
+func (e variableEntry) textTitle() string {
+	if e.Label != "" {
+		return e.Label
+	}
+	return e.Name
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseVariables(t *testing.T) {
+	entries := parseList("PLUGIN_VARIABLES", " DEPLOY_ENV = Environment , IMAGE_TAG=Image,BUILD_ID, REGION=Environment ,=Orphan")
+
+	var variables []displayVariable
+	output := captureStdout(t, func() { variables = parseVariables(entries) })
+
+	expected := []displayVariable{
+		{Name: "DEPLOY_ENV", Label: "Environment"},
+		{Name: "IMAGE_TAG", Label: "Image"},
+		{Name: "BUILD_ID"},
+	}
+	if !reflect.DeepEqual(variables, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, variables)
+	}
+	if !strings.Contains(output, `label "Environment" more than once, skipping REGION`) {
+		t.Errorf("Expected a duplicate label warning, got %q", output)
+	}
+}
+
+func TestVariablesSectionLabels(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"DEPLOY_ENV": "staging",
+		"BUILD_ID":   "42",
+	})
+
+	config := Config{Variables: []string{"DEPLOY_ENV=Environment", "BUILD_ID", "UNSET_VAR=Missing"}}
+	elements := createLarkCard(config, "v1.0.0")["card"].(map[string]any)["elements"].([]map[string]any)
+	content := elements[4]["text"].(map[string]any)["content"]
+	if expected := "**Variables:**\n• **Environment**: staging\n• `BUILD_ID`: 42\n"; content != expected {
+		t.Errorf("Expected %q, got %q", expected, content)
+	}
+
+	text := createLarkTextMessage(config, "v1.0.0")["content"].(map[string]any)["text"].(string)
+	if !strings.Contains(text, "📊 Variables:\n• Environment: staging\n• BUILD_ID: 42\n") {
+		t.Errorf("Unexpected variables in text %q", text)
+	}
+}
+
+func TestVariablesNotSet(t *testing.T) {
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_VARIABLES_SKIP_EMPTY": "false",
+		"DEPLOY_ENV":                  "staging",
+	})
+
+	config := Config{Variables: []string{"DEPLOY_ENV=Environment", "UNSET_VAR=Missing"}}
+	elements := createLarkCard(config, "v1.0.0")["card"].(map[string]any)["elements"].([]map[string]any)
+	content := elements[4]["text"].(map[string]any)["content"]
+	if expected := "**Variables:**\n• **Environment**: staging\n• **Missing**: (not set)\n"; content != expected {
+		t.Errorf("Expected %q, got %q", expected, content)
+	}
+
+	text := createLarkTextMessage(config, "v1.0.0")["content"].(map[string]any)["text"].(string)
+	if !strings.Contains(text, "• Missing: (not set)\n") {
+		t.Errorf("Unexpected variables in text %q", text)
+	}
+}
+
+func TestVariablesAllUnsetSkipsSection(t *testing.T) {
+	setEnvFixture(t, map[string]string{})
+
+	if entries, _ := variableEntries([]string{"UNSET_VAR"}); len(entries) != 0 {
+		t.Errorf("Expected no entries, got %+v", entries)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// Set at build time, e.g.
+// -ldflags "-X main.version=v1.4.0 -X main.commit=0123abc -X main.buildDate=2025-01-31T12:00:00Z"
+var (
+	version   = ""
+	commit    = ""
+	buildDate = ""
+)
+
+// readBuildInfo is overridable in tests
+var readBuildInfo = debug.ReadBuildInfo
+
+// pluginVersion returns the version, commit and build date of the binary.
+// Values not stamped with -ldflags come from the module and VCS information
+// embedded by the go command.
+func pluginVersion() (string, string, string) {
+	v, c, d := version, commit, buildDate
+	if info, ok := readBuildInfo(); ok {
+		if v == "" && info.Main.Version != "" {
+			v = info.Main.Version
+		}
+		for _, setting := range info.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && c == "":
+				c = setting.Value
+			case setting.Key == "vcs.time" && d == "":
+				d = setting.Value
+			}
+		}
+	}
+
+	if v == "" {
+		v = "(devel)"
+	}
+	if len(c) > 7 {
+		c = c[:7]
+	}
+	if c == "" {
+		c = "unknown"
+	}
+	if d == "" {
+		d = "unknown"
+	}
+	return v, c, d
+}
+
+// versionString describes the binary, as printed by --version
+func versionString() string {
+	v, c, d := pluginVersion()
+	return fmt.Sprintf("ci-lark-notification %s (commit %s, built %s)", v, c, d)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"runtime/debug"
+	"strings"
+	"testing"
+)
+
+// setVersionFixture stamps the version variables for a test
+func setVersionFixture(t *testing.T, v, c, d string, info *debug.BuildInfo) {
+	originalVersion, originalCommit, originalBuildDate, originalReadBuildInfo := version, commit, buildDate, readBuildInfo
+	t.Cleanup(func() {
+		version, commit, buildDate, readBuildInfo = originalVersion, originalCommit, originalBuildDate, originalReadBuildInfo
+	})
+	version, commit, buildDate = v, c, d
+	readBuildInfo = func() (*debug.BuildInfo, bool) { return info, info != nil }
+}
+
+func TestMain_Version(t *testing.T) {
+	setVersionFixture(t, "v1.4.0", "0123456789abcdef", "2025-01-31T12:00:00Z", nil)
+
+	originalOsExit := osExit
+	originalArgs := os.Args
+	defer func() {
+		osExit = originalOsExit
+		os.Args = originalArgs
+	}()
+	exitCode := 0
+	osExit = func(code int) { exitCode = code }
+
+	expected := "ci-lark-notification v1.4.0 (commit 0123456, built 2025-01-31T12:00:00Z)\n"
+
+	os.Args = []string{"app-entrypoint", "--version"}
+	if output := captureOutput(t, main); output != expected || exitCode != 0 {
+		t.Errorf("Expected %q and exit code 0, got %q and %d", expected, output, exitCode)
+	}
+
+	os.Args = []string{"app-entrypoint"}
+	setEnvFixture(t, map[string]string{"PLUGIN_PRINT_VERSION": "true"})
+	if output := captureOutput(t, main); output != expected || exitCode != 0 {
+		t.Errorf("Expected %q and exit code 0, got %q and %d", expected, output, exitCode)
+	}
+}
+
+func TestPluginVersion_BuildInfo(t *testing.T) {
+	tests := []struct {
+		name     string
+		info     *debug.BuildInfo
+		expected string
+	}{
+		{
+			name: "go install",
+			info: &debug.BuildInfo{
+				Main: debug.Module{Version: "v1.3.2"},
+				Settings: []debug.BuildSetting{
+					{Key: "vcs.revision", Value: "fedcba9876543210"},
+					{Key: "vcs.time", Value: "2025-01-02T03:04:05Z"},
+				},
+			},
+			expected: "ci-lark-notification v1.3.2 (commit fedcba9, built 2025-01-02T03:04:05Z)",
+		},
+		{
+			name:     "No information",
+			expected: "ci-lark-notification (devel) (commit unknown, built unknown)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setVersionFixture(t, "", "", "", tt.info)
+			if actual := versionString(); actual != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFooterLine_PluginVersion(t *testing.T) {
+	setVersionFixture(t, "v1.4.0", "0123456789abcdef", "", nil)
+	setEnvFixture(t, map[string]string{"PLUGIN_SHOW_PLUGIN_VERSION": "true", "CI_MACHINE": "runner-1"})
+
+	if footer := footerLine(); !strings.HasSuffix(footer, " · ci-lark-notification v1.4.0 (0123456)") {
+		t.Errorf("Expected the plugin version at the end of the footer, got %q", footer)
+	}
+}
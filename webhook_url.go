@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// larkWebhookHosts are the hosts of Lark and Feishu bot webhooks
+var larkWebhookHosts = map[string]bool{
+	"open.feishu.cn":     true,
+	"open.larksuite.com": true,
+}
+
+// larkWebhookPath is the path prefix of bot webhooks on larkWebhookHosts
+const larkWebhookPath = "/open-apis/bot/v2/hook/"
+
+// cleanWebhookURL removes whitespace and the quotes that YAML leaves around
+// a value quoted twice
+func cleanWebhookURL(raw string) string {
+	cleaned := strings.TrimSpace(raw)
+	for len(cleaned) >= 2 && (cleaned[0] == '"' || cleaned[0] == '\'') && cleaned[len(cleaned)-1] == cleaned[0] {
+		cleaned = strings.TrimSpace(cleaned[1 : len(cleaned)-1])
+	}
+	return cleaned
+}
+
+// checkWebhookURL reports what is wrong with a webhook URL of the provider.
+// Hosts that are not Lark's only get a warning, as webhooks may go through
+// a self-hosted gateway. Plain http is accepted for loopback addresses only.
+func checkWebhookURL(rawURL, provider string) (warning string, err error) {
+	target := strings.TrimSuffix(rawURL, publicTargetSuffix)
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", fmt.Errorf("webhook URL %q cannot be parsed: %v", target, err)
+	}
+	switch {
+	case u.Scheme == "" && u.Host == "":
+		return "", fmt.Errorf("webhook URL %q is not an absolute URL, expected https://...", target)
+	case u.Scheme == "http" && !isLoopbackHost(u.Hostname()):
+		return "", fmt.Errorf("webhook URL uses http, %s requires https", providerName(provider))
+	case u.Scheme != "https" && u.Scheme != "http":
+		return "", fmt.Errorf("webhook URL uses %s, %s requires https", u.Scheme, providerName(provider))
+	case u.Hostname() == "":
+		return "", fmt.Errorf("webhook URL %q has no host", target)
+	}
+	if provider != providerLark || isLoopbackHost(u.Hostname()) {
+		return "", nil
+	}
+
+	host := strings.ToLower(u.Hostname())
+	switch {
+	case larkWebhookHosts[host] && !strings.HasPrefix(u.Path, larkWebhookPath):
+		return "", fmt.Errorf("webhook URL path %s is not a bot webhook, expected %s...", u.Path, larkWebhookPath)
+	case larkWebhookHosts[host]:
+		return "", nil
+	case strings.HasSuffix(host, ".feishu.cn") || strings.HasSuffix(host, ".larksuite.com"):
+		return "", fmt.Errorf("webhook URL points to %s, which is not a bot webhook; copy the webhook address from the bot settings of the group", host)
+	}
+	return fmt.Sprintf("webhook host %s is not a Lark host, assuming a gateway", host), nil
+}
+
+// warnWebhookURLs logs the warnings of checkWebhookURL
+func warnWebhookURLs(config Config) {
+	for _, webhookURL := range config.allWebhookURLs() {
+		if warning, _ := checkWebhookURL(webhookURL, config.Provider); warning != "" {
+			logWarn(warning)
+		}
+	}
+}
+
+// isLoopbackHost reports whether host is localhost or a loopback address
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// providerName is the name of a provider as shown in messages
+func providerName(provider string) string {
+	switch provider {
+	case providerWeCom:
+		return "WeCom"
+	case providerDingTalk:
+		return "DingTalk"
+	default:
+		return "Lark"
+	}
+}
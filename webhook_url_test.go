@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckWebhookURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		provider string
+		wantErr  string
+		warning  string
+	}{
+		{"Lark webhook", "https://open.larksuite.com/open-apis/bot/v2/hook/abc", providerLark, "", ""},
+		{"Feishu webhook", "https://open.feishu.cn/open-apis/bot/v2/hook/abc", providerLark, "", ""},
+		{"Surrounding quotes and whitespace", ` "https://open.feishu.cn/open-apis/bot/v2/hook/abc" `, providerLark, "", ""},
+		{"Public target", "https://open.feishu.cn/open-apis/bot/v2/hook/abc#public", providerLark, "", ""},
+		{"Plain http", "http://open.feishu.cn/open-apis/bot/v2/hook/abc", providerLark, "webhook URL uses http, Lark requires https", ""},
+		{"Missing scheme", "open.feishu.cn/open-apis/bot/v2/hook/abc", providerLark, `webhook URL "open.feishu.cn/open-apis/bot/v2/hook/abc" is not an absolute URL`, ""},
+		{"Group chat link", "https://applink.feishu.cn/client/chat/chatter/add_by_link?link_token=abc", providerLark, "webhook URL points to applink.feishu.cn, which is not a bot webhook", ""},
+		{"Wrong path", "https://open.larksuite.com/open-apis/im/v1/chats/oc_123", providerLark, "webhook URL path /open-apis/im/v1/chats/oc_123 is not a bot webhook", ""},
+		{"Control character", "https://open.feishu.cn/open-apis/bot/v2/hook/abc\x7f", providerLark, "cannot be parsed", ""},
+		{"Other scheme", "ftp://open.feishu.cn/hook", providerLark, "webhook URL uses ftp, Lark requires https", ""},
+		{"Gateway", "https://lark-gateway.corp.example.com/hook/abc", providerLark, "", "webhook host lark-gateway.corp.example.com is not a Lark host, assuming a gateway"},
+		{"Local test server", "http://127.0.0.1:8080/hook", providerLark, "", ""},
+		{"WeCom over http", "http://qyapi.weixin.qq.com/cgi-bin/webhook/send?key=abc", providerWeCom, "webhook URL uses http, WeCom requires https", ""},
+		{"DingTalk", "https://oapi.dingtalk.com/robot/send?access_token=abc", providerDingTalk, "", ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			warning, err := checkWebhookURL(cleanWebhookURL(tc.raw), tc.provider)
+			if tc.wantErr == "" && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+			if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Errorf("Expected an error containing %q, got %v", tc.wantErr, err)
+			}
+			if warning != tc.warning {
+				t.Errorf("Expected warning %q, got %q", tc.warning, warning)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_CleansWebhookURLs(t *testing.T) {
+	config, err := LoadConfig(mapGetenv(map[string]string{
+		"PLUGIN_WEBHOOK_URL": `'https://open.feishu.cn/open-apis/bot/v2/hook/abc'`,
+	}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.WebhookURLs[0] != "https://open.feishu.cn/open-apis/bot/v2/hook/abc" {
+		t.Errorf("Expected the quotes to be removed, got %q", config.WebhookURLs[0])
+	}
+
+	_, err = LoadConfig(mapGetenv(map[string]string{
+		"PLUGIN_WEBHOOK_URL": "http://open.feishu.cn/open-apis/bot/v2/hook/abc",
+	}))
+	if err == nil || !strings.Contains(err.Error(), "webhook URL uses http, Lark requires https") {
+		t.Errorf("Expected the http URL to be rejected, got %v", err)
+	}
+}
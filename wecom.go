@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+)
+
+// weComColors maps header colors to the font colors of WeCom markdown
+var weComColors = map[string]string{
+	"green": "info",
+	"red":   "warning",
+}
+
+// weComProvider sends markdown messages to WeCom (企业微信) group robots,
+// which have no signature
+type weComProvider struct{}
+
+func (weComProvider) buildMessage(config Config, projectVersion string, prebuilt map[string]any) (map[string]any, error) {
+	if prebuilt != nil {
+		return maps.Clone(prebuilt), nil
+	}
+	return createWeComMessage(resolveBuildSummary(config, projectVersion)), nil
+}
+
+func (weComProvider) payloadFor(target string, messageBytes []byte, now time.Time) []byte {
+	return messageBytes
+}
+
+func (weComProvider) deliver(ctx context.Context, target string, messageBytes []byte) error {
+	return deliverErrcodeWebhook(ctx, "WeCom", target, messageBytes)
+}
+
+// createWeComMessage renders the summary as a WeCom markdown message
+func createWeComMessage(summary buildSummary) map[string]any {
+	color, ok := weComColors[summary.Style.Color]
+	if !ok {
+		color = "comment"
+	}
+
+	lines := []string{fmt.Sprintf(`## <font color="%s">%s</font>`, color, escapeMarkdown(summary.Title))}
+	for _, field := range summary.Fields {
+		lines = append(lines, fmt.Sprintf("**%s:** %s", field[0], escapeMarkdown(field[1])))
+	}
+	if summary.CommitMessage != "" {
+		lines = append(lines, fmt.Sprintf("> %s", strings.ReplaceAll(escapeMarkdown(summary.CommitMessage), "\n", "\n> ")))
+	}
+	if summary.PipelineURL != "" {
+		lines = append(lines, fmt.Sprintf("[%s](%s)", tr(deploymentLabel("View Pipeline")), summary.PipelineURL))
+	}
+
+	return map[string]any{
+		"msgtype": "markdown",
+		"markdown": map[string]any{
+			"content": strings.Join(lines, "\n"),
+		},
+	}
+}
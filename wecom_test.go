@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMain_WeCom(t *testing.T) {
+	server, bodies, _ := setupProviderServer(t, `{"errcode":0,"errmsg":"ok"}`)
+	setEnvFixture(t, providerFixture)
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_PROVIDER":    providerWeCom,
+		"PLUGIN_WEBHOOK_URL": server.URL + "/cgi-bin/webhook/send?key=robot-key",
+	})
+	exitCode := 0
+	osExit = func(code int) { exitCode = code }
+
+	main()
+
+	if exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d", exitCode)
+	}
+	if len(*bodies) != 1 {
+		t.Fatalf("Expected one message, got %d", len(*bodies))
+	}
+	body := (*bodies)[0]
+	if body["msgtype"] != "markdown" || len(body) != 2 {
+		t.Errorf("Expected only msgtype and markdown, got %v", body)
+	}
+	expected := strings.Join([]string{
+		`## <font color="warning">Pipeline Failed</font>`,
+		"**Project:** octo/backend",
+		"**Branch:** main",
+		"**Author:** octocat",
+		"**Version:** abcdef1",
+		"> Fix the build",
+		"[View Pipeline](https://ci.example.com/octo/backend/42)",
+	}, "\n")
+	markdown := body["markdown"].(map[string]any)
+	if markdown["content"] != expected || len(markdown) != 1 {
+		t.Errorf("Expected content:\n%s\ngot:\n%v", expected, markdown)
+	}
+}
+
+func TestMain_WeComError(t *testing.T) {
+	server, _, _ := setupProviderServer(t, `{"errcode":93000,"errmsg":"invalid webhook url"}`)
+	setEnvFixture(t, providerFixture)
+	setEnvFixture(t, map[string]string{
+		"PLUGIN_PROVIDER":    providerWeCom,
+		"PLUGIN_WEBHOOK_URL": server.URL,
+	})
+	exitCode := 0
+	osExit = func(code int) { exitCode = code }
+
+	output := captureOutput(t, main)
+
+	if exitCode != exitRejected {
+		t.Errorf("Expected exit code 4, got %d", exitCode)
+	}
+	if !strings.Contains(output, "Error: WeCom API error 93000: invalid webhook url") || !strings.Contains(output, "errcode=93000") {
+		t.Errorf("Expected the WeCom error, got:\n%s", output)
+	}
+}